@@ -154,6 +154,22 @@ type Wallet interface {
 	SignTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
 }
 
+// MultisigWallet is implemented by wallets able to partially sign the
+// transactions of multisig accounts their accounts are members of.
+type MultisigWallet interface {
+	Wallet
+
+	// SignMultisigTx requests the wallet to add the signature of the given member
+	// account to a multisig transaction, along with the signatures collected so
+	// far. The transaction is complete once enough members signed it, see
+	// types.MultisigKey.
+	SignMultisigTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+
+	// SignMultisigTxWithPassphrase is identical to SignMultisigTx, but also takes
+	// a password
+	SignMultisigTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
 // Backend is a "wallet provider" that may contain a batch of accounts they can
 // sign transactions with and upon request, do so.
 type Backend interface {
@@ -17,6 +17,9 @@
 package accounts
 
 import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -178,3 +181,30 @@ func LedgerLiveIterator(base DerivationPath) func() DerivationPath {
 		return path
 	}
 }
+
+// DeriveSeed derives the key generation seed of an account from a BIP-39 seed
+// and a derivation path, for the deterministic post-quantum keys of mnemonic
+// wallets.
+//
+// Derivation follows SLIP-10 for ed25519 keys
+// (https://github.com/satoshilabs/slips/blob/master/slip-0010.md), which only
+// supports hardened derivation: the post-quantum schemes have no public key
+// derivation either. Every component is therefore derived as hardened, so that
+// m/44'/60'/0'/0/1 gives the same seed as m/44'/60'/0'/0'/1'. The returned 32
+// bytes are the SLIP-10 private key of the path.
+func DeriveSeed(seed []byte, path DerivationPath) []byte {
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	var data [1 + 32 + 4]byte
+	for _, component := range path {
+		copy(data[1:], sum[:32])
+		binary.BigEndian.PutUint32(data[33:], component|0x80000000)
+
+		mac = hmac.New(sha512.New, sum[32:])
+		mac.Write(data[:])
+		sum = mac.Sum(nil)
+	}
+	return sum[:32]
+}
@@ -17,6 +17,7 @@
 package accounts
 
 import (
+	"encoding/hex"
 	"fmt"
 	"reflect"
 	"testing"
@@ -116,3 +117,34 @@ func TestHdPathIteration(t *testing.T) {
 			"m/44'/60'/8'/0/0", "m/44'/60'/9'/0/0",
 		})
 }
+
+// Tests that seeds are derived according to the SLIP-10 ed25519 test vectors.
+func TestDeriveSeed(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m", "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7"},
+		{"m/0'", "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3"},
+		{"m/0'/1'", "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2"},
+		{"m/0'/1'/2'", "92a5b23c0b8a99e37d07df3fb9966917f5d06e02ddbd909c7e184371463e9fc9"},
+		{"m/0'/1'/2'/2'", "30d1dc7e5fc04c31219ab25a27ae00b50f6fd66622f6e9c913253d6511d1e662"},
+		{"m/0'/1'/2'/2'/1000000000'", "8f94d394a8e8fd6b1bc2f3f49f5c47e385281d5c17e65324b0f62483e37e8793"},
+
+		// Non hardened components are derived hardened
+		{"m/0/1'/2/2'/1000000000", "8f94d394a8e8fd6b1bc2f3f49f5c47e385281d5c17e65324b0f62483e37e8793"},
+	}
+	for i, tt := range tests {
+		var path DerivationPath
+		if tt.path != "m" {
+			var err error
+			if path, err = ParseDerivationPath(tt.path); err != nil {
+				t.Fatalf("test %d: %v", i, err)
+			}
+		}
+		if key := hex.EncodeToString(DeriveSeed(seed, path)); key != tt.key {
+			t.Errorf("test %d: %s: key mismatch: have %s, want %s", i, tt.path, key, tt.key)
+		}
+	}
+}
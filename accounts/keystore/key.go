@@ -35,7 +35,8 @@ import (
 )
 
 const (
-	version = 3
+	version   = 3
+	versionV4 = 4
 )
 
 type Key struct {
@@ -70,6 +71,21 @@ type encryptedKeyJSONV3 struct {
 	Version int        `json:"version"`
 }
 
+type encryptedKeyJSONV4 struct {
+	Address   string           `json:"address"`
+	Crypto    CryptoJSON       `json:"crypto"`
+	Algorithm keyAlgorithmJSON `json:"algorithm"`
+	Id        string           `json:"id"`
+	Version   int              `json:"version"`
+}
+
+// keyAlgorithmJSON identifies the signature algorithm of the key of a v4 key
+// file, by its ID in cryptobase and name.
+type keyAlgorithmJSON struct {
+	Id   byte   `json:"id"`
+	Name string `json:"name"`
+}
+
 type encryptedKeyJSONV1 struct {
 	Address string     `json:"address"`
 	Crypto  CryptoJSON `json:"crypto"`
@@ -83,7 +99,7 @@ type CryptoJSON struct {
 	CipherParams cipherparamsJSON       `json:"cipherparams"`
 	KDF          string                 `json:"kdf"`
 	KDFParams    map[string]interface{} `json:"kdfparams"`
-	MAC          string                 `json:"mac"`
+	MAC          string                 `json:"mac,omitempty"`
 }
 
 type cipherparamsJSON struct {
@@ -22,9 +22,11 @@ package keystore
 
 import (
 	crand "crypto/rand"
+	"encoding/json"
 	"errors"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"path/filepath"
@@ -314,6 +316,32 @@ func (ks *KeyStore) SignTxWithPassphrase(a accounts.Account, passphrase string,
 	return types.SignTx(tx, signer, key.PrivateKey)
 }
 
+// SignMultisigTx adds the signature of the requested member account to a
+// multisig transaction.
+func (ks *KeyStore) SignMultisigTx(a accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	// Look up the key to sign with and abort if it cannot be found
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	unlockedKey, found := ks.unlocked[a.Address]
+	if !found {
+		return nil, ErrLocked
+	}
+	return types.SignMultisigTx(tx, types.LatestSignerForChainID(chainID), unlockedKey.PrivateKey)
+}
+
+// SignMultisigTxWithPassphrase adds the signature of the requested member
+// account to a multisig transaction if its private key can be decrypted with
+// the given passphrase.
+func (ks *KeyStore) SignMultisigTxWithPassphrase(a accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	_, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key.PrivateKey)
+	return types.SignMultisigTx(tx, types.LatestSignerForChainID(chainID), key.PrivateKey)
+}
+
 // Unlock unlocks the given account indefinitely.
 func (ks *KeyStore) Unlock(a accounts.Account, passphrase string) error {
 	return ks.TimedUnlock(a, passphrase, 0)
@@ -496,6 +524,31 @@ func (ks *KeyStore) Update(a accounts.Account, passphrase, newPassphrase string)
 	return ks.storage.StoreKey(a.URL.Path, key, newPassphrase)
 }
 
+// NeedsUpgrade reports whether the key file of an account is encrypted in an
+// older format than the one keys are stored in, in which case Update rewrites
+// it in the current format.
+func (ks *KeyStore) NeedsUpgrade(a accounts.Account) (bool, error) {
+	if _, ok := ks.storage.(*keyStorePassphrase); !ok {
+		return false, nil
+	}
+	a, err := ks.Find(a)
+	if err != nil {
+		return false, err
+	}
+	keyjson, err := ioutil.ReadFile(a.URL.Path)
+	if err != nil {
+		return false, err
+	}
+	var k struct {
+		Version interface{} `json:"version"`
+	}
+	if err := json.Unmarshal(keyjson, &k); err != nil {
+		return false, err
+	}
+	v, ok := k.Version.(float64)
+	return !ok || v < versionV4, nil
+}
+
 // ImportPreSaleKey decrypts the given Ethereum presale wallet and stores
 // a key file in the key directory. The key file is encrypted with the same passphrase.
 func (ks *KeyStore) ImportPreSaleKey(keyJSON []byte, passphrase string) (accounts.Account, error) {
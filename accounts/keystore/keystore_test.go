@@ -17,8 +17,10 @@
 package keystore
 
 import (
+	"context"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"io/ioutil"
+	"math/big"
 	"math/rand"
 	"os"
 	"runtime"
@@ -31,6 +33,7 @@ import (
 
 	"github.com/DogeProtocol/dp/accounts"
 	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
 	"github.com/DogeProtocol/dp/event"
 )
 
@@ -116,6 +119,60 @@ func TestSignWithPassphrase(t *testing.T) {
 	}
 }
 
+// Tests that member accounts can partially sign multisig transactions, locked
+// or unlocked, until the threshold is met.
+func TestSignMultisigTx(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	pass := "passwd"
+	var (
+		accs []accounts.Account
+		pubs []types.MultisigPublicKey
+	)
+	for i := 0; i < 3; i++ {
+		acc, err := ks.NewAccount(pass)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, key, err := ks.getDecryptedKey(acc, pass)
+		if err != nil {
+			t.Fatal(err)
+		}
+		accs = append(accs, acc)
+		pubs = append(pubs, types.MultisigPublicKey{Algorithm: cryptobase.SigAlgIdHybrid, PublicKey: key.PrivateKey.PublicKey.PubData})
+	}
+	key, err := types.NewMultisigKey(2, pubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewTx(&types.SignatureTx{
+		ChainID:   common.Big1,
+		Algorithm: cryptobase.SigAlgIdMultisig,
+		PublicKey: key.Bytes(),
+	})
+	if _, err := ks.SignMultisigTx(accs[0], tx, common.Big1); err != ErrLocked {
+		t.Fatalf("locked account: have %v, want %v", err, ErrLocked)
+	}
+	if _, err := ks.SignMultisigTxWithPassphrase(accs[0], "invalid passwd", tx, common.Big1); err == nil {
+		t.Fatal("expected SignMultisigTxWithPassphrase to fail with invalid password")
+	}
+	tx, err = ks.SignMultisigTxWithPassphrase(accs[0], pass, tx, common.Big1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(accs[2], pass); err != nil {
+		t.Fatal(err)
+	}
+	tx, err = ks.SignMultisigTx(accs[2], tx, common.Big1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := types.Sender(types.LatestSignerForChainID(common.Big1), tx); err != nil || from != key.Address() {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, key.Address())
+	}
+}
+
 func TestTimedUnlock(t *testing.T) {
 	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
@@ -425,6 +482,119 @@ func TestImportRace(t *testing.T) {
 	}
 }
 
+// Tests that mnemonic accounts can be recovered from their mnemonic.
+func TestImportMnemonic(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+	acc, mnemonic, err := ks.NewAccountWithMnemonic("foo")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	if _, err := ks.ImportMnemonic(mnemonic, "", accounts.DefaultBaseDerivationPath, "bar"); err != ErrAccountAlreadyExists {
+		t.Errorf("importing same mnemonic twice: have %v, want %v", err, ErrAccountAlreadyExists)
+	}
+	dir2, ks2 := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir2)
+	if _, err := ks2.ImportMnemonic(mnemonic+" abandon", "", accounts.DefaultBaseDerivationPath, "bar"); err != ErrInvalidMnemonic {
+		t.Errorf("importing invalid mnemonic: have %v, want %v", err, ErrInvalidMnemonic)
+	}
+	acc2, err := ks2.ImportMnemonic(mnemonic, "", accounts.DefaultBaseDerivationPath, "bar")
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	if acc2.Address != acc.Address {
+		t.Errorf("imported account mismatch: have %x, want %x", acc2.Address, acc.Address)
+	}
+}
+
+// Tests that keys derived from a mnemonic are stable, and follow the default
+// derivation paths.
+func TestDeriveMnemonicKeys(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	keys, err := DeriveMnemonicKeys(mnemonic, "", accounts.DefaultBaseDerivationPath, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]common.Address, len(keys))
+	for i, key := range keys {
+		want[i] = cryptobase.SigAlg.PublicKeyToAddressNoError(&key.PublicKey)
+	}
+	if want[0] == want[1] {
+		t.Fatal("same key for different derivation paths")
+	}
+	path, _ := accounts.ParseDerivationPath("m/44'/60'/0'/0/1")
+	key, err := NewKeyFromMnemonic(mnemonic, "", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := cryptobase.SigAlg.PublicKeyToAddressNoError(&key.PublicKey); addr != want[1] {
+		t.Errorf("address mismatch: have %x, want %x", addr, want[1])
+	}
+	if key, err = NewKeyFromMnemonic(mnemonic, "passphrase", path); err != nil {
+		t.Fatal(err)
+	}
+	if addr := cryptobase.SigAlg.PublicKeyToAddressNoError(&key.PublicKey); addr == want[1] {
+		t.Error("same key with and without mnemonic passphrase")
+	}
+}
+
+// usedAccountsChain is a chain state on which only some accounts have a nonce.
+type usedAccountsChain map[common.Address]bool
+
+func (c usedAccountsChain) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return new(big.Int), nil
+}
+
+func (c usedAccountsChain) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (c usedAccountsChain) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (c usedAccountsChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	if c[account] {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Tests that mnemonic accounts are discovered up to the first unused one.
+func TestDiscoverMnemonicAccounts(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	keys, err := DeriveMnemonicKeys(mnemonic, "", accounts.DefaultBaseDerivationPath, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addrs []common.Address
+	for _, key := range keys {
+		addrs = append(addrs, cryptobase.SigAlg.PublicKeyToAddressNoError(&key.PublicKey))
+	}
+	// Only the first account is imported if none is used
+	accs, err := ks.DiscoverMnemonicAccounts(context.Background(), mnemonic, "", accounts.DefaultBaseDerivationPath, "foo", usedAccountsChain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accs) != 1 || accs[0].Address != addrs[0] {
+		t.Fatalf("unused mnemonic: have %v, want account %x", accs, addrs[0])
+	}
+	// Used accounts are imported along the already known ones
+	accs, err = ks.DiscoverMnemonicAccounts(context.Background(), mnemonic, "", accounts.DefaultBaseDerivationPath, "foo", usedAccountsChain{addrs[0]: true, addrs[1]: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accs) != 2 || accs[0].Address != addrs[0] || accs[1].Address != addrs[1] {
+		t.Fatalf("used mnemonic: have %v, want accounts %x", accs, addrs)
+	}
+	if len(ks.Accounts()) != 2 {
+		t.Errorf("keystore accounts: have %d, want 2", len(ks.Accounts()))
+	}
+}
+
 // checkAccounts checks that all known live accounts are present in the wallet list.
 func checkAccounts(t *testing.T, live map[common.Address]accounts.Account, wallets []accounts.Wallet) {
 	if len(live) != len(wallets) {
@@ -0,0 +1,175 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DogeProtocol/dp"
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/tyler-smith/go-bip39"
+)
+
+var (
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// ErrSeedUnsupported is returned if the signature algorithm in use can't
+	// derive keys from a seed.
+	ErrSeedUnsupported = errors.New("signature algorithm does not support seeded keys")
+)
+
+// NewMnemonic generates a random 24 word BIP-39 mnemonic.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// NewKeyFromMnemonic derives the private key at the given path from a BIP-39
+// mnemonic and its optional passphrase.
+//
+// The mnemonic is turned into a seed as specified by BIP-39, the seed of the
+// path is then derived with accounts.DeriveSeed, and fed to the deterministic
+// key generation of the signature algorithm. The same mnemonic, passphrase and
+// path always give the same key.
+func NewKeyFromMnemonic(mnemonic, passphrase string, path accounts.DerivationPath) (*signaturealgorithm.PrivateKey, error) {
+	derive, err := mnemonicDeriver(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return derive(path)
+}
+
+// DeriveMnemonicKeys derives the first n private keys of a mnemonic, following
+// the paths of accounts.DefaultIterator from base.
+func DeriveMnemonicKeys(mnemonic, passphrase string, base accounts.DerivationPath, n int) ([]*signaturealgorithm.PrivateKey, error) {
+	derive, err := mnemonicDeriver(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		next = accounts.DefaultIterator(base)
+		keys = make([]*signaturealgorithm.PrivateKey, n)
+	)
+	for i := range keys {
+		if keys[i], err = derive(next()); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// mnemonicDeriver returns a function deriving the keys of a mnemonic, computing
+// its BIP-39 seed only once.
+func mnemonicDeriver(mnemonic, passphrase string) (func(accounts.DerivationPath) (*signaturealgorithm.PrivateKey, error), error) {
+	var alg signaturealgorithm.SignatureAlgorithm = cryptobase.SigAlg
+	gen, ok := alg.(signaturealgorithm.SeededKeyGenerator)
+	if !ok {
+		return nil, ErrSeedUnsupported
+	}
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, ErrInvalidMnemonic
+	}
+	return func(path accounts.DerivationPath) (*signaturealgorithm.PrivateKey, error) {
+		return gen.GenerateKeyFromSeed(accounts.DeriveSeed(seed, path))
+	}, nil
+}
+
+// NewAccountWithMnemonic generates a new mnemonic, and stores its key at the
+// default derivation path into the key directory, encrypting it with the
+// passphrase. The mnemonic is returned for the user to back up, the key can be
+// recovered from it with ImportMnemonic.
+func (ks *KeyStore) NewAccountWithMnemonic(passphrase string) (accounts.Account, string, error) {
+	mnemonic, err := NewMnemonic()
+	if err != nil {
+		return accounts.Account{}, "", err
+	}
+	account, err := ks.ImportMnemonic(mnemonic, "", accounts.DefaultBaseDerivationPath, passphrase)
+	if err != nil {
+		return accounts.Account{}, "", err
+	}
+	return account, mnemonic, nil
+}
+
+// ImportMnemonic derives the key at the given path from a mnemonic and its
+// passphrase, and stores it into the key directory, encrypting it with
+// passphrase.
+func (ks *KeyStore) ImportMnemonic(mnemonic, mnemonicPassphrase string, path accounts.DerivationPath, passphrase string) (accounts.Account, error) {
+	priv, err := NewKeyFromMnemonic(mnemonic, mnemonicPassphrase, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	defer zeroKey(priv)
+	return ks.ImportKey(priv, passphrase)
+}
+
+// DiscoverMnemonicAccounts recovers the accounts of a mnemonic in use on the
+// chain. It derives keys following the paths of accounts.DefaultIterator from
+// base, up to the first account with neither balance nor nonce, and stores all
+// of them into the key directory, encrypted with passphrase. The first account
+// is always stored, even if unused. Accounts already in the keystore are
+// returned along the new ones.
+func (ks *KeyStore) DiscoverMnemonicAccounts(ctx context.Context, mnemonic, mnemonicPassphrase string, base accounts.DerivationPath, passphrase string, chain dp.ChainStateReader) ([]accounts.Account, error) {
+	derive, err := mnemonicDeriver(mnemonic, mnemonicPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		next  = accounts.DefaultIterator(base)
+		accs  []accounts.Account
+		empty bool
+	)
+	for !empty {
+		priv, err := derive(next())
+		if err != nil {
+			return accs, err
+		}
+		addr := cryptobase.SigAlg.PublicKeyToAddressNoError(&priv.PublicKey)
+
+		// Stop at the first unused account, keeping it only if it's the first
+		balance, err := chain.BalanceAt(ctx, addr, nil)
+		if err != nil {
+			zeroKey(priv)
+			return accs, err
+		}
+		nonce, err := chain.NonceAt(ctx, addr, nil)
+		if err != nil {
+			zeroKey(priv)
+			return accs, err
+		}
+		if empty = balance.Sign() == 0 && nonce == 0; empty && len(accs) > 0 {
+			zeroKey(priv)
+			break
+		}
+		acc, err := ks.ImportKey(priv, passphrase)
+		zeroKey(priv)
+		if err == ErrAccountAlreadyExists {
+			acc, err = ks.Find(accounts.Account{Address: addr})
+		}
+		if err != nil {
+			return accs, err
+		}
+		accs = append(accs, acc)
+	}
+	return accs, nil
+}
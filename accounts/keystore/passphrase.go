@@ -21,6 +21,11 @@ the private key is encrypted and on disk uses another JSON encoding.
 
 The crypto is documented at https://github.com/ethereum/wiki/wiki/Web3-Secret-Storage-Definition
 
+Keys are written in version 4 of the format, which swaps the scrypt KDF, the
+AES-128-CTR cipher and the Keccak MAC of version 3 for Argon2id and an AEAD with
+a 256-bit key, and records the signature algorithm of the key. Version 3 files
+are still read, and are rewritten as version 4 when updated.
+
 */
 
 package keystore
@@ -28,6 +33,7 @@ package keystore
 import (
 	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
@@ -36,6 +42,7 @@ import (
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 
@@ -43,6 +50,8 @@ import (
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/crypto"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
@@ -68,6 +77,25 @@ const (
 
 	scryptR     = 8
 	scryptDKLen = 32
+
+	keyHeaderKDFV4 = "argon2id"
+
+	// CipherAES256GCM and CipherXChaCha20Poly1305 are the ciphers of version 4
+	// key files. Both are AEADs, authenticating the ciphertext in place of the
+	// version 3 MAC.
+	CipherAES256GCM         = "aes-256-gcm"
+	CipherXChaCha20Poly1305 = "xchacha20-poly1305"
+
+	// argon2Time is the number of passes Argon2id makes over its memory, which
+	// is sized from the scrypt parameters, see EncryptDataV4.
+	argon2Time  = 3
+	argon2DKLen = 32
+
+	// argon2MaxTime and argon2MaxMemory bound the Argon2id costs accepted from
+	// key files, so that a crafted file can't make decryption run for hours or
+	// allocate terabytes. The memory limit, 2 GiB, is 8 times the standard cost.
+	argon2MaxTime   = 16
+	argon2MaxMemory = 1 << 21
 )
 
 type keyStorePassphrase struct {
@@ -181,26 +209,103 @@ func EncryptDataV3(data, auth []byte, scryptN, scryptP int) (CryptoJSON, error)
 	return cryptoStruct, nil
 }
 
+// EncryptDataV4 encrypts data with the password auth using the given AEAD
+// cipher, keyed by Argon2id. The Argon2id costs follow the scrypt parameters
+// keystores are configured with: it fills as much memory as scrypt would, N KiB,
+// over P lanes. The additional data is authenticated along with the ciphertext,
+// and must be given again to decrypt it.
+func EncryptDataV4(data, auth, additionalData []byte, cipherName string, scryptN, scryptP int) (CryptoJSON, error) {
+	if scryptN < 1 || scryptN > argon2MaxMemory || scryptP < 1 || scryptP > math.MaxUint8 {
+		return CryptoJSON{}, fmt.Errorf("invalid Argon2id parameters: m=%d p=%d", scryptN, scryptP)
+	}
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	derivedKey := argon2.IDKey(auth, salt, argon2Time, uint32(scryptN), uint8(scryptP), argon2DKLen)
+
+	aead, err := newAEAD(cipherName, derivedKey)
+	if err != nil {
+		return CryptoJSON{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	cipherText := aead.Seal(nil, nonce, data, additionalData)
+
+	argon2ParamsJSON := make(map[string]interface{}, 5)
+	argon2ParamsJSON["t"] = argon2Time
+	argon2ParamsJSON["m"] = scryptN
+	argon2ParamsJSON["p"] = scryptP
+	argon2ParamsJSON["dklen"] = argon2DKLen
+	argon2ParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	cryptoStruct := CryptoJSON{
+		Cipher:       cipherName,
+		CipherText:   hex.EncodeToString(cipherText),
+		CipherParams: cipherparamsJSON{IV: hex.EncodeToString(nonce)},
+		KDF:          keyHeaderKDFV4,
+		KDFParams:    argon2ParamsJSON,
+	}
+	return cryptoStruct, nil
+}
+
 // EncryptKey encrypts a key using the specified scrypt parameters into a json
-// blob that can be decrypted later on.
+// blob that can be decrypted later on. The key is stored in version 4 of the
+// format with AES-256-GCM, see EncryptDataV4 for how the parameters are used.
 func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
+	return EncryptKeyWithCipher(key, auth, CipherAES256GCM, scryptN, scryptP)
+}
 
+// EncryptKeyWithCipher is like EncryptKey, but encrypts the key with the given
+// cipher of the version 4 format.
+func EncryptKeyWithCipher(key *Key, auth, cipherName string, scryptN, scryptP int) ([]byte, error) {
 	keyBytes, err := cryptobase.SigAlg.SerializePrivateKey(key.PrivateKey)
 	if err != nil {
 		return nil, err
 	}
-
-	cryptoStruct, err := EncryptDataV3(keyBytes, []byte(auth), scryptN, scryptP)
+	alg := keyAlgorithm()
+	cryptoStruct, err := EncryptDataV4(keyBytes, []byte(auth), keyAdditionalData(alg), cipherName, scryptN, scryptP)
 	if err != nil {
 		return nil, err
 	}
-	encryptedKeyJSONV3 := encryptedKeyJSONV3{
+	encryptedKeyJSONV4 := encryptedKeyJSONV4{
 		hex.EncodeToString(key.Address[:]),
 		cryptoStruct,
+		alg,
 		key.Id.String(),
-		version,
+		versionV4,
+	}
+	return json.Marshal(encryptedKeyJSONV4)
+}
+
+// keyAlgorithm returns the metadata of the signature algorithm keystores hold
+// keys of.
+func keyAlgorithm() keyAlgorithmJSON {
+	return keyAlgorithmJSON{Id: cryptobase.SigAlgIdHybrid, Name: cryptobase.SigAlg.SignatureName()}
+}
+
+// keyAdditionalData returns the data authenticated along with the key of a
+// version 4 key file, binding the format version and the key algorithm to it.
+func keyAdditionalData(alg keyAlgorithmJSON) []byte {
+	return []byte{versionV4, alg.Id}
+}
+
+// newAEAD creates the cipher of a version 4 key file.
+func newAEAD(cipherName string, key []byte) (cipher.AEAD, error) {
+	switch cipherName {
+	case CipherAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("cipher not supported: %v", cipherName)
 	}
-	return json.Marshal(encryptedKeyJSONV3)
 }
 
 // DecryptKey decrypts a key from a json blob, returning the private key itself.
@@ -221,6 +326,12 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 			return nil, err
 		}
 		keyBytes, keyId, err = decryptKeyV1(k, auth)
+	} else if version, ok := m["version"].(float64); ok && version == versionV4 {
+		k := new(encryptedKeyJSONV4)
+		if err := json.Unmarshal(keyjson, k); err != nil {
+			return nil, err
+		}
+		keyBytes, keyId, err = decryptKeyV4(k, auth)
 	} else {
 		k := new(encryptedKeyJSONV3)
 		if err := json.Unmarshal(keyjson, k); err != nil {
@@ -288,6 +399,60 @@ func DecryptDataV3(cryptoJson CryptoJSON, auth string) ([]byte, error) {
 	return plainText, err
 }
 
+// DecryptDataV4 decrypts data encrypted by EncryptDataV4 with the password auth
+// and the same additional data.
+func DecryptDataV4(cryptoJson CryptoJSON, auth string, additionalData []byte) ([]byte, error) {
+	if cryptoJson.Cipher != CipherAES256GCM && cryptoJson.Cipher != CipherXChaCha20Poly1305 {
+		return nil, fmt.Errorf("cipher not supported: %v", cryptoJson.Cipher)
+	}
+	if cryptoJson.KDF != keyHeaderKDFV4 {
+		return nil, fmt.Errorf("KDF not supported: %v", cryptoJson.KDF)
+	}
+	nonce, err := hex.DecodeString(cryptoJson.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(cryptoJson.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := getKDFKey(cryptoJson, auth)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(cryptoJson.Cipher, derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length: %d", len(nonce))
+	}
+	plainText, err := aead.Open(nil, nonce, cipherText, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plainText, nil
+}
+
+func decryptKeyV4(keyProtected *encryptedKeyJSONV4, auth string) (keyBytes []byte, keyId []byte, err error) {
+	if keyProtected.Version != versionV4 {
+		return nil, nil, fmt.Errorf("version not supported: %v", keyProtected.Version)
+	}
+	if alg := keyAlgorithm(); keyProtected.Algorithm.Id != alg.Id {
+		return nil, nil, fmt.Errorf("key algorithm not supported: %v (%#x)", keyProtected.Algorithm.Name, keyProtected.Algorithm.Id)
+	}
+	keyUUID, err := uuid.Parse(keyProtected.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyId = keyUUID[:]
+	plainText, err := DecryptDataV4(keyProtected.Crypto, auth, keyAdditionalData(keyProtected.Algorithm))
+	if err != nil {
+		return nil, nil, err
+	}
+	return plainText, keyId, err
+}
+
 func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byte, keyId []byte, err error) {
 	if keyProtected.Version != version {
 		return nil, nil, fmt.Errorf("version not supported: %v", keyProtected.Version)
@@ -364,6 +529,15 @@ func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 		}
 		key := pbkdf2.Key(authArray, salt, c, dkLen, sha256.New)
 		return key, nil
+
+	} else if cryptoJSON.KDF == keyHeaderKDFV4 {
+		t := ensureInt(cryptoJSON.KDFParams["t"])
+		m := ensureInt(cryptoJSON.KDFParams["m"])
+		p := ensureInt(cryptoJSON.KDFParams["p"])
+		if t < 1 || t > argon2MaxTime || m < 1 || m > argon2MaxMemory || p < 1 || p > math.MaxUint8 || dkLen < 1 {
+			return nil, fmt.Errorf("invalid Argon2id parameters: t=%d m=%d p=%d", t, m, p)
+		}
+		return argon2.IDKey(authArray, salt, uint32(t), uint32(m), uint8(p), uint32(dkLen)), nil
 	}
 
 	return nil, fmt.Errorf("unsupported KDF: %s", cryptoJSON.KDF)
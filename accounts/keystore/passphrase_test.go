@@ -17,8 +17,15 @@
 package keystore
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 )
@@ -50,3 +57,123 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 	}
 
 }
+
+// Tests that keys are encrypted in the v4 format with both of its ciphers, and
+// that tampering with the key file is detected.
+func TestKeyEncryptDecryptV4(t *testing.T) {
+	key, err := newKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cipherName := range []string{CipherAES256GCM, CipherXChaCha20Poly1305} {
+		keyjson, err := EncryptKeyWithCipher(key, "foo", cipherName, veryLightScryptN, veryLightScryptP)
+		if err != nil {
+			t.Fatalf("%s: failed to encrypt key: %v", cipherName, err)
+		}
+		var k encryptedKeyJSONV4
+		if err := json.Unmarshal(keyjson, &k); err != nil {
+			t.Fatal(err)
+		}
+		if k.Version != versionV4 || k.Crypto.Cipher != cipherName || k.Crypto.KDF != "argon2id" || k.Crypto.MAC != "" {
+			t.Fatalf("%s: unexpected key file: %s", cipherName, keyjson)
+		}
+		if k.Algorithm.Id != cryptobase.SigAlgIdHybrid || k.Algorithm.Name != cryptobase.SigAlg.SignatureName() {
+			t.Fatalf("%s: algorithm mismatch: %+v", cipherName, k.Algorithm)
+		}
+		dec, err := DecryptKey(keyjson, "foo")
+		if err != nil {
+			t.Fatalf("%s: failed to decrypt key: %v", cipherName, err)
+		}
+		if dec.Address != key.Address || dec.Id != key.Id || !bytes.Equal(dec.PrivateKey.PriData, key.PrivateKey.PriData) {
+			t.Fatalf("%s: decrypted key mismatch", cipherName)
+		}
+		if _, err := DecryptKey(keyjson, "bar"); err != ErrDecrypt {
+			t.Errorf("%s: wrong password: have %v, want %v", cipherName, err, ErrDecrypt)
+		}
+		// The algorithm metadata is authenticated along the key
+		forged := k
+		forged.Algorithm.Id = cryptobase.SigAlgIdFalcon
+		if _, _, err := decryptKeyV4(&forged, "foo"); err == nil {
+			t.Errorf("%s: accepted key of another algorithm", cipherName)
+		}
+		if _, err := DecryptDataV4(k.Crypto, "foo", []byte{versionV4, cryptobase.SigAlgIdFalcon}); err != ErrDecrypt {
+			t.Errorf("%s: forged algorithm: have %v, want %v", cipherName, err, ErrDecrypt)
+		}
+		ciphertext, _ := hex.DecodeString(k.Crypto.CipherText)
+		ciphertext[0] ^= 0x01
+		forged = k
+		forged.Crypto.CipherText = hex.EncodeToString(ciphertext)
+		if _, _, err := decryptKeyV4(&forged, "foo"); err != ErrDecrypt {
+			t.Errorf("%s: tampered ciphertext: have %v, want %v", cipherName, err, ErrDecrypt)
+		}
+		// Excessive Argon2id costs are refused before deriving the key
+		for param, value := range map[string]int{"m": 1 << 32, "t": 1 << 20} {
+			forged = k
+			forged.Crypto.KDFParams = make(map[string]interface{})
+			for name, v := range k.Crypto.KDFParams {
+				forged.Crypto.KDFParams[name] = v
+			}
+			forged.Crypto.KDFParams[param] = float64(value)
+			if _, _, err := decryptKeyV4(&forged, "foo"); err == nil || err == ErrDecrypt {
+				t.Errorf("%s: accepted %s=%d: %v", cipherName, param, value, err)
+			}
+		}
+	}
+	if _, err := EncryptKeyWithCipher(key, "foo", "aes-128-ctr", veryLightScryptN, veryLightScryptP); err == nil {
+		t.Error("encrypted key with a v3 cipher")
+	}
+}
+
+// Tests that v3 key files are still read, and are upgraded to the v4 format by
+// updating them.
+func TestUpgradeV3Key(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	key, err := newKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := cryptobase.SigAlg.SerializePrivateKey(key.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cryptoStruct, err := EncryptDataV3(keyBytes, []byte("foo"), veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := json.Marshal(encryptedKeyJSONV3{hex.EncodeToString(key.Address[:]), cryptoStruct, key.Id.String(), version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, keyFileName(key.Address))
+	if err := writeKeyFile(file, keyjson); err != nil {
+		t.Fatal(err)
+	}
+	ks.cache.add(accounts.Account{Address: key.Address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: file}})
+	acc := accounts.Account{Address: key.Address}
+
+	if err := ks.Unlock(acc, "foo"); err != nil {
+		t.Fatalf("failed to unlock v3 key: %v", err)
+	}
+	if upgrade, err := ks.NeedsUpgrade(acc); err != nil || !upgrade {
+		t.Fatalf("v3 key not upgradable: %v %v", upgrade, err)
+	}
+	if err := ks.Update(acc, "foo", "foo"); err != nil {
+		t.Fatalf("failed to upgrade key: %v", err)
+	}
+	if upgrade, err := ks.NeedsUpgrade(acc); err != nil || upgrade {
+		t.Fatalf("upgraded key still upgradable: %v %v", upgrade, err)
+	}
+	upgraded, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := DecryptKey(upgraded, "foo")
+	if err != nil {
+		t.Fatalf("failed to decrypt upgraded key: %v", err)
+	}
+	if dec.Address != key.Address || dec.Id != key.Id || !bytes.Equal(dec.PrivateKey.PriData, key.PrivateKey.PriData) {
+		t.Fatal("upgraded key mismatch")
+	}
+}
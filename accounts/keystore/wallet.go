@@ -147,3 +147,26 @@ func (w *keystoreWallet) SignTxWithPassphrase(account accounts.Account, passphra
 	// Account seems valid, request the keystore to sign
 	return w.keystore.SignTxWithPassphrase(account, passphrase, tx, chainID)
 }
+
+// SignMultisigTx implements accounts.MultisigWallet, attempting to add the
+// signature of the given member account to a multisig transaction.
+func (w *keystoreWallet) SignMultisigTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	// Make sure the requested account is contained within
+	if !w.Contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	// Account seems valid, request the keystore to sign
+	return w.keystore.SignMultisigTx(account, tx, chainID)
+}
+
+// SignMultisigTxWithPassphrase implements accounts.MultisigWallet, attempting
+// to add the signature of the given member account to a multisig transaction
+// using passphrase as extra authentication.
+func (w *keystoreWallet) SignMultisigTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	// Make sure the requested account is contained within
+	if !w.Contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	// Account seems valid, request the keystore to sign
+	return w.keystore.SignMultisigTxWithPassphrase(account, passphrase, tx, chainID)
+}
@@ -23,7 +23,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/DogeProtocol/dp/accounts"
 	"github.com/DogeProtocol/dp/accounts/keystore"
 	"github.com/DogeProtocol/dp/cmd/utils"
 	"github.com/google/uuid"
@@ -33,6 +35,7 @@ import (
 type outputGenerate struct {
 	Address      string
 	AddressEIP55 string
+	Mnemonic     string `json:",omitempty"`
 }
 
 var commandGenerate = cli.Command{
@@ -44,6 +47,11 @@ Generate a new keyfile.
 
 If you want to encrypt an existing private key, it can be specified by setting
 --privatekey with the location of the file containing the private key.
+
+With --mnemonic, the key is derived from a new BIP-39 mnemonic, which is printed
+for backup on paper. The key can be recovered from it at any time by setting
+--mnemonicfile with the location of a file containing the mnemonic. Keys are
+derived at the path set with --hdpath, m/44'/60'/0'/0/0 by default.
 `,
 	Flags: []cli.Flag{
 		passphraseFlag,
@@ -52,6 +60,19 @@ If you want to encrypt an existing private key, it can be specified by setting
 			Name:  "privatekey",
 			Usage: "file containing a raw private key to encrypt",
 		},
+		cli.BoolFlag{
+			Name:  "mnemonic",
+			Usage: "derive the key from a new mnemonic",
+		},
+		cli.StringFlag{
+			Name:  "mnemonicfile",
+			Usage: "file containing a mnemonic to derive the key from",
+		},
+		cli.StringFlag{
+			Name:  "hdpath",
+			Usage: "derivation path of the key in the mnemonic",
+			Value: accounts.DefaultBaseDerivationPath.String(),
+		},
 		cli.BoolFlag{
 			Name:  "lightkdf",
 			Usage: "use less secure scrypt parameters",
@@ -70,8 +91,31 @@ If you want to encrypt an existing private key, it can be specified by setting
 		}
 
 		var privateKey *signaturealgorithm.PrivateKey
+		var mnemonic string
 		var err error
-		if file := ctx.String("privatekey"); file != "" {
+		if ctx.Bool("mnemonic") || ctx.IsSet("mnemonicfile") {
+			if file := ctx.String("mnemonicfile"); file != "" {
+				content, err := ioutil.ReadFile(file)
+				if err != nil {
+					utils.Fatalf("Can't load mnemonic: %v", err)
+				}
+				mnemonic = strings.Join(strings.Fields(string(content)), " ")
+			} else if mnemonic, err = keystore.NewMnemonic(); err != nil {
+				utils.Fatalf("Failed to generate mnemonic: %v", err)
+			}
+			path, err := accounts.ParseDerivationPath(ctx.String("hdpath"))
+			if err != nil {
+				utils.Fatalf("Invalid derivation path: %v", err)
+			}
+			privateKey, err = keystore.NewKeyFromMnemonic(mnemonic, "", path)
+			if err != nil {
+				utils.Fatalf("Failed to derive private key: %v", err)
+			}
+			// Only print back new mnemonics
+			if ctx.IsSet("mnemonicfile") {
+				mnemonic = ""
+			}
+		} else if file := ctx.String("privatekey"); file != "" {
 			// Load private key from file.
 			privateKey, err = cryptobase.SigAlg.LoadPrivateKeyFromFile(file)
 			if err != nil {
@@ -122,12 +166,17 @@ If you want to encrypt an existing private key, it can be specified by setting
 
 		// Output some information.
 		out := outputGenerate{
-			Address: key.Address.Hex(),
+			Address:  key.Address.Hex(),
+			Mnemonic: mnemonic,
 		}
 		if ctx.Bool(jsonFlag.Name) {
 			mustPrintJSON(out)
 		} else {
 			fmt.Println("Address:", out.Address)
+			if out.Mnemonic != "" {
+				fmt.Println("Mnemonic:", out.Mnemonic)
+				fmt.Println("Write the mnemonic down and keep it safe, anyone knowing it can recover the key.")
+			}
 		}
 		return nil
 	},
@@ -152,6 +152,35 @@ For non-interactive use the password can be specified with the --password flag:
 
 Since only one password can be given, only format update can be performed,
 changing your password is only possible interactively.
+`,
+			},
+			{
+				Name:      "upgrade",
+				Usage:     "Upgrade accounts to the newest key file format",
+				Action:    utils.MigrateFlags(accountUpgrade),
+				ArgsUsage: "[<address> ...]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+				},
+				Description: `
+    geth account upgrade [<address> ...]
+
+Re-encrypt the given accounts, or all accounts if none are given, in the newest
+key file format, keeping their passwords. Accounts already in the newest format
+are skipped.
+
+The newest format derives the encryption key with Argon2id, and encrypts the
+private key with AES-256-GCM. Older files use scrypt and AES-128-CTR, which is
+only 128-bit secure against quantum computers.
+
+You are prompted for the password of each account. For non-interactive use the
+passwords can be specified with the --password flag, one per line in the order
+of the accounts:
+
+    geth account upgrade [options] [<address> ...]
 `,
 			},
 			{
@@ -311,6 +340,42 @@ func accountUpdate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountUpgrade re-encrypts accounts stored in an older key file format in the
+// current one, keeping their pass-phrases.
+func accountUpgrade(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	addrs := ctx.Args()
+	if len(addrs) == 0 {
+		for _, account := range ks.Accounts() {
+			addrs = append(addrs, account.Address.Hex())
+		}
+	}
+	passwords := utils.MakePasswordList(ctx)
+	for i, addr := range addrs {
+		account, err := utils.MakeAddress(ks, addr)
+		if err != nil {
+			utils.Fatalf("Could not find account %s: %v", addr, err)
+		}
+		upgrade, err := ks.NeedsUpgrade(account)
+		if err != nil {
+			utils.Fatalf("Could not read the key file of %s: %v", addr, err)
+		}
+		if !upgrade {
+			fmt.Printf("Account {%x} is already in the newest format\n", account.Address)
+			continue
+		}
+		account, password := unlockAccount(ks, addr, i, passwords)
+		if err := ks.Update(account, password, password); err != nil {
+			utils.Fatalf("Could not upgrade the account: %v", err)
+		}
+		ks.Lock(account.Address)
+		fmt.Printf("Upgraded account {%x}\n", account.Address)
+	}
+	return nil
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
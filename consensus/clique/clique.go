@@ -591,7 +591,7 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
 // rewards given.
-func (c *Clique) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) error {
+func (c *Clique) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) error {
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	fmt.Println("Finalize", header.Number)
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
@@ -604,7 +604,7 @@ func (c *Clique) Finalize(chain consensus.ChainHeaderReader, header *types.Heade
 func (c *Clique) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
 	fmt.Println("FinalizeAndAssemble", header.Number)
 	// Finalize block
-	err := c.Finalize(chain, header, state, txs, uncles)
+	err := c.Finalize(chain, header, state, txs, uncles, receipts)
 	if err != nil {
 		return nil, err
 	}
@@ -81,12 +81,13 @@ type Engine interface {
 	Prepare(chain ChainHeaderReader, header *types.Header) error
 
 	// Finalize runs any post-transaction state modifications (e.g. block rewards)
-	// but does not assemble the block.
+	// but does not assemble the block. The receipts are those of the block's
+	// transactions, in the same order.
 	//
 	// Note: The block header and state database might be updated to reflect any
 	// consensus rules that happen at finalization (e.g. block rewards).
 	Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction,
-		uncles []*types.Header) error
+		uncles []*types.Header, receipts []*types.Receipt) error
 
 	// FinalizeAndAssemble runs any post-transaction state modifications (e.g. block
 	// rewards) and assembles the final block.
@@ -117,6 +118,14 @@ type Engine interface {
 	Close() error
 }
 
+// Finality is implemented by consensus engines that deterministically finalize
+// blocks on top of the fork choice rule.
+type Finality interface {
+	// Finalized returns the most recent block finalized on the chain ending in
+	// the given header, or nil if no block is final yet.
+	Finalized(chain ChainHeaderReader, header *types.Header) (*types.Header, error)
+}
+
 // PoW is a consensus engine based on proof-of-work.
 type PoW interface {
 	Engine
@@ -27,15 +27,31 @@ import (
 	"github.com/DogeProtocol/dp/rpc"
 )
 
-// API is a user facing RPC API to allow controlling the signer and voting
-// mechanisms of the proof-of-authority scheme.
+// API is a user facing RPC API to allow inspecting the signer set and sealing
+// activity of the proof-of-stake scheme.
 type API struct {
 	chain        consensus.ChainHeaderReader
 	proofofstake *ProofOfStake
 }
 
+// snapshotWithSchedule is a snapshot extended with the proposer schedule of the
+// block following it.
+type snapshotWithSchedule struct {
+	*Snapshot
+	Proposers []common.Address `json:"proposers"` // Eligible signers of the next block, in-turn proposer first
+}
+
+// newSnapshotWithSchedule attaches the proposer schedule of the next block to a
+// snapshot.
+func newSnapshotWithSchedule(snap *Snapshot, err error) (*snapshotWithSchedule, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotWithSchedule{Snapshot: snap, Proposers: snap.schedule(snap.Number + 1)}, nil
+}
+
 // GetSnapshot retrieves the state snapshot at a given block.
-func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
+func (api *API) GetSnapshot(number *rpc.BlockNumber) (*snapshotWithSchedule, error) {
 	// Retrieve the requested block number (or current if none requested)
 	var header *types.Header
 	if number == nil || *number == rpc.LatestBlockNumber {
@@ -47,16 +63,16 @@ func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
 	if header == nil {
 		return nil, errUnknownBlock
 	}
-	return api.proofofstake.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	return newSnapshotWithSchedule(api.proofofstake.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil))
 }
 
 // GetSnapshotAtHash retrieves the state snapshot at a given block.
-func (api *API) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
+func (api *API) GetSnapshotAtHash(hash common.Hash) (*snapshotWithSchedule, error) {
 	header := api.chain.GetHeaderByHash(hash)
 	if header == nil {
 		return nil, errUnknownBlock
 	}
-	return api.proofofstake.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	return newSnapshotWithSchedule(api.proofofstake.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil))
 }
 
 // GetSigners retrieves the list of authorized signers at the specified block.
@@ -93,46 +109,34 @@ func (api *API) GetSignersAtHash(hash common.Hash) ([]common.Address, error) {
 	return snap.signers(), nil
 }
 
-// Proposals returns the current proposals the node tries to uphold and vote on.
-func (api *API) Proposals() map[common.Address]bool {
-	api.proofofstake.lock.RLock()
-	defer api.proofofstake.lock.RUnlock()
-
-	proposals := make(map[common.Address]bool)
-	for address, auth := range api.proofofstake.proposals {
-		proposals[address] = auth
+// GetBlockReward retrieves the reward paid out by a given block, as recorded in
+// the staking contract's state at that block.
+func (api *API) GetBlockReward(number *rpc.BlockNumber) (*BlockReward, error) {
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
 	}
-	return proposals
-}
-
-// Propose injects a new authorization proposal that the signer will attempt to
-// push through.
-func (api *API) Propose(address common.Address, auth bool) {
-	api.proofofstake.lock.Lock()
-	defer api.proofofstake.lock.Unlock()
-
-	api.proofofstake.proposals[address] = auth
-}
-
-// Discard drops a currently running proposal, stopping the signer from casting
-// further votes (either for or against).
-func (api *API) Discard(address common.Address) {
-	api.proofofstake.lock.Lock()
-	defer api.proofofstake.lock.Unlock()
-
-	delete(api.proofofstake.proposals, address)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.proofofstake.GetBlockReward(header.Number.Uint64(), header.Hash())
 }
 
 type status struct {
-	InturnPercent float64                `json:"inturnPercent"`
-	SigningStatus map[common.Address]int `json:"sealerActivity"`
-	NumBlocks     uint64                 `json:"numBlocks"`
+	InturnPercent float64                   `json:"inturnPercent"`
+	SigningStatus map[common.Address]int    `json:"sealerActivity"`
+	NumBlocks     uint64                    `json:"numBlocks"`
+	MissedSlots   map[common.Address]uint64 `json:"missedSlots"`
+	JailThreshold uint64                    `json:"jailThreshold"`
 }
 
 // Status returns the status of the last N blocks,
 // - the number of active signers,
 // - the number of signers,
 // - the percentage of in-turn blocks
+// - the in-turn slots each signer missed in the current epoch
 func (api *API) Status() (*status, error) {
 	var (
 		numBlocks = uint64(64)
@@ -172,10 +176,16 @@ func (api *API) Status() (*status, error) {
 		}
 		signStatus[sealer]++
 	}
+	missed := make(map[common.Address]uint64)
+	for _, s := range signers {
+		missed[s] = snap.Missed[s]
+	}
 	return &status{
 		InturnPercent: float64(100*optimals) / float64(numBlocks),
 		SigningStatus: signStatus,
 		NumBlocks:     numBlocks,
+		MissedSlots:   missed,
+		JailThreshold: api.proofofstake.config.MissedSlotsThreshold,
 	}, nil
 }
 
@@ -243,6 +253,45 @@ func (api *API) GetValidators() ([]common.Address, error) {
 	return validators, nil
 }
 
+// GetDelegations retrieves the delegations bonded to a validator at the head
+// of the chain.
+func (api *API) GetDelegations(validator common.Address) ([]*Delegation, error) {
+	var header = api.chain.CurrentHeader()
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.proofofstake.GetDelegations(validator, header.Hash())
+}
+
+// GetDelegatorDelegations retrieves the delegations bonded by an account at the
+// head of the chain.
+func (api *API) GetDelegatorDelegations(delegator common.Address) ([]*Delegation, error) {
+	var header = api.chain.CurrentHeader()
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.proofofstake.GetDelegatorDelegations(delegator, header.Hash())
+}
+
+// GetPendingWithdrawals retrieves the withdrawals of an account waiting in the
+// withdrawal queue at the head of the chain, along with the block from which
+// each of them can be claimed.
+func (api *API) GetPendingWithdrawals(account common.Address) ([]*PendingWithdrawal, error) {
+	var header = api.chain.CurrentHeader()
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	withdrawals, err := api.proofofstake.GetPendingWithdrawals(account, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	// Claims are processed by the next block
+	for _, withdrawal := range withdrawals {
+		withdrawal.Claimable = uint64(withdrawal.Maturity) <= header.Number.Uint64()+1
+	}
+	return withdrawals, nil
+}
+
 /*
 // GetDepositBalance retrieves the list of authorized signers at the specified block.
 func (api *API) GetDepositBalance(validator common.Address) (*big.Int, error) {
@@ -0,0 +1,200 @@
+package proofofstake
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/crypto"
+)
+
+var (
+	// errInvalidDelegation is returned if a delegation is unbonded by a
+	// non-positive amount.
+	errInvalidDelegation = errors.New("invalid delegation amount")
+
+	// errInsufficientDelegation is returned if an account unbonds more than it
+	// delegated to a validator.
+	errInsufficientDelegation = errors.New("insufficient delegation")
+)
+
+// Delegations are kept by the staking contract from the delegation fork on, in
+// the state variables declared after the validator list. The engine reads them
+// to share rewards and burns them when slashing.
+var (
+	stakingSlotDelegations    = common.BigToHash(big.NewInt(7))  // mapping (address => mapping (address => uint256)) _delegations
+	stakingSlotDelegatedStake = common.BigToHash(big.NewInt(8))  // mapping (address => uint256) _delegatedStake
+	stakingSlotCommission     = common.BigToHash(big.NewInt(9))  // mapping (address => uint256) _commission
+	stakingSlotDelegators     = common.BigToHash(big.NewInt(10)) // mapping (address => address[]) _delegators
+	stakingSlotDelegatorIndex = common.BigToHash(big.NewInt(11)) // mapping (address => mapping (address => uint256)) _delegatorIndex
+	stakingSlotDelegates      = common.BigToHash(big.NewInt(12)) // mapping (address => address[]) _delegates
+	stakingSlotDelegateIndex  = common.BigToHash(big.NewInt(13)) // mapping (address => mapping (address => uint256)) _delegateIndex
+)
+
+// stateReader is the read access to contract storage needed to list
+// delegations, satisfied by both the state database and the RPC backed reader
+// used outside of block processing.
+type stateReader interface {
+	GetState(addr common.Address, hash common.Hash) common.Hash
+}
+
+// Delegation is a bond of a delegator to a validator.
+type Delegation struct {
+	Validator common.Address `json:"validator"`
+	Delegator common.Address `json:"delegator"`
+	Amount    *hexutil.Big   `json:"amount"`
+}
+
+// delegationSlot returns the contract slot holding the amount a delegator
+// bonded to a validator.
+func delegationSlot(validator common.Address, delegator common.Address) common.Hash {
+	return mappingSlot(common.BytesToHash(delegator[:]), mappingSlot(common.BytesToHash(validator[:]), stakingSlotDelegations))
+}
+
+// delegatedSlot returns the contract slot holding the total amount bonded to a
+// validator.
+func delegatedSlot(validator common.Address) common.Hash {
+	return mappingSlot(common.BytesToHash(validator[:]), stakingSlotDelegatedStake)
+}
+
+// commissionSlot returns the contract slot holding a validator's commission.
+func commissionSlot(validator common.Address) common.Hash {
+	return mappingSlot(common.BytesToHash(validator[:]), stakingSlotCommission)
+}
+
+// addressList is an address[] held in a mapping of the staking contract, along
+// with the mapping from its members to their position plus one that lets the
+// contract remove them by swapping in the last member.
+type addressList struct {
+	length common.Hash // Slot holding the length, the elements follow from its hash
+	index  common.Hash // Slot of the mapping from members to their position plus one
+}
+
+// newAddressList returns the list of an owner kept in the given list and index
+// mappings.
+func newAddressList(owner common.Address, listSlot common.Hash, indexSlot common.Hash) addressList {
+	key := common.BytesToHash(owner[:])
+	return addressList{length: mappingSlot(key, listSlot), index: mappingSlot(key, indexSlot)}
+}
+
+func (l addressList) elementSlot(i uint64) common.Hash {
+	return common.BigToHash(new(big.Int).Add(crypto.Keccak256Hash(l.length[:]).Big(), new(big.Int).SetUint64(i)))
+}
+
+func (l addressList) indexSlot(member common.Address) common.Hash {
+	return mappingSlot(common.BytesToHash(member[:]), l.index)
+}
+
+// members returns the addresses in the list.
+func (l addressList) members(statedb stateReader, contract common.Address) []common.Address {
+	length := statedb.GetState(contract, l.length).Big().Uint64()
+	members := make([]common.Address, length)
+	for i := uint64(0); i < length; i++ {
+		members[i] = common.BytesToAddress(statedb.GetState(contract, l.elementSlot(i)).Bytes())
+	}
+	return members
+}
+
+// remove drops an address from the list, moving the last member into its place.
+func (l addressList) remove(statedb *state.StateDB, contract common.Address, member common.Address) {
+	index := statedb.GetState(contract, l.indexSlot(member)).Big().Uint64()
+	if index == 0 {
+		return
+	}
+	last := statedb.GetState(contract, l.length).Big().Uint64() - 1
+	if index-1 != last {
+		moved := common.BytesToAddress(statedb.GetState(contract, l.elementSlot(last)).Bytes())
+		statedb.SetState(contract, l.elementSlot(index-1), common.BytesToHash(moved[:]))
+		statedb.SetState(contract, l.indexSlot(moved), common.BigToHash(new(big.Int).SetUint64(index)))
+	}
+	statedb.SetState(contract, l.elementSlot(last), common.Hash{})
+	statedb.SetState(contract, l.indexSlot(member), common.Hash{})
+	statedb.SetState(contract, l.length, common.BigToHash(new(big.Int).SetUint64(last)))
+}
+
+// delegatedStake returns the total amount bonded to a validator.
+func delegatedStake(statedb stateReader, contract common.Address, validator common.Address) *big.Int {
+	return statedb.GetState(contract, delegatedSlot(validator)).Big()
+}
+
+// commission returns the percentage of the delegators' rewards a validator keeps.
+func commission(statedb stateReader, contract common.Address, validator common.Address) uint64 {
+	return statedb.GetState(contract, commissionSlot(validator)).Big().Uint64()
+}
+
+// validatorDelegations returns the bonds made to a validator.
+func validatorDelegations(statedb stateReader, contract common.Address, validator common.Address) []*Delegation {
+	delegators := newAddressList(validator, stakingSlotDelegators, stakingSlotDelegatorIndex).members(statedb, contract)
+	delegations := make([]*Delegation, len(delegators))
+	for i, delegator := range delegators {
+		delegations[i] = &Delegation{
+			Validator: validator,
+			Delegator: delegator,
+			Amount:    (*hexutil.Big)(statedb.GetState(contract, delegationSlot(validator, delegator)).Big()),
+		}
+	}
+	return delegations
+}
+
+// delegatorDelegations returns the bonds made by a delegator.
+func delegatorDelegations(statedb stateReader, contract common.Address, delegator common.Address) []*Delegation {
+	validators := newAddressList(delegator, stakingSlotDelegates, stakingSlotDelegateIndex).members(statedb, contract)
+	delegations := make([]*Delegation, len(validators))
+	for i, validator := range validators {
+		delegations[i] = &Delegation{
+			Validator: validator,
+			Delegator: delegator,
+			Amount:    (*hexutil.Big)(statedb.GetState(contract, delegationSlot(validator, delegator)).Big()),
+		}
+	}
+	return delegations
+}
+
+// undelegate unbonds the given amount from a validator the way the staking
+// contract does. The funds stay in the contract; it's up to the caller to
+// dispose of them.
+func undelegate(statedb *state.StateDB, contract common.Address, validator common.Address, delegator common.Address, amount *big.Int) error {
+	var (
+		slot   = delegationSlot(validator, delegator)
+		bonded = statedb.GetState(contract, slot).Big()
+	)
+	if amount.Sign() <= 0 {
+		return errInvalidDelegation
+	}
+	if bonded.Cmp(amount) < 0 {
+		return errInsufficientDelegation
+	}
+	bonded.Sub(bonded, amount)
+	statedb.SetState(contract, slot, common.BigToHash(bonded))
+	statedb.SetState(contract, delegatedSlot(validator), common.BigToHash(new(big.Int).Sub(delegatedStake(statedb, contract, validator), amount)))
+
+	if bonded.Sign() == 0 {
+		newAddressList(validator, stakingSlotDelegators, stakingSlotDelegatorIndex).remove(statedb, contract, delegator)
+		newAddressList(delegator, stakingSlotDelegates, stakingSlotDelegateIndex).remove(statedb, contract, validator)
+	}
+	return nil
+}
+
+// slashDelegations burns the given percentage of every bond made to a
+// validator, returning the amount burned.
+func slashDelegations(statedb *state.StateDB, contract common.Address, validator common.Address, percent uint64) *big.Int {
+	burned := new(big.Int)
+	for _, delegation := range validatorDelegations(statedb, contract, validator) {
+		amount := new(big.Int).Mul(delegation.Amount.ToInt(), new(big.Int).SetUint64(percent))
+		amount.Div(amount, big.NewInt(100))
+		if amount.Cmp(statedb.GetBalance(contract)) > 0 {
+			amount.Set(statedb.GetBalance(contract))
+		}
+		if amount.Sign() == 0 {
+			continue
+		}
+		if err := undelegate(statedb, contract, validator, delegation.Delegator, amount); err != nil {
+			continue
+		}
+		statedb.SubBalance(contract, amount)
+		burned.Add(burned, amount)
+	}
+	return burned
+}
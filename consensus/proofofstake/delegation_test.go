@@ -0,0 +1,242 @@
+package proofofstake
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/DogeProtocol/dp/accounts/abi"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/core/vm"
+	"github.com/DogeProtocol/dp/core/vm/runtime"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/systemcontracts"
+	"github.com/DogeProtocol/dp/systemcontracts1"
+)
+
+// testStakingContract is a staking contract deployed in a test state and
+// called through the EVM.
+type testStakingContract struct {
+	t       *testing.T
+	config  *params.ChainConfig
+	statedb *state.StateDB
+	address common.Address
+	number  *big.Int
+}
+
+// newTestStakingContract predeploys the staking contract at the genesis of a
+// chain whose staking forks activate at the given blocks.
+func newTestStakingContract(t *testing.T, delegationBlock *big.Int, unbondingBlock *big.Int) *testStakingContract {
+	config := *params.AllProofOfStakeProtocolChanges
+	config.DelegationBlock, config.UnbondingBlock, config.JailingBlock = delegationBlock, unbondingBlock, unbondingBlock
+	config.ProofOfStake = &params.ProofOfStakeConfig{Epoch: 100, UnbondingEpochs: 2, StakingContract: common.HexToAddress("0x1000")}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(config.ProofOfStake.StakingContract, systemcontracts1.GetStakingContract_Code())
+
+	contract := &testStakingContract{
+		t:       t,
+		config:  &config,
+		statedb: statedb,
+		address: config.ProofOfStake.StakingContract,
+	}
+	contract.setNumber(0)
+	return contract
+}
+
+// setNumber moves the contract to the given block, upgrading it for the staking
+// forks activated there.
+func (c *testStakingContract) setNumber(number int64) {
+	c.number = big.NewInt(number)
+	if err := systemcontracts.UpgradeStakingContract(c.config, c.number, c.statedb); err != nil {
+		c.t.Fatalf("failed to upgrade staking contract: %v", err)
+	}
+}
+
+// call runs a staking contract method from the given account, funding it with
+// the value sent along. Reverted calls return the revert reason as error.
+func (c *testStakingContract) call(from common.Address, value int64, method string, args ...interface{}) ([]interface{}, string) {
+	abiData := systemcontracts1.GetStakingContract_ABI()
+	input, err := abiData.Pack(method, args...)
+	if err != nil {
+		c.t.Fatalf("failed to pack %s: %v", method, err)
+	}
+	c.statedb.AddBalance(from, big.NewInt(value))
+	ret, _, err := runtime.Call(c.address, input, &runtime.Config{
+		ChainConfig: c.config,
+		Origin:      from,
+		Value:       big.NewInt(value),
+		BlockNumber: c.number,
+		State:       c.statedb,
+	})
+	if err == vm.ErrExecutionReverted {
+		c.statedb.SubBalance(from, big.NewInt(value))
+		reason, _ := abi.UnpackRevert(ret)
+		return nil, reason
+	}
+	if err != nil {
+		c.t.Fatalf("failed to call %s: %v", method, err)
+	}
+	out, err := abiData.Unpack(method, ret)
+	if err != nil {
+		c.t.Fatalf("failed to unpack %s: %v", method, err)
+	}
+	return out, ""
+}
+
+// mustCall runs a staking contract method that is expected to succeed.
+func (c *testStakingContract) mustCall(from common.Address, value int64, method string, args ...interface{}) []interface{} {
+	out, reason := c.call(from, value, method, args...)
+	if reason != "" {
+		c.t.Fatalf("%s reverted: %s", method, reason)
+	}
+	return out
+}
+
+// deposit stakes the given value for the validator derived from a public key,
+// returning the validator's address. Like an account address, it is derived
+// from the key without its leading type byte.
+func (c *testStakingContract) deposit(depositor common.Address, pubkey []byte, value int64) common.Address {
+	c.mustCall(depositor, value, "newDeposit", pubkey)
+	return common.BytesToAddress(crypto.Keccak256(pubkey[1:]))
+}
+
+// Tests that delegations can only be bonded to validators backed by a deposit,
+// and that the engine lists them per validator and per delegator from the
+// staking contract's storage until they are unbonded.
+func TestDelegation(t *testing.T) {
+	var (
+		contract   = newTestStakingContract(t, common.Big0, nil)
+		depositors = []common.Address{common.HexToAddress("0xd1"), common.HexToAddress("0xd2")}
+		delegators = []common.Address{common.HexToAddress("0xb1"), common.HexToAddress("0xb2")}
+		validators = []common.Address{
+			contract.deposit(depositors[0], []byte{0x01, 0x01}, 1000),
+			contract.deposit(depositors[1], []byte{0x01, 0x02}, 1000),
+		}
+		statedb = contract.statedb
+	)
+	if _, reason := contract.call(delegators[0], 100, "delegate", common.HexToAddress("0xa3")); reason != "Unknown validator" {
+		t.Fatalf("delegation to unknown validator: have %q", reason)
+	}
+	if _, reason := contract.call(delegators[0], 0, "delegate", validators[0]); reason != "Invalid amount" {
+		t.Fatalf("empty delegation: have %q", reason)
+	}
+	contract.mustCall(delegators[0], 100, "delegate", validators[0])
+	contract.mustCall(delegators[1], 200, "delegate", validators[0])
+	contract.mustCall(delegators[0], 300, "delegate", validators[1])
+	contract.mustCall(delegators[0], 50, "delegate", validators[0])
+
+	if have := statedb.GetBalance(contract.address); have.Cmp(big.NewInt(2650)) != 0 {
+		t.Errorf("contract balance mismatch: have %v, want 2650", have)
+	}
+	if have := delegatedStake(statedb, contract.address, validators[0]); have.Cmp(big.NewInt(350)) != 0 {
+		t.Errorf("delegated stake mismatch: have %v, want 350", have)
+	}
+	if have := validatorDelegations(statedb, contract.address, validators[0]); len(have) != 2 || have[0].Amount.ToInt().Cmp(big.NewInt(150)) != 0 {
+		t.Errorf("validator delegations mismatch: have %v", have)
+	}
+	if have := delegatorDelegations(statedb, contract.address, delegators[0]); len(have) != 2 || have[1].Validator != validators[1] {
+		t.Errorf("delegator delegations mismatch: have %v", have)
+	}
+	if out := contract.mustCall(common.Address{}, 0, "delegationOf", validators[0], delegators[0]); out[0].(*big.Int).Cmp(big.NewInt(150)) != 0 {
+		t.Errorf("delegationOf mismatch: have %v, want 150", out[0])
+	}
+	if out := contract.mustCall(common.Address{}, 0, "listDelegators", validators[0]); !reflect.DeepEqual(out[0], delegators) {
+		t.Errorf("listDelegators mismatch: have %v, want %v", out[0], delegators)
+	}
+	// Unbonding pays the delegation back and drops emptied bonds from the lists
+	if _, reason := contract.call(delegators[0], 0, "undelegate", validators[0], big.NewInt(151)); reason != "Insufficient delegation" {
+		t.Fatalf("excessive unbonding: have %q", reason)
+	}
+	contract.mustCall(delegators[0], 0, "undelegate", validators[0], big.NewInt(150))
+
+	if have := statedb.GetBalance(delegators[0]); have.Cmp(big.NewInt(150)) != 0 {
+		t.Errorf("delegator balance mismatch after unbonding: have %v, want 150", have)
+	}
+	if have := validatorDelegations(statedb, contract.address, validators[0]); len(have) != 1 || have[0].Delegator != delegators[1] {
+		t.Errorf("validator delegations mismatch after unbonding: have %v", have)
+	}
+	if have := delegatorDelegations(statedb, contract.address, delegators[0]); len(have) != 1 || have[0].Validator != validators[1] {
+		t.Errorf("delegator delegations mismatch after unbonding: have %v", have)
+	}
+	if have := delegatedStake(statedb, contract.address, validators[0]); have.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("delegated stake mismatch after unbonding: have %v, want 200", have)
+	}
+	// Only depositors set the commission of their validator
+	if _, reason := contract.call(delegators[0], 0, "setCommission", big.NewInt(10)); reason != "Sender is not a depositor" {
+		t.Fatalf("commission set by delegator: have %q", reason)
+	}
+	if _, reason := contract.call(depositors[0], 0, "setCommission", big.NewInt(101)); reason != "Invalid commission" {
+		t.Fatalf("excessive commission: have %q", reason)
+	}
+	contract.mustCall(depositors[0], 0, "setCommission", big.NewInt(10))
+	if have := commission(statedb, contract.address, validators[0]); have != 10 {
+		t.Errorf("commission mismatch: have %d, want 10", have)
+	}
+	// Slashing a bond away removes it the way the contract does
+	if burned := slashDelegations(statedb, contract.address, validators[0], 100); burned.Cmp(big.NewInt(200)) != 0 {
+		t.Fatalf("burned amount mismatch: have %v, want 200", burned)
+	}
+	if out := contract.mustCall(common.Address{}, 0, "listDelegators", validators[0]); len(out[0].([]common.Address)) != 0 {
+		t.Errorf("slashed delegator still listed: %v", out[0])
+	}
+	if out := contract.mustCall(common.Address{}, 0, "listDelegates", delegators[1]); len(out[0].([]common.Address)) != 0 {
+		t.Errorf("slashed validator still listed: %v", out[0])
+	}
+}
+
+// Tests that the staking contract only takes delegations from the delegation
+// fork on.
+func TestDelegationFork(t *testing.T) {
+	contract := newTestStakingContract(t, big.NewInt(10), nil)
+	contract.setNumber(9)
+	validator := contract.deposit(common.HexToAddress("0xd1"), []byte{0x01, 0x01}, 1000)
+
+	if _, reason := contract.call(common.HexToAddress("0xb1"), 100, "delegate", validator); reason != "" {
+		t.Fatalf("unexpected revert reason before the fork: %q", reason)
+	}
+	if have := delegatedStake(contract.statedb, contract.address, validator); have.Sign() != 0 {
+		t.Fatalf("delegation accepted before the fork: %v", have)
+	}
+	contract.setNumber(10)
+	contract.mustCall(common.HexToAddress("0xb1"), 100, "delegate", validator)
+
+	if have := delegatedStake(contract.statedb, contract.address, validator); have.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("delegated stake mismatch: have %v, want 100", have)
+	}
+}
+
+// Tests that the block reward is shared pro rata between the depositor and the
+// delegators, the validator's commission going to the depositor.
+func TestPayDelegatedReward(t *testing.T) {
+	var (
+		contract  = newTestStakingContract(t, common.Big0, nil)
+		depositor = common.HexToAddress("0xd1")
+		delegator = common.HexToAddress("0xb1")
+		sealer    = contract.deposit(depositor, []byte{0x01, 0x01}, 1000)
+		header    = &types.Header{Number: big.NewInt(shiftBlockNumber)}
+	)
+	contract.mustCall(depositor, 0, "setCommission", big.NewInt(10))
+	contract.mustCall(delegator, 3000, "delegate", sealer)
+
+	engine := &ProofOfStake{config: &params.ProofOfStakeConfig{BlockReward: big.NewInt(1000)}}
+	reward := engine.payReward(header, contract.statedb, contract.address, sealer, depositor, new(big.Int))
+
+	// The delegator earns 3/4 of the reward, minus 10% commission
+	if have := contract.statedb.GetBalance(delegator); have.Cmp(big.NewInt(675)) != 0 {
+		t.Errorf("delegator balance mismatch: have %v, want 675", have)
+	}
+	if have := contract.statedb.GetBalance(depositor); have.Cmp(big.NewInt(325)) != 0 {
+		t.Errorf("depositor balance mismatch: have %v, want 325", have)
+	}
+	if reward.Commission.ToInt().Cmp(big.NewInt(75)) != 0 {
+		t.Errorf("commission mismatch: have %v, want 75", reward.Commission)
+	}
+	if have := reward.DelegatorRewards[delegator]; have == nil || have.ToInt().Cmp(big.NewInt(675)) != 0 {
+		t.Errorf("delegator reward record mismatch: have %v, want 675", have)
+	}
+}
@@ -0,0 +1,95 @@
+package proofofstake
+
+import (
+	"errors"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// DoubleSignEvidenceAddress is the system address double-sign evidence is sent
+// to. Transactions to it carry an RLP encoded DoubleSignEvidence as payload and
+// are acted upon by the consensus engine when the block is finalized.
+var DoubleSignEvidenceAddress = common.HexToAddress("0x0000000000000000000000000000000000001001")
+
+var (
+	// errEvidenceHeightMismatch is returned if the two headers of a double-sign
+	// evidence are not at the same height.
+	errEvidenceHeightMismatch = errors.New("evidence headers at different heights")
+
+	// errEvidenceSameHeader is returned if the two headers of a double-sign
+	// evidence are the same block.
+	errEvidenceSameHeader = errors.New("evidence headers are identical")
+
+	// errEvidenceSignerMismatch is returned if the two headers of a double-sign
+	// evidence were sealed by different validators.
+	errEvidenceSignerMismatch = errors.New("evidence headers sealed by different validators")
+)
+
+// DoubleSignEvidence proves that a validator sealed two different blocks at the
+// same height.
+type DoubleSignEvidence struct {
+	HeaderA *types.Header
+	HeaderB *types.Header
+}
+
+// NewDoubleSignEvidence creates an evidence from two conflicting headers. The
+// headers are ordered by hash so that the same offence always yields the same
+// evidence, no matter the order the headers were seen in.
+func NewDoubleSignEvidence(a, b *types.Header) *DoubleSignEvidence {
+	if hashA, hashB := a.Hash(), b.Hash(); hashA.Big().Cmp(hashB.Big()) > 0 {
+		a, b = b, a
+	}
+	return &DoubleSignEvidence{HeaderA: a, HeaderB: b}
+}
+
+// DecodeDoubleSignEvidence decodes an RLP encoded double-sign evidence.
+func DecodeDoubleSignEvidence(data []byte) (*DoubleSignEvidence, error) {
+	evidence := new(DoubleSignEvidence)
+	if err := rlp.DecodeBytes(data, evidence); err != nil {
+		return nil, err
+	}
+	if evidence.HeaderA == nil || evidence.HeaderB == nil || evidence.HeaderA.Number == nil || evidence.HeaderB.Number == nil {
+		return nil, errUnknownBlock
+	}
+	return evidence, nil
+}
+
+// Number returns the height at which the offence took place.
+func (e *DoubleSignEvidence) Number() uint64 {
+	return e.HeaderA.Number.Uint64()
+}
+
+// Hash returns a unique identifier of the evidence.
+func (e *DoubleSignEvidence) Hash() common.Hash {
+	hashA, hashB := e.HeaderA.Hash(), e.HeaderB.Hash()
+	return crypto.Keccak256Hash(hashA[:], hashB[:])
+}
+
+// Verify checks that the two headers are distinct blocks at the same height
+// whose seals were both produced by the same validator, and returns that
+// validator.
+func (e *DoubleSignEvidence) Verify(sigcache *lru.ARCCache) (common.Address, error) {
+	if e.HeaderA.Number.Cmp(e.HeaderB.Number) != 0 {
+		return common.Address{}, errEvidenceHeightMismatch
+	}
+	signerA, err := ecrecover(e.HeaderA, sigcache)
+	if err != nil {
+		return common.Address{}, err
+	}
+	signerB, err := ecrecover(e.HeaderB, sigcache)
+	if err != nil {
+		return common.Address{}, err
+	}
+	// Both extra-data sections are known to hold a seal by now, so hashing is safe
+	if SealHash(e.HeaderA) == SealHash(e.HeaderB) {
+		return common.Address{}, errEvidenceSameHeader
+	}
+	if signerA != signerB {
+		return common.Address{}, errEvidenceSignerMismatch
+	}
+	return signerA, nil
+}
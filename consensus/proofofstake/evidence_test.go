@@ -0,0 +1,186 @@
+package proofofstake
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Tests that double-sign evidence is only accepted for two distinct blocks
+// sealed by the same validator at the same height.
+func TestDoubleSignEvidence(t *testing.T) {
+	accounts := newTesterAccountPool()
+	sigcache, _ := lru.NewARC(inmemorySignatures)
+
+	newHeader := func(number uint64, signer string, time uint64) *types.Header {
+		header := &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Time:       time,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		accounts.sign(header, signer)
+		return header
+	}
+	a := newHeader(10, "A", 1)
+
+	tests := []struct {
+		other *types.Header
+		err   error
+	}{
+		{other: newHeader(10, "A", 2), err: nil},
+		{other: newHeader(11, "A", 2), err: errEvidenceHeightMismatch},
+		{other: newHeader(10, "A", 1), err: errEvidenceSameHeader},
+		{other: newHeader(10, "B", 2), err: errEvidenceSignerMismatch},
+	}
+	for i, tt := range tests {
+		evidence := NewDoubleSignEvidence(a, tt.other)
+		signer, err := evidence.Verify(sigcache)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err == nil && signer != accounts.address("A") {
+			t.Errorf("test %d: offender mismatch: have %x, want %x", i, signer, accounts.address("A"))
+		}
+	}
+	// Ensure the evidence survives a round trip through a transaction payload
+	evidence := NewDoubleSignEvidence(tests[0].other, a)
+	blob, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		t.Fatalf("failed to encode evidence: %v", err)
+	}
+	decoded, err := DecodeDoubleSignEvidence(blob)
+	if err != nil {
+		t.Fatalf("failed to decode evidence: %v", err)
+	}
+	if decoded.Hash() != evidence.Hash() || decoded.Hash() != NewDoubleSignEvidence(a, tests[0].other).Hash() {
+		t.Errorf("evidence hash mismatch after round trip")
+	}
+}
+
+// Tests that slashing burns part of the offender's deposit and removes it from
+// the staking contract's validator list.
+func TestSlashDeposit(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		contract   = common.HexToAddress("0x01")
+		validators = []common.Address{common.HexToAddress("0xa1"), common.HexToAddress("0xa2"), common.HexToAddress("0xa3")}
+		depositor  = common.HexToAddress("0xd2")
+		start      = crypto.Keccak256Hash(stakingSlotValidatorList[:]).Big()
+	)
+	statedb.SetState(contract, stakingSlotValidatorList, common.BigToHash(big.NewInt(int64(len(validators)))))
+	for i, validator := range validators {
+		statedb.SetState(contract, common.BigToHash(new(big.Int).Add(start, big.NewInt(int64(i)))), common.BytesToHash(validator[:]))
+	}
+	var validatorId common.Hash
+	copy(validatorId[:], validators[1][:])
+	statedb.SetState(contract, mappingSlot(validatorId, stakingSlotValidatorSender), common.BytesToHash(depositor[:]))
+	statedb.SetState(contract, mappingSlot(common.BytesToHash(depositor[:]), stakingSlotBalances), common.BigToHash(big.NewInt(1000)))
+	statedb.SetState(contract, stakingSlotTotalDepositBalance, common.BigToHash(big.NewInt(3000)))
+	statedb.AddBalance(contract, big.NewInt(3000))
+
+	if !ejectValidator(statedb, contract, validators[1]) {
+		t.Fatalf("failed to eject listed validator")
+	}
+	if ejectValidator(statedb, contract, validators[1]) {
+		t.Fatalf("ejected unlisted validator")
+	}
+	if have := stakingValidators(statedb, contract); len(have) != 2 || have[0] != validators[0] || have[1] != validators[2] {
+		t.Fatalf("validator list mismatch: have %x", have)
+	}
+	if burned := slashDeposit(statedb, contract, validators[1], 10); burned.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("burned amount mismatch: have %v, want 100", burned)
+	}
+	if have := statedb.GetState(contract, mappingSlot(common.BytesToHash(depositor[:]), stakingSlotBalances)).Big(); have.Cmp(big.NewInt(900)) != 0 {
+		t.Errorf("deposit mismatch: have %v, want 900", have)
+	}
+	if have := statedb.GetState(contract, stakingSlotTotalDepositBalance).Big(); have.Cmp(big.NewInt(2900)) != 0 {
+		t.Errorf("total deposit mismatch: have %v, want 2900", have)
+	}
+	if have := statedb.GetBalance(contract); have.Cmp(big.NewInt(2900)) != 0 {
+		t.Errorf("contract balance mismatch: have %v, want 2900", have)
+	}
+}
+
+// stakingKey returns the public key a tester account deposits with, prefixed
+// by the type byte the staking contract skips when deriving the validator.
+func (ap *testerAccountPool) stakingKey(account string) []byte {
+	ap.address(account)
+	pubkey, err := cryptobase.SigAlg.SerializePublicKey(&ap.accounts[account].PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	return append([]byte{0x01}, pubkey...)
+}
+
+// doubleSign returns a transaction submitting evidence that a tester account
+// sealed two distinct blocks at the given height.
+func (ap *testerAccountPool) doubleSign(signer string, number uint64) *types.Transaction {
+	headers := make([]*types.Header, 2)
+	for i := range headers {
+		headers[i] = &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Time:       uint64(i),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		ap.sign(headers[i], signer)
+	}
+	blob, err := rlp.EncodeToBytes(NewDoubleSignEvidence(headers[0], headers[1]))
+	if err != nil {
+		panic(err)
+	}
+	return types.NewTransaction(0, DoubleSignEvidenceAddress, new(big.Int), 0, new(big.Int), blob)
+}
+
+// newTestSlashingEngine returns an engine burning 10% of the stake of the
+// double-signers of the given chain.
+func newTestSlashingEngine(config *params.ChainConfig) *ProofOfStake {
+	sigcache, _ := lru.NewARC(inmemorySignatures)
+	return &ProofOfStake{
+		chainConfig: config,
+		config:      &params.ProofOfStakeConfig{DoubleSignSlashPercent: 10},
+		signatures:  sigcache,
+	}
+}
+
+// Tests that a validator jailed after double-signing is still slashed, and
+// can't be unjailed anymore.
+func TestSlashJailedValidator(t *testing.T) {
+	var (
+		accounts  = newTesterAccountPool()
+		contract  = newTestStakingContract(t, common.Big0, common.Big0)
+		depositor = common.HexToAddress("0xd1")
+		validator = contract.deposit(depositor, accounts.stakingKey("A"), 1000)
+		statedb   = contract.statedb
+		engine    = newTestSlashingEngine(contract.config)
+	)
+	if validator != accounts.address("A") {
+		t.Fatalf("validator mismatch: have %x, want %x", validator, accounts.address("A"))
+	}
+	if !jailValidator(statedb, contract.address, validator, 15) {
+		t.Fatalf("failed to jail validator")
+	}
+	engine.processEvidence(&types.Header{Number: big.NewInt(20)}, statedb, []*types.Transaction{accounts.doubleSign("A", 10)})
+
+	if out := contract.mustCall(common.Address{}, 0, "depositBalanceOf", depositor); out[0].(*big.Int).Cmp(big.NewInt(900)) != 0 {
+		t.Errorf("deposit mismatch: have %v, want 900", out[0])
+	}
+	if unjailValidator(statedb, contract.address, validator, 100, 0) {
+		t.Errorf("unjailed slashed validator")
+	}
+	if have := stakingValidators(statedb, contract.address); len(have) != 0 {
+		t.Errorf("slashed validator listed: %x", have)
+	}
+}
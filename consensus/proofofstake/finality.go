@@ -0,0 +1,137 @@
+package proofofstake
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/consensus"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/log"
+)
+
+// voteLength is the number of extra-data bytes a checkpoint vote takes up in a
+// non-checkpoint header: the checkpoint's number followed by its hash. The vote
+// is signed by the header's seal.
+const voteLength = 8 + common.HashLength
+
+var (
+	// errInvalidVote is returned if a header votes for a block other than the
+	// latest epoch checkpoint of its own chain.
+	errInvalidVote = errors.New("vote for unknown checkpoint")
+
+	// errDuplicateVote is returned if a validator votes twice for the same
+	// epoch checkpoint.
+	errDuplicateVote = errors.New("duplicate checkpoint vote")
+)
+
+// checkpointVote is a validator's attestation that it considers an epoch
+// checkpoint block part of the canonical chain.
+type checkpointVote struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// bytes returns the extra-data encoding of the vote.
+func (v *checkpointVote) bytes() []byte {
+	blob := make([]byte, voteLength)
+	binary.BigEndian.PutUint64(blob, v.Number)
+	copy(blob[8:], v.Hash[:])
+	return blob
+}
+
+// headerVote extracts the checkpoint vote from the extra-data of a
+// non-checkpoint header, returning nil if the header carries none.
+func headerVote(header *types.Header) *checkpointVote {
+	if len(header.Extra) != extraVanity+voteLength+extraSeal {
+		return nil
+	}
+	blob := header.Extra[extraVanity : extraVanity+voteLength]
+	return &checkpointVote{
+		Number: binary.BigEndian.Uint64(blob),
+		Hash:   common.BytesToHash(blob[8:]),
+	}
+}
+
+// checkVote verifies that a vote cast by the given signer targets the latest
+// checkpoint and is the first one the signer cast for it.
+func (s *Snapshot) checkVote(vote *checkpointVote, signer common.Address) error {
+	if vote.Number != s.Checkpoint || vote.Hash != s.CheckpointHash {
+		return errInvalidVote
+	}
+	if _, ok := s.Votes[signer]; ok {
+		return errDuplicateVote
+	}
+	return nil
+}
+
+// castVote records a signer's vote for the latest checkpoint, finalizing the
+// checkpoint once more than two thirds of the epoch's stake voted for it. If
+// no stake is known for the epoch, every signer carries the same weight.
+func (s *Snapshot) castVote(signer common.Address) {
+	s.Votes[signer] = struct{}{}
+	if s.FinalizedHash == s.CheckpointHash {
+		return
+	}
+	var (
+		voted = new(big.Int)
+		total = new(big.Int)
+	)
+	for validator := range s.Signers {
+		total.Add(total, s.weight(validator))
+		if _, ok := s.Votes[validator]; ok {
+			voted.Add(voted, s.weight(validator))
+		}
+	}
+	if total.Sign() > 0 && new(big.Int).Mul(voted, big.NewInt(3)).Cmp(new(big.Int).Mul(total, big.NewInt(2))) > 0 {
+		s.Finalized, s.FinalizedHash = s.Checkpoint, s.CheckpointHash
+	}
+}
+
+// weight returns the voting power of a signer in the current epoch.
+func (s *Snapshot) weight(signer common.Address) *big.Int {
+	if len(s.Stakes) == 0 {
+		return common.Big1
+	}
+	if stake, ok := s.Stakes[signer]; ok {
+		return stake
+	}
+	return common.Big0
+}
+
+// vote returns the checkpoint vote the local validator should include in the
+// header it is about to seal on top of the given snapshot, or nil if it already
+// voted. A validator never votes for two different blocks at the same height.
+func (c *ProofOfStake) vote(snap *Snapshot, validator common.Address) *checkpointVote {
+	if _, ok := snap.Signers[validator]; !ok {
+		return nil
+	}
+	if _, ok := snap.Votes[validator]; ok {
+		return nil
+	}
+	key := append([]byte("proofofstake-vote-"), validator[:]...)
+	key = append(key, new(big.Int).SetUint64(snap.Checkpoint).Bytes()...)
+
+	if voted, err := c.db.Get(key); err == nil && common.BytesToHash(voted) != snap.CheckpointHash {
+		return nil
+	}
+	if err := c.db.Put(key, snap.CheckpointHash[:]); err != nil {
+		log.Warn("Failed to record checkpoint vote", "number", snap.Checkpoint, "err", err)
+		return nil
+	}
+	return &checkpointVote{Number: snap.Checkpoint, Hash: snap.CheckpointHash}
+}
+
+// Finalized implements consensus.Finality, returning the latest checkpoint
+// finalized on the chain ending in the given header.
+func (c *ProofOfStake) Finalized(chain consensus.ChainHeaderReader, header *types.Header) (*types.Header, error) {
+	snap, err := c.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if snap.FinalizedHash == (common.Hash{}) {
+		return nil, nil
+	}
+	return chain.GetHeader(snap.FinalizedHash, snap.Finalized), nil
+}
@@ -0,0 +1,120 @@
+package proofofstake
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/consensus"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/systemcontracts1"
+)
+
+const defaultMissedSlotsThreshold = 1000 // Default number of in-turn slots a validator may miss per epoch
+
+// defaultMinStake is the default deposit a depositor needs to unjail its validator.
+var defaultMinStake = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+
+var (
+	stakingSlotSenderValidator = common.BigToHash(big.NewInt(5)) // mapping (address => bytes32) _senderValidatorIdMapping
+
+	jailedPrefix = []byte("proofofstake-jailed") // Prefix of the contract slots holding the height a validator was jailed at
+)
+
+// jailedSlot returns the contract slot recording whether a validator is jailed.
+func jailedSlot(validator common.Address) common.Hash {
+	return crypto.Keccak256Hash(jailedPrefix, validator[:])
+}
+
+// stakingValidatorOf returns the validator a depositor staked for.
+func stakingValidatorOf(statedb *state.StateDB, contract common.Address, depositor common.Address) common.Address {
+	validatorId := statedb.GetState(contract, mappingSlot(common.BytesToHash(depositor[:]), stakingSlotSenderValidator))
+	return common.BytesToAddress(validatorId[:common.AddressLength])
+}
+
+// jailValidator removes a validator from the staking contract's validator list
+// until its depositor unjails it. It returns false if the validator was not
+// listed.
+func jailValidator(statedb *state.StateDB, contract common.Address, validator common.Address, number uint64) bool {
+	if !ejectValidator(statedb, contract, validator) {
+		return false
+	}
+	statedb.SetState(contract, jailedSlot(validator), common.BigToHash(new(big.Int).SetUint64(number)))
+	return true
+}
+
+// unjailValidator appends a jailed validator back to the staking contract's
+// validator list. It returns false if the validator was not jailed, or was
+// jailed less than period blocks before number.
+func unjailValidator(statedb *state.StateDB, contract common.Address, validator common.Address, number uint64, period uint64) bool {
+	slot := jailedSlot(validator)
+	jailedAt := statedb.GetState(contract, slot)
+	if jailedAt == (common.Hash{}) {
+		return false
+	}
+	if number < jailedAt.Big().Uint64()+period {
+		return false
+	}
+	statedb.SetState(contract, slot, common.Hash{})
+
+	length := statedb.GetState(contract, stakingSlotValidatorList).Big()
+	element := new(big.Int).Add(crypto.Keccak256Hash(stakingSlotValidatorList[:]).Big(), length)
+	statedb.SetState(contract, common.BigToHash(element), common.BytesToHash(validator[:]))
+	statedb.SetState(contract, stakingSlotValidatorList, common.BigToHash(new(big.Int).Add(length, common.Big1)))
+	return true
+}
+
+// processLiveness is the system call run at every block. On epoch checkpoints
+// it jails the validators that missed too many in-turn slots during the closing
+// epoch; these were already left out of the checkpoint's validator set. It also
+// honours the successful unjail() calls the block made to the staking contract:
+// the depositor's validator is listed again if the depositor still holds at
+// least MinStake and the validator was jailed JailPeriod blocks ago or more.
+// Nothing is done before the jailing fork.
+func (c *ProofOfStake) processLiveness(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB, txs []*types.Transaction, receipts []*types.Receipt) error {
+	if !c.chainConfig.IsJailing(header.Number) || systemcontracts1.IsStakingContract(c.chainConfig) != nil {
+		return nil
+	}
+	var (
+		contract = systemcontracts1.GetStakingContract_Address(c.chainConfig)
+		number   = header.Number.Uint64()
+	)
+	if number > 0 && number%c.config.Epoch == 0 {
+		snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+		if err != nil {
+			return err
+		}
+		for _, offender := range snap.offline() {
+			if jailValidator(statedb, contract, offender, number) {
+				log.Warn("Jailed offline validator", "validator", offender, "missed", snap.Missed[offender], "number", number)
+			}
+		}
+	}
+	unjail := systemcontracts1.GetStakingContract_ABI().Methods[systemcontracts1.GetContract_Method_Unjail()].ID
+	for i, receipt := range receipts {
+		tx := txs[i]
+		if receipt.Status != types.ReceiptStatusSuccessful || tx.To() == nil || *tx.To() != contract {
+			continue
+		}
+		if data := tx.Data(); len(data) < len(unjail) || !bytes.Equal(data[:len(unjail)], unjail) {
+			continue
+		}
+		depositor, err := types.Sender(c.signer, tx)
+		if err != nil {
+			continue
+		}
+		balance := statedb.GetState(contract, mappingSlot(common.BytesToHash(depositor[:]), stakingSlotBalances)).Big()
+		if balance.Cmp(c.config.MinStake) < 0 {
+			continue
+		}
+		validator := stakingValidatorOf(statedb, contract, depositor)
+		if unjailValidator(statedb, contract, validator, number, c.config.JailPeriod) {
+			log.Info("Unjailed validator", "validator", validator, "depositor", depositor, "number", number)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package proofofstake
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/systemcontracts1"
+)
+
+// Tests that jailed validators leave the staking contract's validator list
+// until they are unjailed, and only then.
+func TestJailValidator(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		contract  = common.HexToAddress("0x01")
+		validator = common.HexToAddress("0xa1")
+		other     = common.HexToAddress("0xa2")
+		depositor = common.HexToAddress("0xd1")
+	)
+	if unjailValidator(statedb, contract, other, 8, 4) {
+		t.Fatalf("unjailed validator that was never jailed")
+	}
+	// Populate the validator list the way newDeposit does
+	start := crypto.Keccak256Hash(stakingSlotValidatorList[:]).Big()
+	statedb.SetState(contract, common.BigToHash(start), common.BytesToHash(other[:]))
+	statedb.SetState(contract, common.BigToHash(new(big.Int).Add(start, common.Big1)), common.BytesToHash(validator[:]))
+	statedb.SetState(contract, stakingSlotValidatorList, common.BigToHash(big.NewInt(2)))
+
+	var validatorId common.Hash
+	copy(validatorId[:], validator[:])
+	statedb.SetState(contract, mappingSlot(common.BytesToHash(depositor[:]), stakingSlotSenderValidator), validatorId)
+	if have := stakingValidatorOf(statedb, contract, depositor); have != validator {
+		t.Fatalf("depositor's validator mismatch: have %x, want %x", have, validator)
+	}
+	if !jailValidator(statedb, contract, validator, 8) {
+		t.Fatalf("failed to jail listed validator")
+	}
+	if jailValidator(statedb, contract, validator, 8) {
+		t.Fatalf("jailed validator twice")
+	}
+	if have := stakingValidators(statedb, contract); len(have) != 1 || have[0] != other {
+		t.Fatalf("validator list mismatch after jailing: have %x", have)
+	}
+	if have := statedb.GetState(contract, jailedSlot(validator)).Big(); have.Cmp(big.NewInt(8)) != 0 {
+		t.Errorf("jail height mismatch: have %v, want 8", have)
+	}
+	if unjailValidator(statedb, contract, validator, 11, 4) {
+		t.Fatalf("unjailed validator before its jail period passed")
+	}
+	if !unjailValidator(statedb, contract, validator, 12, 4) {
+		t.Fatalf("failed to unjail validator")
+	}
+	if unjailValidator(statedb, contract, validator, 12, 4) {
+		t.Fatalf("unjailed validator twice")
+	}
+	if have := stakingValidators(statedb, contract); len(have) != 2 || have[0] != other || have[1] != validator {
+		t.Fatalf("validator list mismatch after unjailing: have %x", have)
+	}
+}
+
+// Tests that depositors unjail their validator by calling the staking contract,
+// and that the engine only relists it for successful calls made once the jail
+// period is over.
+func TestUnjailCall(t *testing.T) {
+	var (
+		accounts  = newTesterAccountPool()
+		contract  = newTestStakingContract(t, common.Big0, common.Big0)
+		depositor = accounts.address("D")
+		validator = contract.deposit(depositor, accounts.stakingKey("A"), 1000)
+		statedb   = contract.statedb
+	)
+	if _, reason := contract.call(common.HexToAddress("0xd2"), 0, "unjail"); reason != "Sender is not a depositor" {
+		t.Fatalf("unjail by non-depositor: have %q", reason)
+	}
+	// The call is not payable, any value sent along is refunded
+	contract.call(depositor, 5, "unjail")
+	if have := statedb.GetBalance(contract.address); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("contract balance mismatch after value-bearing unjail: have %v, want 1000", have)
+	}
+	contract.mustCall(depositor, 0, "unjail")
+
+	if !jailValidator(statedb, contract.address, validator, 15) {
+		t.Fatalf("failed to jail validator")
+	}
+	engine := newTestSlashingEngine(contract.config)
+	engine.config = &params.ProofOfStakeConfig{Epoch: 100, JailPeriod: 10, MinStake: big.NewInt(1000)}
+	engine.signer = types.NewEIP155Signer(contract.config.ChainID)
+
+	data, err := systemcontracts1.PackUnjail()
+	if err != nil {
+		t.Fatalf("failed to pack unjail: %v", err)
+	}
+	tx, err := types.SignTx(types.NewTransaction(0, contract.address, new(big.Int), 100000, new(big.Int), data), engine.signer, accounts.accounts["D"])
+	if err != nil {
+		t.Fatalf("failed to sign unjail: %v", err)
+	}
+	var (
+		failed  = []*types.Receipt{{Status: types.ReceiptStatusFailed}}
+		success = []*types.Receipt{{Status: types.ReceiptStatusSuccessful}}
+	)
+	for i, test := range []struct {
+		number   int64
+		receipts []*types.Receipt
+		listed   bool
+	}{
+		{24, success, false}, // Jail period not over yet
+		{25, failed, false},  // Reverted unjail call
+		{25, success, true},
+	} {
+		if err := engine.processLiveness(nil, &types.Header{Number: big.NewInt(test.number)}, statedb, []*types.Transaction{tx}, test.receipts); err != nil {
+			t.Fatalf("test %d: failed to process liveness: %v", i, err)
+		}
+		if have := len(stakingValidators(statedb, contract.address)) == 1; have != test.listed {
+			t.Fatalf("test %d: validator listed mismatch: have %v, want %v", i, have, test.listed)
+		}
+	}
+}
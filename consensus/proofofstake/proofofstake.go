@@ -28,6 +28,7 @@ import (
 	"io"
 	"math/big"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
@@ -43,6 +44,7 @@ import (
 	"github.com/DogeProtocol/dp/params"
 	"github.com/DogeProtocol/dp/rlp"
 	"github.com/DogeProtocol/dp/rpc"
+	"github.com/DogeProtocol/dp/systemcontracts1"
 	lru "github.com/hashicorp/golang-lru"
 	"golang.org/x/crypto/sha3"
 )
@@ -55,21 +57,20 @@ const (
 	wiggleTime = 500 * time.Millisecond // Random delay (per validator) to allow concurrent signers
 
 	shiftBlockNumber = 100 // proofofstake contract switches
-
-	systemRewardPercent = 4 // it means 1/2^4 = 1/16 percentage of gas fee incoming will be distributed to system
 )
 
 // ProofOfStake proof-of-authority protocol constants.
 var (
 	maxSystemBalance = new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
 
-	epochLength = uint64(30000) // Default number of blocks after which to checkpoint and reset the pending votes
+	epochLength = uint64(params.DefaultProofOfStakeEpoch) // Default number of blocks after which to checkpoint and reset the pending votes
 
 	extraVanity = 32                                               // Fixed number of extra-data prefix bytes reserved for validator vanity
 	extraSeal   = cryptobase.SigAlg.SignatureWithPublicKeyLength() // Fixed number of extra-data suffix bytes reserved for validator seal
 
-	nonceAuthVote = hexutil.MustDecode("0xffffffffffffffff") // Magic nonce number to vote on adding a new validator
-	nonceDropVote = hexutil.MustDecode("0x0000000000000000") // Magic nonce number to vote on removing a validator.
+	validatorEntryLength = common.AddressLength + common.HashLength // Checkpoint extra-data bytes per validator: address followed by its stake
+
+	nonceAuthVote = hexutil.MustDecode("0xffffffffffffffff") // Magic nonce number to vote on adding a validator, tolerated until the stake schedule fork
 
 	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
 
@@ -90,12 +91,16 @@ var (
 	// block has a beneficiary set to non-zeroes.
 	errInvalidCheckpointBeneficiary = errors.New("beneficiary in checkpoint block non-zero")
 
-	// errInvalidVote is returned if a nonce value is something else that the two
-	// allowed constants of 0x00..0 or 0xff..f.
-	errInvalidVote = errors.New("vote nonce not 0x00..0 or 0xff..f")
+	// errInvalidNonce is returned if a block's nonce is non-zero. Signer changes
+	// are driven by the staking contract, so header votes are not permitted.
+	errInvalidNonce = errors.New("non-zero nonce")
+
+	// errInvalidVoteNonce is returned if a nonce value is something else that the
+	// two allowed constants of 0x00..0 or 0xff..f before the stake schedule fork.
+	errInvalidVoteNonce = errors.New("vote nonce not 0x00..0 or 0xff..f")
 
 	// errInvalidCheckpointVote is returned if a checkpoint/epoch transition block
-	// has a vote nonce set to non-zeroes.
+	// has a vote nonce set to non-zeroes before the stake schedule fork.
 	errInvalidCheckpointVote = errors.New("vote nonce in checkpoint block non-zero")
 
 	// errMissingVanity is returned if a block's extra-data section is shorter than
@@ -118,10 +123,6 @@ var (
 	// invalid list of signers (i.e. non divisible by 20 bytes).
 	errInvalidCheckpointSigners = errors.New("invalid signer list on checkpoint block")
 
-	// errMismatchingCheckpointSigners is returned if a checkpoint block contains a
-	// list of signers different than the one the local node calculated.
-	errMismatchingCheckpointSigners = errors.New("mismatching signer list on checkpoint block")
-
 	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
 	errInvalidMixDigest = errors.New("non-zero mix digest")
 
@@ -139,9 +140,9 @@ var (
 	// the previous block's timestamp + the minimum block period.
 	errInvalidTimestamp = errors.New("invalid timestamp")
 
-	// errInvalidVotingChain is returned if an authorization list is attempted to
-	// be modified via out-of-range or non-contiguous headers.
-	errInvalidVotingChain = errors.New("invalid voting chain")
+	// errInvalidSnapshotChain is returned if a snapshot is attempted to be
+	// advanced via out-of-range or non-contiguous headers.
+	errInvalidSnapshotChain = errors.New("invalid snapshot chain")
 
 	// errUnauthorizedSigner is returned if a header is signed by a non-authorized entity.
 	errUnauthorizedSigner = errors.New("unauthorized signer")
@@ -183,6 +184,41 @@ func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, er
 	return validator, nil
 }
 
+// recoverSeals verifies the seals of a batch of headers across all CPUs, caching
+// the validators of the valid ones for ecrecover to pick up. Invalid seals are
+// left for ecrecover to report.
+func recoverSeals(headers []*types.Header, sigcache *lru.ARCCache) {
+	var (
+		hashes     []common.Hash
+		pubkeys    [][]byte
+		sealHashes [][]byte
+		signatures [][]byte
+	)
+	for _, header := range headers {
+		hash := header.Hash()
+		if sigcache.Contains(hash) || len(header.Extra) < extraSeal {
+			continue
+		}
+		signature := header.Extra[len(header.Extra)-extraSeal:]
+		_, pubkey, err := cryptobase.SigAlg.PublicKeyAndSignatureFromCombinedSignature(nil, signature)
+		if err != nil {
+			continue
+		}
+		hashes = append(hashes, hash)
+		pubkeys = append(pubkeys, pubkey)
+		sealHashes = append(sealHashes, SealHash(header).Bytes())
+		signatures = append(signatures, signature)
+	}
+	valid := cryptobase.SigAlg.VerifyBatch(pubkeys, sealHashes, signatures)
+	for i := range valid {
+		if valid[i] {
+			var validator common.Address
+			copy(validator[:], crypto.Keccak256(pubkeys[i])[12:])
+			sigcache.Add(hashes[i], validator)
+		}
+	}
+}
+
 // ProofOfStake is the proof-of-authority consensus engine proposed to support the
 // Ethereum testnet following the Ropsten attacks.
 type ProofOfStake struct {
@@ -194,8 +230,6 @@ type ProofOfStake struct {
 	recents    *lru.ARCCache // Snapshots for recent block to speed up reorgs
 	signatures *lru.ARCCache // Signatures of recent blocks to speed up mining
 
-	proposals map[common.Address]bool // Current list of proposals we are pushing
-
 	signer    types.Signer
 	validator common.Address
 	signFn    SignerFn // Signer function to authorize hashes with
@@ -218,6 +252,33 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database,
 	if conf.ProofOfStake.Epoch == 0 {
 		conf.ProofOfStake.Epoch = epochLength
 	}
+	if conf.ProofOfStake.DoubleSignSlashPercent == 0 {
+		conf.ProofOfStake.DoubleSignSlashPercent = defaultDoubleSignSlashPercent
+	}
+	if conf.ProofOfStake.MissedSlotsThreshold == 0 {
+		conf.ProofOfStake.MissedSlotsThreshold = defaultMissedSlotsThreshold
+	}
+	if conf.ProofOfStake.JailPeriod == 0 {
+		conf.ProofOfStake.JailPeriod = conf.ProofOfStake.Epoch
+	}
+	if conf.ProofOfStake.MinStake == nil {
+		conf.ProofOfStake.MinStake = new(big.Int).Set(defaultMinStake)
+	}
+	if conf.ProofOfStake.BlockReward == nil {
+		conf.ProofOfStake.BlockReward = new(big.Int).Set(defaultBlockReward)
+	}
+	if conf.ProofOfStake.RewardReductionInterval > 0 && conf.ProofOfStake.RewardReductionPercent == 0 {
+		conf.ProofOfStake.RewardReductionPercent = defaultRewardReductionPercent
+	}
+	if conf.ProofOfStake.RewardReductionPercent > 100 {
+		conf.ProofOfStake.RewardReductionPercent = 100
+	}
+	if conf.ProofOfStake.FeeSharePercent > 100 {
+		conf.ProofOfStake.FeeSharePercent = 100
+	}
+	if conf.ProofOfStake.TreasuryPercent > 100 {
+		conf.ProofOfStake.TreasuryPercent = 100
+	}
 	// Allocate the snapshot caches and c.ProofOfStakereate the engine
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
@@ -230,7 +291,6 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database,
 		ethAPI:      ethAPI,
 		recents:     recents,
 		signatures:  signatures,
-		proposals:   make(map[common.Address]bool),
 		signer:      types.NewEIP155Signer(chainConfig.ChainID),
 	}
 }
@@ -254,6 +314,9 @@ func (c *ProofOfStake) VerifyHeaders(chain consensus.ChainHeaderReader, headers
 	results := make(chan error, len(headers))
 
 	go func() {
+		if len(headers) > 1 {
+			recoverSeals(headers, c.signatures)
+		}
 		for i, header := range headers {
 			err := c.verifyHeader(chain, header, headers[:i])
 
@@ -286,12 +349,19 @@ func (c *ProofOfStake) verifyHeader(chain consensus.ChainHeaderReader, header *t
 	if checkpoint && header.Coinbase != (common.Address{}) {
 		return errInvalidCheckpointBeneficiary
 	}
-	// Nonces must be 0x00..0 or 0xff..f, zeroes enforced on checkpoints
-	if !bytes.Equal(header.Nonce[:], nonceAuthVote) && !bytes.Equal(header.Nonce[:], nonceDropVote) {
-		return errInvalidVote
-	}
-	if checkpoint && !bytes.Equal(header.Nonce[:], nonceDropVote) {
-		return errInvalidCheckpointVote
+	// Nonces must be zero, the signer set is not voted on in headers. Before the
+	// stake schedule fork, 0xff..f is still tolerated outside of checkpoints.
+	if c.chainConfig.IsStakeSchedule(header.Number) {
+		if header.Nonce != (types.BlockNonce{}) {
+			return errInvalidNonce
+		}
+	} else {
+		if header.Nonce != (types.BlockNonce{}) && !bytes.Equal(header.Nonce[:], nonceAuthVote) {
+			return errInvalidVoteNonce
+		}
+		if checkpoint && header.Nonce != (types.BlockNonce{}) {
+			return errInvalidCheckpointVote
+		}
 	}
 	// Check that the extra-data contains both the vanity and signature
 	if len(header.Extra) < extraVanity {
@@ -302,10 +372,10 @@ func (c *ProofOfStake) verifyHeader(chain consensus.ChainHeaderReader, header *t
 	}
 	// Ensure that the extra-data contains a signer list on checkpoint, but none otherwise
 	var signersBytes = len(header.Extra) - extraVanity - extraSeal
-	if !checkpoint && signersBytes != 0 {
+	if !checkpoint && signersBytes != 0 && signersBytes != voteLength {
 		return errExtraSigners
 	}
-	if checkpoint && signersBytes%common.AddressLength != 0 {
+	if checkpoint && number > 0 && signersBytes%checkpointEntryLength(c.chainConfig, header.Number) != 0 {
 		return errInvalidCheckpointSigners
 	}
 	// Ensure that the mix digest is zero as we don't have fork protection currently
@@ -380,16 +450,12 @@ func (c *ProofOfStake) verifyCascadingFields(chain consensus.ChainHeaderReader,
 		return err
 	}
 
-	// If the block is a checkpoint block, verify the signer list
-	if number%c.config.Epoch == 0 {
-		signers := make([]byte, len(snap.Signers)*common.AddressLength)
-		for i, signer := range snap.signers() {
-			copy(signers[i*common.AddressLength:], signer[:])
-		}
-		extraSuffix := len(header.Extra) - extraSeal
-
-		if !bytes.Equal(header.Extra[extraVanity:extraSuffix], signers) {
-			return errMismatchingCheckpointSigners
+	// If the block is a checkpoint block carrying the validator list over from
+	// the snapshot, verify it. Lists read from the staking contract need the
+	// parent state, they are verified when the block is finalized.
+	if number%c.config.Epoch == 0 && !c.stakingContract() {
+		if err := c.verifyEpochValidators(snap, header); err != nil {
+			return err
 		}
 	}
 
@@ -412,7 +478,7 @@ func (c *ProofOfStake) snapshot(chain consensus.ChainHeaderReader, number uint64
 		}
 		// If an on-disk checkpoint snapshot can be found, use that
 		if number%checkpointInterval == 0 {
-			if s, err := loadSnapshot(c.config, c.signatures, c.db, hash); err == nil {
+			if s, err := loadSnapshot(c.chainConfig, c.config, c.signatures, c.db, hash); err == nil {
 				log.Trace("Loaded voting snapshot from disk", "number", number, "hash", hash)
 				snap = s
 				break
@@ -426,19 +492,31 @@ func (c *ProofOfStake) snapshot(chain consensus.ChainHeaderReader, number uint64
 			checkpoint := chain.GetHeaderByNumber(number)
 			if checkpoint != nil {
 				hash := checkpoint.Hash()
-				//dynamic length
-
-				addresslength := len(checkpoint.Extra) - extraVanity - extraSeal
-				if addresslength < 0 {
-					addresslength = len(checkpoint.Extra) - extraVanity - 65
-				}
 
-				signers := make([]common.Address, addresslength/common.AddressLength)
-
-				for i := 0; i < len(signers); i++ {
-					copy(signers[i][:], checkpoint.Extra[extraVanity+i*common.AddressLength:])
+				var (
+					signers []common.Address
+					stakes  map[common.Address]*big.Int
+				)
+				if number == 0 {
+					//dynamic length
+					addresslength := len(checkpoint.Extra) - extraVanity - extraSeal
+					if addresslength < 0 {
+						addresslength = len(checkpoint.Extra) - extraVanity - 65
+					}
+
+					signers = make([]common.Address, addresslength/common.AddressLength)
+
+					for i := 0; i < len(signers); i++ {
+						copy(signers[i][:], checkpoint.Extra[extraVanity+i*common.AddressLength:])
+					}
+				} else {
+					var err error
+					if signers, stakes, err = checkpointValidators(c.chainConfig, checkpoint); err != nil {
+						return nil, err
+					}
 				}
-				snap = newSnapshot(c.config, c.signatures, number, hash, signers)
+				snap = newSnapshot(c.chainConfig, c.config, c.signatures, number, hash, signers)
+				snap.rotate(signers, stakes, checkpoint.ParentHash)
 				if err := snap.store(c.db); err != nil {
 					return nil, err
 				}
@@ -485,6 +563,125 @@ func (c *ProofOfStake) snapshot(chain consensus.ChainHeaderReader, number uint64
 	return snap, err
 }
 
+// stakingContract reports whether the validator set of every epoch is read from
+// the staking contract, rather than carried over from the snapshot.
+func (c *ProofOfStake) stakingContract() bool {
+	return c.ethAPI != nil && systemcontracts1.IsStakingContract(c.chainConfig) == nil
+}
+
+// verifyEpochValidators checks that a checkpoint header commits to the validator
+// set following the given snapshot.
+func (c *ProofOfStake) verifyEpochValidators(snap *Snapshot, header *types.Header) error {
+	validators, stakes, err := c.epochValidators(snap)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(header.Extra[extraVanity:len(header.Extra)-extraSeal], validatorsToBytes(validators, stakes, checkpointEntryLength(c.chainConfig, header.Number))) {
+		return errMismatchingEpochValidators
+	}
+	return nil
+}
+
+// epochValidators returns the validator set, along with the stake backing each
+// validator, that the checkpoint block following the given snapshot must commit
+// to. From the jailing fork on, validators that were offline for too long
+// during the closing epoch are jailed by the checkpoint block and left out.
+func (c *ProofOfStake) epochValidators(snap *Snapshot) ([]common.Address, map[common.Address]*big.Int, error) {
+	validators, stakes, err := c.stakedValidators(snap)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !c.chainConfig.IsJailing(new(big.Int).SetUint64(snap.Number + 1)) {
+		return validators, stakes, nil
+	}
+	jailed := make(map[common.Address]struct{})
+	for _, offender := range snap.offline() {
+		jailed[offender] = struct{}{}
+	}
+	if len(jailed) == 0 {
+		return validators, stakes, nil
+	}
+	active := make([]common.Address, 0, len(validators))
+	for _, validator := range validators {
+		if _, ok := jailed[validator]; !ok {
+			active = append(active, validator)
+		}
+	}
+	if len(active) == 0 {
+		return validators, stakes, nil
+	}
+	return active, stakes, nil
+}
+
+// stakedValidators returns the validators listed by the staking contract at the
+// snapshot's block along with the stake backing each of them, made up of the
+// depositor's own deposit and the delegations bonded to it. If the contract
+// is unavailable or holds no validators yet, the current signers and their
+// stakes carry over into the next epoch.
+func (c *ProofOfStake) stakedValidators(snap *Snapshot) ([]common.Address, map[common.Address]*big.Int, error) {
+	if !c.stakingContract() {
+		return snap.signers(), snap.Stakes, nil
+	}
+	validators, err := c.GetValidatorsAddress1(snap.Number+1, snap.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(validators) == 0 {
+		return snap.signers(), snap.Stakes, nil
+	}
+	stakes := make(map[common.Address]*big.Int, len(validators))
+	for _, validator := range validators {
+		if _, ok := stakes[validator]; ok {
+			continue
+		}
+		depositor, err := c.GetDepositor(validator, snap.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		stake, err := c.GetDepositBalance(depositor, snap.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		delegated, err := c.GetDelegatedStake(validator, snap.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		stakes[validator] = new(big.Int).Add(stake, delegated)
+	}
+	result := make([]common.Address, 0, len(stakes))
+	for validator := range stakes {
+		result = append(result, validator)
+	}
+	sort.Sort(signersAscending(result))
+	return result, stakes, nil
+}
+
+// checkpointEntryLength returns the number of extra-data bytes every validator
+// takes up in the checkpoint block at the given height. Validators are listed
+// along with their stake from the stake schedule fork on, by address only
+// before it and in the genesis block.
+func checkpointEntryLength(config *params.ChainConfig, number *big.Int) int {
+	if number.Sign() == 0 || !config.IsStakeSchedule(number) {
+		return common.AddressLength
+	}
+	return validatorEntryLength
+}
+
+// validatorsToBytes flattens a validator list and the stakes backing the
+// validators into the format stored in the extra-data of checkpoint blocks,
+// with entries of the given length.
+func validatorsToBytes(validators []common.Address, stakes map[common.Address]*big.Int, entryLength int) []byte {
+	buf := make([]byte, len(validators)*entryLength)
+	for i, validator := range validators {
+		entry := buf[i*entryLength:]
+		copy(entry, validator[:])
+		if stake := stakes[validator]; stake != nil && entryLength == validatorEntryLength {
+			copy(entry[common.AddressLength:], common.BigToHash(stake).Bytes())
+		}
+	}
+	return buf
+}
+
 // VerifyUncles implements consensus.Engine, always returning an error for any
 // uncles as this consensus mechanism doesn't permit uncles.
 func (c *ProofOfStake) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
@@ -529,6 +726,12 @@ func (c *ProofOfStake) verifySeal(chain consensus.ChainHeaderReader, header *typ
 			}
 		}
 	}
+	// Ensure any checkpoint vote targets the latest checkpoint of this chain
+	if vote := headerVote(header); vote != nil && number%c.config.Epoch != 0 {
+		if err := snap.checkVote(vote, validator); err != nil {
+			return err
+		}
+	}
 	// Ensure that the difficulty corresponds to the turn-ness of the validator
 	if !c.fakeDiff {
 		inturn := snap.inturn(header.Number.Uint64(), validator)
@@ -545,38 +748,16 @@ func (c *ProofOfStake) verifySeal(chain consensus.ChainHeaderReader, header *typ
 // Prepare implements consensus.Engine, preparing all the consensus fields of the
 // header for running the transactions on top.
 func (c *ProofOfStake) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
-	// If the block isn't a checkpoint, cast a random vote (good enough for now)
 	header.Coinbase = common.Address{}
 	header.Nonce = types.BlockNonce{}
 
 	number := header.Number.Uint64()
 
-	// Assemble the voting snapshot to check which votes make sense
+	// Assemble the snapshot to check who is allowed to seal
 	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
 	if err != nil {
 		return err
 	}
-	if number%c.config.Epoch != 0 {
-		c.lock.RLock()
-
-		// Gather all the proposals that make sense voting on
-		addresses := make([]common.Address, 0, len(c.proposals))
-		for address, authorize := range c.proposals {
-			if snap.validVote(address, authorize) {
-				addresses = append(addresses, address)
-			}
-		}
-		// If there's pending proposals, cast a vote on them
-		if len(addresses) > 0 {
-			header.Coinbase = addresses[rand.Intn(len(addresses))]
-			if c.proposals[header.Coinbase] {
-				copy(header.Nonce[:], nonceAuthVote)
-			} else {
-				copy(header.Nonce[:], nonceDropVote)
-			}
-		}
-		c.lock.RUnlock()
-	}
 	// Set the correct difficulty
 	header.Difficulty = calcDifficulty(snap, c.validator)
 
@@ -587,9 +768,18 @@ func (c *ProofOfStake) Prepare(chain consensus.ChainHeaderReader, header *types.
 	header.Extra = header.Extra[:extraVanity]
 
 	if number%c.config.Epoch == 0 {
+		validators, stakes, err := c.epochValidators(snap)
+		if err != nil {
+			return err
+		}
+		header.Extra = append(header.Extra, validatorsToBytes(validators, stakes, checkpointEntryLength(c.chainConfig, header.Number))...)
+	} else {
+		c.lock.RLock()
+		validator := c.validator
+		c.lock.RUnlock()
 
-		for _, signer := range snap.signers() {
-			header.Extra = append(header.Extra, signer[:]...)
+		if vote := c.vote(snap, validator); vote != nil {
+			header.Extra = append(header.Extra, vote.bytes()...)
 		}
 	}
 	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
@@ -609,31 +799,35 @@ func (c *ProofOfStake) Prepare(chain consensus.ChainHeaderReader, header *types.
 	return nil
 }
 
-// Finalize implements consensus.Engine, ensuring no uncles are set, nor block
-// rewards given.
-func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) error {
-
-	number := header.Number.Uint64()
-
-	if number >= shiftBlockNumber {
-		//Depositor reward
-		validators, err := c.GetValidatorsAddress1(number, header.ParentHash)
+// Finalize implements consensus.Engine, ensuring no uncles are set, verifying
+// the validator list of checkpoint blocks against the staking contract and
+// paying out the block reward.
+func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) error {
+	// The parent state is available now, check the validators read from it
+	if number := header.Number.Uint64(); number > 0 && number%c.config.Epoch == 0 && c.stakingContract() {
+		snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
 		if err != nil {
 			return err
 		}
-		if len(validators) > 0 {
-			index := number % uint64(len(validators))
-			validator := validators[index]
-			depositor, err := c.GetDepositor(validator, header.ParentHash)
-			if err != nil {
-				return err
-			}
-			err = c.accumulateRewards(state, header, uncles, depositor)
-			if err != nil {
-				return err
-			}
+		if err := c.verifyEpochValidators(snap, header); err != nil {
+			return err
 		}
 	}
+	// Pay the depositor backing the validator that sealed the block
+	sealer, err := c.Author(header)
+	if err != nil {
+		return err
+	}
+	if err := c.distributeRewards(header, state, sealer, txs, receipts); err != nil {
+		return err
+	}
+	// Punish any validator proven to have sealed conflicting blocks
+	c.processEvidence(header, state, txs)
+
+	// Jail validators gone offline and release the ones asking to be unjailed
+	if err := c.processLiveness(chain, header, state, txs, receipts); err != nil {
+		return err
+	}
 
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
@@ -642,28 +836,22 @@ func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types
 }
 
 // FinalizeAndAssemble implements consensus.Engine, ensuring no uncles are set,
-// nor block rewards given, and returns the final block.
+// paying out the block reward, and returns the final block.
 func (c *ProofOfStake) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
-	number := header.Number.Uint64()
+	// Pay the depositor backing the local validator, which is about to seal the block
+	c.lock.RLock()
+	sealer := c.validator
+	c.lock.RUnlock()
 
-	if number >= shiftBlockNumber {
-		//Depositor reward
-		validators, err := c.GetValidatorsAddress1(number, header.ParentHash)
-		if err != nil {
-			return nil, err
-		}
-		if len(validators) > 0 {
-			index := number % uint64(len(validators))
-			validator := validators[index]
-			depositor, err := c.GetDepositor(validator, header.ParentHash)
-			if err != nil {
-				return nil, err
-			}
-			err = c.accumulateRewards(state, header, uncles, depositor)
-			if err != nil {
-				return nil, err
-			}
-		}
+	if err := c.distributeRewards(header, state, sealer, txs, receipts); err != nil {
+		return nil, err
+	}
+	// Punish any validator proven to have sealed conflicting blocks
+	c.processEvidence(header, state, txs)
+
+	// Jail validators gone offline and release the ones asking to be unjailed
+	if err := c.processLiveness(chain, header, state, txs, receipts); err != nil {
+		return nil, err
 	}
 
 	if txs == nil {
@@ -745,11 +933,13 @@ func (c *ProofOfStake) Seal(chain consensus.ChainHeaderReader, block *types.Bloc
 	// Sweet, the protocol permits us to sign the block, wait for our time
 	delay := time.Unix(int64(header.Time), 0).Sub(time.Now()) // nolint: gosimple
 	if header.Difficulty.Cmp(diffNoTurn) == 0 {
-		// It's not our turn explicitly to sign, delay it a bit
-		wiggle := time.Duration(len(snap.Signers)/2+1) * wiggleTime
-		delay += time.Duration(rand.Int63n(int64(wiggle)))
+		// It's not our turn explicitly to sign, step in according to our rank in
+		// the proposer schedule, with a bit of jitter to avoid lockstep
+		rank := snap.rank(number, validator)
+		wiggle := time.Duration(rank) * wiggleTime
+		delay += wiggle + time.Duration(rand.Int63n(int64(wiggleTime)))
 
-		log.Trace("Out-of-turn signing requested", "wiggle", common.PrettyDuration(wiggle))
+		log.Trace("Out-of-turn signing requested", "rank", rank, "wiggle", common.PrettyDuration(wiggle))
 	}
 	// Sign all the things!
 	sighash, err := signFn(accounts.Account{Address: validator}, accounts.MimetypeProofOfStake, ProofOfStakeRLP(header))
@@ -777,8 +967,9 @@ func (c *ProofOfStake) Seal(chain consensus.ChainHeaderReader, block *types.Bloc
 
 // CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
 // that a new block should have:
-// * DIFF_NOTURN(2) if BLOCK_NUMBER % SIGNER_COUNT != SIGNER_INDEX
-// * DIFF_INTURN(1) if BLOCK_NUMBER % SIGNER_COUNT == SIGNER_INDEX
+// * DIFF_INTURN(2) if the signer heads the stake weighted proposer schedule, or
+// if BLOCK_NUMBER % SIGNER_COUNT == SIGNER_INDEX before the stake schedule fork
+// * DIFF_NOTURN(1) otherwise
 func (c *ProofOfStake) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
 	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
 	if err != nil {
@@ -868,31 +1059,8 @@ var (
 	ByzantiumBlockReward      = big.NewInt(3e+18) // Block reward in wei for successfully mining a block upward from Byzantium
 	ConstantinopleBlockReward = big.NewInt(2e+18) // Block reward in wei for successfully mining a block upward from Constantinople
 
-	big8  = big.NewInt(8)
-	big32 = big.NewInt(32)
 )
 
-// AccumulateRewards credits the coinbase of the given block with the mining
-// reward. The total reward consists of the static block reward and rewards for
-// included uncles. The coinbase of each uncle block is also rewarded.
-func (c *ProofOfStake) accumulateRewards(state *state.StateDB, header *types.Header,
-	uncles []*types.Header, validator common.Address) error {
-
-	// Select the correct block reward based on chain progression
-	blockReward := FrontierBlockReward
-	// Accumulate the rewards for the miner and any included uncles
-	reward := new(big.Int).Set(blockReward)
-	r := new(big.Int)
-	r.Sub(r, header.Number)
-	r.Mul(r, blockReward)
-	r.Div(r, big8)
-	r.Div(blockReward, big32)
-	reward.Add(reward, r)
-	state.AddBalance(validator, reward)
-
-	return nil
-}
-
 // chain context
 type chainContext struct {
 	Chain        consensus.ChainHeaderReader
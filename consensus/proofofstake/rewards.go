@@ -0,0 +1,285 @@
+package proofofstake
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/systemcontracts1"
+)
+
+const defaultRewardReductionPercent = 50 // Default reduction of the base reward every interval, halving it
+
+// rewardPrefix is the prefix of the staking contract slots recording the reward
+// breakdown of the latest block paying one.
+var rewardPrefix = []byte("proofofstake-reward")
+
+// rewardFieldDelegators is the field of the reward record holding the number of
+// delegator rewards, following the fields of the breakdown.
+const rewardFieldDelegators = 10
+
+// defaultBlockReward is the base reward paid if the chain configuration doesn't
+// set one. It matches the payout of the fixed reward schedule it replaces.
+var defaultBlockReward = new(big.Int).Add(FrontierBlockReward, new(big.Int).Div(FrontierBlockReward, big.NewInt(32)))
+
+// BlockReward is the breakdown of the payout made when finalizing a block. It
+// is recorded in the staking contract's state, so that payouts can be
+// reconciled against balance changes.
+type BlockReward struct {
+	Number          uint64         `json:"number"`
+	Sealer          common.Address `json:"sealer"`          // Validator that sealed the block
+	Depositor       common.Address `json:"depositor"`       // Account backing the sealer, receiving the reward
+	BaseReward      *hexutil.Big   `json:"baseReward"`      // Block subsidy according to the reward schedule
+	Fees            *hexutil.Big   `json:"fees"`            // Priority fees paid by the block's transactions
+	FeeReward       *hexutil.Big   `json:"feeReward"`       // Share of the fees added to the reward
+	Burned          *hexutil.Big   `json:"burned"`          // Share of the fees burned
+	Treasury        *hexutil.Big   `json:"treasury"`        // Share of the reward paid to the treasury
+	DepositorReward *hexutil.Big   `json:"depositorReward"` // Share of the reward paid to the depositor
+
+	Commission       *hexutil.Big                    `json:"commission"`                 // Part of the depositor's share taken from the delegators
+	DelegatorRewards map[common.Address]*hexutil.Big `json:"delegatorRewards,omitempty"` // Share of the reward paid to every delegator
+}
+
+// baseReward returns the block subsidy at the given height, reduced by the
+// configured percentage once every reduction interval.
+func (c *ProofOfStake) baseReward(number uint64) *big.Int {
+	reward := new(big.Int).Set(c.config.BlockReward)
+	if c.config.RewardReductionInterval == 0 {
+		return reward
+	}
+	keep := big.NewInt(int64(100 - c.config.RewardReductionPercent))
+	for i := number / c.config.RewardReductionInterval; i > 0 && reward.Sign() > 0; i-- {
+		reward.Mul(reward, keep)
+		reward.Div(reward, big.NewInt(100))
+	}
+	return reward
+}
+
+// distributeRewards pays the block reward to the depositor backing the sealer
+// of the block and to the accounts delegating to it. The reward is made up of
+// the scheduled subsidy and a share of the priority fees paid by the block's
+// transactions, the rest of which is burned. A percentage of the reward may go
+// to the treasury. The breakdown of the payout is recorded in the staking
+// contract's state.
+//
+// Nothing is paid before the staking contract is in use, or if the sealer isn't
+// backed by a deposit. Before the staking rewards fork, the fixed legacy reward
+// is paid instead.
+func (c *ProofOfStake) distributeRewards(header *types.Header, statedb *state.StateDB, sealer common.Address, txs []*types.Transaction, receipts []*types.Receipt) error {
+	number := header.Number.Uint64()
+	if number < shiftBlockNumber || systemcontracts1.IsStakingContract(c.chainConfig) != nil {
+		return nil
+	}
+	if !c.chainConfig.IsStakingRewards(header.Number) {
+		return c.accumulateLegacyReward(header, statedb)
+	}
+	contract := systemcontracts1.GetStakingContract_Address(c.chainConfig)
+	depositor := stakingDepositor(statedb, contract, sealer)
+	if depositor == (common.Address{}) {
+		return nil
+	}
+	reward := c.payReward(header, statedb, contract, sealer, depositor, blockFees(header, txs, receipts))
+	recordReward(statedb, contract, reward)
+	return nil
+}
+
+// accumulateLegacyReward pays the fixed block reward to the depositor of the
+// in-turn validator of the staking contract's list at the parent block.
+func (c *ProofOfStake) accumulateLegacyReward(header *types.Header, statedb *state.StateDB) error {
+	number := header.Number.Uint64()
+	validators, err := c.GetValidatorsAddress1(number, header.ParentHash)
+	if err != nil {
+		return err
+	}
+	if len(validators) == 0 {
+		return nil
+	}
+	depositor, err := c.GetDepositor(validators[number%uint64(len(validators))], header.ParentHash)
+	if err != nil {
+		return err
+	}
+	statedb.AddBalance(depositor, new(big.Int).Set(defaultBlockReward))
+	return nil
+}
+
+// blockFees returns the priority fees paid by the transactions of a block,
+// which the state transition credited to the block's beneficiary.
+func blockFees(header *types.Header, txs []*types.Transaction, receipts []*types.Receipt) *big.Int {
+	fees := new(big.Int)
+	for i, receipt := range receipts {
+		tip := txs[i].GasPrice()
+		if header.BaseFee != nil {
+			tip = txs[i].EffectiveGasTipValue(header.BaseFee)
+		}
+		fees.Add(fees, new(big.Int).Mul(tip, new(big.Int).SetUint64(receipt.GasUsed)))
+	}
+	return fees
+}
+
+// payReward credits the block reward to the treasury, the given depositor and
+// the sealer's delegators. The given fees are collected from the sealer, which
+// received them as the block's beneficiary. Once the treasury is paid, the
+// reward is shared pro rata to the amounts bonded by the depositor and every
+// delegator, the sealer's commission being deducted from the delegators' shares
+// in favour of the depositor.
+func (c *ProofOfStake) payReward(header *types.Header, statedb *state.StateDB, contract common.Address, sealer common.Address, depositor common.Address, collected *big.Int) *BlockReward {
+	var (
+		number    = header.Number.Uint64()
+		base      = c.baseReward(number)
+		fees      = new(big.Int)
+		feeReward = new(big.Int)
+		treasury  = new(big.Int)
+	)
+	if c.config.FeeSharePercent > 0 {
+		fees.Set(collected)
+		if balance := statedb.GetBalance(sealer); fees.Cmp(balance) > 0 {
+			fees.Set(balance)
+		}
+		statedb.SubBalance(sealer, fees)
+
+		feeReward.Mul(fees, new(big.Int).SetUint64(c.config.FeeSharePercent))
+		feeReward.Div(feeReward, big.NewInt(100))
+	}
+	total := new(big.Int).Add(base, feeReward)
+	if c.config.TreasuryPercent > 0 && c.config.TreasuryAddress != (common.Address{}) {
+		treasury.Mul(total, new(big.Int).SetUint64(c.config.TreasuryPercent))
+		treasury.Div(treasury, big.NewInt(100))
+		statedb.AddBalance(c.config.TreasuryAddress, treasury)
+	}
+	var (
+		payout      = new(big.Int).Sub(total, treasury)
+		commissions = new(big.Int)
+		delegators  map[common.Address]*hexutil.Big
+	)
+	if delegated := delegatedStake(statedb, contract, sealer); delegated.Sign() > 0 {
+		var (
+			deposit = statedb.GetState(contract, mappingSlot(common.BytesToHash(depositor[:]), stakingSlotBalances)).Big()
+			stake   = new(big.Int).Add(deposit, delegated)
+			keep    = new(big.Int).SetUint64(100 - commission(statedb, contract, sealer))
+			paid    = new(big.Int)
+		)
+		delegators = make(map[common.Address]*hexutil.Big)
+		for _, delegation := range validatorDelegations(statedb, contract, sealer) {
+			share := new(big.Int).Mul(payout, delegation.Amount.ToInt())
+			share.Div(share, stake)
+
+			reward := new(big.Int).Mul(share, keep)
+			reward.Div(reward, big.NewInt(100))
+
+			statedb.AddBalance(delegation.Delegator, reward)
+			delegators[delegation.Delegator] = (*hexutil.Big)(reward)
+			commissions.Add(commissions, new(big.Int).Sub(share, reward))
+			paid.Add(paid, reward)
+		}
+		payout.Sub(payout, paid)
+	}
+	statedb.AddBalance(depositor, payout)
+
+	return &BlockReward{
+		Number:          number,
+		Sealer:          sealer,
+		Depositor:       depositor,
+		BaseReward:      (*hexutil.Big)(base),
+		Fees:            (*hexutil.Big)(fees),
+		FeeReward:       (*hexutil.Big)(feeReward),
+		Burned:          (*hexutil.Big)(new(big.Int).Sub(fees, feeReward)),
+		Treasury:        (*hexutil.Big)(treasury),
+		DepositorReward: (*hexutil.Big)(payout),
+
+		Commission:       (*hexutil.Big)(commissions),
+		DelegatorRewards: delegators,
+	}
+}
+
+// rewardSlot returns the staking contract slot holding the given field of the
+// reward record, which describes the payout of the latest block paying one.
+func rewardSlot(field int64) common.Hash {
+	start := crypto.Keccak256Hash(rewardPrefix).Big()
+	return common.BigToHash(start.Add(start, big.NewInt(field)))
+}
+
+// rewardDelegatorSlot returns the staking contract slot holding the given field
+// of the i-th delegator reward of the reward record: the delegator and the
+// amount paid to it. Entries are laid out like a solidity array of structs
+// whose length is the record's delegator count.
+func rewardDelegatorSlot(i uint64, field int64) common.Hash {
+	length := rewardSlot(rewardFieldDelegators)
+	start := crypto.Keccak256Hash(length[:]).Big()
+	start.Add(start, new(big.Int).SetUint64(2*i))
+	return common.BigToHash(start.Add(start, big.NewInt(field)))
+}
+
+// recordReward stores the breakdown of a block's payout in the staking contract,
+// replacing the record of the previous block paying a reward. Delegator rewards
+// are stored ordered by address.
+func recordReward(statedb *state.StateDB, contract common.Address, reward *BlockReward) {
+	fields := []common.Hash{
+		common.BigToHash(new(big.Int).SetUint64(reward.Number)),
+		common.BytesToHash(reward.Sealer[:]),
+		common.BytesToHash(reward.Depositor[:]),
+		common.BigToHash(reward.BaseReward.ToInt()),
+		common.BigToHash(reward.Fees.ToInt()),
+		common.BigToHash(reward.FeeReward.ToInt()),
+		common.BigToHash(reward.Burned.ToInt()),
+		common.BigToHash(reward.Treasury.ToInt()),
+		common.BigToHash(reward.DepositorReward.ToInt()),
+		common.BigToHash(reward.Commission.ToInt()),
+	}
+	for i, value := range fields {
+		statedb.SetState(contract, rewardSlot(int64(i)), value)
+	}
+	delegators := make([]common.Address, 0, len(reward.DelegatorRewards))
+	for delegator := range reward.DelegatorRewards {
+		delegators = append(delegators, delegator)
+	}
+	sort.Slice(delegators, func(i, j int) bool {
+		return bytes.Compare(delegators[i][:], delegators[j][:]) < 0
+	})
+	for i, delegator := range delegators {
+		statedb.SetState(contract, rewardDelegatorSlot(uint64(i), 0), common.BytesToHash(delegator[:]))
+		statedb.SetState(contract, rewardDelegatorSlot(uint64(i), 1), common.BigToHash(reward.DelegatorRewards[delegator].ToInt()))
+	}
+	// Clear the delegator rewards left over from the previous record
+	previous := statedb.GetState(contract, rewardSlot(rewardFieldDelegators)).Big().Uint64()
+	for i := uint64(len(delegators)); i < previous; i++ {
+		statedb.SetState(contract, rewardDelegatorSlot(i, 0), common.Hash{})
+		statedb.SetState(contract, rewardDelegatorSlot(i, 1), common.Hash{})
+	}
+	statedb.SetState(contract, rewardSlot(rewardFieldDelegators), common.BigToHash(new(big.Int).SetUint64(uint64(len(delegators)))))
+}
+
+// loadReward retrieves the reward breakdown of the given block from the staking
+// contract's state at that block. It returns nil if the block paid no reward.
+func loadReward(statedb stateReader, contract common.Address, number uint64) *BlockReward {
+	field := func(i int64) common.Hash {
+		return statedb.GetState(contract, rewardSlot(i))
+	}
+	if field(0).Big().Uint64() != number || field(1) == (common.Hash{}) {
+		return nil
+	}
+	reward := &BlockReward{
+		Number:          number,
+		Sealer:          common.BytesToAddress(field(1).Bytes()),
+		Depositor:       common.BytesToAddress(field(2).Bytes()),
+		BaseReward:      (*hexutil.Big)(field(3).Big()),
+		Fees:            (*hexutil.Big)(field(4).Big()),
+		FeeReward:       (*hexutil.Big)(field(5).Big()),
+		Burned:          (*hexutil.Big)(field(6).Big()),
+		Treasury:        (*hexutil.Big)(field(7).Big()),
+		DepositorReward: (*hexutil.Big)(field(8).Big()),
+		Commission:      (*hexutil.Big)(field(9).Big()),
+	}
+	if count := field(rewardFieldDelegators).Big().Uint64(); count > 0 {
+		reward.DelegatorRewards = make(map[common.Address]*hexutil.Big, count)
+		for i := uint64(0); i < count; i++ {
+			delegator := common.BytesToAddress(statedb.GetState(contract, rewardDelegatorSlot(i, 0)).Bytes())
+			reward.DelegatorRewards[delegator] = (*hexutil.Big)(statedb.GetState(contract, rewardDelegatorSlot(i, 1)).Big())
+		}
+	}
+	return reward
+}
@@ -0,0 +1,124 @@
+package proofofstake
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/params"
+)
+
+// Tests that the base reward is reduced by the configured percentage once every
+// reduction interval.
+func TestBaseReward(t *testing.T) {
+	engine := &ProofOfStake{config: &params.ProofOfStakeConfig{
+		BlockReward:             big.NewInt(1000),
+		RewardReductionInterval: 100,
+		RewardReductionPercent:  50,
+	}}
+	tests := []struct {
+		number uint64
+		reward int64
+	}{
+		{0, 1000}, {99, 1000}, {100, 500}, {199, 500}, {200, 250}, {1000, 0},
+	}
+	for _, tt := range tests {
+		if have := engine.baseReward(tt.number); have.Cmp(big.NewInt(tt.reward)) != 0 {
+			t.Errorf("block %d: reward mismatch: have %v, want %d", tt.number, have, tt.reward)
+		}
+	}
+	engine.config.RewardReductionInterval = 0
+	if have := engine.baseReward(1000); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("reward reduced without interval: have %v, want 1000", have)
+	}
+}
+
+// Tests that the fees of a block are the priority fees paid for the gas used by
+// its transactions.
+func TestBlockFees(t *testing.T) {
+	var (
+		legacy  = types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(10)})
+		dynamic = types.NewTx(&types.DynamicFeeTx{GasTipCap: big.NewInt(3), GasFeeCap: big.NewInt(12)})
+		txs     = []*types.Transaction{legacy, dynamic}
+
+		receipts = []*types.Receipt{{GasUsed: 100}, {GasUsed: 200}}
+	)
+	// Before London the whole gas price is paid to the beneficiary
+	if have := blockFees(&types.Header{}, txs[:1], receipts[:1]); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("pre-London fees mismatch: have %v, want 1000", have)
+	}
+	// Afterwards only the tip above the base fee is
+	header := &types.Header{BaseFee: big.NewInt(8)}
+	if have := blockFees(header, txs, receipts); have.Cmp(big.NewInt(2*100+3*200)) != 0 {
+		t.Errorf("fees mismatch: have %v, want %d", have, 2*100+3*200)
+	}
+}
+
+// Tests that the block reward and the fee share are split between the depositor
+// and the treasury, burning the remaining fees, and that the breakdown of the
+// payout is recorded in the staking contract's state.
+func TestPayReward(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		contract  = common.HexToAddress("0x01")
+		sealer    = common.HexToAddress("0xa1")
+		depositor = common.HexToAddress("0xd1")
+		treasury  = common.HexToAddress("0x7e")
+		zero      = common.Address{}
+		header    = &types.Header{Number: big.NewInt(shiftBlockNumber)}
+	)
+	statedb.AddBalance(sealer, big.NewInt(500))
+	statedb.AddBalance(zero, big.NewInt(10000))
+
+	engine := &ProofOfStake{config: &params.ProofOfStakeConfig{
+		BlockReward:     big.NewInt(1000),
+		FeeSharePercent: 50,
+		TreasuryPercent: 10,
+		TreasuryAddress: treasury,
+	}}
+	reward := engine.payReward(header, statedb, contract, sealer, depositor, big.NewInt(400))
+
+	if have := statedb.GetBalance(sealer); have.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("collected fees mismatch: sealer left with %v, want 100", have)
+	}
+	if have := statedb.GetBalance(zero); have.Cmp(big.NewInt(10000)) != 0 {
+		t.Errorf("zero address balance touched: have %v, want 10000", have)
+	}
+	if have := statedb.GetBalance(treasury); have.Cmp(big.NewInt(120)) != 0 {
+		t.Errorf("treasury balance mismatch: have %v, want 120", have)
+	}
+	if have := statedb.GetBalance(depositor); have.Cmp(big.NewInt(1080)) != 0 {
+		t.Errorf("depositor balance mismatch: have %v, want 1080", have)
+	}
+	if reward.Sealer != sealer || reward.Depositor != depositor {
+		t.Errorf("reward recipients mismatch: have %x/%x, want %x/%x", reward.Sealer, reward.Depositor, sealer, depositor)
+	}
+	if reward.Fees.ToInt().Cmp(big.NewInt(400)) != 0 || reward.Burned.ToInt().Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("fee accounting mismatch: fees %v, burned %v", reward.Fees, reward.Burned)
+	}
+	paid := new(big.Int).Add(reward.Treasury.ToInt(), reward.DepositorReward.ToInt())
+	if want := new(big.Int).Add(reward.BaseReward.ToInt(), reward.FeeReward.ToInt()); paid.Cmp(want) != 0 {
+		t.Errorf("payouts don't reconcile: paid %v, earned %v", paid, want)
+	}
+	// Record the payout and check it's retrievable at its block only
+	recordReward(statedb, contract, reward)
+
+	if stale := loadReward(statedb, contract, shiftBlockNumber+1); stale != nil {
+		t.Errorf("reward record returned for another block: %+v", stale)
+	}
+	stored := loadReward(statedb, contract, shiftBlockNumber)
+	if stored == nil {
+		t.Fatalf("reward record missing")
+	}
+	have, _ := json.Marshal(stored)
+	want, _ := json.Marshal(reward)
+	if !bytes.Equal(have, want) {
+		t.Errorf("reward record mismatch: have %s, want %s", have, want)
+	}
+}
@@ -0,0 +1,163 @@
+package proofofstake
+
+import (
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/systemcontracts1"
+)
+
+const defaultDoubleSignSlashPercent = 10 // Default percentage of a double-signer's deposit to burn
+
+// Storage layout of the staking contract (systemcontracts1/StakingContract.sol).
+// Penalties are applied by the consensus engine directly to the contract state,
+// so these must be kept in sync with the order of the contract's state variables.
+var (
+	stakingSlotTotalDepositBalance = common.BigToHash(big.NewInt(1)) // uint256 _totalDepositBalance
+	stakingSlotBalances            = common.BigToHash(big.NewInt(2)) // mapping (address => uint256) _balances
+	stakingSlotValidatorSender     = common.BigToHash(big.NewInt(4)) // mapping (bytes32 => address) _validatorIdSenderMapping
+	stakingSlotValidatorList       = common.BigToHash(big.NewInt(6)) // address[] _validatorList
+
+	slashedPrefix = []byte("proofofstake-slashed") // Prefix of the contract slots marking already punished offences
+)
+
+// mappingSlot returns the storage slot of a mapping entry, as laid out by solidity.
+func mappingSlot(key common.Hash, slot common.Hash) common.Hash {
+	return crypto.Keccak256Hash(key[:], slot[:])
+}
+
+// stakingDepositor returns the account that deposited for a validator.
+func stakingDepositor(statedb *state.StateDB, contract common.Address, validator common.Address) common.Address {
+	var validatorId common.Hash // bytes32(uint256(uint160(validator)) << 96)
+	copy(validatorId[:], validator[:])
+	return common.BytesToAddress(statedb.GetState(contract, mappingSlot(validatorId, stakingSlotValidatorSender)).Bytes())
+}
+
+// stakingValidators returns the validator list stored in the staking contract.
+func stakingValidators(statedb *state.StateDB, contract common.Address) []common.Address {
+	var (
+		length     = statedb.GetState(contract, stakingSlotValidatorList).Big().Uint64()
+		start      = crypto.Keccak256Hash(stakingSlotValidatorList[:]).Big()
+		validators = make([]common.Address, length)
+	)
+	for i := uint64(0); i < length; i++ {
+		slot := common.BigToHash(new(big.Int).Add(start, new(big.Int).SetUint64(i)))
+		validators[i] = common.BytesToAddress(statedb.GetState(contract, slot).Bytes())
+	}
+	return validators
+}
+
+// ejectValidator removes a validator from the staking contract's validator list,
+// moving the last entry into its place the way solidity's swap-and-pop does.
+// It returns false if the validator was not listed.
+func ejectValidator(statedb *state.StateDB, contract common.Address, validator common.Address) bool {
+	validators := stakingValidators(statedb, contract)
+	start := crypto.Keccak256Hash(stakingSlotValidatorList[:]).Big()
+	elementSlot := func(i int) common.Hash {
+		return common.BigToHash(new(big.Int).Add(start, big.NewInt(int64(i))))
+	}
+	for i, listed := range validators {
+		if listed != validator {
+			continue
+		}
+		last := len(validators) - 1
+		statedb.SetState(contract, elementSlot(i), common.BytesToHash(validators[last][:]))
+		statedb.SetState(contract, elementSlot(last), common.Hash{})
+		statedb.SetState(contract, stakingSlotValidatorList, common.BigToHash(big.NewInt(int64(last))))
+		return true
+	}
+	return false
+}
+
+// slashDeposit burns the given percentage of the deposit backing a validator,
+// returning the amount burned.
+func slashDeposit(statedb *state.StateDB, contract common.Address, validator common.Address, percent uint64) *big.Int {
+	var (
+		depositor   = stakingDepositor(statedb, contract, validator)
+		balanceSlot = mappingSlot(common.BytesToHash(depositor[:]), stakingSlotBalances)
+		balance     = statedb.GetState(contract, balanceSlot).Big()
+		total       = statedb.GetState(contract, stakingSlotTotalDepositBalance).Big()
+		amount      = new(big.Int).Div(new(big.Int).Mul(balance, new(big.Int).SetUint64(percent)), big.NewInt(100))
+	)
+	if amount.Cmp(total) > 0 {
+		amount.Set(total)
+	}
+	if amount.Cmp(statedb.GetBalance(contract)) > 0 {
+		amount.Set(statedb.GetBalance(contract))
+	}
+	statedb.SetState(contract, balanceSlot, common.BigToHash(new(big.Int).Sub(balance, amount)))
+	statedb.SetState(contract, stakingSlotTotalDepositBalance, common.BigToHash(new(big.Int).Sub(total, amount)))
+	statedb.SubBalance(contract, amount)
+	return amount
+}
+
+// slashedSlot returns the contract slot marking that a validator was already
+// punished for double-signing at the given height.
+func slashedSlot(validator common.Address, number uint64) common.Hash {
+	return crypto.Keccak256Hash(slashedPrefix, validator[:], new(big.Int).SetUint64(number).Bytes())
+}
+
+// slashable reports whether any stake is still backing a validator, whether it
+// is listed or not: a deposit, even withdrawn, delegations or withdrawals still
+// unbonding from it.
+func slashable(statedb *state.StateDB, contract common.Address, validator common.Address) bool {
+	if stakingDepositor(statedb, contract, validator) != (common.Address{}) {
+		return true
+	}
+	if delegatedStake(statedb, contract, validator).Sign() > 0 {
+		return true
+	}
+	return len(unbondingList(validator).members(statedb, contract)) > 0
+}
+
+// processEvidence scans the transactions of a block for double-sign evidence
+// and punishes the offenders: part of their deposit, of the delegations made to
+// them and of the stake still unbonding from them is burned, whether they are
+// still listed or not. They are then ejected from the staking contract's
+// validator list, or lose their place in it for good if they were jailed.
+// Invalid evidence, stale evidence and evidence against validators without
+// stake is ignored, the submitter having paid for it in gas. Evidence is only
+// acted upon from the slashing fork on.
+func (c *ProofOfStake) processEvidence(header *types.Header, statedb *state.StateDB, txs []*types.Transaction) {
+	if !c.chainConfig.IsSlashing(header.Number) || systemcontracts1.IsStakingContract(c.chainConfig) != nil {
+		return
+	}
+	contract := systemcontracts1.GetStakingContract_Address(c.chainConfig)
+
+	for _, tx := range txs {
+		if tx.To() == nil || *tx.To() != DoubleSignEvidenceAddress {
+			continue
+		}
+		evidence, err := DecodeDoubleSignEvidence(tx.Data())
+		if err != nil {
+			log.Debug("Discarded undecodable double-sign evidence", "tx", tx.Hash(), "err", err)
+			continue
+		}
+		if evidence.Number() >= header.Number.Uint64() {
+			continue
+		}
+		offender, err := evidence.Verify(c.signatures)
+		if err != nil {
+			log.Debug("Discarded invalid double-sign evidence", "tx", tx.Hash(), "err", err)
+			continue
+		}
+		marker := slashedSlot(offender, evidence.Number())
+		if statedb.GetState(contract, marker) != (common.Hash{}) || !slashable(statedb, contract, offender) {
+			continue
+		}
+		statedb.SetState(contract, marker, common.BigToHash(common.Big1))
+		burned := slashDeposit(statedb, contract, offender, c.config.DoubleSignSlashPercent)
+		burned.Add(burned, slashDelegations(statedb, contract, offender, c.config.DoubleSignSlashPercent))
+		burned.Add(burned, slashUnbonding(statedb, contract, offender, c.config.DoubleSignSlashPercent))
+
+		if !ejectValidator(statedb, contract, offender) {
+			statedb.SetState(contract, jailedSlot(offender), common.Hash{})
+		}
+
+		log.Warn("Slashed double-signing validator", "validator", offender, "height", evidence.Number(), "burned", burned, "evidence", evidence.Hash())
+	}
+}
@@ -18,12 +18,15 @@ package proofofstake
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"math/big"
 	"sort"
 	"time"
 
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/ethdb"
 	"github.com/DogeProtocol/dp/internal/ethapi"
 	"github.com/DogeProtocol/dp/log"
@@ -31,24 +34,11 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 )
 
-// Vote represents a single vote that an authorized signer made to modify the
-// list of authorizations.
-type Vote struct {
-	Signer    common.Address `json:"signer"`    // Authorized signer that cast this vote
-	Block     uint64         `json:"block"`     // Block number the vote was cast in (expire old votes)
-	Address   common.Address `json:"address"`   // Account being voted on to change its authorization
-	Authorize bool           `json:"authorize"` // Whether to authorize or deauthorize the voted account
-}
-
-// Tally is a simple vote tally to keep the current score of votes. Votes that
-// go against the proposal aren't counted since it's equivalent to not voting.
-type Tally struct {
-	Authorize bool `json:"authorize"` // Whether the vote is about authorizing or kicking someone
-	Votes     int  `json:"votes"`     // Number of votes until now wanting to pass the proposal
-}
-
-// Snapshot is the state of the authorization voting at a given point in time.
+// Snapshot is the state of the validator set at a given point in time. The
+// set of signers is only ever replaced on epoch checkpoint blocks, whose
+// extra-data commits to the validators listed by the staking contract.
 type Snapshot struct {
+	chainConfig      *params.ChainConfig        // Chain config, scheduling the consensus forks
 	config           *params.ProofOfStakeConfig // Consensus engine parameters to fine tune behavior
 	ethAPI           *ethapi.PublicBlockChainAPI
 	sigcache         *lru.ARCCache               // Cache of recent block signatures to speed up ecrecover
@@ -57,8 +47,14 @@ type Snapshot struct {
 	Signers          map[common.Address]struct{} `json:"signers"`            // Set of authorized signers at this moment
 	Recents          map[uint64]common.Address   `json:"recents"`            // Set of recent signers for spam protections
 	RecentForkHashes map[uint64]string           `json:"recent_fork_hashes"` // Set of recent forkHash
-	Votes            []*Vote                     `json:"votes"`              // List of votes cast in chronological order
-	Tally            map[common.Address]Tally    `json:"tally"`              // Current vote tally to avoid recalculating
+	Stakes           map[common.Address]*big.Int `json:"stakes"`             // Deposits backing each signer in the current epoch
+	Seed             common.Hash                 `json:"seed"`               // Parent hash of the epoch checkpoint, seeds the proposer schedule
+	Missed           map[common.Address]uint64   `json:"missed"`             // In-turn slots each signer failed to seal in the current epoch
+	Checkpoint       uint64                      `json:"checkpoint"`         // Number of the latest epoch checkpoint, the target of votes
+	CheckpointHash   common.Hash                 `json:"checkpointHash"`     // Hash of the latest epoch checkpoint
+	Votes            map[common.Address]struct{} `json:"votes"`              // Signers that voted for the latest checkpoint
+	Finalized        uint64                      `json:"finalized"`          // Number of the latest finalized checkpoint
+	FinalizedHash    common.Hash                 `json:"finalizedHash"`      // Hash of the latest finalized checkpoint
 }
 
 // signersAscending implements the sort interface to allow sorting a list of addresses
@@ -70,9 +66,10 @@ func (s signersAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
 // newSnapshot creates a new snapshot with the specified startup parameters. This
 // method does not initialize the set of recent signers, so only ever use if for
-// the genesis block.
-func newSnapshot(config *params.ProofOfStakeConfig, sigcache *lru.ARCCache, number uint64, hash common.Hash, signers []common.Address) *Snapshot {
+// the genesis block or trusted checkpoints, which are considered final.
+func newSnapshot(chainConfig *params.ChainConfig, config *params.ProofOfStakeConfig, sigcache *lru.ARCCache, number uint64, hash common.Hash, signers []common.Address) *Snapshot {
 	snap := &Snapshot{
+		chainConfig:      chainConfig,
 		config:           config,
 		sigcache:         sigcache,
 		Number:           number,
@@ -80,7 +77,13 @@ func newSnapshot(config *params.ProofOfStakeConfig, sigcache *lru.ARCCache, numb
 		Signers:          make(map[common.Address]struct{}),
 		Recents:          make(map[uint64]common.Address),
 		RecentForkHashes: make(map[uint64]string),
-		Tally:            make(map[common.Address]Tally),
+		Stakes:           make(map[common.Address]*big.Int),
+		Missed:           make(map[common.Address]uint64),
+		Checkpoint:       number,
+		CheckpointHash:   hash,
+		Votes:            make(map[common.Address]struct{}),
+		Finalized:        number,
+		FinalizedHash:    hash,
 	}
 	for _, signer := range signers {
 		snap.Signers[signer] = struct{}{}
@@ -89,7 +92,7 @@ func newSnapshot(config *params.ProofOfStakeConfig, sigcache *lru.ARCCache, numb
 }
 
 // loadSnapshot loads an existing snapshot from the database.
-func loadSnapshot(config *params.ProofOfStakeConfig, sigcache *lru.ARCCache, db ethdb.Database, hash common.Hash) (*Snapshot, error) {
+func loadSnapshot(chainConfig *params.ChainConfig, config *params.ProofOfStakeConfig, sigcache *lru.ARCCache, db ethdb.Database, hash common.Hash) (*Snapshot, error) {
 	blob, err := db.Get(append([]byte("proofofstake-"), hash[:]...))
 	if err != nil {
 		return nil, err
@@ -98,8 +101,18 @@ func loadSnapshot(config *params.ProofOfStakeConfig, sigcache *lru.ARCCache, db
 	if err := json.Unmarshal(blob, snap); err != nil {
 		return nil, err
 	}
+	snap.chainConfig = chainConfig
 	snap.config = config
 	snap.sigcache = sigcache
+	if snap.Stakes == nil {
+		snap.Stakes = make(map[common.Address]*big.Int)
+	}
+	if snap.Missed == nil {
+		snap.Missed = make(map[common.Address]uint64)
+	}
+	if snap.Votes == nil {
+		snap.Votes = make(map[common.Address]struct{})
+	}
 
 	return snap, nil
 }
@@ -113,76 +126,47 @@ func (s *Snapshot) store(db ethdb.Database) error {
 	return db.Put(append([]byte("proofofstake-"), s.Hash[:]...), blob)
 }
 
-// copy creates a deep copy of the snapshot, though not the individual votes.
+// copy creates a deep copy of the snapshot.
 func (s *Snapshot) copy() *Snapshot {
 	cpy := &Snapshot{
-		config:   s.config,
-		sigcache: s.sigcache,
-		Number:   s.Number,
-		Hash:     s.Hash,
-		Signers:  make(map[common.Address]struct{}),
-		Recents:  make(map[uint64]common.Address),
-		Votes:    make([]*Vote, len(s.Votes)),
-		Tally:    make(map[common.Address]Tally),
+		chainConfig:      s.chainConfig,
+		config:           s.config,
+		sigcache:         s.sigcache,
+		Number:           s.Number,
+		Hash:             s.Hash,
+		Signers:          make(map[common.Address]struct{}),
+		Recents:          make(map[uint64]common.Address),
+		RecentForkHashes: make(map[uint64]string),
+		Stakes:           make(map[common.Address]*big.Int),
+		Seed:             s.Seed,
+		Missed:           make(map[common.Address]uint64),
+		Checkpoint:       s.Checkpoint,
+		CheckpointHash:   s.CheckpointHash,
+		Votes:            make(map[common.Address]struct{}),
+		Finalized:        s.Finalized,
+		FinalizedHash:    s.FinalizedHash,
 	}
 	for signer := range s.Signers {
 		cpy.Signers[signer] = struct{}{}
 	}
+	for signer, stake := range s.Stakes {
+		cpy.Stakes[signer] = new(big.Int).Set(stake)
+	}
+	for signer, missed := range s.Missed {
+		cpy.Missed[signer] = missed
+	}
+	for signer := range s.Votes {
+		cpy.Votes[signer] = struct{}{}
+	}
 	for block, signer := range s.Recents {
 		cpy.Recents[block] = signer
 	}
-	for address, tally := range s.Tally {
-		cpy.Tally[address] = tally
+	for block, forkHash := range s.RecentForkHashes {
+		cpy.RecentForkHashes[block] = forkHash
 	}
-	copy(cpy.Votes, s.Votes)
-
 	return cpy
 }
 
-// validVote returns whether it makes sense to cast the specified vote in the
-// given snapshot context (e.g. don't try to add an already authorized signer).
-func (s *Snapshot) validVote(address common.Address, authorize bool) bool {
-	_, signer := s.Signers[address]
-	return (signer && !authorize) || (!signer && authorize)
-}
-
-// cast adds a new vote into the tally.
-func (s *Snapshot) cast(address common.Address, authorize bool) bool {
-	// Ensure the vote is meaningful
-	if !s.validVote(address, authorize) {
-		return false
-	}
-	// Cast the vote into an existing or new tally
-	if old, ok := s.Tally[address]; ok {
-		old.Votes++
-		s.Tally[address] = old
-	} else {
-		s.Tally[address] = Tally{Authorize: authorize, Votes: 1}
-	}
-	return true
-}
-
-// uncast removes a previously cast vote from the tally.
-func (s *Snapshot) uncast(address common.Address, authorize bool) bool {
-	// If there's no tally, it's a dangling vote, just drop
-	tally, ok := s.Tally[address]
-	if !ok {
-		return false
-	}
-	// Ensure we only revert counted votes
-	if tally.Authorize != authorize {
-		return false
-	}
-	// Otherwise revert the vote
-	if tally.Votes > 1 {
-		tally.Votes--
-		s.Tally[address] = tally
-	} else {
-		delete(s.Tally, address)
-	}
-	return true
-}
-
 func (s *Snapshot) isMajorityFork(forkHash string) bool {
 	ally := 0
 	for _, h := range s.RecentForkHashes {
@@ -193,8 +177,10 @@ func (s *Snapshot) isMajorityFork(forkHash string) bool {
 	return ally > len(s.RecentForkHashes)/2
 }
 
-// apply creates a new authorization snapshot by applying the given headers to
-// the original one.
+// apply creates a new snapshot by applying the given headers to the original
+// one. Checkpoint headers replace the signer set with the validators committed
+// to in their extra-data; those have already been checked against the staking
+// contract during header verification.
 func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 	// Allow passing in no headers for cleaner code
 	if len(headers) == 0 {
@@ -203,11 +189,11 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 	// Sanity check that the headers can be applied
 	for i := 0; i < len(headers)-1; i++ {
 		if headers[i+1].Number.Uint64() != headers[i].Number.Uint64()+1 {
-			return nil, errInvalidVotingChain
+			return nil, errInvalidSnapshotChain
 		}
 	}
 	if headers[0].Number.Uint64() != s.Number+1 {
-		return nil, errInvalidVotingChain
+		return nil, errInvalidSnapshotChain
 	}
 	// Iterate through the headers and create a new snapshot
 	snap := s.copy()
@@ -217,13 +203,8 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 		logged = time.Now()
 	)
 	for i, header := range headers {
-		// Remove any votes on checkpoint blocks
 		number := header.Number.Uint64()
-		if number%s.config.Epoch == 0 {
 
-			snap.Votes = nil
-			snap.Tally = make(map[common.Address]Tally)
-		}
 		// Delete the oldest signer from the recent list to allow it signing again
 		if limit := uint64(len(snap.Signers)/2 + 1); number >= limit {
 			delete(snap.Recents, number-limit)
@@ -241,83 +222,113 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 				return nil, errRecentlySigned
 			}
 		}
+		// Charge the in-turn proposer with a missed slot if someone else sealed
+		if schedule := snap.schedule(number); len(schedule) > 0 && schedule[0] != signer {
+			snap.Missed[schedule[0]]++
+		}
 		snap.Recents[number] = signer
-		// Header authorized, discard any previous votes from the signer
-		for i, vote := range snap.Votes {
-			if vote.Signer == signer && vote.Address == header.Coinbase {
-				// Uncast the vote from the cached tally
-				snap.uncast(vote.Address, vote.Authorize)
 
-				// Uncast the vote from the chronological list
-				snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
-				break // only one vote allowed
+		// Checkpoint blocks rotate in the validator set for the next epoch and
+		// become the target of its votes, other blocks may carry a vote
+		if number%s.config.Epoch == 0 {
+			validators, stakes, err := checkpointValidators(s.chainConfig, header)
+			if err != nil {
+				return nil, err
 			}
-		}
-		// Tally up the new vote from the signer
-		var authorize bool
-		switch {
-		case bytes.Equal(header.Nonce[:], nonceAuthVote):
-			authorize = true
-		case bytes.Equal(header.Nonce[:], nonceDropVote):
-			authorize = false
-		default:
-			return nil, errInvalidVote
-		}
-		if snap.cast(header.Coinbase, authorize) {
-			snap.Votes = append(snap.Votes, &Vote{
-				Signer:    signer,
-				Block:     number,
-				Address:   header.Coinbase,
-				Authorize: authorize,
-			})
-		}
-		// If the vote passed, update the list of signers
-		if tally := snap.Tally[header.Coinbase]; tally.Votes > len(snap.Signers)/2 {
-			if tally.Authorize {
-				snap.Signers[header.Coinbase] = struct{}{}
-			} else {
-				delete(snap.Signers, header.Coinbase)
+			snap.rotate(validators, stakes, header.ParentHash)
 
-				// Signer list shrunk, delete any leftover recent caches
-				if limit := uint64(len(snap.Signers)/2 + 1); number >= limit {
-					delete(snap.Recents, number-limit)
-				}
-				// Discard any previous votes the deauthorized signer cast
-				for i := 0; i < len(snap.Votes); i++ {
-					if snap.Votes[i].Signer == header.Coinbase {
-						// Uncast the vote from the cached tally
-						snap.uncast(snap.Votes[i].Address, snap.Votes[i].Authorize)
+			snap.Checkpoint, snap.CheckpointHash = number, header.Hash()
+			snap.Votes = make(map[common.Address]struct{})
 
-						// Uncast the vote from the chronological list
-						snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
-
-						i--
-					}
+			// Signer list may have shrunk, delete any leftover recent caches
+			limit := uint64(len(snap.Signers)/2 + 1)
+			for block := range snap.Recents {
+				if block+limit <= number {
+					delete(snap.Recents, block)
 				}
 			}
-			// Discard any previous votes around the just changed account
-			for i := 0; i < len(snap.Votes); i++ {
-				if snap.Votes[i].Address == header.Coinbase {
-					snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
-					i--
-				}
+		} else if vote := headerVote(header); vote != nil {
+			if err := snap.checkVote(vote, signer); err != nil {
+				return nil, err
 			}
-			delete(snap.Tally, header.Coinbase)
+			snap.castVote(signer)
 		}
 		// If we're taking too much time (ecrecover), notify the user once a while
 		if time.Since(logged) > 8*time.Second {
-			log.Info("Reconstructing voting history", "processed", i, "total", len(headers), "elapsed", common.PrettyDuration(time.Since(start)))
+			log.Info("Reconstructing validator history", "processed", i, "total", len(headers), "elapsed", common.PrettyDuration(time.Since(start)))
 			logged = time.Now()
 		}
 	}
 	if time.Since(start) > 8*time.Second {
-		log.Info("Reconstructed voting history", "processed", len(headers), "elapsed", common.PrettyDuration(time.Since(start)))
+		log.Info("Reconstructed validator history", "processed", len(headers), "elapsed", common.PrettyDuration(time.Since(start)))
 	}
 	snap.Number += uint64(len(headers))
 	snap.Hash = headers[len(headers)-1].Hash()
 	return snap, nil
 }
 
+// checkpointValidators extracts the validator list and the stake backing each
+// validator committed to in the extra-data of a checkpoint header. The genesis
+// block and the checkpoints before the stake schedule fork only list addresses,
+// later checkpoints pair every address with its 32 byte big endian stake.
+func checkpointValidators(config *params.ChainConfig, header *types.Header) ([]common.Address, map[common.Address]*big.Int, error) {
+	var (
+		validatorsBytes = len(header.Extra) - extraVanity - extraSeal
+		entryLength     = checkpointEntryLength(config, header.Number)
+	)
+	if validatorsBytes < 0 || validatorsBytes%entryLength != 0 {
+		return nil, nil, errInvalidCheckpointSigners
+	}
+	var (
+		validators = make([]common.Address, validatorsBytes/entryLength)
+		stakes     = make(map[common.Address]*big.Int, len(validators))
+	)
+	for i := 0; i < len(validators); i++ {
+		entry := header.Extra[extraVanity+i*entryLength : extraVanity+(i+1)*entryLength]
+		copy(validators[i][:], entry)
+		if entryLength == validatorEntryLength {
+			stakes[validators[i]] = new(big.Int).SetBytes(entry[common.AddressLength:])
+		}
+	}
+	return validators, stakes, nil
+}
+
+// rotate replaces the signer set with a new epoch's validators and the stakes
+// backing them, seeding the epoch's proposer schedule. Missed slot counters
+// start afresh with every epoch.
+func (s *Snapshot) rotate(validators []common.Address, stakes map[common.Address]*big.Int, seed common.Hash) {
+	s.Signers = make(map[common.Address]struct{}, len(validators))
+	s.Stakes = make(map[common.Address]*big.Int, len(validators))
+	s.Missed = make(map[common.Address]uint64)
+	for _, validator := range validators {
+		s.Signers[validator] = struct{}{}
+		if stake, ok := stakes[validator]; ok && stake.Sign() > 0 {
+			s.Stakes[validator] = new(big.Int).Set(stake)
+		}
+	}
+	s.Seed = seed
+}
+
+// offline retrieves the signers that missed at least as many in-turn slots in
+// the current epoch as the configured threshold, in ascending order. If every
+// signer went offline nobody is reported, jailing them all would halt the chain.
+func (s *Snapshot) offline() []common.Address {
+	if s.config.MissedSlotsThreshold == 0 {
+		return nil
+	}
+	var offenders []common.Address
+	for signer, missed := range s.Missed {
+		if missed >= s.config.MissedSlotsThreshold {
+			offenders = append(offenders, signer)
+		}
+	}
+	if len(offenders) >= len(s.Signers) {
+		return nil
+	}
+	sort.Sort(signersAscending(offenders))
+	return offenders
+}
+
 // signers retrieves the list of authorized signers in ascending order.
 func (s *Snapshot) signers() []common.Address {
 	sigs := make([]common.Address, 0, len(s.Signers))
@@ -328,11 +339,105 @@ func (s *Snapshot) signers() []common.Address {
 	return sigs
 }
 
-// inturn returns if a signer at a given block height is in-turn or not.
+// recentlySigned returns whether a signer is still barred from sealing the block
+// at the given height because it sealed one of the most recent blocks.
+func (s *Snapshot) recentlySigned(number uint64, signer common.Address) bool {
+	limit := uint64(len(s.Signers)/2 + 1)
+	for seen, recent := range s.Recents {
+		if recent == signer && (number < limit || seen > number-limit) {
+			return true
+		}
+	}
+	return false
+}
+
+// schedule returns the order in which the signers eligible to seal the block
+// at the given height are expected to do so. The first entry is the in-turn
+// proposer, the rest are the backups in the order they should step in.
+//
+// Proposers are drawn without replacement with a probability proportional to
+// their stake, using randomness derived from the epoch seed and the block
+// height, so every node arrives at the same schedule. If no stake is known for
+// the epoch (e.g. the genesis epoch), signers simply take turns in ascending
+// address order.
+func (s *Snapshot) schedule(number uint64) []common.Address {
+	var (
+		signers  = s.signers()
+		eligible = make([]common.Address, 0, len(signers))
+		weights  = make([]*big.Int, 0, len(signers))
+		total    = new(big.Int)
+	)
+	for _, signer := range signers {
+		if s.recentlySigned(number, signer) {
+			continue
+		}
+		stake := s.Stakes[signer]
+		if stake == nil {
+			stake = new(big.Int)
+		}
+		eligible = append(eligible, signer)
+		weights = append(weights, stake)
+		total.Add(total, stake)
+	}
+	if total.Sign() == 0 {
+		// No stake information, fall back to round robin over all signers
+		order := make([]common.Address, 0, len(eligible))
+		for i := 0; i < len(signers); i++ {
+			signer := signers[(number+uint64(i))%uint64(len(signers))]
+			if !s.recentlySigned(number, signer) {
+				order = append(order, signer)
+			}
+		}
+		return order
+	}
+	var (
+		order = make([]common.Address, 0, len(eligible))
+		seed  = make([]byte, common.HashLength+16)
+	)
+	copy(seed, s.Seed[:])
+	binary.BigEndian.PutUint64(seed[common.HashLength:], number)
+
+	for round := uint64(0); total.Sign() > 0; round++ {
+		binary.BigEndian.PutUint64(seed[common.HashLength+8:], round)
+		target := new(big.Int).SetBytes(crypto.Keccak256(seed))
+		target.Mod(target, total)
+
+		for i, weight := range weights {
+			if target.Cmp(weight) < 0 {
+				order = append(order, eligible[i])
+				total.Sub(total, weight)
+				eligible = append(eligible[:i], eligible[i+1:]...)
+				weights = append(weights[:i], weights[i+1:]...)
+				break
+			}
+			target.Sub(target, weight)
+		}
+	}
+	// Signers without any stake may only step in after everyone else
+	return append(order, eligible...)
+}
+
+// inturn returns if a signer at a given block height is in-turn or not. Before
+// the stake schedule fork, signers take turns in ascending address order.
 func (s *Snapshot) inturn(number uint64, signer common.Address) bool {
-	signers, offset := s.signers(), 0
-	for offset < len(signers) && signers[offset] != signer {
-		offset++
+	if !s.chainConfig.IsStakeSchedule(new(big.Int).SetUint64(number)) {
+		signers, offset := s.signers(), 0
+		for offset < len(signers) && signers[offset] != signer {
+			offset++
+		}
+		return (number % uint64(len(signers))) == uint64(offset)
+	}
+	schedule := s.schedule(number)
+	return len(schedule) > 0 && schedule[0] == signer
+}
+
+// rank returns the position of a signer in the proposer schedule of the given
+// block height, or -1 if the signer is not eligible to seal it.
+func (s *Snapshot) rank(number uint64, signer common.Address) int {
+	for i, proposer := range s.schedule(number) {
+		if proposer == signer {
+			return i
+		}
 	}
-	return (number % uint64(len(signers))) == uint64(offset)
+	return -1
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/DogeProtocol/dp/core/types"
 	"github.com/DogeProtocol/dp/core/vm"
 	"github.com/DogeProtocol/dp/params"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 // testerAccountPool is a pool to maintain currently active tester accounts,
@@ -45,17 +46,16 @@ func newTesterAccountPool() *testerAccountPool {
 	}
 }
 
-// checkpoint creates a Clique checkpoint signer section from the provided list
-// of authorized signers and embeds it into the provided header.
-func (ap *testerAccountPool) checkpoint(header *types.Header, signers []string) {
+// checkpoint creates a checkpoint validator section from the provided list
+// of authorized signers and embeds it into the provided header, with entries
+// of the given length. Signers are listed without any stake backing them.
+func (ap *testerAccountPool) checkpoint(header *types.Header, signers []string, entryLength int) {
 	auths := make([]common.Address, len(signers))
 	for i, signer := range signers {
 		auths[i] = ap.address(signer)
 	}
 	sort.Sort(signersAscending(auths))
-	for i, auth := range auths {
-		copy(header.Extra[extraVanity+i*common.AddressLength:], auth.Bytes())
-	}
+	copy(header.Extra[extraVanity:], validatorsToBytes(auths, nil, entryLength))
 }
 
 // address retrieves the Ethereum address of a tester account by label, creating
@@ -90,268 +90,132 @@ func (ap *testerAccountPool) sign(header *types.Header, signer string) {
 	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
 }
 
-// testerVote represents a single block signed by a parcitular account, where
-// the account may or may not have cast a Clique vote.
-type testerVote struct {
+// testerBlock represents a single block signed by a parcitular account, where
+// the block may or may not be a checkpoint committing to a validator set.
+type testerBlock struct {
 	signer     string
-	voted      string
-	auth       bool
+	nonce      bool
+	authVote   bool
 	checkpoint []string
 	newbatch   bool
 }
 
-// Tests that Clique signer voting is evaluated correctly for various simple and
-// complex scenarios, as well as that a few special corner cases fail correctly.
+// Tests that the proof-of-stake signer set is evaluated correctly for various
+// simple and complex scenarios, as well as that a few special corner cases fail
+// correctly.
 func TestProofOfStake(t *testing.T) {
-	// Define the various voting scenarios to test
+	// Define the various signing scenarios to test
 	tests := []struct {
 		epoch   uint64
+		legacy  bool // Whether the chain predates the stake schedule fork
 		signers []string
-		votes   []testerVote
+		blocks  []testerBlock
 		results []string
 		failure error
 	}{
 		{
-			// Single signer, no votes cast
+			// Single signer, plain block
 			signers: []string{"A"},
-			votes:   []testerVote{{signer: "A"}},
+			blocks:  []testerBlock{{signer: "A"}},
 			results: []string{"A"},
 		}, {
-			// Single signer, voting to add two others (only accept first, second needs 2 votes)
-			signers: []string{"A"},
-			votes: []testerVote{
-				{signer: "A", voted: "B", auth: true},
+			// Two signers taking turns
+			signers: []string{"A", "B"},
+			blocks: []testerBlock{
+				{signer: "A"},
 				{signer: "B"},
-				{signer: "A", voted: "C", auth: true},
+				{signer: "A"},
 			},
 			results: []string{"A", "B"},
 		}, {
-			// Two signers, voting to add three others (only accept first two, third needs 3 votes already)
-			signers: []string{"A", "B"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: true},
-				{signer: "B", voted: "C", auth: true},
-				{signer: "A", voted: "D", auth: true},
-				{signer: "B", voted: "D", auth: true},
-				{signer: "C"},
-				{signer: "A", voted: "E", auth: true},
-				{signer: "B", voted: "E", auth: true},
-			},
-			results: []string{"A", "B", "C", "D"},
-		}, {
-			// Single signer, dropping itself (weird, but one less cornercase by explicitly allowing this)
+			// Header votes are no longer accepted
 			signers: []string{"A"},
-			votes: []testerVote{
-				{signer: "A", voted: "A", auth: false},
+			blocks: []testerBlock{
+				{signer: "A", nonce: true},
 			},
-			results: []string{},
+			failure: errInvalidNonce,
 		}, {
-			// Two signers, actually needing mutual consent to drop either of them (not fulfilled)
+			// Header votes are still tolerated before the stake schedule fork
+			legacy:  true,
 			signers: []string{"A", "B"},
-			votes: []testerVote{
-				{signer: "A", voted: "B", auth: false},
-			},
-			results: []string{"A", "B"},
-		}, {
-			// Two signers, actually needing mutual consent to drop either of them (fulfilled)
-			signers: []string{"A", "B"},
-			votes: []testerVote{
-				{signer: "A", voted: "B", auth: false},
-				{signer: "B", voted: "B", auth: false},
-			},
-			results: []string{"A"},
-		}, {
-			// Three signers, two of them deciding to drop the third
-			signers: []string{"A", "B", "C"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: false},
-				{signer: "B", voted: "C", auth: false},
+			blocks: []testerBlock{
+				{signer: "A", authVote: true},
+				{signer: "B"},
 			},
 			results: []string{"A", "B"},
 		}, {
-			// Four signers, consensus of two not being enough to drop anyone
-			signers: []string{"A", "B", "C", "D"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: false},
-				{signer: "B", voted: "C", auth: false},
-			},
-			results: []string{"A", "B", "C", "D"},
-		}, {
-			// Four signers, consensus of three already being enough to drop someone
-			signers: []string{"A", "B", "C", "D"},
-			votes: []testerVote{
-				{signer: "A", voted: "D", auth: false},
-				{signer: "B", voted: "D", auth: false},
-				{signer: "C", voted: "D", auth: false},
+			// Only well-formed header votes are tolerated before the stake schedule fork
+			legacy:  true,
+			signers: []string{"A"},
+			blocks: []testerBlock{
+				{signer: "A", nonce: true},
 			},
-			results: []string{"A", "B", "C"},
+			failure: errInvalidVoteNonce,
 		}, {
-			// Authorizations are counted once per signer per target
+			// Checkpoints list validators by address only before the stake schedule fork
+			epoch:   3,
+			legacy:  true,
 			signers: []string{"A", "B"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: true},
+			blocks: []testerBlock{
+				{signer: "A"},
 				{signer: "B"},
-				{signer: "A", voted: "C", auth: true},
+				{signer: "A", checkpoint: []string{"A", "B"}},
 				{signer: "B"},
-				{signer: "A", voted: "C", auth: true},
 			},
 			results: []string{"A", "B"},
 		}, {
-			// Authorizing multiple accounts concurrently is permitted
+			// Checkpoints may not carry header votes before the stake schedule fork
+			epoch:   3,
+			legacy:  true,
 			signers: []string{"A", "B"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: true},
-				{signer: "B"},
-				{signer: "A", voted: "D", auth: true},
-				{signer: "B"},
+			blocks: []testerBlock{
 				{signer: "A"},
-				{signer: "B", voted: "D", auth: true},
-				{signer: "A"},
-				{signer: "B", voted: "C", auth: true},
-			},
-			results: []string{"A", "B", "C", "D"},
-		}, {
-			// Deauthorizations are counted once per signer per target
-			signers: []string{"A", "B"},
-			votes: []testerVote{
-				{signer: "A", voted: "B", auth: false},
 				{signer: "B"},
-				{signer: "A", voted: "B", auth: false},
-				{signer: "B"},
-				{signer: "A", voted: "B", auth: false},
+				{signer: "A", authVote: true, checkpoint: []string{"A", "B"}},
 			},
-			results: []string{"A", "B"},
+			failure: errInvalidCheckpointVote,
 		}, {
-			// Deauthorizing multiple accounts concurrently is permitted
-			signers: []string{"A", "B", "C", "D"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: false},
-				{signer: "B"},
-				{signer: "C"},
-				{signer: "A", voted: "D", auth: false},
-				{signer: "B"},
-				{signer: "C"},
-				{signer: "A"},
-				{signer: "B", voted: "D", auth: false},
-				{signer: "C", voted: "D", auth: false},
+			// Checkpoints committing to the current validator set are accepted
+			epoch:   3,
+			signers: []string{"A", "B"},
+			blocks: []testerBlock{
 				{signer: "A"},
-				{signer: "B", voted: "C", auth: false},
-			},
-			results: []string{"A", "B"},
-		}, {
-			// Votes from deauthorized signers are discarded immediately (deauth votes)
-			signers: []string{"A", "B", "C"},
-			votes: []testerVote{
-				{signer: "C", voted: "B", auth: false},
-				{signer: "A", voted: "C", auth: false},
-				{signer: "B", voted: "C", auth: false},
-				{signer: "A", voted: "B", auth: false},
-			},
-			results: []string{"A", "B"},
-		}, {
-			// Votes from deauthorized signers are discarded immediately (auth votes)
-			signers: []string{"A", "B", "C"},
-			votes: []testerVote{
-				{signer: "C", voted: "D", auth: true},
-				{signer: "A", voted: "C", auth: false},
-				{signer: "B", voted: "C", auth: false},
-				{signer: "A", voted: "D", auth: true},
-			},
-			results: []string{"A", "B"},
-		}, {
-			// Cascading changes are not allowed, only the account being voted on may change
-			signers: []string{"A", "B", "C", "D"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: false},
 				{signer: "B"},
-				{signer: "C"},
-				{signer: "A", voted: "D", auth: false},
-				{signer: "B", voted: "C", auth: false},
-				{signer: "C"},
-				{signer: "A"},
-				{signer: "B", voted: "D", auth: false},
-				{signer: "C", voted: "D", auth: false},
-			},
-			results: []string{"A", "B", "C"},
-		}, {
-			// Changes reaching consensus out of bounds (via a deauth) execute on touch
-			signers: []string{"A", "B", "C", "D"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: false},
+				{signer: "A", checkpoint: []string{"A", "B"}},
 				{signer: "B"},
-				{signer: "C"},
-				{signer: "A", voted: "D", auth: false},
-				{signer: "B", voted: "C", auth: false},
-				{signer: "C"},
-				{signer: "A"},
-				{signer: "B", voted: "D", auth: false},
-				{signer: "C", voted: "D", auth: false},
-				{signer: "A"},
-				{signer: "C", voted: "C", auth: true},
 			},
 			results: []string{"A", "B"},
 		}, {
-			// Changes reaching consensus out of bounds (via a deauth) may go out of consensus on first touch
-			signers: []string{"A", "B", "C", "D"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: false},
-				{signer: "B"},
-				{signer: "C"},
-				{signer: "A", voted: "D", auth: false},
-				{signer: "B", voted: "C", auth: false},
-				{signer: "C"},
-				{signer: "A"},
-				{signer: "B", voted: "D", auth: false},
-				{signer: "C", voted: "D", auth: false},
+			// Checkpoints committing to a set other than the staking contract's are rejected
+			epoch:   3,
+			signers: []string{"A", "B"},
+			blocks: []testerBlock{
 				{signer: "A"},
-				{signer: "B", voted: "C", auth: true},
-			},
-			results: []string{"A", "B", "C"},
-		}, {
-			// Ensure that pending votes don't survive authorization status changes. This
-			// corner case can only appear if a signer is quickly added, removed and then
-			// readded (or the inverse), while one of the original voters dropped. If a
-			// past vote is left cached in the system somewhere, this will interfere with
-			// the final signer outcome.
-			signers: []string{"A", "B", "C", "D", "E"},
-			votes: []testerVote{
-				{signer: "A", voted: "F", auth: true}, // Authorize F, 3 votes needed
-				{signer: "B", voted: "F", auth: true},
-				{signer: "C", voted: "F", auth: true},
-				{signer: "D", voted: "F", auth: false}, // Deauthorize F, 4 votes needed (leave A's previous vote "unchanged")
-				{signer: "E", voted: "F", auth: false},
-				{signer: "B", voted: "F", auth: false},
-				{signer: "C", voted: "F", auth: false},
-				{signer: "D", voted: "F", auth: true}, // Almost authorize F, 2/3 votes needed
-				{signer: "E", voted: "F", auth: true},
-				{signer: "B", voted: "A", auth: false}, // Deauthorize A, 3 votes needed
-				{signer: "C", voted: "A", auth: false},
-				{signer: "D", voted: "A", auth: false},
-				{signer: "B", voted: "F", auth: true}, // Finish authorizing F, 3/3 votes needed
+				{signer: "B"},
+				{signer: "A", checkpoint: []string{"A", "B", "C"}},
 			},
-			results: []string{"B", "C", "D", "E", "F"},
+			failure: errMismatchingEpochValidators,
 		}, {
-			// Epoch transitions reset all votes to allow chain checkpointing
+			// Checkpoints are required to carry the validator set
 			epoch:   3,
 			signers: []string{"A", "B"},
-			votes: []testerVote{
-				{signer: "A", voted: "C", auth: true},
+			blocks: []testerBlock{
+				{signer: "A"},
 				{signer: "B"},
-				{signer: "A", checkpoint: []string{"A", "B"}},
-				{signer: "B", voted: "C", auth: true},
+				{signer: "A"},
 			},
-			results: []string{"A", "B"},
+			failure: errMismatchingEpochValidators,
 		}, {
 			// An unauthorized signer should not be able to sign blocks
 			signers: []string{"A"},
-			votes: []testerVote{
+			blocks: []testerBlock{
 				{signer: "B"},
 			},
 			failure: errUnauthorizedSigner,
 		}, {
 			// An authorized signer that signed recenty should not be able to sign again
 			signers: []string{"A", "B"},
-			votes: []testerVote{
+			blocks: []testerBlock{
 				{signer: "A"},
 				{signer: "A"},
 			},
@@ -360,7 +224,7 @@ func TestProofOfStake(t *testing.T) {
 			// Recent signatures should not reset on checkpoint blocks imported in a batch
 			epoch:   3,
 			signers: []string{"A", "B", "C"},
-			votes: []testerVote{
+			blocks: []testerBlock{
 				{signer: "A"},
 				{signer: "B"},
 				{signer: "A", checkpoint: []string{"A", "B", "C"}},
@@ -373,7 +237,7 @@ func TestProofOfStake(t *testing.T) {
 			// seems overly specific and weird, it was a Rinkeby consensus split.
 			epoch:   3,
 			signers: []string{"A", "B", "C"},
-			votes: []testerVote{
+			blocks: []testerBlock{
 				{signer: "A"},
 				{signer: "B"},
 				{signer: "A", checkpoint: []string{"A", "B", "C"}},
@@ -410,22 +274,24 @@ func TestProofOfStake(t *testing.T) {
 		db := rawdb.NewMemoryDatabase()
 		genesis.Commit(db)
 
-		// Assemble a chain of headers from the cast votes
+		// Assemble a chain of headers from the signed blocks
 		config := *params.TestChainConfig
 		config.ProofOfStake = &params.ProofOfStakeConfig{
 			Period: 1,
 			Epoch:  tt.epoch,
 		}
+		if tt.legacy {
+			config.StakeScheduleBlock = nil
+		}
 		engine := New(&config, db, nil, common.Hash{})
 		engine.fakeDiff = true
 
-		blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(tt.votes), func(j int, gen *core.BlockGen) {
-			// Cast the vote contained in this block
-			gen.SetCoinbase(accounts.address(tt.votes[j].voted))
-			if tt.votes[j].auth {
-				var nonce types.BlockNonce
-				copy(nonce[:], nonceAuthVote)
-				gen.SetNonce(nonce)
+		blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(tt.blocks), func(j int, gen *core.BlockGen) {
+			if tt.blocks[j].nonce {
+				gen.SetNonce(types.EncodeNonce(1))
+			}
+			if tt.blocks[j].authVote {
+				gen.SetNonce(types.EncodeNonce(0xffffffffffffffff))
 			}
 		})
 		// Iterate through the blocks and seal them individually
@@ -436,26 +302,27 @@ func TestProofOfStake(t *testing.T) {
 				header.ParentHash = blocks[j-1].Hash()
 			}
 			header.Extra = make([]byte, extraVanity+extraSeal)
-			if auths := tt.votes[j].checkpoint; auths != nil {
-				header.Extra = make([]byte, extraVanity+len(auths)*common.AddressLength+extraSeal)
-				accounts.checkpoint(header, auths)
+			if auths := tt.blocks[j].checkpoint; auths != nil {
+				entryLength := checkpointEntryLength(&config, header.Number)
+				header.Extra = make([]byte, extraVanity+len(auths)*entryLength+extraSeal)
+				accounts.checkpoint(header, auths, entryLength)
 			}
 
 			header.Difficulty = diffInTurn // Ignored, we just need a valid number
 
 			// Generate the signature, embed it into the header and the block
-			accounts.sign(header, tt.votes[j].signer)
+			accounts.sign(header, tt.blocks[j].signer)
 			blocks[j] = block.WithSeal(header)
 		}
 		// Split the blocks up into individual import batches (cornercase testing)
 		batches := [][]*types.Block{nil}
 		for j, block := range blocks {
-			if tt.votes[j].newbatch {
+			if tt.blocks[j].newbatch {
 				batches = append(batches, nil)
 			}
 			batches[len(batches)-1] = append(batches[len(batches)-1], block)
 		}
-		// Pass all the headers through clique and ensure tallying succeeds
+		// Pass all the headers through the engine and ensure verification succeeds
 		chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
 		if err != nil {
 			t.Errorf("test %d: failed to create test chain: %v", i, err)
@@ -478,11 +345,11 @@ func TestProofOfStake(t *testing.T) {
 		if tt.failure != nil {
 			continue
 		}
-		// No failure was produced or requested, generate the final voting snapshot
+		// No failure was produced or requested, generate the final snapshot
 		head := blocks[len(blocks)-1]
 		snap, err := engine.snapshot(chain, head.NumberU64(), head.Hash(), nil)
 		if err != nil {
-			t.Errorf("test %d: failed to retrieve voting snapshot: %v", i, err)
+			t.Errorf("test %d: failed to retrieve snapshot: %v", i, err)
 			continue
 		}
 		// Verify the final list of signers against the expected ones
@@ -509,3 +376,211 @@ func TestProofOfStake(t *testing.T) {
 		}
 	}
 }
+
+// Tests that checkpoint blocks rotate the signer set to the validators they
+// commit to, and that signers dropped from the set can no longer seal.
+func TestSnapshotCheckpointRotation(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.ProofOfStakeConfig{Period: 1, Epoch: 3}
+
+	newHeader := func(number uint64, signer string, checkpoint []string) *types.Header {
+		header := &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+len(checkpoint)*validatorEntryLength+extraSeal),
+		}
+		accounts.checkpoint(header, checkpoint, validatorEntryLength)
+		accounts.sign(header, signer)
+		return header
+	}
+	snap := newSnapshot(params.TestChainConfig, config, nil, 2, common.Hash{}, []common.Address{accounts.address("A"), accounts.address("B")})
+	snap.sigcache, _ = lru.NewARC(inmemorySignatures)
+
+	rotated, err := snap.apply([]*types.Header{newHeader(3, "A", []string{"B", "C"})})
+	if err != nil {
+		t.Fatalf("failed to apply checkpoint: %v", err)
+	}
+	want := []common.Address{accounts.address("B"), accounts.address("C")}
+	sort.Sort(signersAscending(want))
+	if have := rotated.signers(); len(have) != len(want) || have[0] != want[0] || have[1] != want[1] {
+		t.Fatalf("signer set mismatch: have %x, want %x", have, want)
+	}
+	if _, err := rotated.apply([]*types.Header{newHeader(4, "C", nil)}); err != nil {
+		t.Errorf("new validator failed to seal: %v", err)
+	}
+	if _, err := rotated.apply([]*types.Header{newHeader(4, "A", nil)}); err != errUnauthorizedSigner {
+		t.Errorf("dropped validator error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+}
+
+// Tests that the proposer schedule is deterministic, weighted by stake and
+// skips signers that are not allowed to seal because they signed recently.
+func TestSnapshotSchedule(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.ProofOfStakeConfig{Period: 1, Epoch: 30000}
+
+	var (
+		light = accounts.address("A")
+		heavy = accounts.address("B")
+		idle  = accounts.address("C")
+	)
+	snap := newSnapshot(params.TestChainConfig, config, nil, 0, common.Hash{}, nil)
+	snap.rotate([]common.Address{light, heavy, idle}, map[common.Address]*big.Int{
+		light: big.NewInt(1),
+		heavy: big.NewInt(9),
+	}, common.HexToHash("0x01"))
+
+	counts := make(map[common.Address]int)
+	for number := uint64(1); number <= 2000; number++ {
+		schedule := snap.schedule(number)
+		if len(schedule) != 3 {
+			t.Fatalf("block %d: schedule length mismatch: have %d, want 3", number, len(schedule))
+		}
+		if schedule[2] != idle {
+			t.Fatalf("block %d: unstaked signer scheduled before staked ones: %x", number, schedule)
+		}
+		if again := snap.schedule(number); again[0] != schedule[0] || again[1] != schedule[1] {
+			t.Fatalf("block %d: schedule not deterministic: %x != %x", number, schedule, again)
+		}
+		counts[schedule[0]]++
+	}
+	if counts[heavy] < 1600 || counts[light] < 100 {
+		t.Errorf("proposer distribution not stake weighted: heavy %d, light %d", counts[heavy], counts[light])
+	}
+	// A signer that sealed the previous block must not be scheduled
+	snap.Recents[10] = heavy
+	for _, proposer := range snap.schedule(11) {
+		if proposer == heavy {
+			t.Errorf("recent signer scheduled: %x", snap.schedule(11))
+		}
+	}
+	if !snap.inturn(11, light) {
+		t.Errorf("light signer should be in-turn once the heavy one is barred")
+	}
+	// Signers take turns regardless of stake and recents before the fork
+	legacy := *params.TestChainConfig
+	legacy.StakeScheduleBlock = big.NewInt(100)
+	snap.chainConfig = &legacy
+
+	signers := snap.signers()
+	for number := uint64(10); number < 100; number++ {
+		if want := signers[number%uint64(len(signers))]; !snap.inturn(number, want) {
+			t.Fatalf("block %d: round robin signer %x not in-turn", number, want)
+		}
+	}
+}
+
+// Tests that in-turn slots sealed by someone else are charged to the scheduled
+// proposer, and that the counters start afresh on every epoch.
+func TestSnapshotMissedSlots(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.ProofOfStakeConfig{Period: 1, Epoch: 4, MissedSlotsThreshold: 2}
+
+	newHeader := func(number uint64, signer string, checkpoint []string) *types.Header {
+		header := &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Difficulty: diffNoTurn,
+			Extra:      make([]byte, extraVanity+len(checkpoint)*validatorEntryLength+extraSeal),
+		}
+		accounts.checkpoint(header, checkpoint, validatorEntryLength)
+		accounts.sign(header, signer)
+		return header
+	}
+	names := map[common.Address]string{
+		accounts.address("A"): "A",
+		accounts.address("B"): "B",
+		accounts.address("C"): "C",
+	}
+	snap := newSnapshot(params.TestChainConfig, config, nil, 0, common.Hash{}, []common.Address{accounts.address("A"), accounts.address("B"), accounts.address("C")})
+	snap.sigcache, _ = lru.NewARC(inmemorySignatures)
+
+	// Let the last backup seal the first blocks, so the in-turn proposer misses out
+	var err error
+	for number := uint64(1); number <= 3; number++ {
+		schedule := snap.schedule(number)
+		if snap, err = snap.apply([]*types.Header{newHeader(number, names[schedule[len(schedule)-1]], nil)}); err != nil {
+			t.Fatalf("block %d: failed to apply header: %v", number, err)
+		}
+	}
+	var total uint64
+	for _, missed := range snap.Missed {
+		total += missed
+	}
+	if total != 3 {
+		t.Fatalf("missed slot count mismatch: have %d, want 3", total)
+	}
+	offline := snap.offline()
+	for _, signer := range offline {
+		if snap.Missed[signer] < config.MissedSlotsThreshold {
+			t.Errorf("signer %x reported offline with %d missed slots", signer, snap.Missed[signer])
+		}
+	}
+	if len(offline) >= len(snap.Signers) {
+		t.Errorf("every signer reported offline")
+	}
+	// Crossing the epoch boundary must reset the counters
+	schedule := snap.schedule(4)
+	if snap, err = snap.apply([]*types.Header{newHeader(4, names[schedule[0]], []string{"A", "B", "C"})}); err != nil {
+		t.Fatalf("failed to apply checkpoint: %v", err)
+	}
+	if len(snap.Missed) != 0 {
+		t.Errorf("missed slots not reset on checkpoint: %v", snap.Missed)
+	}
+}
+
+// Tests that checkpoints become final once more than two thirds of the signers
+// voted for them, and that malformed or repeated votes are rejected.
+func TestSnapshotFinality(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.ProofOfStakeConfig{Period: 1, Epoch: 5}
+
+	newHeader := func(number uint64, signer string, checkpoint []string, vote *checkpointVote) *types.Header {
+		var voteBytes []byte
+		if vote != nil {
+			voteBytes = vote.bytes()
+		}
+		header := &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+len(checkpoint)*validatorEntryLength+len(voteBytes)+extraSeal),
+		}
+		accounts.checkpoint(header, checkpoint, validatorEntryLength)
+		copy(header.Extra[extraVanity:], voteBytes)
+		accounts.sign(header, signer)
+		return header
+	}
+	snap := newSnapshot(params.TestChainConfig, config, nil, 0, common.Hash{}, []common.Address{accounts.address("A"), accounts.address("B"), accounts.address("C")})
+	snap.sigcache, _ = lru.NewARC(inmemorySignatures)
+
+	var (
+		headers    []*types.Header
+		signers    = []string{"A", "B", "C"}
+		checkpoint = newHeader(5, "B", []string{"A", "B", "C"}, nil)
+		vote       = &checkpointVote{Number: 5, Hash: checkpoint.Hash()}
+	)
+	for number := uint64(1); number < 5; number++ {
+		headers = append(headers, newHeader(number, signers[(number-1)%3], nil, nil))
+	}
+	headers = append(headers, checkpoint, newHeader(6, "C", nil, vote), newHeader(7, "A", nil, vote))
+
+	snap, err := snap.apply(headers)
+	if err != nil {
+		t.Fatalf("failed to apply headers: %v", err)
+	}
+	if snap.Finalized != 0 {
+		t.Fatalf("checkpoint finalized with two thirds of the votes")
+	}
+	if _, err := snap.apply([]*types.Header{newHeader(8, "B", nil, &checkpointVote{Number: 5, Hash: common.Hash{0x01}})}); err != errInvalidVote {
+		t.Errorf("unknown checkpoint vote error mismatch: have %v, want %v", err, errInvalidVote)
+	}
+	final, err := snap.apply([]*types.Header{newHeader(8, "B", nil, vote)})
+	if err != nil {
+		t.Fatalf("failed to apply final vote: %v", err)
+	}
+	if final.Finalized != 5 || final.FinalizedHash != checkpoint.Hash() {
+		t.Errorf("finalized checkpoint mismatch: have %d [%x], want 5 [%x]", final.Finalized, final.FinalizedHash, checkpoint.Hash())
+	}
+	if _, err := final.apply([]*types.Header{newHeader(9, "C", nil, vote)}); err != errDuplicateVote {
+		t.Errorf("duplicate vote error mismatch: have %v, want %v", err, errDuplicateVote)
+	}
+}
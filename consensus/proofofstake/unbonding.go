@@ -0,0 +1,104 @@
+package proofofstake
+
+import (
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/crypto"
+)
+
+// Withdrawals are queued by the staking contract from the unbonding fork on, in
+// the state variables declared after the delegations. The engine reads them to
+// report pending withdrawals and burns them when slashing.
+var (
+	stakingSlotWithdrawals    = common.BigToHash(big.NewInt(14)) // mapping (address => Withdrawal[]) _withdrawals
+	stakingSlotWithdrawalHead = common.BigToHash(big.NewInt(15)) // mapping (address => uint256) _withdrawalHead
+	stakingSlotUnbonding      = common.BigToHash(big.NewInt(16)) // mapping (address => address[]) _unbonding
+	stakingSlotUnbondingIndex = common.BigToHash(big.NewInt(17)) // mapping (address => mapping (address => uint256)) _unbondingIndex
+)
+
+// PendingWithdrawal is stake withdrawn from a validator, waiting for the end of
+// the unbonding period.
+type PendingWithdrawal struct {
+	Validator common.Address `json:"validator"`
+	Amount    *hexutil.Big   `json:"amount"`
+	Maturity  hexutil.Uint64 `json:"maturity"`  // Block from which the withdrawal can be claimed
+	Claimable bool           `json:"claimable"` // Whether the withdrawal matured at the queried block
+}
+
+// withdrawalQueue is the queue of an account's withdrawals in the staking
+// contract: an array of entries, the ones before the head index having been
+// claimed already. Every entry takes three slots: amount, maturity and
+// validator.
+type withdrawalQueue struct {
+	length common.Hash // Slot holding the length, the entries follow from its hash
+	head   common.Hash // Slot holding the index of the first pending entry
+}
+
+func newWithdrawalQueue(account common.Address) withdrawalQueue {
+	key := common.BytesToHash(account[:])
+	return withdrawalQueue{length: mappingSlot(key, stakingSlotWithdrawals), head: mappingSlot(key, stakingSlotWithdrawalHead)}
+}
+
+// entrySlot returns the given field slot of a queue entry.
+func (q withdrawalQueue) entrySlot(i uint64, field uint64) common.Hash {
+	return common.BigToHash(new(big.Int).Add(crypto.Keccak256Hash(q.length[:]).Big(), new(big.Int).SetUint64(3*i+field)))
+}
+
+func (q withdrawalQueue) bounds(statedb stateReader, contract common.Address) (uint64, uint64) {
+	return statedb.GetState(contract, q.head).Big().Uint64(), statedb.GetState(contract, q.length).Big().Uint64()
+}
+
+func (q withdrawalQueue) entry(statedb stateReader, contract common.Address, i uint64) *PendingWithdrawal {
+	return &PendingWithdrawal{
+		Amount:    (*hexutil.Big)(statedb.GetState(contract, q.entrySlot(i, 0)).Big()),
+		Maturity:  hexutil.Uint64(statedb.GetState(contract, q.entrySlot(i, 1)).Big().Uint64()),
+		Validator: common.BytesToAddress(statedb.GetState(contract, q.entrySlot(i, 2)).Bytes()),
+	}
+}
+
+// unbondingList returns the list of the accounts with withdrawals pending from
+// a validator.
+func unbondingList(validator common.Address) addressList {
+	return newAddressList(validator, stakingSlotUnbonding, stakingSlotUnbondingIndex)
+}
+
+// pendingWithdrawals returns the withdrawals of an account not claimed yet.
+func pendingWithdrawals(statedb stateReader, contract common.Address, account common.Address) []*PendingWithdrawal {
+	queue := newWithdrawalQueue(account)
+	head, tail := queue.bounds(statedb, contract)
+
+	withdrawals := make([]*PendingWithdrawal, 0, tail-head)
+	for i := head; i < tail; i++ {
+		withdrawals = append(withdrawals, queue.entry(statedb, contract, i))
+	}
+	return withdrawals
+}
+
+// slashUnbonding burns the given percentage of the stake still unbonding from
+// a validator, returning the amount burned.
+func slashUnbonding(statedb *state.StateDB, contract common.Address, validator common.Address, percent uint64) *big.Int {
+	burned := new(big.Int)
+	for _, account := range unbondingList(validator).members(statedb, contract) {
+		queue := newWithdrawalQueue(account)
+		head, tail := queue.bounds(statedb, contract)
+
+		for i := head; i < tail; i++ {
+			entry := queue.entry(statedb, contract, i)
+			if entry.Validator != validator {
+				continue
+			}
+			amount := new(big.Int).Mul(entry.Amount.ToInt(), new(big.Int).SetUint64(percent))
+			amount.Div(amount, big.NewInt(100))
+			if amount.Cmp(statedb.GetBalance(contract)) > 0 {
+				amount.Set(statedb.GetBalance(contract))
+			}
+			statedb.SetState(contract, queue.entrySlot(i, 0), common.BigToHash(new(big.Int).Sub(entry.Amount.ToInt(), amount)))
+			statedb.SubBalance(contract, amount)
+			burned.Add(burned, amount)
+		}
+	}
+	return burned
+}
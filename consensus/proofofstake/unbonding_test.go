@@ -0,0 +1,141 @@
+package proofofstake
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
+)
+
+// Tests that withdrawn deposits and unbonded delegations wait in the staking
+// contract's queue until the unbonding period is over, remaining slashable in
+// the meantime.
+func TestWithdrawalQueue(t *testing.T) {
+	var (
+		contract  = newTestStakingContract(t, common.Big0, common.Big0)
+		depositor = common.HexToAddress("0xd1")
+		delegator = common.HexToAddress("0xb1")
+		validator = contract.deposit(depositor, []byte{0x01, 0x01}, 1000)
+		statedb   = contract.statedb
+	)
+	if _, reason := contract.call(depositor, 0, "withdraw", big.NewInt(0)); reason != "Invalid amount" {
+		t.Fatalf("empty withdrawal: have %q", reason)
+	}
+	if _, reason := contract.call(depositor, 0, "withdraw", big.NewInt(1001)); reason != "Insufficient funds" {
+		t.Fatalf("excessive withdrawal: have %q", reason)
+	}
+	// A partial withdrawal keeps the validator listed, withdrawing the rest doesn't
+	contract.setNumber(150)
+	contract.mustCall(depositor, 0, "withdraw", big.NewInt(400))
+	if have := stakingValidators(statedb, contract.address); len(have) != 1 {
+		t.Fatalf("validator ejected by partial withdrawal")
+	}
+	contract.mustCall(delegator, 500, "delegate", validator)
+	contract.mustCall(delegator, 0, "undelegate", validator, big.NewInt(200))
+
+	contract.setNumber(250)
+	contract.mustCall(depositor, 0, "withdraw", big.NewInt(600))
+	if have := stakingValidators(statedb, contract.address); len(have) != 0 {
+		t.Fatalf("validator listed after full withdrawal: %x", have)
+	}
+	if have := statedb.GetState(contract.address, stakingSlotTotalDepositBalance).Big(); have.Sign() != 0 {
+		t.Errorf("total deposit mismatch: have %v, want 0", have)
+	}
+	if have := statedb.GetBalance(contract.address); have.Cmp(big.NewInt(1500)) != 0 {
+		t.Errorf("contract balance mismatch: have %v, want 1500", have)
+	}
+	if have := unbondingList(validator).members(statedb, contract.address); len(have) != 2 {
+		t.Fatalf("unbonding list mismatch: have %v", have)
+	}
+	// Slashing reaches the stake still unbonding
+	if burned := slashUnbonding(statedb, contract.address, validator, 10); burned.Cmp(big.NewInt(120)) != 0 {
+		t.Fatalf("burned amount mismatch: have %v, want 120", burned)
+	}
+	pending := pendingWithdrawals(statedb, contract.address, depositor)
+	if len(pending) != 2 || pending[0].Amount.ToInt().Cmp(big.NewInt(360)) != 0 || pending[0].Maturity != 400 || pending[1].Maturity != 500 || pending[1].Validator != validator {
+		t.Fatalf("pending withdrawals mismatch: have %v", pending)
+	}
+	// Only matured withdrawals are paid out
+	contract.setNumber(399)
+	contract.mustCall(depositor, 0, "claim")
+	if have := statedb.GetBalance(depositor); have.Sign() != 0 {
+		t.Fatalf("claimed immature withdrawal: %v", have)
+	}
+	contract.setNumber(400)
+	contract.mustCall(depositor, 0, "claim")
+	contract.mustCall(delegator, 0, "claim")
+
+	if have := statedb.GetBalance(depositor); have.Cmp(big.NewInt(360)) != 0 {
+		t.Fatalf("claimed amount mismatch: have %v, want 360", have)
+	}
+	if have := statedb.GetBalance(delegator); have.Cmp(big.NewInt(180)) != 0 {
+		t.Fatalf("claimed delegation mismatch: have %v, want 180", have)
+	}
+	if have := unbondingList(validator).members(statedb, contract.address); len(have) != 1 || have[0] != depositor {
+		t.Errorf("unbonding list mismatch after claim: have %v", have)
+	}
+	contract.setNumber(500)
+	contract.mustCall(depositor, 0, "claim")
+
+	if have := pendingWithdrawals(statedb, contract.address, depositor); len(have) != 0 {
+		t.Errorf("withdrawals pending after claim: %v", have)
+	}
+	if have := len(unbondingList(validator).members(statedb, contract.address)); have != 0 {
+		t.Errorf("unbonding list retained claimed account")
+	}
+	if have := statedb.GetBalance(depositor); have.Cmp(big.NewInt(900)) != 0 {
+		t.Errorf("depositor balance mismatch: have %v, want 900", have)
+	}
+}
+
+// Tests that the staking contract pays withdrawals out right away until the
+// unbonding fork.
+func TestUnbondingFork(t *testing.T) {
+	var (
+		contract  = newTestStakingContract(t, common.Big0, big.NewInt(10))
+		depositor = common.HexToAddress("0xd1")
+	)
+	contract.deposit(depositor, []byte{0x01, 0x01}, 1000)
+	contract.mustCall(depositor, 0, "withdraw", big.NewInt(100))
+
+	if have := contract.statedb.GetBalance(depositor); have.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("depositor balance mismatch before the fork: have %v, want 100", have)
+	}
+	contract.setNumber(10)
+	contract.mustCall(depositor, 0, "withdraw", big.NewInt(100))
+
+	if have := contract.statedb.GetBalance(depositor); have.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("withdrawal paid out after the fork: have %v, want 100", have)
+	}
+	if have := pendingWithdrawals(contract.statedb, contract.address, depositor); len(have) != 1 || have[0].Maturity != 300 {
+		t.Fatalf("pending withdrawals mismatch: have %v", have)
+	}
+}
+
+// Tests that double-sign evidence submitted after a validator withdrew its whole
+// deposit still burns the stake unbonding from it.
+func TestSlashWithdrawnValidator(t *testing.T) {
+	var (
+		accounts  = newTesterAccountPool()
+		contract  = newTestStakingContract(t, common.Big0, common.Big0)
+		depositor = common.HexToAddress("0xd1")
+		validator = contract.deposit(depositor, accounts.stakingKey("A"), 1000)
+		statedb   = contract.statedb
+	)
+	contract.setNumber(20)
+	contract.mustCall(depositor, 0, "withdraw", big.NewInt(1000))
+	if have := stakingValidators(statedb, contract.address); len(have) != 0 {
+		t.Fatalf("validator listed after full withdrawal: %x", have)
+	}
+	evidence := accounts.doubleSign("A", 10)
+	newTestSlashingEngine(contract.config).processEvidence(&types.Header{Number: big.NewInt(21)}, statedb, []*types.Transaction{evidence})
+
+	pending := pendingWithdrawals(statedb, contract.address, depositor)
+	if len(pending) != 1 || pending[0].Validator != validator || pending[0].Amount.ToInt().Cmp(big.NewInt(900)) != 0 {
+		t.Fatalf("pending withdrawals mismatch: have %v", pending)
+	}
+	if have := statedb.GetBalance(contract.address); have.Cmp(big.NewInt(900)) != 0 {
+		t.Errorf("contract balance mismatch: have %v, want 900", have)
+	}
+}
@@ -20,9 +20,9 @@ func (p *ProofOfStake) GetValidators1(blockHash common.Hash) ([]common.Address,
 
 func (p *ProofOfStake) GetValidators(number uint64, blockHash common.Hash) ([]common.Address, error) {
 
-	err := systemcontracts.IsStakingContract()
+	err := systemcontracts.IsStakingContract(p.chainConfig)
 	if err != nil {
-		log.Warn("GETH_STAKING_CONTRACT : Contract address is empty")
+		log.Warn("Staking contract is not configured")
 		return nil, err
 	}
 	//blockNumber = new(big.Int).SetUint64(172)
@@ -31,7 +31,7 @@ func (p *ProofOfStake) GetValidators(number uint64, blockHash common.Hash) ([]co
 
 	method := systemcontracts.GetContract_Method_ListValidator()
 	abiData := systemcontracts.GetStakingContract_ABI()
-	contractAddress := common.HexToAddress(systemcontracts.GetStakingContract_Address_String())
+	contractAddress := common.HexToAddress(systemcontracts.GetStakingContract_Address_String(p.chainConfig))
 
 	// call
 	data, err := abiData.Pack(method)
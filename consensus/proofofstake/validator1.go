@@ -8,12 +8,13 @@ import (
 	"github.com/DogeProtocol/dp/log"
 	"github.com/DogeProtocol/dp/rpc"
 	"github.com/DogeProtocol/dp/systemcontracts1"
+	"math/big"
 )
 
 func (p *ProofOfStake) GetValidatorsAddress1(number uint64, blockHash common.Hash) ([]common.Address, error) {
-	err := systemcontracts1.IsStakingContract()
+	err := systemcontracts1.IsStakingContract(p.chainConfig)
 	if err != nil {
-		log.Warn("GETH_STAKING_CONTRACT_ADDRESS: Contract1 address is empty")
+		log.Warn("Staking contract is not configured")
 		return nil, err
 	}
 	//blockNumber = new(big.Int).SetUint64(172)
@@ -22,7 +23,7 @@ func (p *ProofOfStake) GetValidatorsAddress1(number uint64, blockHash common.Has
 
 	method := systemcontracts1.GetContract_Method_ListValidator()
 	abiData := systemcontracts1.GetStakingContract_ABI()
-	contractAddress := common.HexToAddress(systemcontracts1.GetStakingContract_Address_String())
+	contractAddress := common.HexToAddress(systemcontracts1.GetStakingContract_Address_String(p.chainConfig))
 
 	// call
 	data, err := abiData.Pack(method)
@@ -66,9 +67,9 @@ func (p *ProofOfStake) GetValidatorsAddress1(number uint64, blockHash common.Has
 }
 
 func (p *ProofOfStake) GetDepositor(validator common.Address, blockHash common.Hash) (common.Address, error) {
-	err := systemcontracts1.IsStakingContract()
+	err := systemcontracts1.IsStakingContract(p.chainConfig)
 	if err != nil {
-		log.Warn("GETH_STAKING_CONTRACT_ADDRESS: Contract1 address is empty")
+		log.Warn("Staking contract is not configured")
 		return common.Address{}, err
 	}
 	//blockNumber = new(big.Int).SetUint64(172)
@@ -77,7 +78,7 @@ func (p *ProofOfStake) GetDepositor(validator common.Address, blockHash common.H
 
 	method := systemcontracts1.GetContract_Method_GetDepositor()
 	abiData := systemcontracts1.GetStakingContract_ABI()
-	contractAddress := common.HexToAddress(systemcontracts1.GetStakingContract_Address_String())
+	contractAddress := common.HexToAddress(systemcontracts1.GetStakingContract_Address_String(p.chainConfig))
 
 	// call
 	data, err := abiData.Pack(method, validator)
@@ -114,3 +115,143 @@ func (p *ProofOfStake) GetDepositor(validator common.Address, blockHash common.H
 
 	return *out, nil
 }
+
+func (p *ProofOfStake) GetDepositBalance(depositor common.Address, blockHash common.Hash) (*big.Int, error) {
+	err := systemcontracts1.IsStakingContract(p.chainConfig)
+	if err != nil {
+		log.Warn("Staking contract is not configured")
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // cancel when we are finished consuming integers
+
+	method := systemcontracts1.GetContract_Method_GetDepositBalanceOf()
+	abiData := systemcontracts1.GetStakingContract_ABI()
+	contractAddress := common.HexToAddress(systemcontracts1.GetStakingContract_Address_String(p.chainConfig))
+
+	// call
+	data, err := abiData.Pack(method, depositor)
+	if err != nil {
+		log.Error("Unable to pack tx for get deposit balance", "error", err)
+		return nil, err
+	}
+	// block
+	blockNr := rpc.BlockNumberOrHashWithHash(blockHash, false)
+
+	msgData := (hexutil.Bytes)(data)
+	result, err := p.ethAPI.Call(ctx, ethapi.TransactionArgs{
+		To:   &contractAddress,
+		Data: &msgData,
+	}, blockNr, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return new(big.Int), nil
+	}
+
+	var (
+		ret0 = new(*big.Int)
+	)
+	out := ret0
+
+	if err := abiData.UnpackIntoInterface(out, method, result); err != nil {
+		return nil, err
+	}
+
+	return *out, nil
+}
+
+// storageReader reads the staking contract's storage at a given block through
+// the RPC backend. The first error encountered is kept, later reads returning
+// empty slots.
+type storageReader struct {
+	ethAPI    *ethapi.PublicBlockChainAPI
+	blockHash common.Hash
+	err       error
+}
+
+func (r *storageReader) GetState(addr common.Address, hash common.Hash) common.Hash {
+	if r.err != nil {
+		return common.Hash{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err := r.ethAPI.GetStorageAt(ctx, addr, hash.Hex(), rpc.BlockNumberOrHashWithHash(r.blockHash, false))
+	if err != nil {
+		r.err = err
+		return common.Hash{}
+	}
+	return common.BytesToHash(result)
+}
+
+func (p *ProofOfStake) GetDelegatedStake(validator common.Address, blockHash common.Hash) (*big.Int, error) {
+	err := systemcontracts1.IsStakingContract(p.chainConfig)
+	if err != nil {
+		log.Warn("Staking contract is not configured")
+		return nil, err
+	}
+	reader := &storageReader{ethAPI: p.ethAPI, blockHash: blockHash}
+	stake := delegatedStake(reader, systemcontracts1.GetStakingContract_Address(p.chainConfig), validator)
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	return stake, nil
+}
+
+func (p *ProofOfStake) GetDelegations(validator common.Address, blockHash common.Hash) ([]*Delegation, error) {
+	err := systemcontracts1.IsStakingContract(p.chainConfig)
+	if err != nil {
+		log.Warn("Staking contract is not configured")
+		return nil, err
+	}
+	reader := &storageReader{ethAPI: p.ethAPI, blockHash: blockHash}
+	delegations := validatorDelegations(reader, systemcontracts1.GetStakingContract_Address(p.chainConfig), validator)
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	return delegations, nil
+}
+
+func (p *ProofOfStake) GetDelegatorDelegations(delegator common.Address, blockHash common.Hash) ([]*Delegation, error) {
+	err := systemcontracts1.IsStakingContract(p.chainConfig)
+	if err != nil {
+		log.Warn("Staking contract is not configured")
+		return nil, err
+	}
+	reader := &storageReader{ethAPI: p.ethAPI, blockHash: blockHash}
+	delegations := delegatorDelegations(reader, systemcontracts1.GetStakingContract_Address(p.chainConfig), delegator)
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	return delegations, nil
+}
+
+func (p *ProofOfStake) GetPendingWithdrawals(account common.Address, blockHash common.Hash) ([]*PendingWithdrawal, error) {
+	err := systemcontracts1.IsStakingContract(p.chainConfig)
+	if err != nil {
+		log.Warn("Staking contract is not configured")
+		return nil, err
+	}
+	reader := &storageReader{ethAPI: p.ethAPI, blockHash: blockHash}
+	withdrawals := pendingWithdrawals(reader, systemcontracts1.GetStakingContract_Address(p.chainConfig), account)
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	return withdrawals, nil
+}
+
+func (p *ProofOfStake) GetBlockReward(number uint64, blockHash common.Hash) (*BlockReward, error) {
+	err := systemcontracts1.IsStakingContract(p.chainConfig)
+	if err != nil {
+		log.Warn("Staking contract is not configured")
+		return nil, err
+	}
+	reader := &storageReader{ethAPI: p.ethAPI, blockHash: blockHash}
+	reward := loadReward(reader, systemcontracts1.GetStakingContract_Address(p.chainConfig), number)
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	return reward, nil
+}
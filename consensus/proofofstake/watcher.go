@@ -0,0 +1,127 @@
+package proofofstake
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const inmemorySeals = 4096 // Number of recent (validator, height) seals to keep in memory
+
+// EvidencePool is the subset of the transaction pool the double-sign watcher
+// needs to submit evidence.
+type EvidencePool interface {
+	Nonce(addr common.Address) uint64
+	GasPrice() *big.Int
+	AddLocal(tx *types.Transaction) error
+}
+
+// sealKey identifies a seal slot a validator may fill only once.
+type sealKey struct {
+	signer common.Address
+	number uint64
+}
+
+// DoubleSignWatcher keeps track of the headers propagated on the network and
+// detects validators sealing two different blocks at the same height. When the
+// local node is an authorized validator, the evidence is submitted on chain.
+type DoubleSignWatcher struct {
+	engine *ProofOfStake
+	pool   EvidencePool
+
+	seals    *lru.ARCCache // Hash of the first header seen per (validator, height)
+	headers  *lru.ARCCache // Headers by hash, to assemble evidence from
+	reported *lru.ARCCache // Evidence already submitted
+	lock     sync.Mutex
+}
+
+// NewDoubleSignWatcher creates a watcher reporting offences through the given
+// transaction pool.
+func NewDoubleSignWatcher(engine *ProofOfStake, pool EvidencePool) *DoubleSignWatcher {
+	seals, _ := lru.NewARC(inmemorySeals)
+	headers, _ := lru.NewARC(inmemorySeals)
+	reported, _ := lru.NewARC(inmemorySeals)
+
+	return &DoubleSignWatcher{
+		engine:   engine,
+		pool:     pool,
+		seals:    seals,
+		headers:  headers,
+		reported: reported,
+	}
+}
+
+// Observe records a verified header, returning the evidence if the header
+// conflicts with another one sealed by the same validator at the same height.
+func (w *DoubleSignWatcher) Observe(header *types.Header) *DoubleSignEvidence {
+	signer, err := ecrecover(header, w.engine.signatures)
+	if err != nil {
+		return nil
+	}
+	hash := header.Hash()
+	key := sealKey{signer: signer, number: header.Number.Uint64()}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	seen, ok := w.seals.Get(key)
+	if !ok {
+		w.seals.Add(key, hash)
+		w.headers.Add(hash, header)
+		return nil
+	}
+	if seen.(common.Hash) == hash {
+		return nil
+	}
+	first, ok := w.headers.Get(seen.(common.Hash))
+	if !ok {
+		return nil
+	}
+	evidence := NewDoubleSignEvidence(first.(*types.Header), header)
+	if _, err := evidence.Verify(w.engine.signatures); err != nil {
+		return nil
+	}
+	if w.reported.Contains(evidence.Hash()) {
+		return nil
+	}
+	w.reported.Add(evidence.Hash(), struct{}{})
+
+	log.Warn("Detected double-signing validator", "validator", signer, "number", key.number, "evidence", evidence.Hash())
+	if err := w.submit(evidence); err != nil {
+		log.Warn("Failed to submit double-sign evidence", "evidence", evidence.Hash(), "err", err)
+	}
+	return evidence
+}
+
+// submit sends the evidence to the chain in a transaction signed by the local
+// validator. Nodes not authorized to seal only log the offence.
+func (w *DoubleSignWatcher) submit(evidence *DoubleSignEvidence) error {
+	w.engine.lock.RLock()
+	validator, signTxFn := w.engine.validator, w.engine.signTxFn
+	w.engine.lock.RUnlock()
+
+	if signTxFn == nil || w.pool == nil {
+		return nil
+	}
+	data, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		return err
+	}
+	gas, err := core.IntrinsicGas(data, nil, false, true, true)
+	if err != nil {
+		return err
+	}
+	tx := types.NewTransaction(w.pool.Nonce(validator), DoubleSignEvidenceAddress, common.Big0, gas, w.pool.GasPrice(), data)
+	signed, err := signTxFn(accounts.Account{Address: validator}, tx, w.engine.chainConfig.ChainID)
+	if err != nil {
+		return err
+	}
+	return w.pool.AddLocal(signed)
+}
@@ -51,6 +51,7 @@ var (
 	headBlockGauge     = metrics.NewRegisteredGauge("chain/head/block", nil)
 	headHeaderGauge    = metrics.NewRegisteredGauge("chain/head/header", nil)
 	headFastBlockGauge = metrics.NewRegisteredGauge("chain/head/receipt", nil)
+	headFinalizedGauge = metrics.NewRegisteredGauge("chain/head/finalized", nil)
 
 	accountReadTimer   = metrics.NewRegisteredTimer("chain/account/reads", nil)
 	accountHashTimer   = metrics.NewRegisteredTimer("chain/account/hashes", nil)
@@ -80,6 +81,7 @@ var (
 	blockPrefetchInterruptMeter = metrics.NewRegisteredMeter("chain/prefetch/interrupts", nil)
 
 	errInsertionInterrupted = errors.New("insertion is interrupted")
+	errReorgFinalized       = errors.New("reorg below finalized block")
 )
 
 const (
@@ -187,6 +189,7 @@ type BlockChain struct {
 
 	currentBlock     atomic.Value // Current head of the block chain
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
+	currentFinalized atomic.Value // Latest finalized block of the canonical chain, reorgs never go below it
 
 	stateCache    state.Database // State database to reuse between imports (contains state cache)
 	bodyCache     *lru.Cache     // Cache for the most recent block bodies
@@ -263,6 +266,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	var nilBlock *types.Block
 	bc.currentBlock.Store(nilBlock)
 	bc.currentFastBlock.Store(nilBlock)
+	bc.currentFinalized.Store(nilBlock)
 
 	// Initialize the chain with ancient data if it isn't empty.
 	var txIndexBlock uint64
@@ -292,7 +296,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		if diskRoot != (common.Hash{}) {
 			log.Warn("Head state missing, repairing", "number", head.Number(), "hash", head.Hash(), "snaproot", diskRoot)
 
-			snapDisk, err := bc.SetHeadBeyondRoot(head.NumberU64(), diskRoot)
+			snapDisk, err := bc.setHeadBeyondRoot(head.NumberU64(), diskRoot, true)
 			if err != nil {
 				return nil, err
 			}
@@ -302,7 +306,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		} else {
 			log.Warn("Head state missing, repairing", "number", head.Number(), "hash", head.Hash())
-			if err := bc.SetHead(head.NumberU64()); err != nil {
+			if _, err := bc.setHeadBeyondRoot(head.NumberU64(), common.Hash{}, true); err != nil {
 				return nil, err
 			}
 		}
@@ -333,7 +337,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		}
 		if needRewind {
 			log.Error("Truncating ancient chain", "from", bc.CurrentHeader().Number.Uint64(), "to", low)
-			if err := bc.SetHead(low); err != nil {
+			if _, err := bc.setHeadBeyondRoot(low, common.Hash{}, true); err != nil {
 				return nil, err
 			}
 		}
@@ -351,7 +355,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			// make sure the headerByNumber (if present) is in our current canonical chain
 			if headerByNumber != nil && headerByNumber.Hash() == header.Hash() {
 				log.Error("Found bad hash, rewinding chain", "number", header.Number, "hash", header.ParentHash)
-				if err := bc.SetHead(header.Number.Uint64() - 1); err != nil {
+				if _, err := bc.setHeadBeyondRoot(header.Number.Uint64()-1, common.Hash{}, true); err != nil {
 					return nil, err
 				}
 				log.Error("Chain rewind was successful, resuming normal operation")
@@ -456,6 +460,16 @@ func (bc *BlockChain) loadLastState() error {
 			headFastBlockGauge.Update(int64(block.NumberU64()))
 		}
 	}
+	// Restore the last finalized block, unless a rewind dropped it from the canonical chain
+	var finalized *types.Block
+	if hash := rawdb.ReadFinalizedBlockHash(bc.db); hash != (common.Hash{}) {
+		if block := bc.GetBlockByHash(hash); block != nil && block.NumberU64() <= currentBlock.NumberU64() && bc.GetCanonicalHash(block.NumberU64()) == hash {
+			finalized = block
+			headFinalizedGauge.Update(int64(block.NumberU64()))
+		}
+	}
+	bc.currentFinalized.Store(finalized)
+
 	// Issue a status log for the user
 	currentFastBlock := bc.CurrentFastBlock()
 
@@ -466,6 +480,9 @@ func (bc *BlockChain) loadLastState() error {
 	log.Info("Loaded most recent local header", "number", currentHeader.Number, "hash", currentHeader.Hash(), "td", headerTd, "age", common.PrettyAge(time.Unix(int64(currentHeader.Time), 0)))
 	log.Info("Loaded most recent local full block", "number", currentBlock.Number(), "hash", currentBlock.Hash(), "td", blockTd, "age", common.PrettyAge(time.Unix(int64(currentBlock.Time()), 0)))
 	log.Info("Loaded most recent local fast block", "number", currentFastBlock.Number(), "hash", currentFastBlock.Hash(), "td", fastTd, "age", common.PrettyAge(time.Unix(int64(currentFastBlock.Time()), 0)))
+	if finalized != nil {
+		log.Info("Loaded most recent finalized block", "number", finalized.Number(), "hash", finalized.Hash(), "age", common.PrettyAge(time.Unix(int64(finalized.Time()), 0)))
+	}
 	if pivot := rawdb.ReadLastPivotNumber(bc.db); pivot != nil {
 		log.Info("Loaded last fast-sync pivot marker", "number", *pivot)
 	}
@@ -474,7 +491,8 @@ func (bc *BlockChain) loadLastState() error {
 
 // SetHead rewinds the local chain to a new head. Depending on whether the node
 // was fast synced or full synced and in which state, the method will try to
-// delete minimal data from disk whilst retaining chain consistency.
+// delete minimal data from disk whilst retaining chain consistency. Rewinding
+// below the finalized block is refused.
 func (bc *BlockChain) SetHead(head uint64) error {
 	_, err := bc.SetHeadBeyondRoot(head, common.Hash{})
 	return err
@@ -489,9 +507,19 @@ func (bc *BlockChain) SetHead(head uint64) error {
 //
 // The method returns the block number where the requested root cap was found.
 func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64, error) {
+	return bc.setHeadBeyondRoot(head, root, false)
+}
+
+// setHeadBeyondRoot implements SetHeadBeyondRoot. Unless repair is set, it refuses
+// to rewind below the finalized block; repairs of a damaged database may have to.
+func (bc *BlockChain) setHeadBeyondRoot(head uint64, root common.Hash, repair bool) (uint64, error) {
 	bc.chainmu.Lock()
 	defer bc.chainmu.Unlock()
 
+	if finalized := bc.CurrentFinalizedBlock(); !repair && finalized != nil && head < finalized.NumberU64() {
+		return 0, errReorgFinalized
+	}
+
 	// Track the block number of the requested root hash
 	var rootNumber uint64 // (no root == always 0)
 
@@ -671,6 +699,12 @@ func (bc *BlockChain) CurrentFastBlock() *types.Block {
 	return bc.currentFastBlock.Load().(*types.Block)
 }
 
+// CurrentFinalizedBlock retrieves the latest finalized block of the canonical
+// chain, or nil if the consensus engine did not finalize any block yet.
+func (bc *BlockChain) CurrentFinalizedBlock() *types.Block {
+	return bc.currentFinalized.Load().(*types.Block)
+}
+
 // Validator returns the current validator.
 func (bc *BlockChain) Validator() Validator {
 	return bc.validator
@@ -705,7 +739,7 @@ func (bc *BlockChain) Reset() error {
 // specified genesis state.
 func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 	// Dump the entire block chain and purge the caches
-	if err := bc.SetHead(0); err != nil {
+	if _, err := bc.setHeadBeyondRoot(0, common.Hash{}, true); err != nil {
 		return err
 	}
 	bc.chainmu.Lock()
@@ -1548,6 +1582,10 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 			reorg = !currentPreserve && (blockPreserve || mrand.Float64() < 0.5)
 		}
 	}
+	if reorg && block.ParentHash() != currentBlock.Hash() && !bc.extendsFinalized(block) {
+		log.Warn("Refusing to reorg below finalized block", "number", block.Number(), "hash", block.Hash(), "finalized", bc.CurrentFinalizedBlock().Number())
+		reorg = false
+	}
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != currentBlock.Hash() {
@@ -1562,6 +1600,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.writeHeadBlock(block)
+		bc.updateFinalized(block)
 	}
 	bc.futureBlocks.Remove(block.Hash())
 
@@ -1584,6 +1623,52 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	return status, nil
 }
 
+// extendsFinalized reports whether the given block descends from the latest
+// finalized block, i.e. whether it may become the head of the canonical chain.
+func (bc *BlockChain) extendsFinalized(block *types.Block) bool {
+	finalized := bc.CurrentFinalizedBlock()
+	if finalized == nil {
+		return true
+	}
+	for header := block.Header(); header != nil; header = bc.GetHeader(header.ParentHash, header.Number.Uint64()-1) {
+		number := header.Number.Uint64()
+		if number <= finalized.NumberU64() {
+			return number == finalized.NumberU64() && header.Hash() == finalized.Hash()
+		}
+		// Joining the canonical chain above the finalized block is enough
+		if bc.GetCanonicalHash(number) == header.Hash() {
+			return true
+		}
+	}
+	return false
+}
+
+// updateFinalized asks the consensus engine, if it supports finality, for the
+// latest block finalized by the new canonical head and advances the finalized
+// marker if it moved forward.
+func (bc *BlockChain) updateFinalized(head *types.Block) {
+	engine, ok := bc.engine.(consensus.Finality)
+	if !ok {
+		return
+	}
+	header, err := engine.Finalized(bc, head.Header())
+	if err != nil || header == nil {
+		return
+	}
+	if current := bc.CurrentFinalizedBlock(); current != nil && current.NumberU64() >= header.Number.Uint64() {
+		return
+	}
+	block := bc.GetBlock(header.Hash(), header.Number.Uint64())
+	if block == nil {
+		return
+	}
+	rawdb.WriteFinalizedBlockHash(bc.db, block.Hash())
+	bc.currentFinalized.Store(block)
+	headFinalizedGauge.Update(int64(block.NumberU64()))
+
+	log.Info("Finalized block", "number", block.Number(), "hash", block.Hash())
+}
+
 // addFutureBlock checks if the block is within the max allowed window to get
 // accepted for future processing, and returns an error if the block is too far
 // ahead and was not added.
@@ -1664,7 +1749,8 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 	if atomic.LoadInt32(&bc.procInterrupt) == 1 {
 		return 0, nil
 	}
-	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
+	// Start a parallel batch verification of the transaction signatures (signer
+	// will fluke on fork transition, minimal perf loss)
 	senderCacher.recoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number()), chain)
 	var (
 		stats     = insertStats{startTime: mclock.Now()}
@@ -2081,8 +2167,12 @@ func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator) (i
 
 // reorg takes two blocks, an old chain and a new chain and will reconstruct the
 // blocks and inserts them to be part of the new canonical chain and accumulates
-// potential missing transactions and post an event about them.
+// potential missing transactions and post an event about them. Reorgs dropping
+// the finalized block from the canonical chain are refused.
 func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
+	if !bc.extendsFinalized(newBlock) {
+		return errReorgFinalized
+	}
 	var (
 		newChain    types.Blocks
 		oldChain    types.Blocks
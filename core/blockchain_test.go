@@ -3335,3 +3335,71 @@ func TestEIP1559Transition(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// finalityEngine is a consensus engine finalizing a fixed block height, as soon
+// as the chain grows past it.
+type finalityEngine struct {
+	consensus.Engine
+	number uint64
+}
+
+func (e *finalityEngine) Finalized(chain consensus.ChainHeaderReader, header *types.Header) (*types.Header, error) {
+	for ; header != nil && header.Number.Uint64() > e.number; header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1) {
+	}
+	if header == nil || header.Number.Uint64() != e.number {
+		return nil, nil
+	}
+	return header, nil
+}
+
+// Tests that the chain tracks the blocks finalized by the consensus engine and
+// refuses to reorg below them, even to a heavier chain.
+func TestReorgBelowFinalized(t *testing.T) {
+	engine := &finalityEngine{Engine: ethash.NewFaker(), number: 5}
+
+	_, blockchain, err := newCanonical(engine, 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	canonical := makeBlockChain(blockchain.Genesis(), 10, engine, blockchain.db, canonicalSeed)
+	if _, err := blockchain.InsertChain(canonical); err != nil {
+		t.Fatalf("failed to insert canonical chain: %v", err)
+	}
+	if finalized := blockchain.CurrentFinalizedBlock(); finalized == nil || finalized.Hash() != canonical[4].Hash() {
+		t.Fatalf("finalized block mismatch: have %v, want %v", finalized, canonical[4].Number())
+	}
+	// A heavier fork branching off below the finalized block must stay a side chain
+	below := makeBlockChain(canonical[2], 20, engine, blockchain.db, forkSeed)
+	if _, err := blockchain.InsertChain(below); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if head := blockchain.CurrentBlock(); head.Hash() != canonical[len(canonical)-1].Hash() {
+		t.Errorf("reorged below finalized block: head %d [%x]", head.Number(), head.Hash().Bytes()[:4])
+	}
+	// A heavier fork branching off above the finalized block may take over
+	fork := makeBlockChain(canonical[6], 20, engine, blockchain.db, forkSeed)
+	if _, err := blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if head := blockchain.CurrentBlock(); head.Hash() != fork[len(fork)-1].Hash() {
+		t.Errorf("failed to reorg above finalized block: head %d [%x]", head.Number(), head.Hash().Bytes()[:4])
+	}
+	if finalized := blockchain.CurrentFinalizedBlock(); finalized == nil || finalized.Hash() != canonical[4].Hash() {
+		t.Errorf("finalized block changed across reorg")
+	}
+	// Reorgs and rewinds dropping the finalized block are refused
+	if err := blockchain.reorg(blockchain.CurrentBlock(), below[len(below)-1]); err != errReorgFinalized {
+		t.Errorf("reorg below finalized block: have %v, want %v", err, errReorgFinalized)
+	}
+	if err := blockchain.SetHead(canonical[3].NumberU64()); err != errReorgFinalized {
+		t.Errorf("rewind below finalized block: have %v, want %v", err, errReorgFinalized)
+	}
+	if head := blockchain.CurrentBlock(); head.Hash() != fork[len(fork)-1].Hash() {
+		t.Errorf("head changed by refused rewind: head %d [%x]", head.Number(), head.Hash().Bytes()[:4])
+	}
+	if err := blockchain.SetHead(canonical[4].NumberU64()); err != nil {
+		t.Errorf("failed to rewind to finalized block: %v", err)
+	}
+}
@@ -72,6 +72,10 @@ var (
 	// current network configuration.
 	ErrTxTypeNotSupported = types.ErrTxTypeNotSupported
 
+	// ErrSigAlgNotSupported is returned if a transaction is signed with a signature
+	// algorithm not enabled in the current network configuration.
+	ErrSigAlgNotSupported = errors.New("signature algorithm not supported")
+
 	// ErrTipAboveFeeCap is a sanity error to ensure no one is able to specify a
 	// transaction with a tip higher than the total fee cap.
 	ErrTipAboveFeeCap = errors.New("max priority fee per gas higher than max fee per gas")
@@ -255,6 +255,12 @@ func gatherForks(config *params.ChainConfig) []uint64 {
 			forks = append(forks, rule.Uint64())
 		}
 	}
+	// Add the system contract upgrades, which change the state transition too
+	for _, upgrade := range config.SystemContractUpgrades {
+		if upgrade.Block != nil {
+			forks = append(forks, upgrade.Block.Uint64())
+		}
+	}
 	// Sort the fork block numbers to permit chronological XOR
 	for i := 0; i < len(forks); i++ {
 		for j := i + 1; j < len(forks); j++ {
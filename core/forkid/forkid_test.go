@@ -19,6 +19,7 @@ package forkid
 import (
 	"bytes"
 	"math"
+	"math/big"
 	"testing"
 
 	"github.com/DogeProtocol/dp/common"
@@ -245,3 +246,29 @@ func TestEncoding(t *testing.T) {
 		}
 	}
 }
+
+// Tests that system contract upgrades are part of the fork ID, so that peers
+// which haven't scheduled an upgrade are rejected once it has passed.
+func TestSystemContractUpgrades(t *testing.T) {
+	config := *params.MainnetChainConfig
+	config.SystemContractUpgrades = []*params.SystemContractUpgrade{
+		{Name: "test", Block: big.NewInt(30000000)},
+	}
+	// Before the upgrade, the next fork is announced but the checksum is unchanged
+	legacy := NewID(params.MainnetChainConfig, params.MainnetGenesisHash, 29999999)
+	if have := NewID(&config, params.MainnetGenesisHash, 29999999); have.Hash != legacy.Hash || have.Next != 30000000 {
+		t.Fatalf("pre-upgrade fork ID mismatch: have %x, want hash %x, next 30000000", have, legacy.Hash)
+	}
+	if have := NewID(&config, params.MainnetGenesisHash, 30000000); have.Hash == legacy.Hash || have.Next != 0 {
+		t.Fatalf("post-upgrade fork ID retained the legacy checksum: %x", have)
+	}
+	// An un-upgraded peer is accepted before the upgrade and rejected after it
+	filter := newFilter(&config, params.MainnetGenesisHash, func() uint64 { return 29999999 })
+	if err := filter(legacy); err != nil {
+		t.Errorf("un-upgraded peer rejected before the upgrade: %v", err)
+	}
+	filter = newFilter(&config, params.MainnetGenesisHash, func() uint64 { return 30000000 })
+	if err := filter(NewID(params.MainnetChainConfig, params.MainnetGenesisHash, 30000000)); err != ErrRemoteStale {
+		t.Errorf("un-upgraded peer after the upgrade: have %v, want %v", err, ErrRemoteStale)
+	}
+}
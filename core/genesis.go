@@ -36,6 +36,8 @@ import (
 	"github.com/DogeProtocol/dp/log"
 	"github.com/DogeProtocol/dp/params"
 	"github.com/DogeProtocol/dp/rlp"
+	"github.com/DogeProtocol/dp/systemcontracts"
+	"github.com/DogeProtocol/dp/systemcontracts1"
 	"github.com/DogeProtocol/dp/trie"
 )
 
@@ -273,6 +275,14 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 			statedb.SetState(addr, key, value)
 		}
 	}
+	// Predeploy the staking contract, unless the allocation already provides it,
+	// and upgrade it for the staking forks activated at genesis
+	if systemcontracts1.IsStakingContract(g.Config) == nil {
+		if contract := systemcontracts1.GetStakingContract_Address(g.Config); statedb.GetCodeSize(contract) == 0 {
+			statedb.SetCode(contract, systemcontracts1.GetStakingContract_Code())
+			systemcontracts.UpgradeStakingContract(g.Config, new(big.Int).SetUint64(g.Number), statedb)
+		}
+	}
 	root := statedb.IntermediateRoot(false)
 	head := &types.Header{
 		Number:     new(big.Int).SetUint64(g.Number),
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"math/big"
 	"reflect"
 	"testing"
@@ -24,9 +25,11 @@ import (
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/consensus/ethash"
 	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/core/state"
 	"github.com/DogeProtocol/dp/core/vm"
 	"github.com/DogeProtocol/dp/ethdb"
 	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/systemcontracts1"
 	"github.com/davecgh/go-spew/spew"
 )
 
@@ -197,3 +200,42 @@ func TestGenesisHashes(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the staking contract declared by the chain configuration is
+// predeployed at genesis, unless the allocation provides its code.
+func TestGenesisStakingContract(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0x0000000000000000000000000000000000001000")
+		config   = &params.ChainConfig{ChainID: big.NewInt(1337), ProofOfStake: &params.ProofOfStakeConfig{StakingContract: contract}}
+	)
+	db := rawdb.NewMemoryDatabase()
+	block := (&Genesis{Config: config}).ToBlock(db)
+
+	statedb, err := state.New(block.Root(), state.NewDatabase(db), nil)
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	code := statedb.GetCode(contract)
+	if len(code) == 0 || !bytes.Equal(code, systemcontracts1.GetStakingContract_Code()) {
+		t.Fatalf("staking contract not predeployed: have %x", code)
+	}
+	// Allocated code takes precedence over the built-in contract
+	db = rawdb.NewMemoryDatabase()
+	block = (&Genesis{Config: config, Alloc: GenesisAlloc{contract: {Code: []byte{0x00}, Balance: common.Big0}}}).ToBlock(db)
+
+	statedb, _ = state.New(block.Root(), state.NewDatabase(db), nil)
+	if code := statedb.GetCode(contract); !bytes.Equal(code, []byte{0x00}) {
+		t.Fatalf("allocated staking contract overwritten: have %x", code)
+	}
+	// Staking forks activated at genesis upgrade the predeployed contract
+	forked := *config
+	forked.DelegationBlock, forked.UnbondingBlock = common.Big0, common.Big0
+
+	db = rawdb.NewMemoryDatabase()
+	block = (&Genesis{Config: &forked}).ToBlock(db)
+
+	statedb, _ = state.New(block.Root(), state.NewDatabase(db), nil)
+	if code, want := statedb.GetCode(contract), systemcontracts1.GetStakingContract_CodeAt(&forked, common.Big0); !bytes.Equal(code, want) || bytes.Equal(code, systemcontracts1.GetStakingContract_Code()) {
+		t.Fatalf("staking contract not upgraded at genesis: have %x", code)
+	}
+}
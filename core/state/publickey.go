@@ -0,0 +1,87 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+)
+
+// PublicKeyRegistryAddress is the system account whose storage holds the public
+// keys revealed by transaction senders. Keeping them there leaves the storage of
+// the senders themselves untouched.
+var PublicKeyRegistryAddress = common.HexToAddress("0x0000000000000000000000000000000000001005")
+
+// publicKeyPrefix is mixed into the registry slots of every account.
+var publicKeyPrefix = crypto.Keccak256([]byte("publickey"))
+
+// GetPublicKey retrieves the public key registered for an account along with
+// the ID of its signature algorithm, or nil if the account didn't reveal its
+// public key yet.
+func (s *StateDB) GetPublicKey(addr common.Address) (byte, []byte) {
+	slot := publicKeySlot(addr)
+	header := s.GetState(PublicKeyRegistryAddress, slot)
+	if header == (common.Hash{}) {
+		return 0, nil
+	}
+	size := int(binary.BigEndian.Uint32(header[common.HashLength-4:]))
+
+	pub := make([]byte, 0, size+common.HashLength)
+	for i := 0; len(pub) < size; i++ {
+		chunk := s.GetState(PublicKeyRegistryAddress, publicKeyChunkSlot(slot, i))
+		pub = append(pub, chunk[:]...)
+	}
+	return header[0], pub[:size]
+}
+
+// SetPublicKey registers the public key of an account in the storage of the
+// public key registry.
+func (s *StateDB) SetPublicKey(addr common.Address, id byte, pub []byte) {
+	// The registry has neither code nor balance, give it a nonce so that it is
+	// never deleted as an empty account.
+	if s.GetNonce(PublicKeyRegistryAddress) == 0 {
+		s.SetNonce(PublicKeyRegistryAddress, 1)
+	}
+	slot := publicKeySlot(addr)
+
+	var header common.Hash
+	header[0] = id
+	binary.BigEndian.PutUint32(header[common.HashLength-4:], uint32(len(pub)))
+	s.SetState(PublicKeyRegistryAddress, slot, header)
+
+	for i := 0; i*common.HashLength < len(pub); i++ {
+		var chunk common.Hash
+		copy(chunk[:], pub[i*common.HashLength:])
+		s.SetState(PublicKeyRegistryAddress, publicKeyChunkSlot(slot, i), chunk)
+	}
+}
+
+// publicKeySlot returns the registry slot holding the algorithm ID and length
+// of the public key of an account.
+func publicKeySlot(addr common.Address) common.Hash {
+	return crypto.Keccak256Hash(publicKeyPrefix, addr.Bytes())
+}
+
+// publicKeyChunkSlot returns the i-th of the registry slots holding the public
+// key itself in 32 byte chunks, following the account's header slot.
+func publicKeyChunkSlot(slot common.Hash, i int) common.Hash {
+	base := crypto.Keccak256Hash(slot[:]).Big()
+	return common.BigToHash(base.Add(base, big.NewInt(int64(i))))
+}
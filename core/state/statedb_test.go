@@ -695,6 +695,40 @@ func TestDeleteCreateRevert(t *testing.T) {
 	}
 }
 
+// TestPublicKeyRegistry tests that public keys of any length survive a commit
+// and are kept separate per account.
+func TestPublicKeyRegistry(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	keys := map[common.Address][]byte{
+		common.BytesToAddress([]byte("empty")): {},
+		common.BytesToAddress([]byte("short")): {0x01, 0x02, 0x03},
+		common.BytesToAddress([]byte("chunk")): bytes.Repeat([]byte{0xaa}, common.HashLength),
+		common.BytesToAddress([]byte("long")):  bytes.Repeat([]byte{0x00, 0xff, 0x10}, 300),
+	}
+	for addr, pub := range keys {
+		if _, have := state.GetPublicKey(addr); have != nil {
+			t.Fatalf("%x: unexpected key before registration", addr)
+		}
+		state.SetPublicKey(addr, byte(len(pub)%250+1), pub)
+	}
+	root, _ := state.Commit(true)
+	state, _ = New(root, state.db, state.snaps)
+
+	for addr, pub := range keys {
+		if state.Exist(addr) {
+			t.Errorf("%x: key registration touched the account itself", addr)
+		}
+		id, have := state.GetPublicKey(addr)
+		if id != byte(len(pub)%250+1) {
+			t.Errorf("%x: algorithm mismatch: have %d, want %d", addr, id, len(pub)%250+1)
+		}
+		if have == nil || !bytes.Equal(have, pub) {
+			t.Errorf("%x: key mismatch: have %x, want %x", addr, have, pub)
+		}
+	}
+}
+
 // TestMissingTrieNodes tests that if the StateDB fails to load parts of the trie,
 // the Commit operation fails with an error
 // If we are missing trie nodes, we should not continue writing to the trie
@@ -28,6 +28,7 @@ import (
 	"github.com/DogeProtocol/dp/core/vm"
 	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/systemcontracts"
 )
 
 // StateProcessor is a basic Processor, which takes care of transitioning
@@ -70,11 +71,12 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
 		misc.ApplyDAOHardFork(statedb)
 	}
+	systemcontracts.UpgradeBuildInSystemContract(p.config, block.Number(), statedb)
 	blockContext := NewEVMBlockContext(header, p.bc, nil)
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, cfg)
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
-		msg, err := tx.AsMessage(types.MakeSigner(p.config, header.Number), header.BaseFee)
+		msg, err := tx.AsMessage(types.NewRegistrySigner(types.MakeSigner(p.config, header.Number), statedb), header.BaseFee)
 		if err != nil {
 			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 		}
@@ -88,12 +90,16 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	fmt.Println("state Process finalize", header.Number, block.Number())
-	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles())
+	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
 
 	return receipts, allLogs, *usedGas, nil
 }
 
 func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
+	// Reject transactions signed with an algorithm not enabled yet.
+	if !types.TxSignatureAlgorithmsEnabled(config, tx, blockNumber) {
+		return nil, ErrSigAlgNotSupported
+	}
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
 	evm.Reset(txContext, statedb)
@@ -103,6 +109,7 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 	if err != nil {
 		return nil, err
 	}
+	RegisterPublicKey(statedb, tx, msg.From())
 
 	// Update the state with pending changes.
 	var root []byte
@@ -138,12 +145,25 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 	return receipt, err
 }
 
+// RegisterPublicKey remembers the public key revealed by a transaction for its
+// sender, so that later transactions from the same account may omit it. Keys
+// already registered are never replaced.
+func RegisterPublicKey(statedb *state.StateDB, tx *types.Transaction, from common.Address) {
+	_, pub := tx.RawSignature()
+	if len(pub) == 0 {
+		return
+	}
+	if _, known := statedb.GetPublicKey(from); known == nil {
+		statedb.SetPublicKey(from, tx.SignatureAlgorithm(), pub)
+	}
+}
+
 // ApplyTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. It returns the receipt
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
 func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, error) {
-	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number), header.BaseFee)
+	msg, err := tx.AsMessage(types.NewRegistrySigner(types.MakeSigner(config, header.Number), statedb), header.BaseFee)
 	if err != nil {
 		return nil, err
 	}
@@ -27,23 +27,23 @@ var senderCacher = newTxSenderCacher(runtime.NumCPU())
 
 // txSenderCacherRequest is a request for recovering transaction senders with a
 // specific signature scheme and caching it into the transactions themselves.
-//
-// The inc field defines the number of transactions to skip after each recovery,
-// which is used to feed the same underlying input array to different threads but
-// ensure they process the early transactions fast.
 type txSenderCacherRequest struct {
 	signer types.Signer
 	txs    []*types.Transaction
-	inc    int
 }
 
-// txSenderCacher is a helper structure to concurrently ecrecover transaction
-// senders from digital signatures on background threads.
+// txSenderCacher is a helper structure to concurrently verify transaction
+// signatures and recover their senders on background threads.
 type txSenderCacher struct {
 	threads int
 	tasks   chan *txSenderCacherRequest
 }
 
+// txSenderCacherBatch is the number of transactions whose signatures are verified
+// together. Batches are verified across all CPUs, in the order they are fed to
+// the cacher, so that the early transactions are processed fast.
+const txSenderCacherBatch = 256
+
 // newTxSenderCacher creates a new transaction sender background cacher and starts
 // as many processing goroutines as allowed by the GOMAXPROCS on construction.
 func newTxSenderCacher(threads int) *txSenderCacher {
@@ -61,9 +61,7 @@ func newTxSenderCacher(threads int) *txSenderCacher {
 // data structures.
 func (cacher *txSenderCacher) cache() {
 	for task := range cacher.tasks {
-		for i := 0; i < len(task.txs); i += task.inc {
-			types.Sender(task.signer, task.txs[i])
-		}
+		types.VerifySenders(task.signer, task.txs)
 	}
 }
 
@@ -75,18 +73,21 @@ func (cacher *txSenderCacher) recover(signer types.Signer, txs []*types.Transact
 	if len(txs) == 0 {
 		return
 	}
-	// Ensure we have meaningful task sizes and schedule the recoveries
-	tasks := cacher.threads
-	if len(txs) < tasks*4 {
-		tasks = (len(txs) + 3) / 4
-	}
-	for i := 0; i < tasks; i++ {
-		cacher.tasks <- &txSenderCacherRequest{
-			signer: signer,
-			txs:    txs[i:],
-			inc:    tasks,
+	// Feed the batches from the background, so that the caller doesn't block
+	// until most of them are picked up
+	go func() {
+		for len(txs) > 0 {
+			batch := txs
+			if len(batch) > txSenderCacherBatch {
+				batch = batch[:txSenderCacherBatch]
+			}
+			cacher.tasks <- &txSenderCacherRequest{
+				signer: signer,
+				txs:    batch,
+			}
+			txs = txs[len(batch):]
 		}
-	}
+	}()
 }
 
 // recoverFromBlocks recovers the senders from a batch of blocks and caches them
@@ -234,6 +234,9 @@ type TxPool struct {
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
+	sigTx    bool // Fork indicator whether we are using signature transactions.
+
+	pendingNumber *big.Int // Number of the next pending block, selecting the accepted signature algorithms
 
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
@@ -577,6 +580,14 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if !pool.eip1559 && tx.Type() == types.DynamicFeeTxType {
 		return ErrTxTypeNotSupported
 	}
+	// Reject signature transactions until their fork activates.
+	if !pool.sigTx && tx.Type() == types.SignatureTxType {
+		return ErrTxTypeNotSupported
+	}
+	// Reject transactions signed with an algorithm not enabled yet.
+	if !types.TxSignatureAlgorithmsEnabled(pool.chainconfig, tx, pool.pendingNumber) {
+		return ErrSigAlgNotSupported
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	if uint64(tx.Size()) > txMaxSize {
 		return ErrOversizedData
@@ -602,7 +613,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrTipAboveFeeCap
 	}
 	// Make sure the transaction is signed properly.
-	from, err := types.Sender(pool.signer, tx)
+	from, err := types.Sender(types.NewRegistrySigner(pool.signer, pool.currentState), tx)
 	if err != nil {
 		return ErrInvalidSender
 	}
@@ -863,8 +874,9 @@ func (pool *TxPool) AddRemote(tx *types.Transaction) error {
 func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 	// Filter out known ones without obtaining the pool lock or recovering signatures
 	var (
-		errs = make([]error, len(txs))
-		news = make([]*types.Transaction, 0, len(txs))
+		errs    = make([]error, len(txs))
+		news    = make([]*types.Transaction, 0, len(txs))
+		unknown = make([]*types.Transaction, 0, len(txs))
 	)
 	for i, tx := range txs {
 		// If the transaction is known, pre-set the error slot
@@ -873,11 +885,23 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 			knownTxMeter.Mark(1)
 			continue
 		}
+		unknown = append(unknown, tx)
+	}
+	// Verify the signatures of all unknown transactions in one batch, spread
+	// across all CPUs
+	if len(unknown) > 1 {
+		types.VerifySenders(pool.signer, unknown)
+	}
+	for i, tx := range txs {
+		if errs[i] != nil {
+			continue
+		}
 		// Exclude transactions with invalid signatures as soon as
 		// possible and cache senders in transactions before
-		// obtaining lock
+		// obtaining lock. Senders relying on a registered public key are
+		// resolved against the state during validation.
 		_, err := types.Sender(pool.signer, tx)
-		if err != nil {
+		if err != nil && err != types.ErrMissingPublicKey {
 			errs[i] = ErrInvalidSender
 			invalidTxMeter.Mark(1)
 			continue
@@ -1271,6 +1295,8 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
 	pool.eip2718 = pool.chainconfig.IsBerlin(next)
 	pool.eip1559 = pool.chainconfig.IsLondon(next)
+	pool.sigTx = pool.chainconfig.IsSignatureTx(next)
+	pool.pendingNumber = next
 }
 
 // promoteExecutables moves transactions that have become processable from the
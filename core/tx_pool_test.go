@@ -415,6 +415,74 @@ func TestTransactionVeryHighValues(t *testing.T) {
 	}
 }
 
+func TestTransactionSignatureAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	falcon, _ := cryptobase.SigAlgById(cryptobase.SigAlgIdFalcon)
+	key, _ := falcon.GenerateKey()
+
+	tx, _ := types.SignTxWithAlgorithm(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   params.TestChainConfig.ChainID,
+		GasTipCap: big.NewInt(2),
+		GasFeeCap: big.NewInt(1),
+		Gas:       100,
+		To:        &common.Address{},
+	}), types.LatestSignerForChainID(params.TestChainConfig.ChainID), cryptobase.SigAlgIdFalcon, key)
+
+	// Falcon-512 signatures are rejected until their fork
+	config := *eip1559Config
+	config.FalconBlock = nil
+
+	pool, _ := setupTxPoolWithConfig(&config)
+	defer pool.Stop()
+
+	if err := pool.AddRemote(tx); err != ErrSigAlgNotSupported {
+		t.Error("expected", ErrSigAlgNotSupported, "got", err)
+	}
+	pool, _ = setupTxPoolWithConfig(eip1559Config)
+	defer pool.Stop()
+
+	if err := pool.AddRemote(tx); err != ErrTipAboveFeeCap {
+		t.Error("expected", ErrTipAboveFeeCap, "got", err)
+	}
+}
+
+func TestTransactionRegisteredPublicKey(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupTxPoolWithConfig(eip1559Config)
+	defer pool.Stop()
+
+	falcon, _ := cryptobase.SigAlgById(cryptobase.SigAlgIdFalcon)
+	key, _ := falcon.GenerateKey()
+
+	tx, _ := types.SignNewTx(key, pool.signer, &types.SignatureTx{
+		ChainID:   params.TestChainConfig.ChainID,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(1),
+		Gas:       100000,
+		To:        &common.Address{},
+		Algorithm: cryptobase.SigAlgIdFalcon,
+	})
+	short, err := tx.WithoutPublicKey()
+	if err != nil {
+		t.Fatalf("failed to strip public key: %v", err)
+	}
+	// Without a registered key the sender can't be recovered
+	if err := pool.AddRemote(short); err != ErrInvalidSender {
+		t.Error("expected", ErrInvalidSender, "got", err)
+	}
+	// Once registered, the stored key is used to verify the signature
+	from, _ := short.RegisteredKeySender()
+	pool.mu.Lock()
+	pool.currentState.SetPublicKey(from, cryptobase.SigAlgIdFalcon, key.PublicKey.PubData)
+	pool.mu.Unlock()
+
+	if err := pool.AddRemote(short); err != ErrInsufficientFunds {
+		t.Error("expected", ErrInsufficientFunds, "got", err)
+	}
+}
+
 func TestTransactionChainFork(t *testing.T) {
 	t.Parallel()
 
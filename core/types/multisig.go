@@ -0,0 +1,321 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/DogeProtocol/dp/rlp"
+)
+
+// MaxMultisigKeys is the maximum number of public keys of a multisig account,
+// bounding the signature checks of a transaction.
+const MaxMultisigKeys = 16
+
+var (
+	ErrInvalidMultisigKey  = errors.New("invalid multisig key")
+	ErrMultisigThreshold   = errors.New("not enough multisig signatures")
+	ErrNotMultisigTx       = errors.New("not a multisig transaction")
+	ErrNotMultisigMember   = errors.New("key not part of the multisig key")
+	ErrMultisigTxMismatch  = errors.New("multisig transactions differ")
+	errInvalidMultisigSigs = errors.New("invalid multisig signature list")
+)
+
+// MultisigKey is the public key of a multisignature account: a list of public
+// keys of the other signature algorithms, and the number of them that must sign
+// a transaction. The address of the account is derived from the RLP encoding of
+// the key like for the other algorithms, with SigAlgIdMultisig as ID.
+//
+// Transactions of multisig accounts are signature transactions declaring
+// SigAlgIdMultisig as algorithm. Their public key is the encoded multisig key
+// (or left out once registered), and their signature the RLP encoded list of
+// the signatures of its members, sorted by key index. Members sign the hash of
+// the transaction along with the address of the account, see MultisigSigHash.
+type MultisigKey struct {
+	Threshold uint8
+	Keys      []MultisigPublicKey
+}
+
+// MultisigPublicKey is a public key of a multisig account member.
+type MultisigPublicKey struct {
+	Algorithm uint8
+	PublicKey []byte
+}
+
+// MultisigSignature is the signature of a multisig account member, as made by
+// its signature algorithm without the public key.
+type MultisigSignature struct {
+	Index     uint8 // Index of the public key of the member in the multisig key
+	Signature []byte
+}
+
+// NewMultisigKey creates the key of a multisig account requiring threshold of
+// the given keys to sign its transactions. The order of the keys matters, it
+// is part of the address of the account.
+func NewMultisigKey(threshold int, keys []MultisigPublicKey) (*MultisigKey, error) {
+	if threshold < 1 || threshold > len(keys) {
+		return nil, ErrInvalidMultisigKey
+	}
+	key := &MultisigKey{Threshold: uint8(threshold), Keys: make([]MultisigPublicKey, len(keys))}
+	for i := range keys {
+		key.Keys[i] = MultisigPublicKey{keys[i].Algorithm, common.CopyBytes(keys[i].PublicKey)}
+	}
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DecodeMultisigKey decodes and validates an encoded multisig key.
+func DecodeMultisigKey(b []byte) (*MultisigKey, error) {
+	key := new(MultisigKey)
+	if err := rlp.DecodeBytes(b, key); err != nil {
+		return nil, ErrInvalidMultisigKey
+	}
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// validate checks the threshold and the public keys of a multisig key. Member
+// keys must be of a registered algorithm other than multisig, and distinct.
+func (k *MultisigKey) validate() error {
+	if len(k.Keys) == 0 || len(k.Keys) > MaxMultisigKeys || k.Threshold == 0 || int(k.Threshold) > len(k.Keys) {
+		return ErrInvalidMultisigKey
+	}
+	for i, key := range k.Keys {
+		if key.Algorithm == cryptobase.SigAlgIdMultisig {
+			return ErrInvalidMultisigKey
+		}
+		alg, err := cryptobase.SigAlgById(key.Algorithm)
+		if err != nil || len(key.PublicKey) != alg.PublicKeyLength() {
+			return ErrInvalidMultisigKey
+		}
+		for _, other := range k.Keys[:i] {
+			if other.Algorithm == key.Algorithm && bytes.Equal(other.PublicKey, key.PublicKey) {
+				return ErrInvalidMultisigKey
+			}
+		}
+	}
+	return nil
+}
+
+// Bytes returns the RLP encoding of the key, which is the public key carried by
+// the transactions of the account.
+func (k *MultisigKey) Bytes() []byte {
+	b, err := rlp.EncodeToBytes(k)
+	if err != nil {
+		panic("can't encode multisig key: " + err.Error())
+	}
+	return b
+}
+
+// Address returns the address of the multisig account.
+func (k *MultisigKey) Address() common.Address {
+	return multisigAddress(k.Bytes())
+}
+
+// IndexOf returns the index of the given public key in the multisig key.
+func (k *MultisigKey) IndexOf(pub []byte) (int, bool) {
+	for i, key := range k.Keys {
+		if bytes.Equal(key.PublicKey, pub) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func multisigAddress(pub []byte) common.Address {
+	return common.BytesToAddress(crypto.Keccak256([]byte{cryptobase.SigAlgIdMultisig}, pub)[12:])
+}
+
+// publicKeyToAddress derives the address of a public key of the given signature
+// algorithm, multisig keys included.
+func publicKeyToAddress(id byte, pub []byte) (common.Address, error) {
+	if id != cryptobase.SigAlgIdMultisig {
+		return cryptobase.PublicKeyToAddress(id, pub)
+	}
+	if _, err := DecodeMultisigKey(pub); err != nil {
+		return common.Address{}, err
+	}
+	return multisigAddress(pub), nil
+}
+
+// MultisigSigHash returns the hash the members of a multisig account sign for a
+// transaction hash. It binds their signatures to the account, as the
+// transaction hash doesn't cover the public key.
+func MultisigSigHash(sighash common.Hash, from common.Address) common.Hash {
+	return crypto.Keccak256Hash(sighash[:], from[:])
+}
+
+// recoverMultisig verifies the member signatures of a multisig transaction,
+// returning the address of the multisig key if enough of them are valid.
+func recoverMultisig(sighash common.Hash, sig, pub []byte) (common.Address, error) {
+	key, err := DecodeMultisigKey(pub)
+	if err != nil {
+		return common.Address{}, err
+	}
+	sigs, err := decodeMultisigSignatures(sig, len(key.Keys))
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(sigs) < int(key.Threshold) {
+		return common.Address{}, ErrMultisigThreshold
+	}
+	var (
+		from = multisigAddress(pub)
+		hash = MultisigSigHash(sighash, from)
+	)
+	for _, s := range sigs {
+		member := key.Keys[s.Index]
+		if _, err := recoverSignature(hash, member.Algorithm, s.Signature, member.PublicKey); err != nil {
+			return common.Address{}, ErrInvalidSig
+		}
+	}
+	return from, nil
+}
+
+// decodeMultisigSignatures decodes the member signatures of a multisig
+// transaction, which must be sorted by strictly increasing key index.
+func decodeMultisigSignatures(sig []byte, keys int) ([]MultisigSignature, error) {
+	if len(sig) == 0 {
+		return nil, nil
+	}
+	var sigs []MultisigSignature
+	if err := rlp.DecodeBytes(sig, &sigs); err != nil {
+		return nil, errInvalidMultisigSigs
+	}
+	for i, s := range sigs {
+		if int(s.Index) >= keys || (i > 0 && s.Index <= sigs[i-1].Index) {
+			return nil, errInvalidMultisigSigs
+		}
+	}
+	return sigs, nil
+}
+
+// MultisigSignatures returns the member signatures collected so far by a
+// multisig transaction.
+func (tx *Transaction) MultisigSignatures() ([]MultisigSignature, error) {
+	inner, ok := tx.inner.(*SignatureTx)
+	if !ok || inner.Algorithm != cryptobase.SigAlgIdMultisig {
+		return nil, ErrNotMultisigTx
+	}
+	return decodeMultisigSignatures(inner.Signature, MaxMultisigKeys)
+}
+
+// SignMultisigTx signs a multisig transaction with the key of one of the members
+// of the account, returning the transaction with the signature added to those
+// already collected. The transaction must carry the multisig key.
+func SignMultisigTx(tx *Transaction, s Signer, prv *signaturealgorithm.PrivateKey) (*Transaction, error) {
+	inner, ok := tx.inner.(*SignatureTx)
+	if !ok || inner.Algorithm != cryptobase.SigAlgIdMultisig {
+		return nil, ErrNotMultisigTx
+	}
+	if len(inner.PublicKey) == 0 {
+		return nil, ErrMissingPublicKey
+	}
+	key, err := DecodeMultisigKey(inner.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	index, ok := key.IndexOf(prv.PublicKey.PubData)
+	if !ok {
+		return nil, ErrNotMultisigMember
+	}
+	alg, err := cryptobase.SigAlgById(key.Keys[index].Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, _, err := s.SignatureValues(tx, nil); err != nil {
+		return nil, err
+	}
+	hash := MultisigSigHash(s.Hash(tx), key.Address())
+	combined, err := alg.Sign(hash[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	sig, _, err := alg.PublicKeyAndSignatureFromCombinedSignature(hash[:], combined)
+	if err != nil {
+		return nil, err
+	}
+	sigs, err := decodeMultisigSignatures(inner.Signature, len(key.Keys))
+	if err != nil {
+		return nil, err
+	}
+	sigs = mergeMultisigSignatures(sigs, []MultisigSignature{{uint8(index), common.CopyBytes(sig)}})
+	return tx.withMultisigSignatures(s, sigs)
+}
+
+// CombineMultisigTxs merges the member signatures of copies of the same
+// multisig transaction, signed separately by the members of the account.
+func CombineMultisigTxs(s Signer, txs ...*Transaction) (*Transaction, error) {
+	if len(txs) == 0 {
+		return nil, ErrNotMultisigTx
+	}
+	var (
+		hash   = s.Hash(txs[0])
+		_, pub = txs[0].RawSignature()
+		sigs   []MultisigSignature
+	)
+	for _, tx := range txs {
+		txSigs, err := tx.MultisigSignatures()
+		if err != nil {
+			return nil, err
+		}
+		if _, txPub := tx.RawSignature(); s.Hash(tx) != hash || !bytes.Equal(txPub, pub) {
+			return nil, ErrMultisigTxMismatch
+		}
+		sigs = mergeMultisigSignatures(sigs, txSigs)
+	}
+	return txs[0].withMultisigSignatures(s, sigs)
+}
+
+// mergeMultisigSignatures adds signatures to a sorted list, replacing those of
+// the same members.
+func mergeMultisigSignatures(sigs []MultisigSignature, add []MultisigSignature) []MultisigSignature {
+	merged := make(map[uint8]MultisigSignature)
+	for _, list := range [][]MultisigSignature{sigs, add} {
+		for _, s := range list {
+			merged[s.Index] = s
+		}
+	}
+	sigs = make([]MultisigSignature, 0, len(merged))
+	for _, s := range merged {
+		sigs = append(sigs, s)
+	}
+	sort.Slice(sigs, func(i, j int) bool { return sigs[i].Index < sigs[j].Index })
+	return sigs
+}
+
+// withMultisigSignatures returns a copy of a multisig transaction carrying the
+// given member signatures.
+func (tx *Transaction) withMultisigSignatures(s Signer, sigs []MultisigSignature) (*Transaction, error) {
+	sig, err := rlp.EncodeToBytes(sigs)
+	if err != nil {
+		return nil, err
+	}
+	cpy := tx.inner.copy().(*SignatureTx)
+	cpy.setSignatureBytes(s.ChainID(), sig, cpy.PublicKey)
+	return &Transaction{inner: cpy, time: tx.time}, nil
+}
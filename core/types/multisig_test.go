@@ -0,0 +1,248 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+)
+
+// newMultisigKeys generates the member keys of a multisig account, alternating
+// between the hybrid and Falcon algorithms.
+func newMultisigKeys(t *testing.T, n int) ([]*signaturealgorithm.PrivateKey, []MultisigPublicKey) {
+	var (
+		privs = make([]*signaturealgorithm.PrivateKey, n)
+		pubs  = make([]MultisigPublicKey, n)
+	)
+	for i := range privs {
+		id := []byte{cryptobase.SigAlgIdHybrid, cryptobase.SigAlgIdFalcon}[i%2]
+		alg, _ := cryptobase.SigAlgById(id)
+		key, err := alg.GenerateKey()
+		if err != nil {
+			t.Fatalf("could not generate key: %v", err)
+		}
+		privs[i], pubs[i] = key, MultisigPublicKey{Algorithm: id, PublicKey: key.PublicKey.PubData}
+	}
+	return privs, pubs
+}
+
+func newMultisigTx(key *MultisigKey) *Transaction {
+	recipient := common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	return NewTx(&SignatureTx{
+		ChainID:   big.NewInt(1),
+		To:        &recipient,
+		Gas:       123457,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Data:      []byte("abcdef"),
+		Algorithm: cryptobase.SigAlgIdMultisig,
+		PublicKey: key.Bytes(),
+	})
+}
+
+func TestMultisigKey(t *testing.T) {
+	_, pubs := newMultisigKeys(t, 3)
+
+	key, err := NewMultisigKey(2, pubs)
+	if err != nil {
+		t.Fatalf("could not create key: %v", err)
+	}
+	dec, err := DecodeMultisigKey(key.Bytes())
+	if err != nil {
+		t.Fatalf("could not decode key: %v", err)
+	}
+	if dec.Address() != key.Address() {
+		t.Fatalf("address mismatch after decoding: have %x, want %x", dec.Address(), key.Address())
+	}
+	if addr, err := publicKeyToAddress(cryptobase.SigAlgIdMultisig, key.Bytes()); err != nil || addr != key.Address() {
+		t.Fatalf("address mismatch: have %x (%v), want %x", addr, err, key.Address())
+	}
+	if i, ok := key.IndexOf(pubs[1].PublicKey); !ok || i != 1 {
+		t.Errorf("wrong key index: have %d (%v), want 1", i, ok)
+	}
+	// Thresholds and keys are part of the address
+	other, _ := NewMultisigKey(3, pubs)
+	if other.Address() == key.Address() {
+		t.Error("threshold not part of the address")
+	}
+	other, _ = NewMultisigKey(2, []MultisigPublicKey{pubs[1], pubs[0], pubs[2]})
+	if other.Address() == key.Address() {
+		t.Error("key order not part of the address")
+	}
+	tests := []struct {
+		threshold int
+		keys      []MultisigPublicKey
+	}{
+		{0, pubs},
+		{4, pubs},
+		{1, nil},
+		{1, []MultisigPublicKey{pubs[0], pubs[0]}},
+		{1, []MultisigPublicKey{{cryptobase.SigAlgIdMultisig, key.Bytes()}}},
+		{1, []MultisigPublicKey{{cryptobase.SigAlgIdFalcon, pubs[0].PublicKey}}},
+		{1, []MultisigPublicKey{{0xff, pubs[0].PublicKey}}},
+		{1, make([]MultisigPublicKey, MaxMultisigKeys+1)},
+	}
+	for i, tt := range tests {
+		if _, err := NewMultisigKey(tt.threshold, tt.keys); err != ErrInvalidMultisigKey {
+			t.Errorf("test %d: have %v, want %v", i, err, ErrInvalidMultisigKey)
+		}
+	}
+	if _, err := DecodeMultisigKey([]byte{0x01, 0x02}); err != ErrInvalidMultisigKey {
+		t.Errorf("garbage key: have %v, want %v", err, ErrInvalidMultisigKey)
+	}
+}
+
+// Tests that multisig transactions signed separately by their members can be
+// combined, and recover to the multisig account once the threshold is met.
+func TestMultisigTx(t *testing.T) {
+	var (
+		signer      = NewSignatureTxSigner(common.Big1)
+		privs, pubs = newMultisigKeys(t, 3)
+	)
+	key, _ := NewMultisigKey(2, pubs)
+	tx := newMultisigTx(key)
+
+	// A single member isn't enough
+	tx0, err := SignMultisigTx(tx, signer, privs[0])
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if _, err := Sender(signer, tx0); err != ErrMultisigThreshold {
+		t.Fatalf("single signature: have %v, want %v", err, ErrMultisigThreshold)
+	}
+	// Signing twice with the same member replaces its signature
+	if tx00, err := SignMultisigTx(tx0, signer, privs[0]); err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	} else if sigs, _ := tx00.MultisigSignatures(); len(sigs) != 1 {
+		t.Fatalf("signature count mismatch: have %d, want 1", len(sigs))
+	}
+	// Members signing in parallel can combine their signatures
+	tx2, err := SignMultisigTx(tx, signer, privs[2])
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	combined, err := CombineMultisigTxs(signer, tx2, tx0)
+	if err != nil {
+		t.Fatalf("could not combine transactions: %v", err)
+	}
+	sigs, err := combined.MultisigSignatures()
+	if err != nil || len(sigs) != 2 || sigs[0].Index != 0 || sigs[1].Index != 2 {
+		t.Fatalf("combined signatures mismatch: %v %v", sigs, err)
+	}
+	for _, decode := range []func(*Transaction) (*Transaction, error){encodeDecodeBinary, encodeDecodeJSON} {
+		parsedTx, err := decode(combined)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if from, err := Sender(signer, parsedTx); err != nil || from != key.Address() {
+			t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, key.Address())
+		}
+	}
+	// Signing on top of a partially signed transaction works the same
+	seq, err := SignMultisigTx(tx0, signer, privs[1])
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if from, err := Sender(signer, seq); err != nil || from != key.Address() {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, key.Address())
+	}
+	// Outsiders can't sign
+	outsiders, _ := newMultisigKeys(t, 1)
+	if _, err := SignMultisigTx(tx, signer, outsiders[0]); err != ErrNotMultisigMember {
+		t.Errorf("outsider: have %v, want %v", err, ErrNotMultisigMember)
+	}
+	// Only copies of the same transaction can be combined
+	other := tx.inner.copy().(*SignatureTx)
+	other.Nonce++
+	otherTx, _ := SignMultisigTx(NewTx(other), signer, privs[1])
+	if _, err := CombineMultisigTxs(signer, tx0, otherTx); err != ErrMultisigTxMismatch {
+		t.Errorf("different transactions: have %v, want %v", err, ErrMultisigTxMismatch)
+	}
+	// A signature of a member in place of another is rejected
+	forged := combined.inner.copy().(*SignatureTx)
+	forgedSigs := []MultisigSignature{sigs[0], {Index: 1, Signature: sigs[1].Signature}}
+	forgedTx, _ := NewTx(forged).withMultisigSignatures(signer, forgedSigs)
+	if _, err := Sender(signer, forgedTx); err != ErrInvalidSig {
+		t.Errorf("swapped signature: have %v, want %v", err, ErrInvalidSig)
+	}
+	// Unsorted or duplicate signatures are rejected
+	for _, list := range [][]MultisigSignature{{sigs[1], sigs[0]}, {sigs[0], sigs[0]}} {
+		tx, _ := NewTx(forged).withMultisigSignatures(signer, list)
+		if _, err := Sender(signer, tx); err != errInvalidMultisigSigs {
+			t.Errorf("invalid signature list: have %v, want %v", err, errInvalidMultisigSigs)
+		}
+	}
+	// Only multisig transactions are signed by members
+	plain := tx.inner.copy().(*SignatureTx)
+	plain.Algorithm = cryptobase.SigAlgIdFalcon
+	if _, err := SignMultisigTx(NewTx(plain), signer, privs[0]); err != ErrNotMultisigTx {
+		t.Errorf("plain transaction: have %v, want %v", err, ErrNotMultisigTx)
+	}
+}
+
+// Tests that the signatures of the members of a multisig account can't be
+// replayed for another account sharing some of its members.
+func TestMultisigTxReplay(t *testing.T) {
+	var (
+		signer      = NewSignatureTxSigner(common.Big1)
+		privs, pubs = newMultisigKeys(t, 3)
+	)
+	key, _ := NewMultisigKey(2, pubs[:2])
+	other, _ := NewMultisigKey(1, pubs)
+
+	tx := newMultisigTx(key)
+	tx, _ = SignMultisigTx(tx, signer, privs[0])
+	tx, _ = SignMultisigTx(tx, signer, privs[1])
+	if from, err := Sender(signer, tx); err != nil || from != key.Address() {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, key.Address())
+	}
+	replay := tx.inner.copy().(*SignatureTx)
+	replay.PublicKey = other.Bytes()
+	if _, err := Sender(signer, NewTx(replay)); err != ErrInvalidSig {
+		t.Errorf("replayed signatures: have %v, want %v", err, ErrInvalidSig)
+	}
+}
+
+func TestMultisigRegistrySigner(t *testing.T) {
+	var (
+		signer      = NewSignatureTxSigner(common.Big1)
+		privs, pubs = newMultisigKeys(t, 2)
+	)
+	key, _ := NewMultisigKey(1, pubs)
+	tx, err := SignMultisigTx(newMultisigTx(key), signer, privs[1])
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	short, err := tx.WithoutPublicKey()
+	if err != nil {
+		t.Fatalf("could not strip public key: %v", err)
+	}
+	if from, ok := short.RegisteredKeySender(); !ok || from != key.Address() {
+		t.Fatalf("registered key sender mismatch: have %x (%v), want %x", from, ok, key.Address())
+	}
+	if _, err := Sender(signer, short); err != ErrMissingPublicKey {
+		t.Errorf("unregistered key: have %v, want %v", err, ErrMissingPublicKey)
+	}
+	keys := publicKeyMap{key.Address(): append([]byte{cryptobase.SigAlgIdMultisig}, key.Bytes()...)}
+	if from, err := Sender(NewRegistrySigner(signer, keys), short); err != nil || from != key.Address() {
+		t.Errorf("sender mismatch: have %x (%v), want %x", from, err, key.Address())
+	}
+}
@@ -176,7 +176,7 @@ func (r *Receipt) DecodeRLP(s *rlp.Stream) error {
 			return errEmptyTypedReceipt
 		}
 		r.Type = b[0]
-		if r.Type == AccessListTxType || r.Type == DynamicFeeTxType {
+		if r.Type == AccessListTxType || r.Type == DynamicFeeTxType || r.Type == SignatureTxType {
 			var dec receiptRLP
 			if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
 				return err
@@ -345,6 +345,9 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 	case DynamicFeeTxType:
 		w.WriteByte(DynamicFeeTxType)
 		rlp.Encode(w, data)
+	case SignatureTxType:
+		w.WriteByte(SignatureTxType)
+		rlp.Encode(w, data)
 	default:
 		// For unsupported types, write nothing. Since this is for
 		// DeriveSha, the error will be caught matching the derived hash
@@ -373,8 +376,13 @@ func (r Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, num
 
 		// The contract address can be derived from the transaction itself
 		if txs[i].To() == nil {
-			// Deriving the signer is expensive, only do if it's actually needed
-			from, _ := Sender(signer, txs[i])
+			// Deriving the signer is expensive, only do if it's actually needed.
+			// Transactions of the block relying on a registered public key were
+			// verified on import, their named sender can be trusted.
+			from, ok := txs[i].RegisteredKeySender()
+			if !ok {
+				from, _ = Sender(signer, txs[i])
+			}
 			r[i].ContractAddress = crypto.CreateAddress(from, txs[i].Nonce())
 		}
 		// The used gas can be calculated based on previous r
@@ -0,0 +1,107 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+)
+
+// SignatureTx is a dynamic fee transaction carrying its post-quantum signature
+// as bytes, along with the ID of the signature algorithm it was made with. Once
+// the public key of the sender is registered in state, the transaction may name
+// its sender instead of carrying the key.
+type SignatureTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nil"` // nil means contract creation
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+	Algorithm  uint8
+
+	// Signature values
+	Signature []byte          `json:"signature" gencodec:"required"`
+	PublicKey []byte          `json:"publicKey" rlp:"optional"`
+	From      *common.Address `json:"sender" rlp:"optional"` // Sender of a transaction without public key
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *SignatureTx) copy() TxData {
+	cpy := &SignatureTx{
+		Nonce:     tx.Nonce,
+		To:        tx.To, // TODO: copy pointed-to address
+		Data:      common.CopyBytes(tx.Data),
+		Gas:       tx.Gas,
+		Algorithm: tx.Algorithm,
+		Signature: common.CopyBytes(tx.Signature),
+		PublicKey: common.CopyBytes(tx.PublicKey),
+		From:      tx.From,
+		// These are copied below.
+		AccessList: make(AccessList, len(tx.AccessList)),
+		Value:      new(big.Int),
+		ChainID:    new(big.Int),
+		GasTipCap:  new(big.Int),
+		GasFeeCap:  new(big.Int),
+	}
+	copy(cpy.AccessList, tx.AccessList)
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasTipCap != nil {
+		cpy.GasTipCap.Set(tx.GasTipCap)
+	}
+	if tx.GasFeeCap != nil {
+		cpy.GasFeeCap.Set(tx.GasFeeCap)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *SignatureTx) txType() byte           { return SignatureTxType }
+func (tx *SignatureTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *SignatureTx) protected() bool        { return true }
+func (tx *SignatureTx) accessList() AccessList { return tx.AccessList }
+func (tx *SignatureTx) data() []byte           { return tx.Data }
+func (tx *SignatureTx) gas() uint64            { return tx.Gas }
+func (tx *SignatureTx) gasFeeCap() *big.Int    { return tx.GasFeeCap }
+func (tx *SignatureTx) gasTipCap() *big.Int    { return tx.GasTipCap }
+func (tx *SignatureTx) gasPrice() *big.Int     { return tx.GasFeeCap }
+func (tx *SignatureTx) value() *big.Int        { return tx.Value }
+func (tx *SignatureTx) nonce() uint64          { return tx.Nonce }
+func (tx *SignatureTx) to() *common.Address    { return tx.To }
+
+// rawSignatureValues returns zero values: the signature of the transaction is
+// only available as bytes.
+func (tx *SignatureTx) rawSignatureValues() (v, r, s *big.Int) {
+	return new(big.Int), new(big.Int), new(big.Int)
+}
+
+func (tx *SignatureTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID = chainID
+}
+
+func (tx *SignatureTx) setSignatureBytes(chainID *big.Int, sig, pub []byte) {
+	tx.ChainID, tx.Signature, tx.PublicKey = chainID, sig, pub
+}
@@ -33,6 +33,7 @@ import (
 
 var (
 	ErrInvalidSig           = errors.New("invalid transaction v, r, s values")
+	ErrMissingPublicKey     = errors.New("missing public key in transaction")
 	ErrUnexpectedProtection = errors.New("transaction type does not supported EIP-155 protected signatures")
 	ErrInvalidTxType        = errors.New("transaction type not valid in this context")
 	ErrTxTypeNotSupported   = errors.New("transaction type not supported")
@@ -45,6 +46,7 @@ const (
 	LegacyTxType = iota
 	AccessListTxType
 	DynamicFeeTxType
+	SignatureTxType
 )
 
 // Transaction is an Ethereum transaction.
@@ -67,7 +69,7 @@ func NewTx(inner TxData) *Transaction {
 
 // TxData is the underlying data of a transaction.
 //
-// This is implemented by DynamicFeeTx, LegacyTx and AccessListTx.
+// This is implemented by DynamicFeeTx, LegacyTx, AccessListTx and SignatureTx.
 type TxData interface {
 	txType() byte // returns the type ID
 	copy() TxData // creates a deep copy and initializes all fields
@@ -186,6 +188,10 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 		var inner DynamicFeeTx
 		err := rlp.DecodeBytes(b[1:], &inner)
 		return &inner, err
+	case SignatureTxType:
+		var inner SignatureTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
 	default:
 		return nil, ErrTxTypeNotSupported
 	}
@@ -219,7 +225,7 @@ func sanityCheckSignature(v *big.Int, r *big.Int, s *big.Int, maybeProtected boo
 		// must already be equal to the recovery id.
 		plainV = byte(v.Uint64())
 	}
-	if !cryptobase.SigAlg.ValidateSignatureValues(plainV, r, s, false) {
+	if !validateSignatureValues(plainV, r, s, false) {
 		return ErrInvalidSig
 	}
 
@@ -306,6 +312,63 @@ func (tx *Transaction) RawSignatureValues() (v, r, s *big.Int) {
 	return tx.inner.rawSignatureValues()
 }
 
+// RawSignature returns the signature and public key bytes of a signature
+// transaction, nil for the other transaction types. The return values should
+// not be modified by the caller.
+func (tx *Transaction) RawSignature() (sig, pub []byte) {
+	if inner, ok := tx.inner.(*SignatureTx); ok {
+		return inner.Signature, inner.PublicKey
+	}
+	return nil, nil
+}
+
+// RegisteredKeySender returns the sender named by a signature transaction that
+// omits its public key, relying on the key registered in state for the sender.
+func (tx *Transaction) RegisteredKeySender() (common.Address, bool) {
+	if inner, ok := tx.inner.(*SignatureTx); ok && len(inner.PublicKey) == 0 && inner.From != nil {
+		return *inner.From, true
+	}
+	return common.Address{}, false
+}
+
+// WithoutPublicKey returns a copy of a signed signature transaction naming its
+// sender instead of carrying the public key, for senders whose key is already
+// registered in state.
+func (tx *Transaction) WithoutPublicKey() (*Transaction, error) {
+	inner, ok := tx.inner.(*SignatureTx)
+	if !ok {
+		return nil, ErrTxTypeNotSupported
+	}
+	if len(inner.PublicKey) == 0 {
+		return nil, ErrMissingPublicKey
+	}
+	from, err := publicKeyToAddress(inner.Algorithm, inner.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	cpy := inner.copy().(*SignatureTx)
+	cpy.PublicKey, cpy.From = nil, &from
+	return &Transaction{inner: cpy, time: tx.time}, nil
+}
+
+// SignatureAlgorithm returns the ID of the signature algorithm the transaction
+// is signed with. Signature transactions declare it, the other typed ones carry
+// it in their V value and legacy ones are always signed with the algorithm the
+// chain launched with.
+func (tx *Transaction) SignatureAlgorithm() byte {
+	switch inner := tx.inner.(type) {
+	case *LegacyTx:
+		return cryptobase.SigAlgIdHybrid
+	case *SignatureTx:
+		return inner.Algorithm
+	}
+	v, _, _ := tx.RawSignatureValues()
+	if v.BitLen() > 8 {
+		return 0
+	}
+	return byte(v.Uint64())
+}
+
 // GasFeeCapCmp compares the fee cap of two transactions.
 func (tx *Transaction) GasFeeCapCmp(other *Transaction) int {
 	return tx.inner.gasFeeCap().Cmp(other.inner.gasFeeCap())
@@ -395,6 +458,9 @@ func (tx *Transaction) Size() common.StorageSize {
 // WithSignature returns a new transaction with the given signature.
 // This signature needs to be in the [R || S || V] format where V is 0 or 1.
 func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, error) {
+	if tx.Type() == SignatureTxType {
+		return tx.withSignatureBytes(signer, sig)
+	}
 	r, s, v, err := signer.SignatureValues(tx, sig)
 	if err != nil {
 		return nil, err
@@ -404,6 +470,25 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	return &Transaction{inner: cpy, time: tx.time}, nil
 }
 
+// withSignatureBytes returns a new signature transaction with the given
+// signature, split into the signature and public key fields.
+func (tx *Transaction) withSignatureBytes(signer Signer, sig []byte) (*Transaction, error) {
+	if _, _, _, err := signer.SignatureValues(tx, sig); err != nil {
+		return nil, err
+	}
+	alg, err := cryptobase.SigAlgById(tx.SignatureAlgorithm())
+	if err != nil {
+		return nil, err
+	}
+	signature, pub, err := alg.PublicKeyAndSignatureFromCombinedSignature(nil, sig)
+	if err != nil {
+		return nil, err
+	}
+	cpy := tx.inner.copy().(*SignatureTx)
+	cpy.setSignatureBytes(signer.ChainID(), common.CopyBytes(signature), common.CopyBytes(pub))
+	return &Transaction{inner: cpy, time: tx.time}, nil
+}
+
 // Transactions implements DerivableList for transactions.
 type Transactions []*Transaction
 
@@ -19,6 +19,7 @@ package types
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"math/big"
 
 	"github.com/DogeProtocol/dp/common"
@@ -46,6 +47,12 @@ type txJSON struct {
 	ChainID    *hexutil.Big `json:"chainId,omitempty"`
 	AccessList *AccessList  `json:"accessList,omitempty"`
 
+	// Signature transaction fields:
+	Algorithm *hexutil.Uint64 `json:"algorithm,omitempty"`
+	Signature *hexutil.Bytes  `json:"signature,omitempty"`
+	PublicKey *hexutil.Bytes  `json:"publicKey,omitempty"`
+	Sender    *common.Address `json:"sender,omitempty"`
+
 	// Only used for encoding:
 	Hash common.Hash `json:"hash"`
 }
@@ -94,6 +101,23 @@ func (t *Transaction) MarshalJSON() ([]byte, error) {
 		enc.V = (*hexutil.Big)(tx.V)
 		enc.R = (*hexutil.Big)(tx.R)
 		enc.S = (*hexutil.Big)(tx.S)
+	case *SignatureTx:
+		algorithm := hexutil.Uint64(tx.Algorithm)
+		enc.ChainID = (*hexutil.Big)(tx.ChainID)
+		enc.AccessList = &tx.AccessList
+		enc.Nonce = (*hexutil.Uint64)(&tx.Nonce)
+		enc.Gas = (*hexutil.Uint64)(&tx.Gas)
+		enc.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap)
+		enc.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap)
+		enc.Value = (*hexutil.Big)(tx.Value)
+		enc.Data = (*hexutil.Bytes)(&tx.Data)
+		enc.To = t.To()
+		enc.Algorithm = &algorithm
+		enc.Signature = (*hexutil.Bytes)(&tx.Signature)
+		if len(tx.PublicKey) > 0 {
+			enc.PublicKey = (*hexutil.Bytes)(&tx.PublicKey)
+		}
+		enc.Sender = tx.From
 	}
 	return json.Marshal(&enc)
 }
@@ -263,6 +287,60 @@ func (t *Transaction) UnmarshalJSON(input []byte) error {
 			}
 		}
 
+	case SignatureTxType:
+		var itx SignatureTx
+		inner = &itx
+		// Access list is optional for now.
+		if dec.AccessList != nil {
+			itx.AccessList = *dec.AccessList
+		}
+		if dec.ChainID == nil {
+			return errors.New("missing required field 'chainId' in transaction")
+		}
+		itx.ChainID = (*big.Int)(dec.ChainID)
+		if dec.To != nil {
+			itx.To = dec.To
+		}
+		if dec.Nonce == nil {
+			return errors.New("missing required field 'nonce' in transaction")
+		}
+		itx.Nonce = uint64(*dec.Nonce)
+		if dec.MaxPriorityFeePerGas == nil {
+			return errors.New("missing required field 'maxPriorityFeePerGas' for txdata")
+		}
+		itx.GasTipCap = (*big.Int)(dec.MaxPriorityFeePerGas)
+		if dec.MaxFeePerGas == nil {
+			return errors.New("missing required field 'maxFeePerGas' for txdata")
+		}
+		itx.GasFeeCap = (*big.Int)(dec.MaxFeePerGas)
+		if dec.Gas == nil {
+			return errors.New("missing required field 'gas' for txdata")
+		}
+		itx.Gas = uint64(*dec.Gas)
+		if dec.Value == nil {
+			return errors.New("missing required field 'value' in transaction")
+		}
+		itx.Value = (*big.Int)(dec.Value)
+		if dec.Data == nil {
+			return errors.New("missing required field 'input' in transaction")
+		}
+		itx.Data = *dec.Data
+		if dec.Algorithm == nil {
+			return errors.New("missing required field 'algorithm' in transaction")
+		}
+		if *dec.Algorithm > math.MaxUint8 {
+			return errors.New("invalid signature algorithm in transaction")
+		}
+		itx.Algorithm = uint8(*dec.Algorithm)
+		if dec.Signature == nil {
+			return errors.New("missing required field 'signature' in transaction")
+		}
+		itx.Signature = *dec.Signature
+		if dec.PublicKey != nil {
+			itx.PublicKey = *dec.PublicKey
+		}
+		itx.From = dec.Sender
+
 	default:
 		return ErrTxTypeNotSupported
 	}
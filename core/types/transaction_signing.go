@@ -23,7 +23,6 @@ import (
 	"math/big"
 
 	"github.com/DogeProtocol/dp/common"
-	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/params"
 )
 
@@ -40,6 +39,8 @@ type sigCache struct {
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
 	switch {
+	case config.IsSignatureTx(blockNumber):
+		signer = NewSignatureTxSigner(config.ChainID)
 	case config.IsLondon(blockNumber):
 		signer = NewLondonSigner(config.ChainID)
 	case config.IsBerlin(blockNumber):
@@ -63,6 +64,9 @@ func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 // have the current block number available, use MakeSigner instead.
 func LatestSigner(config *params.ChainConfig) Signer {
 	if config.ChainID != nil {
+		if config.SignatureTxBlock != nil {
+			return NewSignatureTxSigner(config.ChainID)
+		}
 		if config.LondonBlock != nil {
 			return NewLondonSigner(config.ChainID)
 		}
@@ -87,30 +91,122 @@ func LatestSignerForChainID(chainID *big.Int) Signer {
 	if chainID == nil {
 		return HomesteadSigner{}
 	}
-	return NewLondonSigner(chainID)
+	return NewSignatureTxSigner(chainID)
 }
 
 // SignTx signs the transaction using the given signer and private key.
 func SignTx(tx *Transaction, s Signer, prv *signaturealgorithm.PrivateKey) (*Transaction, error) {
+	alg, err := sigAlgOf(tx)
+	if err != nil {
+		return nil, err
+	}
 	h := s.Hash(tx)
-	sig, err := cryptobase.SigAlg.Sign(h[:], prv)
+	sig, err := alg.Sign(h[:], prv)
 	if err != nil {
 		return nil, err
 	}
 	return tx.WithSignature(s, sig)
 }
 
+// SignTxWithAlgorithm signs the transaction using the given signer, tagging the
+// signature with the ID of the signature algorithm the private key belongs to.
+// Only typed transactions can carry algorithms other than the launch one.
+func SignTxWithAlgorithm(tx *Transaction, s Signer, id byte, prv *signaturealgorithm.PrivateKey) (*Transaction, error) {
+	alg, err := cryptobase.SigAlgById(id)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Type() == LegacyTxType && id != cryptobase.SigAlgIdHybrid {
+		return nil, ErrTxTypeNotSupported
+	}
+	if inner, ok := tx.inner.(*SignatureTx); ok {
+		cpy := inner.copy().(*SignatureTx)
+		cpy.Algorithm = id
+		tx = &Transaction{inner: cpy, time: tx.time}
+	}
+	h := s.Hash(tx)
+	sig, err := alg.Sign(h[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := tx.WithSignature(s, sig)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Type() != LegacyTxType && tx.Type() != SignatureTxType {
+		_, r, s := signed.RawSignatureValues()
+		signed.inner.setSignatureValues(signed.ChainId(), new(big.Int).SetUint64(uint64(id)), r, s)
+	}
+	return signed, nil
+}
+
+// SignatureAlgorithmEnabled returns whether transactions signed with the given
+// signature algorithm are accepted at the given block.
+func SignatureAlgorithmEnabled(config *params.ChainConfig, id byte, blockNumber *big.Int) bool {
+	switch id {
+	case cryptobase.SigAlgIdHybrid:
+		return true
+	case cryptobase.SigAlgIdFalcon:
+		return config.IsFalcon(blockNumber)
+	case cryptobase.SigAlgIdMultisig:
+		return config.IsMultisig(blockNumber)
+	default:
+		return false
+	}
+}
+
+// TxSignatureAlgorithmsEnabled returns whether all the signature algorithms a
+// transaction is signed with are enabled at the given block number: the one it
+// declares and, for multisig transactions carrying their key, the algorithms of
+// the members. Registered multisig keys were checked when registered.
+func TxSignatureAlgorithmsEnabled(config *params.ChainConfig, tx *Transaction, blockNumber *big.Int) bool {
+	id := tx.SignatureAlgorithm()
+	if !SignatureAlgorithmEnabled(config, id, blockNumber) {
+		return false
+	}
+	if id != cryptobase.SigAlgIdMultisig {
+		return true
+	}
+	_, pub := tx.RawSignature()
+	if len(pub) == 0 {
+		return true
+	}
+	key, err := DecodeMultisigKey(pub)
+	if err != nil {
+		return false
+	}
+	for _, member := range key.Keys {
+		if !SignatureAlgorithmEnabled(config, member.Algorithm, blockNumber) {
+			return false
+		}
+	}
+	return true
+}
+
 // SignNewTx creates a transaction and signs it.
 func SignNewTx(prv *signaturealgorithm.PrivateKey, s Signer, txdata TxData) (*Transaction, error) {
 	tx := NewTx(txdata)
+	alg, err := sigAlgOf(tx)
+	if err != nil {
+		return nil, err
+	}
 	h := s.Hash(tx)
-	sig, err := cryptobase.SigAlg.Sign(h[:], prv)
+	sig, err := alg.Sign(h[:], prv)
 	if err != nil {
 		return nil, err
 	}
 	return tx.WithSignature(s, sig)
 }
 
+// sigAlgOf returns the signature algorithm to sign a transaction with: the one
+// declared by signature transactions, the default one otherwise.
+func sigAlgOf(tx *Transaction) (signaturealgorithm.SignatureAlgorithm, error) {
+	if inner, ok := tx.inner.(*SignatureTx); ok {
+		return cryptobase.SigAlgById(inner.Algorithm)
+	}
+	return cryptobase.SigAlg, nil
+}
+
 // MustSignNewTx creates a transaction and signs it.
 // This panics if the transaction cannot be signed.
 func MustSignNewTx(prv *signaturealgorithm.PrivateKey, s Signer, txdata TxData) *Transaction {
@@ -169,6 +265,129 @@ type Signer interface {
 	Equal(Signer) bool
 }
 
+type signatureTxSigner struct{ londonSigner }
+
+// NewSignatureTxSigner returns a signer that accepts
+// - signature transactions carrying their signature as bytes,
+// - EIP-1559 dynamic fee transactions
+// - EIP-2930 access list transactions,
+// - EIP-155 replay protected transactions, and
+// - legacy Homestead transactions.
+func NewSignatureTxSigner(chainId *big.Int) Signer {
+	return signatureTxSigner{londonSigner{eip2930Signer{NewEIP155Signer(chainId)}}}
+}
+
+func (s signatureTxSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != SignatureTxType {
+		return s.londonSigner.Sender(tx)
+	}
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	sig, pub := tx.RawSignature()
+	addr, err := recoverSignature(s.Hash(tx), tx.SignatureAlgorithm(), sig, pub)
+	if err != nil {
+		return common.Address{}, err
+	}
+	// A sender named along with the public key must match it
+	if from := tx.inner.(*SignatureTx).From; from != nil && *from != addr {
+		return common.Address{}, ErrInvalidSig
+	}
+	return addr, nil
+}
+
+func (s signatureTxSigner) Equal(s2 Signer) bool {
+	x, ok := s2.(signatureTxSigner)
+	return ok && x.chainId.Cmp(s.chainId) == 0
+}
+
+// SignatureValues returns zero values for signature transactions, whose
+// signature is set as bytes by WithSignature.
+func (s signatureTxSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	txdata, ok := tx.inner.(*SignatureTx)
+	if !ok {
+		return s.londonSigner.SignatureValues(tx, sig)
+	}
+	// Check that chain ID of tx matches the signer. We also accept ID zero here,
+	// because it indicates that the chain ID was not specified in the tx.
+	if txdata.ChainID.Sign() != 0 && txdata.ChainID.Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	return new(big.Int), new(big.Int), new(big.Int), nil
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s signatureTxSigner) Hash(tx *Transaction) common.Hash {
+	if tx.Type() != SignatureTxType {
+		return s.londonSigner.Hash(tx)
+	}
+	return prefixedRlpHash(
+		tx.Type(),
+		[]interface{}{
+			s.chainId,
+			tx.Nonce(),
+			tx.GasTipCap(),
+			tx.GasFeeCap(),
+			tx.Gas(),
+			tx.To(),
+			tx.Value(),
+			tx.Data(),
+			tx.AccessList(),
+			tx.SignatureAlgorithm(),
+		})
+}
+
+// PublicKeyReader gives access to the public keys registered in state.
+type PublicKeyReader interface {
+	GetPublicKey(addr common.Address) (byte, []byte)
+}
+
+type registrySigner struct {
+	Signer
+	keys PublicKeyReader
+}
+
+// NewRegistrySigner returns a signer recovering the sender of the signature
+// transactions omitting their public key from the key registered in state for
+// the sender they name. The other transactions are left to the given signer.
+func NewRegistrySigner(signer Signer, keys PublicKeyReader) Signer {
+	return registrySigner{signer, keys}
+}
+
+func (s registrySigner) Sender(tx *Transaction) (common.Address, error) {
+	addr, err := s.Signer.Sender(tx)
+	if err != ErrMissingPublicKey {
+		return addr, err
+	}
+	from, ok := tx.RegisteredKeySender()
+	if !ok {
+		return common.Address{}, err
+	}
+	id, pub := s.keys.GetPublicKey(from)
+	if pub == nil {
+		return common.Address{}, ErrMissingPublicKey
+	}
+	if id != tx.SignatureAlgorithm() {
+		return common.Address{}, ErrInvalidSig
+	}
+	sig, _ := tx.RawSignature()
+	if addr, err = recoverSignature(s.Hash(tx), id, sig, pub); err != nil {
+		return common.Address{}, err
+	}
+	if addr != from {
+		return common.Address{}, ErrInvalidSig
+	}
+	return addr, nil
+}
+
+func (s registrySigner) Equal(s2 Signer) bool {
+	if x, ok := s2.(registrySigner); ok {
+		s2 = x.Signer
+	}
+	return s.Signer.Equal(s2)
+}
+
 type londonSigner struct{ eip2930Signer }
 
 // NewLondonSigner returns a signer that accepts
@@ -505,28 +724,70 @@ func recoverPlain(sighash common.Hash, R, S, Vb *big.Int, homestead bool) (commo
 		return common.Address{}, ErrInvalidSig
 	}
 	V := byte(Vb.Uint64() - 27)
-	if !cryptobase.SigAlg.ValidateSignatureValues(V, R, S, homestead) {
+	if !validateSignatureValues(V, R, S, homestead) {
 		return common.Address{}, ErrInvalidSig
 	}
+	alg, err := cryptobase.SigAlgById(V)
+	if err != nil {
+		return common.Address{}, err
+	}
 	// encode the signature in uncompressed format
 	r, s := R.Bytes(), S.Bytes()
 
-	combinedSignature, err := cryptobase.SigAlg.CombinePublicKeySignature(s, r)
+	combinedSignature, err := alg.CombinePublicKeySignature(s, r)
 	if err != nil {
 		return common.Address{}, err
 	}
 
 	// recover the public key from the signature
-	pub, err := cryptobase.SigAlg.PublicKeyBytesFromSignature(sighash[:], combinedSignature)
+	pub, err := alg.PublicKeyBytesFromSignature(sighash[:], combinedSignature)
 	if err != nil {
 		return common.Address{}, err
 	}
-	if len(pub) != 0 && len(pub) != cryptobase.SigAlg.PublicKeyLength() {
+	if len(pub) != alg.PublicKeyLength() {
 		return common.Address{}, errors.New("invalid public key")
 	}
-	var addr common.Address
-	copy(addr[:], crypto.Keccak256(pub[:])[12:])
-	return addr, nil
+	return cryptobase.PublicKeyToAddress(V, pub)
+}
+
+// recoverSignature verifies the signature of a signature transaction, returning
+// the address of its public key.
+func recoverSignature(sighash common.Hash, id byte, sig, pub []byte) (common.Address, error) {
+	if id == cryptobase.SigAlgIdMultisig {
+		if len(pub) == 0 {
+			return common.Address{}, ErrMissingPublicKey
+		}
+		return recoverMultisig(sighash, sig, pub)
+	}
+	alg, err := cryptobase.SigAlgById(id)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(pub) == 0 {
+		return common.Address{}, ErrMissingPublicKey
+	}
+	if len(pub) != alg.PublicKeyLength() || len(sig) == 0 {
+		return common.Address{}, ErrInvalidSig
+	}
+	combinedSignature, err := alg.CombinePublicKeySignature(sig, pub)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if !alg.Verify(pub, sighash[:], combinedSignature) {
+		return common.Address{}, ErrInvalidSig
+	}
+	return cryptobase.PublicKeyToAddress(id, pub)
+}
+
+// validateSignatureValues checks the signature values against the rules of the
+// signature algorithm selected by the V value. The post-quantum schemes have no
+// recovery id, V carries the algorithm ID instead.
+func validateSignatureValues(v byte, r, s *big.Int, homestead bool) bool {
+	alg, err := cryptobase.SigAlgById(v)
+	if err != nil {
+		return false
+	}
+	return alg.ValidateSignatureValues(1, r, s, homestead)
 }
 
 // deriveChainId derives the chain id from the given v parameter
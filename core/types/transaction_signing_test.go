@@ -142,3 +142,136 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestSignTxWithAlgorithm(t *testing.T) {
+	falcon, _ := cryptobase.SigAlgById(cryptobase.SigAlgIdFalcon)
+	key, _ := falcon.GenerateKey()
+	addr, err := cryptobase.PublicKeyToAddress(cryptobase.SigAlgIdFalcon, key.PublicKey.PubData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr == falcon.PublicKeyToAddressNoError(&key.PublicKey) {
+		t.Error("expected algorithm ID to be hashed into the address")
+	}
+
+	signer := NewLondonSigner(big.NewInt(18))
+	tx, err := SignTxWithAlgorithm(NewTx(&DynamicFeeTx{ChainID: big.NewInt(18), GasTipCap: new(big.Int), GasFeeCap: new(big.Int), To: &addr}), signer, cryptobase.SigAlgIdFalcon, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := tx.SignatureAlgorithm(); id != cryptobase.SigAlgIdFalcon {
+		t.Errorf("signature algorithm mismatch: have %d, want %d", id, cryptobase.SigAlgIdFalcon)
+	}
+	from, err := Sender(signer, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != addr {
+		t.Errorf("exected from and address to be equal. Got %x want %x", from, addr)
+	}
+
+	// Legacy transactions can't carry the algorithm ID
+	if _, err := SignTxWithAlgorithm(NewTransaction(0, addr, new(big.Int), 0, new(big.Int), nil), signer, cryptobase.SigAlgIdFalcon, key); err != ErrTxTypeNotSupported {
+		t.Errorf("legacy transaction: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	if _, err := SignTxWithAlgorithm(tx, signer, 0xff, key); err != cryptobase.ErrUnknownSigAlg {
+		t.Errorf("unknown algorithm: have %v, want %v", err, cryptobase.ErrUnknownSigAlg)
+	}
+}
+
+// Tests that VerifySenders caches the senders of the transactions with a valid
+// signature, and leaves the invalid ones to Sender.
+func TestVerifySenders(t *testing.T) {
+	key, _ := cryptobase.SigAlg.GenerateKey()
+	addr, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := LatestSignerForChainID(big.NewInt(18))
+
+	// Sign until the signature is accepted, S values encoding with a leading
+	// zero byte are rejected by the signature value checks
+	sign := func(i int) *Transaction {
+		for {
+			var (
+				tx  *Transaction
+				err error
+			)
+			if i < 4 {
+				tx, err = SignTx(NewTransaction(uint64(i), addr, new(big.Int), 0, new(big.Int), nil), NewEIP155Signer(big.NewInt(18)), key)
+			} else {
+				tx, err = SignNewTx(key, signer, &DynamicFeeTx{ChainID: big.NewInt(18), Nonce: uint64(i), To: &addr, Gas: 21000, GasTipCap: new(big.Int), GasFeeCap: new(big.Int), Value: new(big.Int)})
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Sender(signer, NewTx(tx.inner)); err == nil {
+				return tx
+			}
+		}
+	}
+	var txs []*Transaction
+	for i := 0; i < 8; i++ {
+		txs = append(txs, sign(i))
+	}
+	// Swap the signatures of two transactions to invalidate them
+	invalid := []*Transaction{NewTx(txs[0].inner), NewTx(txs[5].inner)}
+	v, r, s := txs[1].inner.rawSignatureValues()
+	invalid[0].inner.setSignatureValues(big.NewInt(18), v, r, s)
+	v, r, s = txs[6].inner.rawSignatureValues()
+	invalid[1].inner.setSignatureValues(big.NewInt(18), v, r, s)
+
+	VerifySenders(signer, append(append([]*Transaction{}, txs...), invalid...))
+	for i, tx := range txs {
+		sc := tx.from.Load()
+		if sc == nil {
+			t.Fatalf("tx %d: sender not cached", i)
+		}
+		if from := sc.(sigCache).from; from != addr {
+			t.Errorf("tx %d: sender mismatch: have %x, want %x", i, from, addr)
+		}
+	}
+	for i, tx := range invalid {
+		if tx.from.Load() != nil {
+			t.Errorf("invalid tx %d: sender cached", i)
+		}
+		if _, err := Sender(signer, tx); err == nil {
+			t.Errorf("invalid tx %d: sender recovered", i)
+		}
+	}
+	// Transactions the signer rejects regardless of their signature stay uncached
+	unsupported := NewTx(txs[5].inner)
+	VerifySenders(NewEIP155Signer(big.NewInt(18)), []*Transaction{unsupported})
+	if unsupported.from.Load() != nil {
+		t.Errorf("sender of unsupported tx type cached")
+	}
+}
+
+func BenchmarkSenders(b *testing.B) {
+	key, _ := cryptobase.SigAlg.GenerateKey()
+	signer := LatestSignerForChainID(big.NewInt(18))
+
+	txs := make([]*Transaction, 128)
+	for i := range txs {
+		txs[i], _ = SignTx(NewTransaction(uint64(i), common.Address{}, new(big.Int), 0, new(big.Int), nil), signer, key)
+	}
+	fresh := func() []*Transaction {
+		copies := make([]*Transaction, len(txs))
+		for i, tx := range txs {
+			copies[i] = NewTx(tx.inner)
+		}
+		return copies
+	}
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, tx := range fresh() {
+				Sender(signer, tx)
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			VerifySenders(signer, fresh())
+		}
+	})
+}
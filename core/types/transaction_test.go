@@ -501,6 +501,142 @@ func TestTransactionCoding(t *testing.T) {
 	}
 }
 
+// Tests that signature transactions keep their signature bytes intact through
+// the RLP and JSON encodings, and that their sender can be recovered.
+func TestSignatureTxCoding(t *testing.T) {
+	var (
+		signer    = NewSignatureTxSigner(common.Big1)
+		recipient = common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	)
+	for _, id := range []byte{cryptobase.SigAlgIdHybrid, cryptobase.SigAlgIdFalcon} {
+		alg, _ := cryptobase.SigAlgById(id)
+		key, err := alg.GenerateKey()
+		if err != nil {
+			t.Fatalf("could not generate key: %v", err)
+		}
+		addr, _ := cryptobase.PublicKeyToAddress(id, key.PublicKey.PubData)
+
+		tx, err := SignNewTx(key, signer, &SignatureTx{
+			ChainID:   big.NewInt(1),
+			To:        &recipient,
+			Gas:       123457,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(10),
+			Data:      []byte("abcdef"),
+			Algorithm: id,
+		})
+		if err != nil {
+			t.Fatalf("could not sign transaction: %v", err)
+		}
+		sig, pub := tx.RawSignature()
+		if !bytes.Equal(pub, key.PublicKey.PubData) || len(sig) == 0 {
+			t.Fatalf("algorithm %d: signature bytes not set", id)
+		}
+		for _, decode := range []func(*Transaction) (*Transaction, error){encodeDecodeBinary, encodeDecodeJSON} {
+			parsedTx, err := decode(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := assertEqual(parsedTx, tx); err != nil {
+				t.Fatal(err)
+			}
+			if parsedSig, parsedPub := parsedTx.RawSignature(); !bytes.Equal(parsedSig, sig) || !bytes.Equal(parsedPub, pub) {
+				t.Fatalf("algorithm %d: signature bytes mismatch", id)
+			}
+			if from, err := Sender(signer, parsedTx); err != nil || from != addr {
+				t.Fatalf("algorithm %d: sender mismatch: have %x (%v), want %x", id, from, err, addr)
+			}
+		}
+		// Changing the declared algorithm invalidates the signature
+		forged := tx.inner.copy().(*SignatureTx)
+		forged.Algorithm ^= 0x03
+		if _, err := Sender(signer, NewTx(forged)); err == nil {
+			t.Errorf("algorithm %d: accepted signature under another algorithm", id)
+		}
+		// Without a public key, the sender can't be recovered
+		stripped := tx.inner.copy().(*SignatureTx)
+		stripped.PublicKey = nil
+		if _, err := Sender(signer, NewTx(stripped)); err != ErrMissingPublicKey {
+			t.Errorf("algorithm %d: missing public key: have %v, want %v", id, err, ErrMissingPublicKey)
+		}
+	}
+	// Signers predating the fork don't support signature transactions
+	if _, err := Sender(NewLondonSigner(common.Big1), NewTx(&SignatureTx{ChainID: common.Big1})); err != ErrTxTypeNotSupported {
+		t.Errorf("london signer: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+type publicKeyMap map[common.Address][]byte
+
+func (m publicKeyMap) GetPublicKey(addr common.Address) (byte, []byte) {
+	pub := m[addr]
+	if pub == nil {
+		return 0, nil
+	}
+	return pub[0], pub[1:]
+}
+
+func TestRegistrySigner(t *testing.T) {
+	var (
+		signer    = NewSignatureTxSigner(common.Big1)
+		recipient = common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	)
+	alg, _ := cryptobase.SigAlgById(cryptobase.SigAlgIdFalcon)
+	key, err := alg.GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	addr, _ := cryptobase.PublicKeyToAddress(cryptobase.SigAlgIdFalcon, key.PublicKey.PubData)
+
+	tx, err := SignNewTx(key, signer, &SignatureTx{
+		ChainID:   big.NewInt(1),
+		To:        &recipient,
+		Gas:       123457,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Algorithm: cryptobase.SigAlgIdFalcon,
+	})
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	short, err := tx.WithoutPublicKey()
+	if err != nil {
+		t.Fatalf("could not strip public key: %v", err)
+	}
+	if from, ok := short.RegisteredKeySender(); !ok || from != addr {
+		t.Fatalf("registered key sender mismatch: have %x (%v), want %x", from, ok, addr)
+	}
+	for _, decode := range []func(*Transaction) (*Transaction, error){encodeDecodeBinary, encodeDecodeJSON} {
+		parsedTx, err := decode(short)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := assertEqual(parsedTx, short); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Unregistered senders can't be recovered
+	if _, err := Sender(NewRegistrySigner(signer, publicKeyMap{}), short); err != ErrMissingPublicKey {
+		t.Errorf("unregistered key: have %v, want %v", err, ErrMissingPublicKey)
+	}
+	// Keys registered for another algorithm are rejected
+	keys := publicKeyMap{addr: append([]byte{cryptobase.SigAlgIdHybrid}, key.PublicKey.PubData...)}
+	if _, err := Sender(NewRegistrySigner(signer, keys), short); err != ErrInvalidSig {
+		t.Errorf("algorithm mismatch: have %v, want %v", err, ErrInvalidSig)
+	}
+	// The registered key recovers the sender
+	keys[addr][0] = cryptobase.SigAlgIdFalcon
+	if from, err := Sender(NewRegistrySigner(signer, keys), short); err != nil || from != addr {
+		t.Errorf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	// Senders named along with the public key must match it
+	named := tx.inner.copy().(*SignatureTx)
+	named.From = &recipient
+	if _, err := Sender(signer, NewTx(named)); err != ErrInvalidSig {
+		t.Errorf("mismatched sender: have %v, want %v", err, ErrInvalidSig)
+	}
+}
+
 func encodeDecodeJSON(tx *Transaction) (*Transaction, error) {
 	data, err := json.Marshal(tx)
 	if err != nil {
@@ -0,0 +1,146 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+)
+
+// sigCheck is the signature check of a transaction sender recovery, split off so
+// that the checks of many transactions can be verified as a batch.
+type sigCheck struct {
+	tx   *Transaction
+	hash common.Hash
+	pub  []byte
+	sig  []byte // Public key and signature combined, as verified
+}
+
+// VerifySenders verifies the signatures of the transactions in batches per
+// signature algorithm, spread across all CPUs, and caches the senders of the
+// valid ones into the transactions. Nothing is reported for the invalid ones,
+// that is up to the Sender calls made later.
+func VerifySenders(signer Signer, txs []*Transaction) {
+	batches := make(map[byte][]sigCheck)
+	for _, tx := range txs {
+		if sc := tx.from.Load(); sc != nil && sc.(sigCache).signer.Equal(signer) {
+			continue
+		}
+		id, pub, sig, ok := signatureValues(signer, tx)
+		if !ok {
+			continue
+		}
+		alg, err := cryptobase.SigAlgById(id)
+		if err != nil || len(pub) != alg.PublicKeyLength() {
+			continue
+		}
+		combined, err := alg.CombinePublicKeySignature(sig, pub)
+		if err != nil {
+			continue
+		}
+		batches[id] = append(batches[id], sigCheck{tx: tx, hash: signer.Hash(tx), pub: pub, sig: combined})
+	}
+	for id, checks := range batches {
+		alg, _ := cryptobase.SigAlgById(id)
+
+		pubs := make([][]byte, len(checks))
+		hashes := make([][]byte, len(checks))
+		sigs := make([][]byte, len(checks))
+		for i := range checks {
+			pubs[i], hashes[i], sigs[i] = checks[i].pub, checks[i].hash.Bytes(), checks[i].sig
+		}
+		valid := alg.VerifyBatch(pubs, hashes, sigs)
+
+		// Cache the senders of the valid transactions the way Sender does
+		for i, check := range checks {
+			if !valid[i] {
+				continue
+			}
+			from, err := cryptobase.PublicKeyToAddress(id, check.pub)
+			if err != nil {
+				continue
+			}
+			// A sender named along with the signature must match the key
+			if inner, ok := check.tx.inner.(*SignatureTx); ok && inner.From != nil && *inner.From != from {
+				continue
+			}
+			check.tx.from.Store(sigCache{signer: signer, from: from})
+		}
+	}
+}
+
+// acceptsTx reports whether the signer goes on to check the signature of a
+// transaction, rather than rejecting its type or chain ID beforehand.
+func acceptsTx(signer Signer, tx *Transaction) bool {
+	if rs, ok := signer.(registrySigner); ok {
+		signer = rs.Signer
+	}
+	switch signer.(type) {
+	case signatureTxSigner:
+	case londonSigner:
+		if tx.Type() == SignatureTxType {
+			return false
+		}
+	case eip2930Signer:
+		if tx.Type() != LegacyTxType && tx.Type() != AccessListTxType {
+			return false
+		}
+	case EIP155Signer:
+		if tx.Type() != LegacyTxType {
+			return false
+		}
+	case HomesteadSigner, FrontierSigner:
+		return tx.Type() == LegacyTxType && !tx.Protected()
+	default:
+		return false
+	}
+	if tx.Type() == LegacyTxType && !tx.Protected() {
+		return true
+	}
+	return tx.ChainId().Cmp(signer.ChainID()) == 0
+}
+
+// signatureValues returns the signature algorithm, public key and signature the
+// given signer verifies to recover the sender of a transaction.
+func signatureValues(signer Signer, tx *Transaction) (id byte, pub, sig []byte, ok bool) {
+	if !acceptsTx(signer, tx) {
+		return 0, nil, nil, false
+	}
+	if tx.Type() == SignatureTxType {
+		sig, pub = tx.RawSignature()
+		if len(pub) == 0 {
+			rs, isRegistry := signer.(registrySigner)
+			if !isRegistry {
+				return 0, nil, nil, false
+			}
+			from, ok := tx.RegisteredKeySender()
+			if !ok {
+				return 0, nil, nil, false
+			}
+			if id, pub = rs.keys.GetPublicKey(from); pub == nil || id != tx.SignatureAlgorithm() {
+				return 0, nil, nil, false
+			}
+		}
+		return tx.SignatureAlgorithm(), pub, sig, len(sig) > 0
+	}
+	// The other transaction types carry the algorithm in V, offset like recovery
+	// ids are for their type
+	V, R, S := tx.RawSignatureValues()
+	switch {
+	case tx.Type() != LegacyTxType:
+		V = new(big.Int).Set(V)
+	case tx.Protected():
+		V = new(big.Int).Sub(V, new(big.Int).Mul(tx.ChainId(), big.NewInt(2)))
+		V.Sub(V, big.NewInt(35))
+	default:
+		V = new(big.Int).Sub(V, big.NewInt(27))
+	}
+	if V.Sign() < 0 || V.BitLen() > 8 {
+		return 0, nil, nil, false
+	}
+	_, frontier := signer.(FrontierSigner)
+	if !validateSignatureValues(byte(V.Uint64()), R, S, !frontier) {
+		return 0, nil, nil, false
+	}
+	return byte(V.Uint64()), R.Bytes(), S.Bytes(), true
+}
@@ -25,7 +25,6 @@ import (
 
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/common/math"
-	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/crypto/blake2b"
 	"github.com/DogeProtocol/dp/crypto/bls12381"
 	"github.com/DogeProtocol/dp/crypto/bn256"
@@ -93,6 +92,22 @@ var PrecompiledContractsBerlin = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{9}): &blake2F{},
 }
 
+// PrecompiledContractsPQVerify contains the default set of pre-compiled
+// contracts used since the post-quantum signature verification fork. It drops
+// ecrecover, and adds the signature verification contracts.
+var PrecompiledContractsPQVerify = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{2}):    &sha256hash{},
+	common.BytesToAddress([]byte{3}):    &ripemd160hash{},
+	common.BytesToAddress([]byte{4}):    &dataCopy{},
+	common.BytesToAddress([]byte{5}):    &bigModExp{eip2565: true},
+	common.BytesToAddress([]byte{6}):    &bn256AddIstanbul{},
+	common.BytesToAddress([]byte{7}):    &bn256ScalarMulIstanbul{},
+	common.BytesToAddress([]byte{8}):    &bn256PairingIstanbul{},
+	common.BytesToAddress([]byte{9}):    &blake2F{},
+	common.BytesToAddress([]byte{1, 1}): &pqVerify{id: cryptobase.SigAlgIdHybrid, gas: params.PQVerifyHybridGas},
+	common.BytesToAddress([]byte{1, 2}): &pqVerify{id: cryptobase.SigAlgIdFalcon, gas: params.PQVerifyFalconGas},
+}
+
 // PrecompiledContractsBLS contains the set of pre-compiled Ethereum
 // contracts specified in EIP-2537. These are exported for testing purposes.
 var PrecompiledContractsBLS = map[common.Address]PrecompiledContract{
@@ -108,6 +123,7 @@ var PrecompiledContractsBLS = map[common.Address]PrecompiledContract{
 }
 
 var (
+	PrecompiledAddressesPQVerify  []common.Address
 	PrecompiledAddressesBerlin    []common.Address
 	PrecompiledAddressesIstanbul  []common.Address
 	PrecompiledAddressesByzantium []common.Address
@@ -127,11 +143,16 @@ func init() {
 	for k := range PrecompiledContractsBerlin {
 		PrecompiledAddressesBerlin = append(PrecompiledAddressesBerlin, k)
 	}
+	for k := range PrecompiledContractsPQVerify {
+		PrecompiledAddressesPQVerify = append(PrecompiledAddressesPQVerify, k)
+	}
 }
 
 // ActivePrecompiles returns the precompiles enabled with the current configuration.
 func ActivePrecompiles(rules params.Rules) []common.Address {
 	switch {
+	case rules.IsPQVerify:
+		return PrecompiledAddressesPQVerify
 	case rules.IsBerlin:
 		return PrecompiledAddressesBerlin
 	case rules.IsIstanbul:
@@ -165,38 +186,54 @@ func (c *ecrecover) RequiredGas(input []byte) uint64 {
 	return params.EcrecoverGas
 }
 
+// Run fails to recover any address. The post-quantum signature schemes can't
+// recover public keys from signatures, contracts verify them through the PQ
+// verification precompiles instead, and ecrecover is dropped at their fork.
 func (c *ecrecover) Run(input []byte) ([]byte, error) {
-	panic("error fix")
-	// "input" is (hash, v, r, s), each 32 bytes
-	// but for ecrecover we want (r, s, v)
+	return nil, nil
+}
 
-	const ecRecoverInputLength = 64
-	input = common.RightPadBytes(input, ecRecoverInputLength+cryptobase.SigAlg.SignatureWithPublicKeyLength())
+// pqVerify implements a native post-quantum signature verification contract.
+// The input is the 32 byte message hash, followed by the signature, followed
+// by the public key of the signer. It returns the address of the signer if the
+// signature is valid, and nothing otherwise. The contract verifying an algorithm
+// lives at address 0x0100 plus its signature algorithm ID. Only algorithms that
+// are verified in builds with and without cgo may get a contract, or nodes would
+// disagree on its result.
+type pqVerify struct {
+	id  byte   // Signature algorithm ID
+	gas uint64 // Gas price of a verification
+}
 
-	r := new(big.Int).SetBytes(input[ecRecoverInputLength : len(input)-ecRecoverInputLength-cryptobase.SigAlg.PublicKeyLength()])
-	s := new(big.Int).SetBytes(input[len(input)-ecRecoverInputLength-cryptobase.SigAlg.PublicKeyLength():])
-	v := byte(28 - 27)
+func (c *pqVerify) RequiredGas(input []byte) uint64 {
+	return c.gas
+}
+
+func (c *pqVerify) Run(input []byte) ([]byte, error) {
+	const hashLength = 32
 
-	// tighter sig s values input homestead only apply to tx sigs
-	if !allZero(input[32:63]) || !cryptobase.SigAlg.ValidateSignatureValues(v, r, s, false) {
+	alg, err := cryptobase.SigAlgById(c.id)
+	if err != nil {
+		return nil, err
+	}
+	pubLength := alg.PublicKeyLength()
+	if len(input) <= hashLength+pubLength {
 		return nil, nil
 	}
-
-	// We must make sure not to modify the 'input', so placing the 'v' along with
-	// the signature needs to be done on a new allocation
-
-	sig := make([]byte, cryptobase.SigAlg.SignatureWithPublicKeyLength())
-	copy(sig, input[ecRecoverInputLength:])
-
-	// v needs to be at the end for libsecp256k1
-	panic("fix here")
-	pubKey, err := cryptobase.SigAlg.PublicKeyBytesFromSignature(input[:32], sig)
-	// make sure the public key is a valid one
+	var (
+		hash = input[:hashLength]
+		sig  = input[hashLength : len(input)-pubLength]
+		pub  = input[len(input)-pubLength:]
+	)
+	combined, err := alg.CombinePublicKeySignature(sig, pub)
+	if err != nil || !alg.Verify(pub, hash, combined) {
+		return nil, nil
+	}
+	addr, err := cryptobase.PublicKeyToAddress(c.id, pub)
 	if err != nil {
 		return nil, nil
 	}
-	// the first byte of pubkey is bitcoin heritage
-	return common.LeftPadBytes(crypto.Keccak256(pubKey[:])[12:], 32), nil
+	return common.LeftPadBytes(addr.Bytes(), 32), nil
 }
 
 // SHA256 implemented as a native contract.
@@ -25,6 +25,9 @@ import (
 	"time"
 
 	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -65,6 +68,8 @@ var allPrecompiles = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{16}):   &bls12381Pairing{},
 	common.BytesToAddress([]byte{17}):   &bls12381MapG1{},
 	common.BytesToAddress([]byte{18}):   &bls12381MapG2{},
+	common.BytesToAddress([]byte{1, 1}): &pqVerify{id: cryptobase.SigAlgIdHybrid, gas: params.PQVerifyHybridGas},
+	common.BytesToAddress([]byte{1, 2}): &pqVerify{id: cryptobase.SigAlgIdFalcon, gas: params.PQVerifyFalconGas},
 }
 
 // EIP-152 test vectors
@@ -196,7 +201,7 @@ func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 func BenchmarkPrecompiledEcrecover(bench *testing.B) {
 	t := precompiledTest{
 		Input:    "38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e000000000000000000000000000000000000000000000000000000000000001b38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e789d1dd423d25f0772d2748d60f7e4b81bb14d086eba8e8e8efb6dcff8a4ae02",
-		Expected: "000000000000000000000000ceaccac640adf55b2028469bd36ba501f28b699d",
+		Expected: "",
 		Name:     "",
 	}
 	benchmarkPrecompiled("01", t, bench)
@@ -273,6 +278,73 @@ func TestPrecompileBlake2FMalformedInput(t *testing.T) {
 
 func TestPrecompiledEcrecover(t *testing.T) { testJson("ecRecover", "01", t) }
 
+// pqVerifyTests signs a message with a fresh key of the given signature
+// algorithm, and returns the valid input along with tampered ones.
+func pqVerifyTests(t testing.TB, id byte) []precompiledTest {
+	alg, err := cryptobase.SigAlgById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := alg.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("pq verify"))
+	combined, err := alg.Sign(hash, key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	sig, pub, err := alg.PublicKeyAndSignatureFromCombinedSignature(nil, combined)
+	if err != nil {
+		t.Fatalf("failed to split signature: %v", err)
+	}
+	addr, _ := cryptobase.PublicKeyToAddress(id, pub)
+
+	input := append(append(append([]byte{}, hash...), sig...), pub...)
+	tampered := common.CopyBytes(input)
+	tampered[0] ^= 0xff
+	gas := allPrecompiles[common.BytesToAddress([]byte{1, id})].RequiredGas(nil)
+
+	return []precompiledTest{
+		{Input: common.Bytes2Hex(input), Expected: common.Bytes2Hex(common.LeftPadBytes(addr.Bytes(), 32)), Gas: gas, Name: "ValidSignature"},
+		{Input: common.Bytes2Hex(tampered), Expected: "", Gas: gas, Name: "WrongMessage", NoBenchmark: true},
+		{Input: common.Bytes2Hex(input[:len(input)-1]), Expected: "", Gas: gas, Name: "ShortPublicKey", NoBenchmark: true},
+		{Input: common.Bytes2Hex(append(hash, pub...)), Expected: "", Gas: gas, Name: "MissingSignature", NoBenchmark: true},
+		{Input: "", Expected: "", Gas: gas, Name: "Empty", NoBenchmark: true},
+	}
+}
+
+func testPQVerify(id byte, t *testing.T) {
+	for _, test := range pqVerifyTests(t, id) {
+		testPrecompiled(fmt.Sprintf("01%02x", id), test, t)
+	}
+}
+
+func benchPQVerify(id byte, b *testing.B) {
+	for _, test := range pqVerifyTests(b, id) {
+		benchmarkPrecompiled(fmt.Sprintf("01%02x", id), test, b)
+	}
+}
+
+func TestPrecompiledPQVerifyFalcon(t *testing.T) { testPQVerify(cryptobase.SigAlgIdFalcon, t) }
+func TestPrecompiledPQVerifyHybrid(t *testing.T) { testPQVerify(cryptobase.SigAlgIdHybrid, t) }
+
+// Tests that the algorithm of every PQ verification contract is available in
+// the current build, as contracts must verify alike with and without cgo.
+func TestPrecompiledPQVerifyAvailable(t *testing.T) {
+	for addr, p := range PrecompiledContractsPQVerify {
+		if c, ok := p.(*pqVerify); ok {
+			if _, err := cryptobase.SigAlgById(c.id); err != nil {
+				t.Errorf("contract %x: algorithm %#x unavailable: %v", addr, c.id, err)
+			}
+		}
+	}
+}
+
+// Benchmarks the PQ signature verifications, to price them against ecrecover.
+func BenchmarkPrecompiledPQVerifyFalcon(b *testing.B) { benchPQVerify(cryptobase.SigAlgIdFalcon, b) }
+func BenchmarkPrecompiledPQVerifyHybrid(b *testing.B) { benchPQVerify(cryptobase.SigAlgIdHybrid, b) }
+
 func testJson(name, addr string, t *testing.T) {
 	tests, err := loadJson(name)
 	if err != nil {
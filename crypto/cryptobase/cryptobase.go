@@ -1,9 +1,85 @@
 package cryptobase
 
-import "github.com/DogeProtocol/dp/crypto/hybrid"
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/falcon"
+	"github.com/DogeProtocol/dp/crypto/hybrid"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+)
 
 var SigAlg = hybrid.CreateHybridSig()
 
 //var SigAlg = falcon.CreateFalconSig()
 
 //var SigAlg = mocksignaturealgorithm.CreateMockSig()
+
+// Signature algorithm IDs. The ID of the algorithm a transaction is signed with
+// is carried in its V value, and is hashed along with the public keys of the
+// algorithms added after launch to derive their addresses.
+const (
+	SigAlgIdHybrid    byte = 0x01 // Falcon-512 + ed25519, the algorithm the chain launched with
+	SigAlgIdFalcon    byte = 0x02 // Falcon-512
+	SigAlgIdDilithium byte = 0x03 // Dilithium2, only available with cgo and not accepted in transactions yet
+	SigAlgIdMLDSA44   byte = 0x04 // ML-DSA-44, not accepted in transactions yet
+	SigAlgIdMLDSA65   byte = 0x05 // ML-DSA-65, not accepted in transactions yet
+	SigAlgIdSLHDSA    byte = 0x06 // SLH-DSA-SHA2-128s, not accepted in transactions yet
+	SigAlgIdMultisig  byte = 0x07 // M-of-N multisignature account over the other algorithms, see core/types
+)
+
+// ErrUnknownSigAlg is returned if a signature algorithm ID isn't registered.
+var ErrUnknownSigAlg = errors.New("unknown signature algorithm")
+
+var (
+	sigAlgs = map[byte]signaturealgorithm.SignatureAlgorithm{
+		SigAlgIdHybrid: SigAlg,
+		SigAlgIdFalcon: falcon.CreateFalconSig(),
+	}
+	sigAlgsLock sync.RWMutex
+)
+
+// RegisterSigAlg registers a signature algorithm under the given ID. It panics
+// if the ID is already taken.
+func RegisterSigAlg(id byte, alg signaturealgorithm.SignatureAlgorithm) {
+	sigAlgsLock.Lock()
+	defer sigAlgsLock.Unlock()
+
+	if _, ok := sigAlgs[id]; ok {
+		panic(fmt.Sprintf("signature algorithm %#x already registered", id))
+	}
+	sigAlgs[id] = alg
+}
+
+// SigAlgById returns the signature algorithm registered under the given ID.
+func SigAlgById(id byte) (signaturealgorithm.SignatureAlgorithm, error) {
+	sigAlgsLock.RLock()
+	defer sigAlgsLock.RUnlock()
+
+	alg, ok := sigAlgs[id]
+	if !ok {
+		return nil, ErrUnknownSigAlg
+	}
+	return alg, nil
+}
+
+// PublicKeyToAddress derives the address of a public key of the given signature
+// algorithm. Addresses of the launch algorithm are the plain Keccak hash of the
+// key, the other algorithms hash their ID along so that the same bytes never
+// map to the same account under two schemes.
+func PublicKeyToAddress(id byte, pub []byte) (common.Address, error) {
+	alg, err := SigAlgById(id)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(pub) != alg.PublicKeyLength() {
+		return common.Address{}, errors.New("invalid public key")
+	}
+	if id == SigAlgIdHybrid {
+		return common.BytesToAddress(crypto.Keccak256(pub)[12:]), nil
+	}
+	return common.BytesToAddress(crypto.Keccak256([]byte{id}, pub)[12:]), nil
+}
@@ -0,0 +1,22 @@
+// +build cgo
+
+package cryptobase
+
+import "github.com/DogeProtocol/dp/crypto/oqs"
+
+// The liboqs schemes are only available with cgo, so they aren't registered
+// when built without it. The schemes selectable for keys are skipped if liboqs
+// was built without them.
+func init() {
+	RegisterSigAlg(SigAlgIdDilithium, oqs.InitDilithium())
+
+	if oqs.IsSigEnabled(oqs.MLDSA44SigName) {
+		RegisterSigAlg(SigAlgIdMLDSA44, oqs.InitMLDSA44())
+	}
+	if oqs.IsSigEnabled(oqs.MLDSA65SigName) {
+		RegisterSigAlg(SigAlgIdMLDSA65, oqs.InitMLDSA65())
+	}
+	if oqs.IsSigEnabled(oqs.SLHDSASigName) {
+		RegisterSigAlg(SigAlgIdSLHDSA, oqs.InitSLHDSA())
+	}
+}
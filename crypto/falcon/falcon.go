@@ -1,15 +1,9 @@
 package falcon
 
-/*
-#cgo pkg-config: libhybridpqc
-#include <hybridpqc/api.h>
-*/
-import "C"
 import (
 	"bytes"
 	"errors"
 	"github.com/DogeProtocol/dp/common"
-	"unsafe"
 )
 
 const (
@@ -38,60 +32,22 @@ var (
 	ErrInvalidLen             = errors.New("invalid length")
 	ErrVerifyFailed           = errors.New("verify length")
 	ErrRecoverPublicKeyFailed = errors.New("recover public key length")
+	ErrCgoRequired            = errors.New("not available when built without cgo")
 )
 
-func GenerateKey() (publicKey []byte, secretKey []byte, err error) {
-	publicKey = make([]byte, CRYPTO_PUBLICKEY_BYTES)
-	secretKey = make([]byte, CRYPTO_SECRETKEY_BYTES)
-
-	rv := C.crypto_sign_falcon_keypair(
-		(*C.uchar)(unsafe.Pointer(&publicKey[0])),
-		(*C.uchar)(unsafe.Pointer(&secretKey[0])))
-
-	if rv != OK {
-		return nil, nil, errors.New("GenerateKey failed")
-	}
-	return publicKey, secretKey, nil
+// Verify verifies the validity of a signed message, returning true if the
+// signature is valid, and false otherwise. It uses the C implementation when
+// built with cgo, and the pure Go one otherwise.
+func Verify(message []byte, signature []byte, publicKey []byte) error {
+	return verify(message, signature, publicKey, open)
 }
 
-func Sign(secretKey []byte, message []byte) ([]byte, error) {
-	if len(secretKey) != CRYPTO_SECRETKEY_BYTES {
-		return nil, ErrInvalidPrivateKeyLen
-	}
-
-	if len(message) == 0 || len(message) != CRYPTO_MESSAGE_LEN {
-		return nil, ErrInvalidMsgLen
-	}
-
-	signature := make([]byte, CRYPTO_SIGNATURE_BYTES)
-
-	var lenSig uint64
-
-	rv := C.crypto_sign_falcon((*C.uchar)(unsafe.Pointer(&signature[0])),
-		(*C.size_t)(unsafe.Pointer(&lenSig)),
-		(*C.uchar)(unsafe.Pointer(&message[0])),
-		(C.size_t)(uint64(len(message))),
-		(*C.uchar)(unsafe.Pointer(&secretKey[0])))
-
-	if rv != OK {
-		return nil, ErrSignFailed
-	}
-
-	if lenSig > CRYPTO_SIGNATURE_BYTES {
-		return nil, ErrInvalidSignatureLen
-	}
-
-	b := common.LenToBytes(int(lenSig))
-
-	signature = append(b[:], signature...)
-
-	return signature, nil
+// VerifyPure is like Verify, but always uses the pure Go implementation.
+func VerifyPure(message []byte, signature []byte, publicKey []byte) error {
+	return verify(message, signature, publicKey, OpenPure)
 }
 
-// Verify verifies the validity of a signed message, returning true if the
-// signature is valid, and false otherwise.
-func Verify(message []byte, signature []byte, publicKey []byte) error {
-
+func verify(message []byte, signature []byte, publicKey []byte, open func(sm []byte, publicKey []byte) ([]byte, error)) error {
 	if len(message) == 0 || len(signature) == 0 || len(publicKey) == 0 {
 		return ErrInvalidLen
 	}
@@ -103,28 +59,16 @@ func Verify(message []byte, signature []byte, publicKey []byte) error {
 	}
 
 	lenSig := common.BytesToLen(signature[:common.LengthByteSize])
-	if lenSig > CRYPTO_SIGNATURE_BYTES_WITH_LENGTH {
+	if lenSig == 0 || lenSig > CRYPTO_SIGNATURE_BYTES {
 		return ErrInvalidSignatureLen
 	}
 	sigExtracted := signature[common.LengthByteSize : common.LengthByteSize+lenSig]
-	msgLenCheck := 0
-
-	messageCheck := make([]byte, CRYPTO_SIGNATURE_BYTES+len(message))
-
-	rv := C.crypto_sign_falcon_open((*C.uchar)(unsafe.Pointer(&messageCheck[0])),
-		(*C.size_t)(unsafe.Pointer(&msgLenCheck)),
-		(*C.uchar)(unsafe.Pointer(&sigExtracted[0])),
-		(C.size_t)(uint64(len(sigExtracted))),
-		(*C.uchar)(unsafe.Pointer(&publicKey[0])))
-
-	if rv != OK {
-		return ErrVerifyFailed
-	}
 
-	if msgLenCheck != len(message) {
+	messageCheck, err := open(sigExtracted, publicKey)
+	if err != nil {
 		return ErrVerifyFailed
 	}
-	if bytes.Compare(message, messageCheck[:msgLenCheck]) != 0 {
+	if bytes.Compare(message, messageCheck) != 0 {
 		return ErrVerifyFailed
 	}
 
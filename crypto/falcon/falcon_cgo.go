@@ -0,0 +1,141 @@
+// +build cgo
+
+package falcon
+
+/*
+#cgo pkg-config: libhybridpqc
+#include <stdint.h>
+#include <stddef.h>
+#include <hybridpqc/api.h>
+
+// Internals of the reference implementation behind crypto_sign_falcon_keypair.
+// The SHAKE256 context is only handled through pointers, so it is given room
+// for the largest layout of the bundled fips202 code.
+typedef struct { uint64_t opaque[26]; } shake256incctx;
+
+void shake256_inc_init(shake256incctx *state);
+void shake256_inc_absorb(shake256incctx *state, const uint8_t *input, size_t inlen);
+void shake256_inc_finalize(shake256incctx *state);
+void shake256_inc_ctx_release(shake256incctx *state);
+void PQCLEAN_FALCON512_CLEAN_keygen(shake256incctx *rng, int8_t *f, int8_t *g,
+	int8_t *F, int8_t *G, uint16_t *h, unsigned logn, uint8_t *tmp);
+
+#define FALCON_KEYGEN_TEMP_9 14336
+
+// falcon_keygen_seeded is crypto_sign_falcon_keypair with the random source
+// seeded from seed instead of the system RNG, returning the raw polynomials.
+static void falcon_keygen_seeded(const uint8_t *seed, size_t seedlen,
+	int8_t *f, int8_t *g, int8_t *F, uint16_t *h) {
+	union {
+		uint8_t b[FALCON_KEYGEN_TEMP_9];
+		uint64_t dummy_u64;
+		double dummy_fpr;
+	} tmp;
+	shake256incctx rng;
+
+	shake256_inc_init(&rng);
+	shake256_inc_absorb(&rng, seed, seedlen);
+	shake256_inc_finalize(&rng);
+	PQCLEAN_FALCON512_CLEAN_keygen(&rng, f, g, F, NULL, h, 9, tmp.b);
+	shake256_inc_ctx_release(&rng);
+}
+*/
+import "C"
+import (
+	"errors"
+	"github.com/DogeProtocol/dp/common"
+	"unsafe"
+)
+
+func GenerateKey() (publicKey []byte, secretKey []byte, err error) {
+	publicKey = make([]byte, CRYPTO_PUBLICKEY_BYTES)
+	secretKey = make([]byte, CRYPTO_SECRETKEY_BYTES)
+
+	rv := C.crypto_sign_falcon_keypair(
+		(*C.uchar)(unsafe.Pointer(&publicKey[0])),
+		(*C.uchar)(unsafe.Pointer(&secretKey[0])))
+
+	if rv != OK {
+		return nil, nil, errors.New("GenerateKey failed")
+	}
+	return publicKey, secretKey, nil
+}
+
+// generateKeyFromSeed runs the reference key generation with a SHAKE256 random
+// source seeded from seed.
+func generateKeyFromSeed(seed []byte) (publicKey []byte, secretKey []byte, err error) {
+	var (
+		f, g, F [n]int8
+		h       [n]uint16
+	)
+	C.falcon_keygen_seeded((*C.uint8_t)(unsafe.Pointer(&seed[0])), C.size_t(len(seed)),
+		(*C.int8_t)(unsafe.Pointer(&f[0])), (*C.int8_t)(unsafe.Pointer(&g[0])),
+		(*C.int8_t)(unsafe.Pointer(&F[0])), (*C.uint16_t)(unsafe.Pointer(&h[0])))
+
+	var h32 [n]uint32
+	for i, c := range h {
+		h32[i] = uint32(c)
+	}
+	return encodeKeyPair(widen(f[:]), widen(g[:]), widen(F[:]), &h32)
+}
+
+func widen(poly []int8) []int64 {
+	res := make([]int64, len(poly))
+	for i, c := range poly {
+		res[i] = int64(c)
+	}
+	return res
+}
+
+func Sign(secretKey []byte, message []byte) ([]byte, error) {
+	if len(secretKey) != CRYPTO_SECRETKEY_BYTES {
+		return nil, ErrInvalidPrivateKeyLen
+	}
+
+	if len(message) == 0 || len(message) != CRYPTO_MESSAGE_LEN {
+		return nil, ErrInvalidMsgLen
+	}
+
+	signature := make([]byte, CRYPTO_SIGNATURE_BYTES)
+
+	var lenSig uint64
+
+	rv := C.crypto_sign_falcon((*C.uchar)(unsafe.Pointer(&signature[0])),
+		(*C.size_t)(unsafe.Pointer(&lenSig)),
+		(*C.uchar)(unsafe.Pointer(&message[0])),
+		(C.size_t)(uint64(len(message))),
+		(*C.uchar)(unsafe.Pointer(&secretKey[0])))
+
+	if rv != OK {
+		return nil, ErrSignFailed
+	}
+
+	if lenSig > CRYPTO_SIGNATURE_BYTES {
+		return nil, ErrInvalidSignatureLen
+	}
+
+	b := common.LenToBytes(int(lenSig))
+
+	signature = append(b[:], signature...)
+
+	return signature, nil
+}
+
+// open checks a signed message with the C implementation, returning the
+// message if the signature is valid.
+func open(sm []byte, publicKey []byte) ([]byte, error) {
+	msgLenCheck := 0
+
+	messageCheck := make([]byte, len(sm))
+
+	rv := C.crypto_sign_falcon_open((*C.uchar)(unsafe.Pointer(&messageCheck[0])),
+		(*C.size_t)(unsafe.Pointer(&msgLenCheck)),
+		(*C.uchar)(unsafe.Pointer(&sm[0])),
+		(C.size_t)(uint64(len(sm))),
+		(*C.uchar)(unsafe.Pointer(&publicKey[0])))
+
+	if rv != OK {
+		return nil, ErrVerifyFailed
+	}
+	return messageCheck[:msgLenCheck], nil
+}
@@ -0,0 +1,21 @@
+// +build !cgo
+
+package falcon
+
+func GenerateKey() (publicKey []byte, secretKey []byte, err error) {
+	return nil, nil, ErrCgoRequired
+}
+
+func generateKeyFromSeed(seed []byte) (publicKey []byte, secretKey []byte, err error) {
+	return nil, nil, ErrCgoRequired
+}
+
+func Sign(secretKey []byte, message []byte) ([]byte, error) {
+	return nil, ErrCgoRequired
+}
+
+// open checks a signed message with the pure Go implementation, which is the
+// only one available without cgo.
+func open(sm []byte, publicKey []byte) ([]byte, error) {
+	return OpenPure(sm, publicKey)
+}
@@ -0,0 +1,91 @@
+package falcon
+
+import "errors"
+
+const (
+	secretKeyHeader = 0x50 + logN // First byte of an encoded secret key
+	fgBits          = 6           // Bits per coefficient of f and g in a secret key
+	bigFGBits       = 8           // Bits per coefficient of F (and G) in a secret key
+)
+
+var ErrSeedTooShort = errors.New("seed too short, need at least 32 bytes")
+
+// GenerateKeyFromSeed deterministically generates a Falcon-512 key pair from a
+// seed of at least 32 bytes, in the encoding of GenerateKey. It is only
+// available when built with cgo.
+//
+// The key generation of the reference implementation is run with its random
+// source, a SHAKE256 context, seeded with the seed instead of the system RNG,
+// so the keys follow the distribution of the specification.
+func GenerateKeyFromSeed(seed []byte) (publicKey []byte, secretKey []byte, err error) {
+	if len(seed) < 32 {
+		return nil, nil, ErrSeedTooShort
+	}
+	return generateKeyFromSeed(seed)
+}
+
+// encodeKeyPair encodes the public key h and the secret key (f, g, F) like
+// crypto_sign_falcon_keypair.
+func encodeKeyPair(f, g, F []int64, h *[n]uint32) (publicKey []byte, secretKey []byte, err error) {
+	if !fitsBits(f, fgBits) || !fitsBits(g, fgBits) || !fitsBits(F, bigFGBits) {
+		return nil, nil, errors.New("generated key out of range")
+	}
+	publicKey = append([]byte{publicKeyHeader}, modqEncode(h)...)
+	secretKey = append([]byte{secretKeyHeader}, trimEncode(f, fgBits)...)
+	secretKey = append(secretKey, trimEncode(g, fgBits)...)
+	secretKey = append(secretKey, trimEncode(F, bigFGBits)...)
+	return publicKey, secretKey, nil
+}
+
+// fitsBits reports whether all coefficients fit the signed encoding on the
+// given number of bits, which excludes the lowest value.
+func fitsBits(poly []int64, bits uint) bool {
+	max := int64(1)<<(bits-1) - 1
+	for _, c := range poly {
+		if c < -max || c > max {
+			return false
+		}
+	}
+	return true
+}
+
+// modqEncode packs a public key polynomial as 14 bits per coefficient.
+func modqEncode(h *[n]uint32) []byte {
+	out := make([]byte, 0, (n*14+7)>>3)
+	var (
+		acc    uint32
+		accLen uint
+	)
+	for _, w := range h {
+		acc = acc<<14 | w
+		accLen += 14
+		for accLen >= 8 {
+			accLen -= 8
+			out = append(out, byte(acc>>accLen))
+		}
+	}
+	return out
+}
+
+// trimEncode packs the coefficients of a small polynomial on the given number of
+// bits each.
+func trimEncode(poly []int64, bits uint) []byte {
+	out := make([]byte, 0, (uint(len(poly))*bits+7)>>3)
+	var (
+		acc    uint32
+		accLen uint
+		mask   = uint32(1)<<bits - 1
+	)
+	for _, c := range poly {
+		acc = acc<<bits | uint32(c)&mask
+		accLen += bits
+		for accLen >= 8 {
+			accLen -= 8
+			out = append(out, byte(acc>>accLen))
+		}
+	}
+	if accLen > 0 {
+		out = append(out, byte(acc<<(8-accLen)))
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+// +build cgo
+
+package falcon
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+// trimDecode unpacks the coefficients of a small polynomial stored on the given
+// number of bits each.
+func trimDecode(in []byte, bits uint) []int64 {
+	var (
+		out    = make([]int64, 0, n)
+		acc    uint32
+		accLen uint
+	)
+	for _, b := range in {
+		acc = acc<<8 | uint32(b)
+		accLen += 8
+		for accLen >= bits && len(out) < n {
+			accLen -= bits
+			w := int64(acc>>accLen) & (1<<bits - 1)
+			if w >= 1<<(bits-1) {
+				w -= 1 << bits
+			}
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func TestGenerateKeyFromSeed(t *testing.T) {
+	seed := make([]byte, 32)
+	pub, sk, err := GenerateKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pub) != CRYPTO_PUBLICKEY_BYTES || len(sk) != CRYPTO_SECRETKEY_BYTES {
+		t.Fatalf("key lengths: have %d/%d, want %d/%d", len(pub), len(sk), CRYPTO_PUBLICKEY_BYTES, CRYPTO_SECRETKEY_BYTES)
+	}
+	pub2, sk2, err := GenerateKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pub, pub2) || !bytes.Equal(sk, sk2) {
+		t.Error("key generation not deterministic")
+	}
+	seed[0] = 1
+	if pub3, _, _ := GenerateKeyFromSeed(seed); bytes.Equal(pub, pub3) {
+		t.Error("same key for different seeds")
+	}
+}
+
+func TestGenerateKeyFromSeedValid(t *testing.T) {
+	pub, sk, err := GenerateKeyFromSeed(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub[0] != publicKeyHeader || sk[0] != secretKeyHeader {
+		t.Fatalf("invalid key headers %#x/%#x", pub[0], sk[0])
+	}
+	h16, ok := modqDecode(pub[1:])
+	if !ok {
+		t.Fatal("invalid public key encoding")
+	}
+	var (
+		f = trimDecode(sk[1:385], fgBits)
+		g = trimDecode(sk[385:769], fgBits)
+		F = trimDecode(sk[769:], bigFGBits)
+	)
+	// The public key must be h = g / f, and the basis must solve the NTRU
+	// equation f G - g F = q, with G = h F mod q.
+	var h, a, hF [n]uint32
+	for i := range h {
+		h[i] = uint32(h16[i])
+		a[i] = uint32((f[i] + q) % q)
+	}
+	hCopy := h
+	polyMul(&a, &hCopy)
+	for i := range a {
+		if a[i] != uint32((g[i]+q)%q) {
+			t.Fatalf("f * h != g at coefficient %d", i)
+		}
+	}
+	for i := range hF {
+		hF[i] = uint32((F[i] + q) % q)
+	}
+	polyMul(&hF, &h)
+	G := make([]int64, n)
+	for i := range G {
+		G[i] = int64(hF[i])
+		if G[i] > q/2 {
+			G[i] -= q
+		}
+	}
+	fG, gF := polyMulBig(toBig(f), toBig(G)), polyMulBig(toBig(g), toBig(F))
+	for i := range fG {
+		want := int64(0)
+		if i == 0 {
+			want = q
+		}
+		if d := fG[i].Sub(fG[i], gF[i]); !d.IsInt64() || d.Int64() != want {
+			t.Fatalf("f G - g F != q at coefficient %d", i)
+		}
+	}
+}
+
+func BenchmarkGenerateKeyFromSeed(b *testing.B) {
+	seed := make([]byte, 32)
+	for i := 0; i < b.N; i++ {
+		seed[0] = byte(i)
+		if _, _, err := GenerateKeyFromSeed(seed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// polyMulBig multiplies two polynomials modulo x^m + 1.
+func polyMulBig(a, b []*big.Int) []*big.Int {
+	m := len(a)
+	res := make([]*big.Int, m)
+	for i := range res {
+		res[i] = new(big.Int)
+	}
+	t := new(big.Int)
+	for i := range a {
+		for j := range b {
+			t.Mul(a[i], b[j])
+			if i+j < m {
+				res[i+j].Add(res[i+j], t)
+			} else {
+				res[i+j-m].Sub(res[i+j-m], t)
+			}
+		}
+	}
+	return res
+}
+
+func toBig(a []int64) []*big.Int {
+	res := make([]*big.Int, len(a))
+	for i, c := range a {
+		res[i] = big.NewInt(c)
+	}
+	return res
+}
@@ -0,0 +1,9 @@
+package falcon
+
+import "testing"
+
+func TestGenerateKeyFromSeedShort(t *testing.T) {
+	if _, _, err := GenerateKeyFromSeed(make([]byte, 31)); err != ErrSeedTooShort {
+		t.Errorf("short seed: have %v, want %v", err, ErrSeedTooShort)
+	}
+}
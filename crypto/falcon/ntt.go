@@ -0,0 +1,87 @@
+package falcon
+
+var (
+	psiPow    [n]uint32        // Powers of a primitive 2n-th root of unity modulo q
+	psiInvPow [n]uint32        // Powers of its inverse, scaled by 1/n
+	omegas    [logN + 1]uint32 // Primitive 2^i-th roots of unity, for each NTT layer
+	omegaInvs [logN + 1]uint32 // Their inverses
+)
+
+func init() {
+	// Find a generator of the multiplicative group modulo q, whose order is
+	// q-1 = 2^12 * 3, and derive the roots of unity from it.
+	g := uint32(2)
+	for powMod(g, (q-1)/2) == 1 || powMod(g, (q-1)/3) == 1 {
+		g++
+	}
+	psi := powMod(g, (q-1)/(2*n))
+	psiInv := powMod(psi, q-2)
+	nInv := powMod(n, q-2)
+
+	psiPow[0], psiInvPow[0] = 1, nInv
+	for i := 1; i < n; i++ {
+		psiPow[i] = psiPow[i-1] * psi % q
+		psiInvPow[i] = psiInvPow[i-1] * psiInv % q
+	}
+	for i := uint(1); i <= logN; i++ {
+		omegas[i] = powMod(psi, 2*n>>i)
+		omegaInvs[i] = powMod(omegas[i], q-2)
+	}
+}
+
+// powMod computes b^e modulo q.
+func powMod(b, e uint32) uint32 {
+	r := uint32(1)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = r * b % q
+		}
+		b = b * b % q
+	}
+	return r
+}
+
+// polyMul sets a to a*b modulo (x^n + 1, q). Both inputs must be reduced
+// modulo q, b is left in NTT representation.
+func polyMul(a, b *[n]uint32) {
+	for i := range a {
+		a[i] = a[i] * psiPow[i] % q
+		b[i] = b[i] * psiPow[i] % q
+	}
+	ntt(a, &omegas)
+	ntt(b, &omegas)
+	for i := range a {
+		a[i] = a[i] * b[i] % q
+	}
+	ntt(a, &omegaInvs)
+	for i := range a {
+		a[i] = a[i] * psiInvPow[i] % q
+	}
+}
+
+// ntt computes the cyclic number theoretic transform of a in place, with the
+// given roots of unity.
+func ntt(a *[n]uint32, roots *[logN + 1]uint32) {
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	for layer := uint(1); layer <= logN; layer++ {
+		size := 1 << layer
+		for start := 0; start < n; start += size {
+			w := uint32(1)
+			for k := 0; k < size/2; k++ {
+				u, v := a[start+k], a[start+k+size/2]*w%q
+				a[start+k] = (u + v) % q
+				a[start+k+size/2] = (u + q - v) % q
+				w = w * roots[layer] % q
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package falcon
+
+import (
+	"github.com/DogeProtocol/dp/common"
+	"golang.org/x/crypto/sha3"
+)
+
+// Falcon-512 parameters, as used by the pure Go verifier. The verifier is a port
+// of crypto_sign_falcon_open, and accepts exactly the signed messages the C
+// implementation accepts.
+const (
+	logN     = 9
+	n        = 1 << logN
+	q        = 12289
+	nonceLen = 40
+	l2Bound  = 34034726 // Maximum squared norm of a valid (s1, s2) vector
+
+	publicKeyHeader = 0x00 + logN // First byte of an encoded public key
+	signatureHeader = 0x20 + logN // First byte of a compressed signature
+)
+
+// OpenPure checks a signed message in the format produced by the C signer
+// (signature length, nonce, message, compressed signature) against the given
+// public key, and returns the message if the signature is valid. It does not
+// depend on cgo.
+func OpenPure(sm []byte, publicKey []byte) ([]byte, error) {
+	if len(publicKey) != CRYPTO_PUBLICKEY_BYTES || publicKey[0] != publicKeyHeader {
+		return nil, ErrInvalidPubkey
+	}
+	h, ok := modqDecode(publicKey[1:])
+	if !ok {
+		return nil, ErrInvalidPubkey
+	}
+	if len(sm) < 2+nonceLen {
+		return nil, ErrInvalidSignatureLen
+	}
+	sigLen := int(sm[0])<<8 | int(sm[1])
+	if sigLen > len(sm)-2-nonceLen {
+		return nil, ErrInvalidSignatureLen
+	}
+	msgLen := len(sm) - 2 - nonceLen - sigLen
+	esig := sm[2+nonceLen+msgLen:]
+	if sigLen < 1 || esig[0] != signatureHeader {
+		return nil, ErrInvalidSignatureLen
+	}
+	s2, ok := compDecode(esig[1:])
+	if !ok {
+		return nil, ErrVerifyFailed
+	}
+	c := hashToPoint(sm[2 : 2+nonceLen+msgLen])
+	if !verifyRaw(&c, &s2, &h) {
+		return nil, ErrVerifyFailed
+	}
+	return common.CopyBytes(sm[2+nonceLen : 2+nonceLen+msgLen]), nil
+}
+
+// modqDecode decodes a public key polynomial, packed as 14 bits per coefficient.
+// All coefficients must be lower than q, and the unused bits zero.
+func modqDecode(in []byte) ([n]uint16, bool) {
+	var (
+		x      [n]uint16
+		acc    uint32
+		accLen uint
+	)
+	if len(in) != (n*14+7)>>3 {
+		return x, false
+	}
+	for i, u := 0, 0; u < n; i++ {
+		acc = acc<<8 | uint32(in[i])
+		accLen += 8
+		if accLen >= 14 {
+			accLen -= 14
+			w := (acc >> accLen) & 0x3FFF
+			if w >= q {
+				return x, false
+			}
+			x[u] = uint16(w)
+			u++
+		}
+	}
+	return x, acc&((1<<accLen)-1) == 0
+}
+
+// compDecode decodes a compressed signature polynomial. Each coefficient is a
+// sign bit, the low seven bits of its absolute value, and the high bits of it
+// in unary. The whole input must be consumed, and the unused bits be zero.
+func compDecode(in []byte) ([n]int16, bool) {
+	var (
+		x      [n]int16
+		acc    uint32
+		accLen uint
+		v      int
+	)
+	for u := 0; u < n; u++ {
+		// Get next eight bits: sign and low seven bits of the absolute value
+		if v >= len(in) {
+			return x, false
+		}
+		acc = acc<<8 | uint32(in[v])
+		v++
+		b := acc >> accLen
+		s := b & 128
+		m := b & 127
+
+		// Get next bits until a 1 is reached
+		for {
+			if accLen == 0 {
+				if v >= len(in) {
+					return x, false
+				}
+				acc = acc<<8 | uint32(in[v])
+				v++
+				accLen = 8
+			}
+			accLen--
+			if (acc>>accLen)&1 != 0 {
+				break
+			}
+			m += 128
+			if m > 2047 {
+				return x, false
+			}
+		}
+		// "-0" is forbidden
+		if s != 0 && m == 0 {
+			return x, false
+		}
+		if s != 0 {
+			x[u] = -int16(m)
+		} else {
+			x[u] = int16(m)
+		}
+	}
+	if acc&((1<<accLen)-1) != 0 {
+		return x, false
+	}
+	return x, v == len(in)
+}
+
+// hashToPoint hashes the nonce and message into a polynomial with coefficients
+// modulo q, by rejection sampling 16 bit SHAKE256 outputs.
+func hashToPoint(data []byte) [n]uint16 {
+	var (
+		c   [n]uint16
+		buf [2]byte
+	)
+	shake := sha3.NewShake256()
+	shake.Write(data)
+	for u := 0; u < n; {
+		shake.Read(buf[:])
+		w := uint32(buf[0])<<8 | uint32(buf[1])
+		if w < 5*q {
+			c[u] = uint16(w % q)
+			u++
+		}
+	}
+	return c
+}
+
+// verifyRaw checks that s1 = c - s2*h is short enough along with s2.
+func verifyRaw(c *[n]uint16, s2 *[n]int16, h *[n]uint16) bool {
+	var tt [n]uint32
+	for u, w := range s2 {
+		if w < 0 {
+			tt[u] = uint32(int32(w) + q)
+		} else {
+			tt[u] = uint32(w)
+		}
+	}
+	var hh [n]uint32
+	for u, w := range h {
+		hh[u] = uint32(w)
+	}
+	polyMul(&tt, &hh)
+
+	// Compute -s1 = s2*h - c, normalized to the (-q/2, q/2] range
+	var norm uint64
+	for u := range tt {
+		w := int64(tt[u]) - int64(c[u])
+		if w < 0 {
+			w += q
+		}
+		if w > q>>1 {
+			w -= q
+		}
+		norm += uint64(w*w) + uint64(int64(s2[u])*int64(s2[u]))
+	}
+	return norm <= l2Bound
+}
@@ -0,0 +1,69 @@
+// +build cgo
+
+package falcon
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+)
+
+// Tests that the C and the pure Go verifiers agree on the shared test vectors.
+func TestVerifyVectorsCrossCheck(t *testing.T) {
+	for _, v := range loadTestVectors(t) {
+		var (
+			msg = common.Hex2Bytes(v.Message)
+			sig = common.Hex2Bytes(v.Signature)
+			pub = common.Hex2Bytes(v.PublicKey)
+		)
+		if errC, errGo := Verify(msg, sig, pub), VerifyPure(msg, sig, pub); errC != errGo {
+			t.Errorf("%s: C verifier returned %v, pure Go verifier %v", v.Name, errC, errGo)
+		}
+	}
+}
+
+// Tests that the pure Go verifier accepts signatures made by the C signer, and
+// rejects the same corrupted ones the C verifier rejects.
+func TestVerifyCrossCheck(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		pub, priv, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := make([]byte, CRYPTO_MESSAGE_LEN)
+		r.Read(msg)
+		sig, err := Sign(priv, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyPure(msg, sig, pub); err != nil {
+			t.Fatalf("valid signature rejected: %v", err)
+		}
+		corrupted := common.CopyBytes(sig)
+		corrupted[r.Intn(len(corrupted))] ^= byte(1 + r.Intn(255))
+		if errC, errGo := Verify(msg, corrupted, pub), VerifyPure(msg, corrupted, pub); errC != errGo {
+			t.Fatalf("corrupted signature: C verifier returned %v, pure Go verifier %v", errC, errGo)
+		}
+	}
+}
+
+// Tests that keys generated from a seed sign with the C signer.
+func TestGenerateKeyFromSeedSign(t *testing.T) {
+	pub, priv, err := GenerateKeyFromSeed(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, CRYPTO_MESSAGE_LEN)
+	sig, err := Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(msg, sig, pub); err != nil {
+		t.Fatalf("C verifier rejected signature: %v", err)
+	}
+	if err := VerifyPure(msg, sig, pub); err != nil {
+		t.Fatalf("pure Go verifier rejected signature: %v", err)
+	}
+}
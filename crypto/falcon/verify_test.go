@@ -0,0 +1,107 @@
+package falcon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+)
+
+// testVector is a signature check shared by the C and the pure Go verifiers.
+type testVector struct {
+	Name      string
+	PublicKey string
+	Message   string
+	Signature string
+	Valid     bool
+}
+
+func loadTestVectors(t testing.TB) []testVector {
+	data, err := ioutil.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []testVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	return vectors
+}
+
+func TestVerifyPureVectors(t *testing.T) {
+	for _, v := range loadTestVectors(t) {
+		err := VerifyPure(common.Hex2Bytes(v.Message), common.Hex2Bytes(v.Signature), common.Hex2Bytes(v.PublicKey))
+		if v.Valid && err != nil {
+			t.Errorf("%s: expected valid signature, got %v", v.Name, err)
+		}
+		if !v.Valid && err == nil {
+			t.Errorf("%s: expected invalid signature", v.Name)
+		}
+	}
+}
+
+func TestVerifyPureInvalidLengths(t *testing.T) {
+	v := loadTestVectors(t)[0]
+	var (
+		msg = common.Hex2Bytes(v.Message)
+		sig = common.Hex2Bytes(v.Signature)
+		pub = common.Hex2Bytes(v.PublicKey)
+	)
+	if err := VerifyPure(msg, sig[:len(sig)-1], pub); err != ErrInvalidSignatureLen {
+		t.Errorf("short signature: have %v, want %v", err, ErrInvalidSignatureLen)
+	}
+	if err := VerifyPure(msg, sig, pub[1:]); err != ErrInvalidPublicKeyLen {
+		t.Errorf("short public key: have %v, want %v", err, ErrInvalidPublicKeyLen)
+	}
+	if err := VerifyPure(nil, sig, pub); err != ErrInvalidLen {
+		t.Errorf("empty message: have %v, want %v", err, ErrInvalidLen)
+	}
+	if err := VerifyPure(msg[1:], sig, pub); err != ErrVerifyFailed {
+		t.Errorf("truncated message: have %v, want %v", err, ErrVerifyFailed)
+	}
+}
+
+func TestPolyMul(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		var a, b [n]uint32
+		for j := range a {
+			a[j], b[j] = uint32(r.Intn(q)), uint32(r.Intn(q))
+		}
+		// Schoolbook multiplication modulo x^n + 1
+		var want [n]int64
+		for j := range a {
+			for k := range b {
+				p := int64(a[j]) * int64(b[k])
+				if j+k < n {
+					want[j+k] += p
+				} else {
+					want[j+k-n] -= p
+				}
+			}
+		}
+		polyMul(&a, &b)
+		for j := range a {
+			if w := uint32((want[j]%q + q) % q); a[j] != w {
+				t.Fatalf("coefficient %d mismatch: have %d, want %d", j, a[j], w)
+			}
+		}
+	}
+}
+
+func BenchmarkVerifyPure(b *testing.B) {
+	v := loadTestVectors(b)[0]
+	var (
+		msg = common.Hex2Bytes(v.Message)
+		sig = common.Hex2Bytes(v.Signature)
+		pub = common.Hex2Bytes(v.PublicKey)
+	)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := VerifyPure(msg, sig, pub); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,14 +1,7 @@
 package hybrid
 
-/*
-#cgo pkg-config: libhybridpqc
-#include <hybridpqc/hybrid.h>
-*/
-import "C"
 import (
-	"bytes"
 	"errors"
-	"unsafe"
 )
 
 const (
@@ -36,64 +29,28 @@ var (
 	ErrInvalidLen             = errors.New("invalid length")
 	ErrVerifyFailed           = errors.New("verify length")
 	ErrRecoverPublicKeyFailed = errors.New("recover public key length")
+	ErrCgoRequired            = errors.New("not available when built without cgo")
 )
 
-func GenerateKey() (publicKey []byte, secretKey []byte, err error) {
-	publicKey = make([]byte, CRYPTO_PUBLICKEY_BYTES)
-	secretKey = make([]byte, CRYPTO_SECRETKEY_BYTES)
-
-	rv := C.crypto_sign_falcon_ed25519_keypair(
-		(*C.uchar)(unsafe.Pointer(&publicKey[0])),
-		(*C.uchar)(unsafe.Pointer(&secretKey[0])))
-
-	if rv != OK {
-		return nil, nil, errors.New("GenerateKey failed")
-	}
-
-	if bytes.Compare(publicKey[:32], secretKey[32:64]) != 0 {
-		return nil, nil, ErrKeypairFailed
-	}
-
-	if bytes.Compare(publicKey[32:], secretKey[64+1281:]) != 0 {
-		return nil, nil, ErrKeypairFailed
+// Verify verifies the validity of a signed message, returning true if the
+// signature is valid, and false otherwise. It uses the C implementation when
+// built with cgo, and the pure Go one otherwise.
+func Verify(message []byte, signature []byte, publicKey []byte) error {
+	if err := checkLengths(message, signature, publicKey); err != nil {
+		return err
 	}
-
-	return publicKey[:], secretKey[:], nil
+	return verify(message, signature, publicKey)
 }
 
-func Sign(secretKey []byte, message []byte) ([]byte, error) {
-	if len(secretKey) != CRYPTO_SECRETKEY_BYTES {
-		return nil, ErrInvalidPrivateKeyLen
+// VerifyPure is like Verify, but always uses the pure Go implementation.
+func VerifyPure(message []byte, signature []byte, publicKey []byte) error {
+	if err := checkLengths(message, signature, publicKey); err != nil {
+		return err
 	}
-
-	if len(message) != CRYPTO_MESSAGE_LEN {
-		return nil, ErrInvalidMsgLen
-	}
-
-	signature := make([]byte, CRYPTO_SIGNATURE_BYTES)
-
-	var lenSig uint64
-
-	rv := C.crypto_sign_falcon_ed25519((*C.uchar)(unsafe.Pointer(&signature[0])),
-		(*C.ulonglong)(unsafe.Pointer(&lenSig)),
-		(*C.uchar)(unsafe.Pointer(&message[0])),
-		(C.ulonglong)(uint64(len(message))),
-		(*C.uchar)(unsafe.Pointer(&secretKey[0])))
-
-	if rv != OK {
-		return nil, ErrSignFailed
-	}
-
-	if lenSig != CRYPTO_SIGNATURE_BYTES {
-		return nil, ErrInvalidSignatureLen
-	}
-
-	return signature, nil
+	return verifyPure(message, signature, publicKey)
 }
 
-// Verify verifies the validity of a signed message, returning true if the
-// signature is valid, and false otherwise.
-func Verify(message []byte, signature []byte, publicKey []byte) error {
+func checkLengths(message []byte, signature []byte, publicKey []byte) error {
 	if len(message) != CRYPTO_MESSAGE_LEN || len(signature) == 0 || len(publicKey) == 0 {
 		return ErrInvalidLen
 	}
@@ -103,34 +60,5 @@ func Verify(message []byte, signature []byte, publicKey []byte) error {
 	if len(signature) != CRYPTO_SIGNATURE_BYTES {
 		return ErrInvalidSignatureLen
 	}
-
-	rv := C.crypto_verify_falcon_ed25519((*C.uchar)(unsafe.Pointer(&message[0])),
-		(C.ulonglong)(uint64(len(message))),
-		(*C.uchar)(unsafe.Pointer(&signature[0])),
-		(C.ulonglong)(uint64(len(signature))),
-		(*C.uchar)(unsafe.Pointer(&publicKey[0])))
-
-	if rv != OK {
-		return ErrVerifyFailed
-	}
-
 	return nil
 }
-
-func PrivateAndPublicFromPrivateKey(compositePrivateKey []byte) (privateBytes []byte, publicBytes []byte, err error) {
-	if len(compositePrivateKey) != CRYPTO_SECRETKEY_BYTES {
-		return nil, nil, ErrInvalidPrivateKeyLen
-	}
-
-	pubKeyBytes := make([]byte, CRYPTO_PUBLICKEY_BYTES)
-
-	rv := C.crypto_public_key_from_private_key_falcon_ed25519(
-		(*C.uchar)(unsafe.Pointer(&pubKeyBytes[0])),
-		(*C.uchar)(unsafe.Pointer(&compositePrivateKey[0])))
-
-	if rv != 0 {
-		return nil, nil, ErrRecoverPublicKeyFailed
-	}
-
-	return compositePrivateKey, pubKeyBytes, nil
-}
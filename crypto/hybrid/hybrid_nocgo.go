@@ -0,0 +1,21 @@
+// +build !cgo
+
+package hybrid
+
+func GenerateKey() (publicKey []byte, secretKey []byte, err error) {
+	return nil, nil, ErrCgoRequired
+}
+
+func Sign(secretKey []byte, message []byte) ([]byte, error) {
+	return nil, ErrCgoRequired
+}
+
+func PrivateAndPublicFromPrivateKey(compositePrivateKey []byte) (privateBytes []byte, publicBytes []byte, err error) {
+	return nil, nil, ErrCgoRequired
+}
+
+// verify checks a signature with the pure Go implementation, which is the
+// only one available without cgo.
+func verify(message []byte, signature []byte, publicKey []byte) error {
+	return verifyPure(message, signature, publicKey)
+}
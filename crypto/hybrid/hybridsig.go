@@ -75,6 +75,21 @@ func (s HybridSig) GenerateKey() (*signaturealgorithm.PrivateKey, error) {
 	return privy, nil
 }
 
+// GenerateKeyFromSeed deterministically generates a key from a seed of at least
+// 32 bytes, see the package level GenerateKeyFromSeed.
+func (s HybridSig) GenerateKeyFromSeed(seed []byte) (*signaturealgorithm.PrivateKey, error) {
+	pubKey, priKey, err := GenerateKeyFromSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	privy := new(signaturealgorithm.PrivateKey)
+	privy.PriData = priKey
+	privy.PublicKey.PubData = pubKey
+
+	return privy, nil
+}
+
 func (s HybridSig) SerializePrivateKey(priv *signaturealgorithm.PrivateKey) ([]byte, error) {
 	priBytes, err := s.exportPrivateKey(priv)
 	if err != nil {
@@ -252,6 +267,10 @@ func (s HybridSig) Verify(pubKey []byte, digestHash []byte, signature []byte) bo
 	}
 }
 
+func (s HybridSig) VerifyBatch(pubKeys [][]byte, digestHashes [][]byte, signatures [][]byte) []bool {
+	return signaturealgorithm.VerifyParallel(s, pubKeys, digestHashes, signatures)
+}
+
 func (s HybridSig) PublicKeyAndSignatureFromCombinedSignature(digestHash []byte, sig []byte) (signature []byte, pubKey []byte, err error) {
 	signature, pubKey, err = common.ExtractTwoParts(sig)
 	if err != nil {
@@ -0,0 +1,44 @@
+package hybrid
+
+import (
+	"crypto/ed25519"
+
+	"github.com/DogeProtocol/dp/crypto/falcon"
+	"golang.org/x/crypto/sha3"
+)
+
+// GenerateKeyFromSeed deterministically generates a hybrid key pair from a seed
+// of at least 32 bytes. It is only available when built with cgo.
+//
+// The ed25519 key is made from the first 32 bytes of SHAKE256("DP hybrid
+// ed25519" || seed), and the Falcon-512 key with falcon.GenerateKeyFromSeed
+// from the first 32 bytes of SHAKE256("DP hybrid falcon" || seed). Both halves
+// are laid out like the keys of GenerateKey.
+func GenerateKeyFromSeed(seed []byte) (publicKey []byte, secretKey []byte, err error) {
+	if len(seed) < 32 {
+		return nil, nil, falcon.ErrSeedTooShort
+	}
+	edKey := ed25519.NewKeyFromSeed(subSeed("DP hybrid ed25519", seed))
+	falconPub, falconKey, err := falcon.GenerateKeyFromSeed(subSeed("DP hybrid falcon", seed))
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey = make([]byte, 0, CRYPTO_PUBLICKEY_BYTES)
+	publicKey = append(publicKey, edKey.Public().(ed25519.PublicKey)...)
+	publicKey = append(publicKey, falconPub...)
+
+	secretKey = make([]byte, 0, CRYPTO_SECRETKEY_BYTES)
+	secretKey = append(secretKey, edKey...)
+	secretKey = append(secretKey, falconKey...)
+	secretKey = append(secretKey, falconPub...)
+	return publicKey, secretKey, nil
+}
+
+func subSeed(domain string, seed []byte) []byte {
+	out := make([]byte, 32)
+	shake := sha3.NewShake256()
+	shake.Write([]byte(domain))
+	shake.Write(seed)
+	shake.Read(out)
+	return out
+}
@@ -0,0 +1,30 @@
+// +build cgo
+
+package hybrid
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateKeyFromSeed(t *testing.T) {
+	seed := make([]byte, 32)
+	pub, sk, err := GenerateKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pub) != CRYPTO_PUBLICKEY_BYTES || len(sk) != CRYPTO_SECRETKEY_BYTES {
+		t.Fatalf("key lengths: have %d/%d, want %d/%d", len(pub), len(sk), CRYPTO_PUBLICKEY_BYTES, CRYPTO_SECRETKEY_BYTES)
+	}
+	// Same consistency checks as GenerateKey
+	if !bytes.Equal(pub[:32], sk[32:64]) || !bytes.Equal(pub[32:], sk[64+1281:]) {
+		t.Fatal("public key not embedded in secret key")
+	}
+	pub2, sk2, err := GenerateKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pub, pub2) || !bytes.Equal(sk, sk2) {
+		t.Error("key generation not deterministic")
+	}
+}
@@ -0,0 +1,13 @@
+package hybrid
+
+import (
+	"testing"
+
+	"github.com/DogeProtocol/dp/crypto/falcon"
+)
+
+func TestGenerateKeyFromSeedShort(t *testing.T) {
+	if _, _, err := GenerateKeyFromSeed(make([]byte, 31)); err != falcon.ErrSeedTooShort {
+		t.Errorf("short seed: have %v, want %v", err, falcon.ErrSeedTooShort)
+	}
+}
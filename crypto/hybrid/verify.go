@@ -0,0 +1,46 @@
+package hybrid
+
+import (
+	"bytes"
+	"crypto/ed25519"
+
+	"github.com/DogeProtocol/dp/crypto/falcon"
+)
+
+// Layout of a hybrid signature: the length of the Falcon signed message, the
+// ed25519 signature of the message, then the Falcon signed message itself,
+// zero padded up to CRYPTO_SIGNATURE_BYTES.
+const (
+	falconLenOffset = 0
+	ed25519Offset   = falconLenOffset + 2
+	falconOffset    = ed25519Offset + ed25519.SignatureSize
+)
+
+// verifyPure checks both halves of a hybrid signature in pure Go. Lengths are
+// expected to be validated by the caller, any other failure is reported as
+// ErrVerifyFailed like the C implementation does.
+func verifyPure(message []byte, signature []byte, publicKey []byte) error {
+	falconLen := int(signature[falconLenOffset])<<8 | int(signature[falconLenOffset+1])
+	if falconLen == 0 || falconLen > len(signature)-falconOffset {
+		return ErrVerifyFailed
+	}
+	// The padding is not covered by either signature, it has to be zero for the
+	// signature to be unique
+	for _, b := range signature[falconOffset+falconLen:] {
+		if b != 0 {
+			return ErrVerifyFailed
+		}
+	}
+	edPublicKey := publicKey[:ed25519.PublicKeySize]
+	if !ed25519.Verify(edPublicKey, message, signature[ed25519Offset:falconOffset]) {
+		return ErrVerifyFailed
+	}
+	messageCheck, err := falcon.OpenPure(signature[falconOffset:falconOffset+falconLen], publicKey[ed25519.PublicKeySize:])
+	if err != nil {
+		return ErrVerifyFailed
+	}
+	if bytes.Compare(message, messageCheck) != 0 {
+		return ErrVerifyFailed
+	}
+	return nil
+}
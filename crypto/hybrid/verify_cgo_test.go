@@ -0,0 +1,155 @@
+// +build cgo
+
+package hybrid
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+)
+
+// Tests that the C and the pure Go verifiers agree on the shared test vectors.
+func TestVerifyVectorsCrossCheck(t *testing.T) {
+	for _, v := range loadTestVectors(t) {
+		var (
+			msg = common.Hex2Bytes(v.Message)
+			sig = common.Hex2Bytes(v.Signature)
+			pub = common.Hex2Bytes(v.PublicKey)
+		)
+		if errC, errGo := Verify(msg, sig, pub), VerifyPure(msg, sig, pub); errC != errGo {
+			t.Errorf("%s: C verifier returned %v, pure Go verifier %v", v.Name, errC, errGo)
+		}
+	}
+}
+
+// Tests that the pure Go verifier accepts signatures made by the C signer, and
+// rejects the same corrupted ones the C verifier rejects.
+func TestVerifyCrossCheck(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		pub, priv, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := make([]byte, CRYPTO_MESSAGE_LEN)
+		r.Read(msg)
+		sig, err := Sign(priv, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyPure(msg, sig, pub); err != nil {
+			t.Fatalf("valid signature rejected: %v", err)
+		}
+		corrupted := common.CopyBytes(sig)
+		corrupted[r.Intn(len(corrupted))] ^= byte(1 + r.Intn(255))
+		if errC, errGo := Verify(msg, corrupted, pub), VerifyPure(msg, corrupted, pub); errC != errGo {
+			t.Fatalf("corrupted signature: C verifier returned %v, pure Go verifier %v", errC, errGo)
+		}
+	}
+}
+
+// Tests that the C and the pure Go verifiers agree on the ed25519 edge cases the
+// implementations are known to differ on: small-order and non-canonical public
+// keys and R points. The Falcon half of the signature is left valid.
+func TestVerifyEd25519EdgeCasesCrossCheck(t *testing.T) {
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, CRYPTO_MESSAGE_LEN)
+	sig, err := Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		identity     = common.Hex2Bytes("0100000000000000000000000000000000000000000000000000000000000000")
+		order2       = common.Hex2Bytes("ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f")
+		order4       = common.Hex2Bytes("0000000000000000000000000000000000000000000000000000000000000000")
+		order8       = common.Hex2Bytes("c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a")
+		nonCanonical = common.Hex2Bytes("eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f") // identity, y = p + 1
+		zero         = make([]byte, 32)
+	)
+	tests := []struct {
+		name string
+		a    []byte // ed25519 public key, nil to keep the generated one
+		r, s []byte // ed25519 signature halves, nil to keep the generated ones
+	}{
+		{"identity key", identity, identity, zero},
+		{"order 2 key", order2, identity, zero},
+		{"order 4 key", order4, identity, zero},
+		{"order 8 key", order8, identity, zero},
+		{"small order key, original signature", order8, nil, nil},
+		{"identity R", nil, identity, nil},
+		{"order 8 R", nil, order8, nil},
+		{"non-canonical key", nonCanonical, identity, zero},
+		{"non-canonical R", identity, nonCanonical, zero},
+	}
+	for _, test := range tests {
+		var (
+			tpub = common.CopyBytes(pub)
+			tsig = common.CopyBytes(sig)
+		)
+		if test.a != nil {
+			copy(tpub, test.a)
+		}
+		if test.r != nil {
+			copy(tsig[ed25519Offset:], test.r)
+		}
+		if test.s != nil {
+			copy(tsig[ed25519Offset+32:], test.s)
+		}
+		if errC, errGo := Verify(msg, tsig, tpub), VerifyPure(msg, tsig, tpub); errC != errGo {
+			t.Errorf("%s: C verifier returned %v, pure Go verifier %v", test.name, errC, errGo)
+		}
+	}
+}
+
+// Tests that the C and the pure Go verifiers agree on signatures with non-zero
+// padding after the Falcon signed message.
+func TestVerifyPaddingCrossCheck(t *testing.T) {
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, CRYPTO_MESSAGE_LEN)
+	sig, err := Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	falconLen := int(sig[falconLenOffset])<<8 | int(sig[falconLenOffset+1])
+	if falconOffset+falconLen == len(sig) {
+		t.Skip("signature without padding")
+	}
+	sig[len(sig)-1] = 0x01
+	if errC, errGo := Verify(msg, sig, pub), VerifyPure(msg, sig, pub); errC != errGo {
+		t.Errorf("C verifier returned %v, pure Go verifier %v", errC, errGo)
+	}
+}
+
+// Tests that keys generated from a seed sign with the C signer.
+func TestGenerateKeyFromSeedSign(t *testing.T) {
+	pub, priv, err := GenerateKeyFromSeed(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, CRYPTO_MESSAGE_LEN)
+	sig, err := Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(msg, sig, pub); err != nil {
+		t.Fatalf("C verifier rejected signature: %v", err)
+	}
+	if err := VerifyPure(msg, sig, pub); err != nil {
+		t.Fatalf("pure Go verifier rejected signature: %v", err)
+	}
+	_, derivedPub, err := PrivateAndPublicFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(derivedPub, pub) {
+		t.Fatal("public key mismatch")
+	}
+}
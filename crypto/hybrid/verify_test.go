@@ -0,0 +1,91 @@
+package hybrid
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+)
+
+// testVector is a hybrid signature check shared by the C and the pure Go
+// verifiers.
+type testVector struct {
+	Name      string
+	PublicKey string
+	Message   string
+	Signature string
+	Valid     bool
+}
+
+func loadTestVectors(t testing.TB) []testVector {
+	data, err := ioutil.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []testVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	return vectors
+}
+
+func TestVerifyPureVectors(t *testing.T) {
+	for _, v := range loadTestVectors(t) {
+		err := VerifyPure(common.Hex2Bytes(v.Message), common.Hex2Bytes(v.Signature), common.Hex2Bytes(v.PublicKey))
+		if v.Valid && err != nil {
+			t.Errorf("%s: expected valid signature, got %v", v.Name, err)
+		}
+		if !v.Valid && err == nil {
+			t.Errorf("%s: expected invalid signature", v.Name)
+		}
+	}
+}
+
+func TestVerifyPureInvalidLengths(t *testing.T) {
+	v := loadTestVectors(t)[0]
+	var (
+		msg = common.Hex2Bytes(v.Message)
+		sig = common.Hex2Bytes(v.Signature)
+		pub = common.Hex2Bytes(v.PublicKey)
+	)
+	if err := VerifyPure(msg, sig[:len(sig)-1], pub); err != ErrInvalidSignatureLen {
+		t.Errorf("short signature: have %v, want %v", err, ErrInvalidSignatureLen)
+	}
+	if err := VerifyPure(msg, sig, pub[1:]); err != ErrInvalidPublicKeyLen {
+		t.Errorf("short public key: have %v, want %v", err, ErrInvalidPublicKeyLen)
+	}
+	if err := VerifyPure(msg[1:], sig, pub); err != ErrInvalidLen {
+		t.Errorf("short message: have %v, want %v", err, ErrInvalidLen)
+	}
+}
+
+// Tests that signatures with anything but zeroes after the Falcon signed message
+// are rejected.
+func TestVerifyPurePadding(t *testing.T) {
+	v := loadTestVectors(t)[0]
+	var (
+		msg = common.Hex2Bytes(v.Message)
+		sig = common.Hex2Bytes(v.Signature)
+		pub = common.Hex2Bytes(v.PublicKey)
+	)
+	sig[len(sig)-1] = 0x01
+	if err := VerifyPure(msg, sig, pub); err != ErrVerifyFailed {
+		t.Errorf("non-zero padding: have %v, want %v", err, ErrVerifyFailed)
+	}
+}
+
+func BenchmarkVerifyPure(b *testing.B) {
+	v := loadTestVectors(b)[0]
+	var (
+		msg = common.Hex2Bytes(v.Message)
+		sig = common.Hex2Bytes(v.Signature)
+		pub = common.Hex2Bytes(v.PublicKey)
+	)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := VerifyPure(msg, sig, pub); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -280,6 +280,10 @@ func (s MockSig) Verify(pubKey []byte, digestHash []byte, signature []byte) bool
 	}
 }
 
+func (s MockSig) VerifyBatch(pubKeys [][]byte, digestHashes [][]byte, signatures [][]byte) []bool {
+	return signaturealgorithm.VerifyParallel(s, pubKeys, digestHashes, signatures)
+}
+
 func (s MockSig) PublicKeyAndSignatureFromCombinedSignature(digestHash []byte, sig []byte) (signature []byte, pubKey []byte, err error) {
 	signature, pubKey, err = common.ExtractTwoParts(sig)
 	if err != nil {
@@ -0,0 +1,15 @@
+package oqs
+
+// DilithiumSigName is the liboqs name of the Dilithium parameter set the chain
+// verifies, at NIST security level 2.
+const DilithiumSigName = "Dilithium2"
+
+type Dilithium struct {
+	OqsSig
+}
+
+func InitDilithium() Dilithium {
+	return Dilithium{
+		CreateOqs(DilithiumSigName),
+	}
+}
@@ -0,0 +1,10 @@
+package oqs
+
+// liboqs names of the KEMs the RLPx handshake can negotiate. ML-KEM-768 is the
+// FIPS 203 parameter set at NIST security level 3, sntrup761 is the Streamlined
+// NTRU Prime parameter set used by OpenSSH.
+const (
+	Kyber512KemName  = "Kyber512"
+	MLKEM768KemName  = "ML-KEM-768"
+	Sntrup761KemName = "sntrup761"
+)
@@ -0,0 +1,28 @@
+package oqs
+
+// liboqs names of the ML-DSA (FIPS 204) parameter sets, at NIST security levels
+// 2 and 3.
+const (
+	MLDSA44SigName = "ML-DSA-44"
+	MLDSA65SigName = "ML-DSA-65"
+)
+
+type MLDSA44 struct {
+	OqsSig
+}
+
+func InitMLDSA44() MLDSA44 {
+	return MLDSA44{
+		CreateOqs(MLDSA44SigName),
+	}
+}
+
+type MLDSA65 struct {
+	OqsSig
+}
+
+func InitMLDSA65() MLDSA65 {
+	return MLDSA65{
+		CreateOqs(MLDSA65SigName),
+	}
+}
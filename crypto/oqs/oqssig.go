@@ -257,6 +257,10 @@ func (s OqsSig) Verify(pubKey []byte, digestHash []byte, signature []byte) bool
 	return VerifySignature(s.sigName, pubKey, digestHash, sigBytes)
 }
 
+func (s OqsSig) VerifyBatch(pubKeys [][]byte, digestHashes [][]byte, signatures [][]byte) []bool {
+	return signaturealgorithm.VerifyParallel(s, pubKeys, digestHashes, signatures)
+}
+
 func (s OqsSig) PublicKeyAndSignatureFromCombinedSignature(digestHash []byte, sig []byte) (signature []byte, pubKey []byte, err error) {
 	signature, pubKey, err = common.ExtractTwoParts(sig)
 	if err != nil {
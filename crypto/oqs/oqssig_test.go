@@ -13,3 +13,39 @@ func TestOqsSig_Basic(t *testing.T) {
 
 	signaturealgorithm.SignatureAlgorithmTest(t, sig)
 }
+
+func TestOqsSig_Dilithium(t *testing.T) {
+	InitOqs()
+
+	var sig signaturealgorithm.SignatureAlgorithm
+	sig = InitDilithium()
+
+	signaturealgorithm.SignatureAlgorithmTest(t, sig)
+}
+
+func TestOqsSig_MLDSA44(t *testing.T) {
+	InitOqs()
+
+	var sig signaturealgorithm.SignatureAlgorithm
+	sig = InitMLDSA44()
+
+	signaturealgorithm.SignatureAlgorithmTest(t, sig)
+}
+
+func TestOqsSig_MLDSA65(t *testing.T) {
+	InitOqs()
+
+	var sig signaturealgorithm.SignatureAlgorithm
+	sig = InitMLDSA65()
+
+	signaturealgorithm.SignatureAlgorithmTest(t, sig)
+}
+
+func TestOqsSig_SLHDSA(t *testing.T) {
+	InitOqs()
+
+	var sig signaturealgorithm.SignatureAlgorithm
+	sig = InitSLHDSA()
+
+	signaturealgorithm.SignatureAlgorithmTest(t, sig)
+}
@@ -0,0 +1,16 @@
+package oqs
+
+// SLHDSASigName is the liboqs name of the SPHINCS+ parameter set standardized as
+// SLH-DSA-SHA2-128s (FIPS 205). Its security only rests on the hash function,
+// at the cost of large and slow signatures.
+const SLHDSASigName = "SPHINCS+-SHA2-128s-simple"
+
+type SLHDSA struct {
+	OqsSig
+}
+
+func InitSLHDSA() SLHDSA {
+	return SLHDSA{
+		CreateOqs(SLHDSASigName),
+	}
+}
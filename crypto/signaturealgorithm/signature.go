@@ -3,6 +3,9 @@ package signaturealgorithm
 import (
 	"github.com/DogeProtocol/dp/common"
 	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
 )
 
 type PublicKey struct {
@@ -50,6 +53,10 @@ type SignatureAlgorithm interface {
 	Sign(digestHash []byte, prv *PrivateKey) (sig []byte, err error)
 	Verify(pubKey []byte, digestHash []byte, signature []byte) bool
 
+	// VerifyBatch verifies a batch of signatures, returning the validity of
+	// each of them in the order of the inputs.
+	VerifyBatch(pubKeys [][]byte, digestHashes [][]byte, signatures [][]byte) []bool
+
 	Zeroize(prv *PrivateKey)
 
 	//PrivateKeyAsBigInt(prv *PrivateKey) *big.Int
@@ -67,3 +74,42 @@ type SignatureAlgorithm interface {
 	//what is this? todo
 	ValidateSignatureValues(v byte, r, s *big.Int, homestead bool) bool
 }
+
+// SeededKeyGenerator is implemented by the signature algorithms able to derive
+// keys deterministically from a seed, such as one made from a mnemonic.
+type SeededKeyGenerator interface {
+	GenerateKeyFromSeed(seed []byte) (*PrivateKey, error)
+}
+
+// VerifyParallel verifies a batch of signatures with the given algorithm across
+// all CPUs. It is the VerifyBatch of the algorithms lacking a dedicated batch
+// verification.
+func VerifyParallel(alg SignatureAlgorithm, pubKeys [][]byte, digestHashes [][]byte, signatures [][]byte) []bool {
+	valid := make([]bool, len(signatures))
+	if len(pubKeys) != len(signatures) || len(digestHashes) != len(signatures) {
+		return valid
+	}
+	threads := runtime.NumCPU()
+	if threads > len(signatures) {
+		threads = len(signatures)
+	}
+	var (
+		next int64 = -1
+		wg   sync.WaitGroup
+	)
+	wg.Add(threads)
+	for i := 0; i < threads; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				j := int(atomic.AddInt64(&next, 1))
+				if j >= len(signatures) {
+					return
+				}
+				valid[j] = alg.Verify(pubKeys[j], digestHashes[j], signatures[j])
+			}
+		}()
+	}
+	wg.Wait()
+	return valid
+}
@@ -152,6 +152,13 @@ func SignatureAlgorithmTest(t *testing.T, sig SignatureAlgorithm) {
 		t.Fatal("Verify failed")
 	}
 
+	valid := sig.VerifyBatch([][]byte{pubBytes1, pubBytes1, pubBytes1, pubBytes1},
+		[][]byte{digestHash1, digestHash2, digestHash1, digestHash2},
+		[][]byte{signature1, signature2, signature2, signature1})
+	if len(valid) != 4 || !valid[0] || !valid[1] || valid[2] || valid[3] {
+		t.Fatal("VerifyBatch failed", valid)
+	}
+
 	sigExtracted, pubExtracted, err := sig.PublicKeyAndSignatureFromCombinedSignature(digestHash1, signature1)
 	if err != nil {
 		t.Fatal(err)
@@ -219,6 +219,12 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if checkpoint == nil {
 		checkpoint = params.TrustedCheckpoints[genesisHash]
 	}
+	// Watch propagated headers for double-signing validators
+	var headerObserver func(*types.Header)
+	if engine, ok := eth.engine.(*proofofstake.ProofOfStake); ok {
+		watcher := proofofstake.NewDoubleSignWatcher(engine, eth.txPool)
+		headerObserver = func(header *types.Header) { watcher.Observe(header) }
+	}
 	if eth.handler, err = newHandler(&handlerConfig{
 		Database:   chainDb,
 		Chain:      eth.blockchain,
@@ -229,6 +235,8 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		EventMux:   eth.eventMux,
 		Checkpoint: checkpoint,
 		Whitelist:  config.Whitelist,
+
+		HeaderObserver: headerObserver,
 	}); err != nil {
 		return nil, err
 	}
@@ -199,6 +199,10 @@ func (es *EventSystem) SubscribeLogs(crit dp.FilterQuery, logs chan []*types.Log
 		to = rpc.BlockNumber(crit.ToBlock.Int64())
 	}
 
+	// the finalized block lags behind the mined logs the subscription delivers
+	if from == rpc.FinalizedBlockNumber || to == rpc.FinalizedBlockNumber {
+		return nil, fmt.Errorf("log subscriptions don't support the finalized block")
+	}
 	// only interested in pending logs
 	if from == rpc.PendingBlockNumber && to == rpc.PendingBlockNumber {
 		return es.subscribePendingLogs(crit, logs), nil
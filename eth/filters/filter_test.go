@@ -30,6 +30,7 @@ import (
 	"github.com/DogeProtocol/dp/core/rawdb"
 	"github.com/DogeProtocol/dp/core/types"
 	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rpc"
 )
 
 func makeReceipt(addr common.Address) *types.Receipt {
@@ -228,4 +229,22 @@ func TestFilters(t *testing.T) {
 	if len(logs) != 0 {
 		t.Error("expected 0 log, got", len(logs))
 	}
+
+	filter = NewRangeFilter(backend, 0, rpc.FinalizedBlockNumber.Int64(), []common.Address{addr}, nil)
+	if _, err := filter.Logs(context.Background()); err == nil {
+		t.Error("expected error without a finalized block")
+	}
+
+	rawdb.WriteFinalizedBlockHash(db, chain[499].Hash())
+	filter = NewRangeFilter(backend, 0, rpc.FinalizedBlockNumber.Int64(), []common.Address{addr}, nil)
+	logs, _ = filter.Logs(context.Background())
+	if len(logs) != 2 {
+		t.Error("expected 2 log, got", len(logs))
+	}
+
+	filter = NewRangeFilter(backend, rpc.FinalizedBlockNumber.Int64(), -1, []common.Address{addr}, nil)
+	logs, _ = filter.Logs(context.Background())
+	if len(logs) != 2 {
+		t.Error("expected 2 log, got", len(logs))
+	}
 }
@@ -234,6 +234,23 @@ func generateTestChain() (*core.Genesis, []*types.Block) {
 	return genesis, blocks
 }
 
+func TestToBlockNumArg(t *testing.T) {
+	for _, tt := range []struct {
+		number *big.Int
+		want   string
+	}{
+		{nil, "latest"},
+		{big.NewInt(-1), "pending"},
+		{big.NewInt(rpc.FinalizedBlockNumber.Int64()), "finalized"},
+		{big.NewInt(0), "0x0"},
+		{big.NewInt(18), "0x12"},
+	} {
+		if have := toBlockNumArg(tt.number); have != tt.want {
+			t.Errorf("toBlockNumArg(%v) = %q, want %q", tt.number, have, tt.want)
+		}
+	}
+}
+
 func TestEthClient(t *testing.T) {
 	backend, chain := newTestBackend(t)
 	client, _ := backend.Attach()
@@ -1250,25 +1250,30 @@ func (s *PublicBlockChainAPI) rpcMarshalBlock(ctx context.Context, b *types.Bloc
 
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
 type RPCTransaction struct {
-	BlockHash        *common.Hash      `json:"blockHash"`
-	BlockNumber      *hexutil.Big      `json:"blockNumber"`
-	From             common.Address    `json:"from"`
-	Gas              hexutil.Uint64    `json:"gas"`
-	GasPrice         *hexutil.Big      `json:"gasPrice"`
-	GasFeeCap        *hexutil.Big      `json:"maxFeePerGas,omitempty"`
-	GasTipCap        *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
-	Hash             common.Hash       `json:"hash"`
-	Input            hexutil.Bytes     `json:"input"`
-	Nonce            hexutil.Uint64    `json:"nonce"`
-	To               *common.Address   `json:"to"`
-	TransactionIndex *hexutil.Uint64   `json:"transactionIndex"`
-	Value            *hexutil.Big      `json:"value"`
-	Type             hexutil.Uint64    `json:"type"`
-	Accesses         *types.AccessList `json:"accessList,omitempty"`
-	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
-	V                *hexutil.Big      `json:"v"`
-	R                *hexutil.Big      `json:"r"`
-	S                *hexutil.Big      `json:"s"`
+	BlockHash         *common.Hash      `json:"blockHash"`
+	BlockNumber       *hexutil.Big      `json:"blockNumber"`
+	From              common.Address    `json:"from"`
+	Gas               hexutil.Uint64    `json:"gas"`
+	GasPrice          *hexutil.Big      `json:"gasPrice"`
+	GasFeeCap         *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	GasTipCap         *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
+	Hash              common.Hash       `json:"hash"`
+	Input             hexutil.Bytes     `json:"input"`
+	Nonce             hexutil.Uint64    `json:"nonce"`
+	To                *common.Address   `json:"to"`
+	TransactionIndex  *hexutil.Uint64   `json:"transactionIndex"`
+	Value             *hexutil.Big      `json:"value"`
+	Type              hexutil.Uint64    `json:"type"`
+	Accesses          *types.AccessList `json:"accessList,omitempty"`
+	ChainID           *hexutil.Big      `json:"chainId,omitempty"`
+	V                 *hexutil.Big      `json:"v"`
+	R                 *hexutil.Big      `json:"r"`
+	S                 *hexutil.Big      `json:"s"`
+	Algorithm         *hexutil.Uint64   `json:"algorithm,omitempty"`
+	Signature         hexutil.Bytes     `json:"signature,omitempty"`
+	PublicKey         hexutil.Bytes     `json:"publicKey,omitempty"`
+	Sender            *common.Address   `json:"sender,omitempty"`
+	UsesRegisteredKey bool              `json:"usesRegisteredKey,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1285,6 +1290,9 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		signer = types.HomesteadSigner{}
 	}
 	from, _ := types.Sender(signer, tx)
+	if sender, ok := tx.RegisteredKeySender(); ok {
+		from = sender
+	}
 	v, r, s := tx.RawSignatureValues()
 	result := &RPCTransaction{
 		Type:     hexutil.Uint64(tx.Type()),
@@ -1310,12 +1318,24 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		al := tx.AccessList()
 		result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.SignatureTxType:
 		al := tx.AccessList()
 		result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
 		result.GasFeeCap = (*hexutil.Big)(tx.GasFeeCap())
 		result.GasTipCap = (*hexutil.Big)(tx.GasTipCap())
+		// Signature transactions carry their signature as bytes
+		if tx.Type() == types.SignatureTxType {
+			algorithm := hexutil.Uint64(tx.SignatureAlgorithm())
+			result.Algorithm = &algorithm
+			result.Signature, result.PublicKey = tx.RawSignature()
+			result.V, result.R, result.S = nil, nil, nil
+			// Note transactions relying on the sender's registered public key
+			if sender, ok := tx.RegisteredKeySender(); ok {
+				result.Sender = &sender
+				result.UsesRegisteredKey = true
+			}
+		}
 		// if the transaction has been mined, compute the effective gas price
 		if baseFee != nil && blockHash != (common.Hash{}) {
 			// price = min(tip, gasFeeCap - baseFee) + baseFee
@@ -1608,6 +1628,10 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	bigblock := new(big.Int).SetUint64(blockNumber)
 	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
 	from, _ := types.Sender(signer, tx)
+	sender, usesRegisteredKey := tx.RegisteredKeySender()
+	if usesRegisteredKey {
+		from = sender
+	}
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
@@ -1643,6 +1667,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.Logs == nil {
 		fields["logs"] = [][]*types.Log{}
 	}
+	if usesRegisteredKey {
+		fields["usesRegisteredKey"] = true
+	}
 	// If the ContractAddress is 20 0x0 bytes, assume it is not a contract creation
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
@@ -41,6 +41,9 @@ var (
 	egressConnectMeter  = metrics.NewRegisteredMeter("p2p/dials", nil)
 	egressTrafficMeter  = metrics.NewRegisteredMeter(egressMeterName, nil)
 	activePeerGauge     = metrics.NewRegisteredGauge("p2p/peers", nil)
+
+	fullHandshakeMeter    = metrics.NewRegisteredMeter("p2p/handshakes/full", nil)
+	resumedHandshakeMeter = metrics.NewRegisteredMeter("p2p/handshakes/resumed", nil)
 )
 
 // meteredConn is a wrapper around a net.Conn that meters both the
@@ -485,6 +485,7 @@ type PeerInfo struct {
 		Inbound       bool   `json:"inbound"`
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
+		KeyExchange   string `json:"keyExchange,omitempty"` // Key exchange negotiated in the RLPx handshake
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
 }
@@ -512,6 +513,9 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.Inbound = p.rw.is(inboundConn)
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
+	if t, ok := p.rw.transport.(*rlpxTransport); ok {
+		info.Network.KeyExchange = t.conn.KeyExchange()
+	}
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
@@ -3,40 +3,68 @@ package rlpx
 import (
 	"bytes"
 	cipher2 "crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"errors"
 	"github.com/DogeProtocol/dp/crypto"
-	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/crypto/keyestablishmentalgorithm"
 	"github.com/DogeProtocol/dp/crypto/oqs"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"github.com/DogeProtocol/dp/rlp"
 	"io"
 	"sync"
+	"time"
 )
 
+// clientHelloMessage starts with the fields of the original handshake, so that peers
+// predating the hybrid key exchange can still decode it. They get the other fields
+// as extra elements.
 type clientHelloMessage struct {
-	ClientKemPublicKey    []byte //kemPublicKeyLen
+	ClientKemPublicKey    []byte //key share for legacyKemName, if offered
 	ClientHelloRandomData [shaLen]byte
-	Version               uint
+	Version               uint           //always handshakeVersion1, the versions offered are listed in Versions
+	KeyShares             []kemKeyShare  `rlp:"optional"` //one per other offered KEM, in order of preference
+	ClientX25519PublicKey []byte         `rlp:"optional"` //unset by legacy peers
+	Versions              []uint         `rlp:"optional"` //from version 2 on, highest first
+	Aeads                 []string       `rlp:"optional"`
+	SignatureAlgorithms   []string       `rlp:"optional"`
+	SessionTicket         []byte         `rlp:"optional"` //from version 3 on, set when resuming a session
 	Rest                  []rlp.RawValue `rlp:"tail"`
 }
 
 type clientVerifyMessage struct {
 	Signature    []byte //SignPublicKeyLen
 	SignatureLen uint
+	Finished     []byte         `rlp:"optional"` //replaces the signature in resumed sessions
 	Rest         []rlp.RawValue `rlp:"tail"`
 }
 
 type Client struct {
 	ephemeralKemPrivateKey  *keyestablishmentalgorithm.PrivateKey
+	kems                    []string
+	kemShares               map[string]*oqs.KeyEncapsulation
 	kem                     *oqs.KeyEncapsulation
+	kemName                 string
 	kemCipherText           []byte //kemCipherTextLength
 	kemSharedSecret         []byte //kemSecretLength
+	x25519PrivateKey        []byte
+	ecdhSharedSecret        []byte
+	legacyKeyExchange       bool //the server only used the legacy KEM
 	Nonce                   uint
 	clientSigningPrivateKey *signaturealgorithm.PrivateKey
 	serverSigningPublicKey  *signaturealgorithm.PublicKey
 
+	versions            []uint
+	aeads               []string
+	signatureAlgorithms []string
+	version             uint
+	aead                string
+	sigAlg              signaturealgorithm.SignatureAlgorithm
+
+	resumption *Resumption
+	session    *clientSession //offered in the client hello
+	resumed    bool
+
 	clientHelloMessage  *clientHelloMessage
 	serverHelloMessage  *serverHelloMessage
 	serverVerifyMessage *serverVerifyMessage
@@ -65,6 +93,8 @@ type Client struct {
 	handshakeDone bool
 	mutex         sync.Mutex
 
+	keyUpdate keyUpdateSchedule
+
 	context string
 }
 
@@ -77,6 +107,10 @@ func NewClient(conn io.ReadWriter, clientSigningPrivateKey *signaturealgorithm.P
 		conn:                    conn,
 		clientSigningPrivateKey: clientSigningPrivateKey,
 		serverSigningPublicKey:  serverSigningPublicKey,
+		kems:                    SupportedKems,
+		versions:                SupportedVersions,
+		aeads:                   SupportedAeads,
+		signatureAlgorithms:     SupportedSignatureAlgorithms,
 	}
 
 	client.serializer = NewRlpxSerializer()
@@ -85,6 +119,7 @@ func NewClient(conn io.ReadWriter, clientSigningPrivateKey *signaturealgorithm.P
 
 	client.serverSeqNumApplication = 1
 	client.clientSeqNumApplication = 1
+	client.keyUpdate = newKeyUpdateSchedule()
 	client.serializer.SetContext("client " + context)
 	client.context = context
 
@@ -99,6 +134,40 @@ func (c *Client) SetServerSigningPublicKey(serverSigningPublicKey *signaturealgo
 	c.serverSigningPublicKey = serverSigningPublicKey
 }
 
+// SetKems sets the KEMs the client offers key shares for, in order of preference.
+func (c *Client) SetKems(kems []string) {
+	c.kems = kems
+}
+
+// SetVersions sets the handshake versions the client offers, highest first.
+func (c *Client) SetVersions(versions []uint) {
+	c.versions = versions
+}
+
+// SetAeads sets the AEADs the client offers, in order of preference.
+func (c *Client) SetAeads(aeads []string) {
+	c.aeads = aeads
+}
+
+// SetResumption sets the state used to resume sessions with servers the client
+// connected to before. Without it, every handshake is a full one.
+func (c *Client) SetResumption(resumption *Resumption) {
+	c.resumption = resumption
+}
+
+// Resumed reports whether the handshake resumed an earlier session.
+func (c *Client) Resumed() bool {
+	return c.resumed
+}
+
+// KeyExchange returns the name of the key exchange negotiated in the handshake.
+func (c *Client) KeyExchange() string {
+	if c.kemName == "" {
+		return ""
+	}
+	return keyExchangeName(c.kemName, c.legacyKeyExchange)
+}
+
 func (c *Client) PerformHandshake() error {
 
 	c.mutex.Lock()
@@ -108,17 +177,23 @@ func (c *Client) PerformHandshake() error {
 		return errors.New("Handshake already done")
 	}
 
-	//Initialize KEM
-	kem := oqs.KeyEncapsulation{}
-
-	err := kem.Init(oqs.KemName, nil)
-	if err != nil {
-		return err
+	//Initialize a KEM for every key share offered
+	kemNames := enabledKems(c.kems)
+	if len(kemNames) == 0 {
+		return errNoKemEnabled
+	}
+	c.kemShares = make(map[string]*oqs.KeyEncapsulation, len(kemNames))
+	for _, kemName := range kemNames {
+		kem := oqs.KeyEncapsulation{}
+		err := kem.Init(kemName, nil)
+		if err != nil {
+			return err
+		}
+		c.kemShares[kemName] = &kem
 	}
-	c.kem = &kem
 
 	//Make client hello message
-	err = c.makeClientHello()
+	err := c.makeClientHello(kemNames)
 	if err != nil {
 		return err
 	}
@@ -162,7 +237,14 @@ func (c *Client) PerformHandshake() error {
 	c.transcript = transcript
 
 	//Create the secrets
-	secret, err := NewSessionSecret(transcriptHash, c.kemSharedSecret[:])
+	var psk []byte
+	if c.resumed {
+		psk = c.session.psk
+	}
+	secret, err := newSessionSecret(psk, transcriptHash, c.kemSharedSecret[:], c.ecdhSharedSecret, c.aead)
+	if err != nil {
+		return err
+	}
 	c.secret = *secret
 
 	//Receive the server verify message
@@ -173,25 +255,36 @@ func (c *Client) PerformHandshake() error {
 		return err
 	}
 
-	//Verify the signature to make sure the server is what it is claiming to be
-	serverPubKeyDataLocal, err := cryptobase.SigAlg.SerializePublicKey(c.serverSigningPublicKey)
-	if err != nil {
-		return err
-	}
+	if c.resumed {
+		//Only the server that issued the ticket knows the PSK
+		finished, err := c.secret.serverFinished(transcriptHash)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal(finished, serverVerifyMessage.Finished) {
+			return errors.New("server's finished verification failed")
+		}
+	} else {
+		//Verify the signature to make sure the server is what it is claiming to be
+		serverPubKeyDataLocal, err := c.sigAlg.SerializePublicKey(c.serverSigningPublicKey)
+		if err != nil {
+			return err
+		}
 
-	//Recover the public key from the signature
-	serverPubKeyDataRemote, err := cryptobase.SigAlg.PublicKeyBytesFromSignature(transcriptHash, serverVerifyMessage.Signature[:serverVerifyMessage.SignatureLen])
-	if err != nil {
-		return err
-	}
+		//Recover the public key from the signature
+		serverPubKeyDataRemote, err := c.sigAlg.PublicKeyBytesFromSignature(transcriptHash, serverVerifyMessage.Signature[:serverVerifyMessage.SignatureLen])
+		if err != nil {
+			return err
+		}
 
-	//Validate that expected public key and remote public key are the same (additional sanity check)
-	if !bytes.Equal(serverPubKeyDataLocal, serverPubKeyDataRemote) {
-		return errors.New("Public key mismatch")
-	}
+		//Validate that expected public key and remote public key are the same (additional sanity check)
+		if !bytes.Equal(serverPubKeyDataLocal, serverPubKeyDataRemote) {
+			return errors.New("Public key mismatch")
+		}
 
-	if !cryptobase.SigAlg.Verify(serverPubKeyDataLocal, transcriptHash, serverVerifyMessage.Signature[:serverVerifyMessage.SignatureLen]) {
-		return errors.New("server's signature verification failed")
+		if !c.sigAlg.Verify(serverPubKeyDataLocal, transcriptHash, serverVerifyMessage.Signature[:serverVerifyMessage.SignatureLen]) {
+			return errors.New("server's signature verification failed")
+		}
 	}
 
 	//Create the transcript
@@ -205,17 +298,24 @@ func (c *Client) PerformHandshake() error {
 	c.transcript = transcript
 	c.serverVerifyMessage = serverVerifyMessage
 
-	//Sign the transcript hash
-	signature, err := cryptobase.SigAlg.Sign(transcriptHash, c.clientSigningPrivateKey)
-	if err != nil {
-		return err
-	}
-
-	//Serialize the server verify message
+	//Serialize the client verify message
 	clientVerifyMessage := new(clientVerifyMessage)
-	clientVerifyMessage.Signature = make([]byte, cryptobase.SigAlg.SignatureWithPublicKeyLength())
-	copy(clientVerifyMessage.Signature[:], signature)
-	clientVerifyMessage.SignatureLen = uint(len(signature))
+	if c.resumed {
+		clientVerifyMessage.Finished, err = c.secret.clientFinished(transcriptHash)
+		if err != nil {
+			return err
+		}
+	} else {
+		//Sign the transcript hash
+		signature, err := c.sigAlg.Sign(transcriptHash, c.clientSigningPrivateKey)
+		if err != nil {
+			return err
+		}
+
+		clientVerifyMessage.Signature = make([]byte, c.sigAlg.SignatureWithPublicKeyLength())
+		copy(clientVerifyMessage.Signature[:], signature)
+		clientVerifyMessage.SignatureLen = uint(len(signature))
+	}
 	c.clientVerifyMessage = clientVerifyMessage
 
 	clientVerifyPacket, err := c.serializer.Serialize(clientVerifyMessage)
@@ -243,22 +343,59 @@ func (c *Client) PerformHandshake() error {
 	}
 
 	c.handshakeDone = true
+	c.keyUpdate.reset()
+
+	//Legacy peers don't know key update records
+	if c.legacyKeyExchange {
+		c.keyUpdate.recordLimit, c.keyUpdate.interval = 0, 0
+	}
 
 	return nil
 }
 
-func (c *Client) makeClientHello() error {
+func (c *Client) makeClientHello(kemNames []string) error {
 	clientHelloMessage := new(clientHelloMessage)
-	clientHelloMessage.Version = 1
+	clientHelloMessage.Version = handshakeVersion1
+	if highestVersion(c.versions) >= handshakeVersion2 {
+		clientHelloMessage.Versions = c.versions
+		clientHelloMessage.Aeads = c.aeads
+		clientHelloMessage.SignatureAlgorithms = c.signatureAlgorithms
+	}
+
+	//Offer a ticket from an earlier session with this server, if there is one
+	if c.resumption != nil && containsVersion(c.versions, handshakeVersion3) {
+		if session, ok := c.resumption.takeSession(c.serverSigningPublicKey.PubData); ok {
+			c.session = &session
+			clientHelloMessage.SessionTicket = session.ticket
+		}
+	}
 
-	//Generate an ephemeral kem keypair
-	kemPrivateKey, err := c.kem.GenerateKemKeyPair()
+	//Generate an ephemeral kem keypair for every offered KEM
+	for _, kemName := range kemNames {
+		kem := c.kemShares[kemName]
+		kemPrivateKey, err := kem.GenerateKemKeyPair()
+		if err != nil {
+			return err
+		}
+		keyShare := kemKeyShare{
+			KemName:   kemName,
+			PublicKey: make([]byte, kem.AlgDetails.LengthPublicKey),
+		}
+		kemPrivateKey.N.FillBytes(keyShare.PublicKey)
+		if kemName == legacyKemName {
+			clientHelloMessage.ClientKemPublicKey = keyShare.PublicKey
+			continue
+		}
+		clientHelloMessage.KeyShares = append(clientHelloMessage.KeyShares, keyShare)
+	}
+
+	//Generate an ephemeral X25519 keypair
+	x25519PrivateKey, x25519PublicKey, err := generateX25519Key()
 	if err != nil {
 		return err
 	}
-	c.ephemeralKemPrivateKey = kemPrivateKey
-	clientHelloMessage.ClientKemPublicKey = make([]byte, c.kem.AlgDetails.LengthPublicKey)
-	copy(clientHelloMessage.ClientKemPublicKey[:], c.ephemeralKemPrivateKey.N.Bytes())
+	c.x25519PrivateKey = x25519PrivateKey
+	clientHelloMessage.ClientX25519PublicKey = x25519PublicKey
 
 	// Generate ClientRandomData
 	randomData := make([]byte, shaLength)
@@ -274,13 +411,33 @@ func (c *Client) makeClientHello() error {
 }
 
 func (c *Client) Cleanup() {
-	if c.kem != nil {
-		c.kem.Clean()
+	for _, kem := range c.kemShares {
+		kem.Clean()
 	}
 }
 
 func (c *Client) handleServerHello() error {
 
+	err := c.handleNegotiation()
+	if err != nil {
+		return err
+	}
+
+	//A legacy server doesn't name the KEM, and only uses the legacy one
+	kemName := c.serverHelloMessage.KemName
+	if kemName == "" {
+		kemName = legacyKemName
+		c.legacyKeyExchange = true
+	}
+
+	//Only a KEM the client offered a key share for may be selected
+	kem, ok := c.kemShares[kemName]
+	if !ok {
+		return errUnexpectedKem
+	}
+	c.kem = kem
+	c.kemName = kemName
+
 	sharedSecret, err := c.kem.DecapsulateSecret(c.serverHelloMessage.CipherText[:])
 	if err != nil {
 		return err
@@ -289,6 +446,53 @@ func (c *Client) handleServerHello() error {
 	c.kemSharedSecret = make([]byte, c.kem.AlgDetails.LengthSharedSecret)
 	copy(c.kemSharedSecret[:], sharedSecret[:])
 
+	if c.legacyKeyExchange {
+		if len(c.serverHelloMessage.ServerX25519PublicKey) != 0 || c.serverHelloMessage.Version != handshakeVersion1 {
+			return errUnexpectedSelection
+		}
+		return nil
+	}
+
+	c.ecdhSharedSecret, err = x25519SharedSecret(c.x25519PrivateKey, c.serverHelloMessage.ServerX25519PublicKey)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// handleNegotiation checks that the version, AEAD and signature algorithm the server
+// selected were offered. A MITM removing options from the client hello is detected
+// later, since the handshake keys and signatures are bound to the transcript.
+func (c *Client) handleNegotiation() error {
+	serverHelloMessage := c.serverHelloMessage
+	if !containsVersion(c.versions, serverHelloMessage.Version) {
+		return errUnexpectedSelection
+	}
+
+	aead, signatureAlgorithmName := version1Aead, version1SignatureAlgorithm
+	if serverHelloMessage.Version >= handshakeVersion2 {
+		aead, signatureAlgorithmName = serverHelloMessage.Aead, serverHelloMessage.SignatureAlgorithm
+	}
+	if !containsName(c.aeads, aead) || !containsName(c.signatureAlgorithms, signatureAlgorithmName) {
+		return errUnexpectedSelection
+	}
+
+	sigAlg, err := signatureAlgorithm(signatureAlgorithmName)
+	if err != nil {
+		return err
+	}
+
+	//The server can only resume the session the client offered a ticket for
+	if serverHelloMessage.Resumed && (serverHelloMessage.Version < handshakeVersion3 || c.session == nil) {
+		return errUnexpectedSelection
+	}
+
+	c.version = serverHelloMessage.Version
+	c.aead = aead
+	c.sigAlg = sigAlg
+	c.resumed = serverHelloMessage.Resumed
+
 	return nil
 }
 
@@ -311,6 +515,11 @@ func (c *Client) WriteEncrypted(data []byte, context uint64, packetType PacketTy
 	if packetType == PacketTypeApplicationData {
 		if c.handshakeDone != true {
 		}
+		if c.keyUpdate.due(c.clientSeqNumApplication) {
+			if err := c.UpdateKeys(false); err != nil {
+				return err
+			}
+		}
 	}
 
 	additionalData := make([]byte, shaLength)
@@ -370,7 +579,38 @@ func (c *Client) WriteEncrypted(data []byte, context uint64, packetType PacketTy
 	return nil
 }
 
+// ReadAndDecrypt reads the next record of the given type. Key update and session
+// ticket records received while reading application data are processed transparently.
 func (c *Client) ReadAndDecrypt(packetType PacketType) (*DataPacket, error) {
+	for {
+		dataPacket, err := c.readRecord(packetType)
+		if err != nil {
+			return nil, err
+		}
+
+		if packetType == PacketTypeApplicationData && dataPacket.packetType == PacketTypeKeyUpdate {
+			if err = c.handleKeyUpdate(dataPacket); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if packetType == PacketTypeApplicationData && dataPacket.packetType == PacketTypeNewSessionTicket {
+			if err = c.handleNewSessionTicket(dataPacket); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if dataPacket.packetType != packetType {
+			return nil, errors.New("packetType mismatch")
+		}
+
+		return dataPacket, nil
+	}
+}
+
+func (c *Client) readRecord(packetType PacketType) (*DataPacket, error) {
 	if packetType == PacketTypeApplicationData {
 
 	}
@@ -418,9 +658,6 @@ func (c *Client) ReadAndDecrypt(packetType PacketType) (*DataPacket, error) {
 		return nil, err
 	}
 
-	if dataPacket.packetType != packetType {
-		return nil, errors.New("packetType mismatch")
-	}
 	dataPacket.context = header.Context
 
 	if packetType == PacketTypeHandshake {
@@ -432,6 +669,83 @@ func (c *Client) ReadAndDecrypt(packetType PacketType) (*DataPacket, error) {
 	return dataPacket, nil
 }
 
+// UpdateKeys sends a key update record and ratchets the client's sending keys
+// forward. If requestUpdate is set, the server is asked to do the same with its
+// own sending keys. It must not be called concurrently with WriteEncrypted, and
+// fails if the peer predates key updates.
+func (c *Client) UpdateKeys(requestUpdate bool) error {
+	if c.legacyKeyExchange {
+		return errKeyUpdateUnsupported
+	}
+	keyUpdatePacket, err := c.serializer.Serialize(&keyUpdateMessage{UpdateRequested: requestUpdate})
+	if err != nil {
+		return err
+	}
+
+	err = c.WriteEncrypted(keyUpdatePacket, 0, PacketTypeKeyUpdate)
+	if err != nil {
+		return err
+	}
+
+	err = c.secret.UpdateClientApplicationSecrets()
+	if err != nil {
+		return err
+	}
+	c.clientSeqNumApplication = 1
+	c.keyUpdate.reset()
+
+	return nil
+}
+
+// SetKeyUpdateLimits sets after how many application records, or how much time,
+// the client automatically updates its sending keys. Zero disables a limit.
+func (c *Client) SetKeyUpdateLimits(records uint, interval time.Duration) {
+	c.keyUpdate.recordLimit = records
+	c.keyUpdate.interval = interval
+}
+
+func (c *Client) handleKeyUpdate(dataPacket *DataPacket) error {
+	keyUpdateMessage := new(keyUpdateMessage)
+	_, err := c.serializer.Deserialize(keyUpdateMessage, bytes.NewReader(dataPacket.fragment))
+	if err != nil {
+		return err
+	}
+
+	err = c.secret.UpdateServerApplicationSecrets()
+	if err != nil {
+		return err
+	}
+	c.serverSeqNumApplication = 1
+
+	if keyUpdateMessage.UpdateRequested {
+		c.keyUpdate.request()
+	}
+
+	return nil
+}
+
+// handleNewSessionTicket stores a ticket sent by the server, to resume the session
+// on the next connection to it.
+func (c *Client) handleNewSessionTicket(dataPacket *DataPacket) error {
+	if c.version < handshakeVersion3 {
+		return errors.New("unexpected session ticket")
+	}
+
+	newSessionTicketMessage := new(newSessionTicketMessage)
+	_, err := c.serializer.Deserialize(newSessionTicketMessage, bytes.NewReader(dataPacket.fragment))
+	if err != nil {
+		return err
+	}
+
+	if c.resumption != nil {
+		lifetime := time.Duration(newSessionTicketMessage.Lifetime) * time.Second
+		c.resumption.storeSession(c.serverSigningPublicKey.PubData, newSessionTicketMessage.Ticket, c.secret.resumptionSecret, lifetime)
+	}
+
+	return nil
+}
+
 func (c *Client) InitWithSecrets(secret SessionSecret) {
 	c.secret = secret
+	c.keyUpdate.reset()
 }
@@ -26,12 +26,20 @@ const (
 type PacketType byte
 
 const (
-	PacketTypeHandshake       PacketType = 21
-	PacketTypeApplicationData PacketType = 23
-	ReadTimeout                          = time.Second * 10
-	WriteTimeout                         = time.Second * 20
+	PacketTypeHandshake        PacketType = 21
+	PacketTypeApplicationData  PacketType = 23
+	PacketTypeKeyUpdate        PacketType = 24
+	PacketTypeNewSessionTicket PacketType = 25
+	ReadTimeout                           = time.Second * 10
+	WriteTimeout                          = time.Second * 20
 )
 
+// maxSeqNum is the highest record sequence number. A record can't be sent or
+// accepted with it, since the nonce would repeat once the counter wraps.
+const maxSeqNum = ^uint(0)
+
+var errSeqNumExhausted = errors.New("record sequence number exhausted")
+
 type DataPacket struct {
 	packetType PacketType
 	seqNum     uint
@@ -73,6 +81,9 @@ func CalculateNonce(recordCount uint, input []byte) []byte {
 }
 
 func Encrypt(cipher1 cipher.AEAD, fragment []byte, additionalData []byte, packetType PacketType, handshakeIv []byte, seqNum uint) (encrypted []byte, err error) {
+	if seqNum == maxSeqNum {
+		return nil, errSeqNumExhausted
+	}
 	dataLen := len(fragment)
 
 	nonce := CalculateNonce(seqNum, handshakeIv)
@@ -97,6 +108,9 @@ func Encrypt(cipher1 cipher.AEAD, fragment []byte, additionalData []byte, packet
 }
 
 func Decrypt(cipher1 cipher.AEAD, encryptedData []byte, additionalData []byte, packetType PacketType, iv []byte, seqNum uint) (*DataPacket, error) {
+	if seqNum == maxSeqNum {
+		return nil, errSeqNumExhausted
+	}
 	if len(encryptedData) < cipher1.Overhead() {
 		return nil, errors.New("invalid data")
 	}
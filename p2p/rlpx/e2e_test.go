@@ -3,10 +3,17 @@ package rlpx
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/oqs"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"github.com/DogeProtocol/dp/p2p/simulations/pipes"
+	"github.com/DogeProtocol/dp/rlp"
+	"io"
 	"math/rand"
+	"net"
 	"testing"
 	"time"
 )
@@ -267,3 +274,680 @@ func Test_e2eHandshake(t *testing.T) {
 		}
 	}
 }
+
+// runHandshake performs the handshake on both ends and returns their errors. The
+// connections are closed if either side fails, so that the other one doesn't block.
+func runHandshake(client *Client, server *Server, clientConn, serverConn net.Conn) (clientErr, serverErr error) {
+	serverDone := make(chan error, 1)
+	go func() {
+		err := server.PerformHandshake()
+		if err != nil {
+			serverConn.Close()
+		}
+		serverDone <- err
+	}()
+	clientErr = client.PerformHandshake()
+	if clientErr != nil {
+		clientConn.Close()
+	}
+	return clientErr, <-serverDone
+}
+
+func newTestPeers(t *testing.T, clientConn, serverConn net.Conn) (*Client, *Server) {
+	serverSigningKey, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	clientConn.SetDeadline(deadline)
+	serverConn.SetDeadline(deadline)
+	return NewClient(clientConn, clientKey, &serverSigningKey.PublicKey, "test"), NewServer(serverConn, serverSigningKey, "test")
+}
+
+func Test_MixedVersions(t *testing.T) {
+	tests := []struct {
+		clientVersions, serverVersions []uint
+		want                           uint
+	}{
+		{SupportedVersions, SupportedVersions, handshakeVersion3},
+		{[]uint{handshakeVersion1}, SupportedVersions, handshakeVersion1},
+		{SupportedVersions, []uint{handshakeVersion1}, handshakeVersion1},
+		{[]uint{handshakeVersion1}, []uint{handshakeVersion1}, handshakeVersion1},
+		{[]uint{handshakeVersion2}, []uint{handshakeVersion1}, 0},
+		{[]uint{handshakeVersion1}, []uint{handshakeVersion2}, 0},
+	}
+	for i, test := range tests {
+		clientConn, serverConn, err := pipes.TCPPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, server := newTestPeers(t, clientConn, serverConn)
+		client.SetVersions(test.clientVersions)
+		server.SetVersions(test.serverVersions)
+
+		clientErr, serverErr := runHandshake(client, server, clientConn, serverConn)
+		if test.want == 0 {
+			if clientErr == nil || serverErr == nil {
+				t.Errorf("test %d: handshake succeeded without a common version", i)
+			}
+			clientConn.Close()
+			serverConn.Close()
+			continue
+		}
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("test %d: handshake failed: client %v, server %v", i, clientErr, serverErr)
+		}
+		if client.version != test.want || server.version != test.want {
+			t.Errorf("test %d: negotiated client version %d, server version %d, want %d", i, client.version, server.version, test.want)
+		}
+		if client.aead != server.aead {
+			t.Errorf("test %d: AEAD mismatch: client %s, server %s", i, client.aead, server.aead)
+		}
+
+		payload := []byte("ping")
+		if err := client.WriteEncrypted(payload, 1, PacketTypeApplicationData); err != nil {
+			t.Fatalf("test %d: write failed: %v", i, err)
+		}
+		dataPacket, err := server.ReadAndDecrypt(PacketTypeApplicationData)
+		if err != nil {
+			t.Fatalf("test %d: read failed: %v", i, err)
+		}
+		if !bytes.Equal(dataPacket.fragment, payload) {
+			t.Errorf("test %d: wrong payload %x", i, dataPacket.fragment)
+		}
+		clientConn.Close()
+		serverConn.Close()
+	}
+}
+
+func Test_AeadNegotiation(t *testing.T) {
+	tests := []struct {
+		clientAeads, serverAeads []string
+		want                     string
+	}{
+		{SupportedAeads, SupportedAeads, AeadAes256Gcm},
+		{[]string{AeadChaCha20Poly1305, AeadAes256Gcm}, SupportedAeads, AeadAes256Gcm},
+		{[]string{AeadChaCha20Poly1305}, SupportedAeads, AeadChaCha20Poly1305},
+		{SupportedAeads, []string{AeadChaCha20Poly1305}, AeadChaCha20Poly1305},
+	}
+	for i, test := range tests {
+		clientConn, serverConn, err := pipes.TCPPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, server := newTestPeers(t, clientConn, serverConn)
+		client.SetAeads(test.clientAeads)
+		server.SetAeads(test.serverAeads)
+
+		clientErr, serverErr := runHandshake(client, server, clientConn, serverConn)
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("test %d: handshake failed: client %v, server %v", i, clientErr, serverErr)
+		}
+		if client.aead != test.want || server.aead != test.want {
+			t.Errorf("test %d: negotiated client AEAD %s, server AEAD %s, want %s", i, client.aead, server.aead, test.want)
+		}
+		clientConn.Close()
+		serverConn.Close()
+	}
+}
+
+// clientHelloMessageV1 is the client hello as sent by version 1 implementations.
+type clientHelloMessageV1 struct {
+	ClientKemPublicKey    []byte
+	ClientHelloRandomData [shaLen]byte
+	Version               uint
+	KeyShares             []kemKeyShare  `rlp:"optional"`
+	ClientX25519PublicKey []byte         `rlp:"optional"`
+	Rest                  []rlp.RawValue `rlp:"tail"`
+}
+
+func Test_ClientHelloVersion1Encoding(t *testing.T) {
+	for _, test := range []struct {
+		versions []uint
+		wantRest int
+	}{
+		{[]uint{handshakeVersion1}, 0},
+		{SupportedVersions, 3},
+	} {
+		client := NewClient(nil, nil, nil, "test")
+		client.SetKems([]string{oqs.Kyber512KemName})
+		client.SetVersions(test.versions)
+		client.kemShares = make(map[string]*oqs.KeyEncapsulation)
+		kem := oqs.KeyEncapsulation{}
+		if err := kem.Init(oqs.Kyber512KemName, nil); err != nil {
+			t.Fatal(err)
+		}
+		client.kemShares[oqs.Kyber512KemName] = &kem
+		if err := client.makeClientHello([]string{oqs.Kyber512KemName}); err != nil {
+			t.Fatal(err)
+		}
+		client.Cleanup()
+
+		enc, err := rlp.EncodeToBytes(client.clientHelloMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A version 1 peer must be able to decode the hello, and gets the
+		// negotiation lists as extra elements.
+		var hello clientHelloMessageV1
+		if err := rlp.DecodeBytes(enc, &hello); err != nil {
+			t.Fatalf("versions %v: version 1 decoding failed: %v", test.versions, err)
+		}
+		if hello.Version != handshakeVersion1 || len(hello.Rest) != test.wantRest {
+			t.Errorf("versions %v: got version %d with %d extra elements, want %d", test.versions, hello.Version, len(hello.Rest), test.wantRest)
+		}
+	}
+}
+
+// The messages of the original handshake, which exchanged a single Kyber512 key
+// and negotiated nothing. Nodes still running it must be able to connect.
+type legacyClientHelloMessage struct {
+	ClientKemPublicKey    []byte
+	ClientHelloRandomData [shaLen]byte
+	Version               uint
+	Rest                  []rlp.RawValue `rlp:"tail"`
+}
+
+type legacyServerHelloMessage struct {
+	CipherText            []byte
+	ServerHelloRandomData [shaLen]byte
+	Version               uint
+	Rest                  []rlp.RawValue `rlp:"tail"`
+}
+
+type legacyVerifyMessage struct {
+	Signature    []byte
+	SignatureLen uint
+	Rest         []rlp.RawValue `rlp:"tail"`
+}
+
+// legacyTranscript concatenates the encodings of the given handshake messages.
+func legacyTranscript(msgs ...interface{}) ([]byte, error) {
+	var transcript []byte
+	for _, msg := range msgs {
+		enc, err := NewRlpxSerializer().SerializeDeterministic(msg, 0)
+		if err != nil {
+			return nil, err
+		}
+		transcript = append(transcript, enc...)
+	}
+	return transcript, nil
+}
+
+// legacyVerify checks the signature of a legacy verify message over the transcript hash.
+func legacyVerify(msg *legacyVerifyMessage, transcriptHash []byte, pub *signaturealgorithm.PublicKey) error {
+	pubData, err := cryptobase.SigAlg.SerializePublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if msg.SignatureLen > uint(len(msg.Signature)) || !cryptobase.SigAlg.Verify(pubData, transcriptHash, msg.Signature[:msg.SignatureLen]) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// legacySign makes a legacy verify message signing the transcript hash.
+func legacySign(transcriptHash []byte, key *signaturealgorithm.PrivateKey) (*legacyVerifyMessage, error) {
+	signature, err := cryptobase.SigAlg.Sign(transcriptHash, key)
+	if err != nil {
+		return nil, err
+	}
+	msg := &legacyVerifyMessage{
+		Signature:    make([]byte, cryptobase.SigAlg.SignatureWithPublicKeyLength()),
+		SignatureLen: uint(len(signature)),
+	}
+	copy(msg.Signature, signature)
+	return msg, nil
+}
+
+// legacyClientHandshake runs the client side of the original handshake, using the
+// record layer of client.
+func legacyClientHandshake(client *Client) error {
+	kem := oqs.KeyEncapsulation{}
+	if err := kem.Init(oqs.Kyber512KemName, nil); err != nil {
+		return err
+	}
+	defer kem.Clean()
+	kemPrivateKey, err := kem.GenerateKemKeyPair()
+	if err != nil {
+		return err
+	}
+	hello := &legacyClientHelloMessage{
+		ClientKemPublicKey: make([]byte, kem.AlgDetails.LengthPublicKey),
+		Version:            1,
+	}
+	copy(hello.ClientKemPublicKey, kemPrivateKey.N.Bytes())
+	rand.Read(hello.ClientHelloRandomData[:])
+
+	packet, err := client.serializer.Serialize(hello)
+	if err != nil {
+		return err
+	}
+	if _, err := client.conn.Write(packet); err != nil {
+		return err
+	}
+	serverHello := new(legacyServerHelloMessage)
+	if _, err := client.serializer.Deserialize(serverHello, client.conn); err != nil {
+		return err
+	}
+	if len(serverHello.Rest) != 0 {
+		return fmt.Errorf("server hello has %d extra elements", len(serverHello.Rest))
+	}
+	sharedSecret, err := kem.DecapsulateSecret(serverHello.CipherText)
+	if err != nil {
+		return err
+	}
+
+	transcript, err := legacyTranscript(hello, serverHello)
+	if err != nil {
+		return err
+	}
+	secret, err := NewSessionSecret(crypto.Keccak256(transcript), sharedSecret, nil, AeadAes256Gcm)
+	if err != nil {
+		return err
+	}
+	client.secret = *secret
+
+	serverVerify := new(legacyVerifyMessage)
+	if err := client.ReadAndDecryptMessage(serverVerify, PacketTypeHandshake); err != nil {
+		return err
+	}
+	if err := legacyVerify(serverVerify, crypto.Keccak256(transcript), client.serverSigningPublicKey); err != nil {
+		return err
+	}
+	verifyTranscript, err := legacyTranscript(serverVerify)
+	if err != nil {
+		return err
+	}
+	transcript = append(transcript, verifyTranscript...)
+
+	clientVerify, err := legacySign(crypto.Keccak256(transcript), client.clientSigningPrivateKey)
+	if err != nil {
+		return err
+	}
+	packet, err = client.serializer.Serialize(clientVerify)
+	if err != nil {
+		return err
+	}
+	if err := client.WriteEncrypted(packet, 0, PacketTypeHandshake); err != nil {
+		return err
+	}
+	verifyTranscript, err = legacyTranscript(clientVerify)
+	if err != nil {
+		return err
+	}
+	transcript = append(transcript, verifyTranscript...)
+	client.handshakeDone = true
+	client.SetKeyUpdateLimits(0, 0)
+	return client.secret.CreateApplicationSecrets(crypto.Keccak256(transcript))
+}
+
+// legacyServerHandshake runs the server side of the original handshake, using the
+// record layer of server.
+func legacyServerHandshake(server *Server, clientKey *signaturealgorithm.PublicKey) error {
+	hello := new(legacyClientHelloMessage)
+	if _, err := server.serializer.Deserialize(hello, server.conn); err != nil {
+		return err
+	}
+	if hello.Version != 1 {
+		return fmt.Errorf("client hello version %d", hello.Version)
+	}
+	kem := oqs.KeyEncapsulation{}
+	if err := kem.Init(oqs.Kyber512KemName, nil); err != nil {
+		return err
+	}
+	defer kem.Clean()
+	cipherText, sharedSecret, err := kem.EncapsulateSecret(hello.ClientKemPublicKey)
+	if err != nil {
+		return err
+	}
+	serverHello := &legacyServerHelloMessage{
+		CipherText: make([]byte, kem.AlgDetails.LengthCiphertext),
+		Version:    1,
+	}
+	copy(serverHello.CipherText, cipherText)
+	rand.Read(serverHello.ServerHelloRandomData[:])
+
+	packet, err := server.serializer.Serialize(serverHello)
+	if err != nil {
+		return err
+	}
+	if _, err := server.conn.Write(packet); err != nil {
+		return err
+	}
+
+	transcript, err := legacyTranscript(hello, serverHello)
+	if err != nil {
+		return err
+	}
+	secret, err := NewSessionSecret(crypto.Keccak256(transcript), sharedSecret, nil, AeadAes256Gcm)
+	if err != nil {
+		return err
+	}
+	server.secret = *secret
+
+	serverVerify, err := legacySign(crypto.Keccak256(transcript), server.serverSigningPrivateKey)
+	if err != nil {
+		return err
+	}
+	packet, err = server.serializer.Serialize(serverVerify)
+	if err != nil {
+		return err
+	}
+	if err := server.WriteEncrypted(packet, 0, PacketTypeHandshake); err != nil {
+		return err
+	}
+	verifyTranscript, err := legacyTranscript(serverVerify)
+	if err != nil {
+		return err
+	}
+	transcript = append(transcript, verifyTranscript...)
+
+	clientVerify := new(legacyVerifyMessage)
+	if err := server.ReadAndDecryptMessage(clientVerify, PacketTypeHandshake); err != nil {
+		return err
+	}
+	if err := legacyVerify(clientVerify, crypto.Keccak256(transcript), clientKey); err != nil {
+		return err
+	}
+	verifyTranscript, err = legacyTranscript(clientVerify)
+	if err != nil {
+		return err
+	}
+	transcript = append(transcript, verifyTranscript...)
+	server.handshakeDone = true
+	server.SetKeyUpdateLimits(0, 0)
+	return server.secret.CreateApplicationSecrets(crypto.Keccak256(transcript))
+}
+
+// exchangePings sends an application record each way.
+func exchangePings(client *Client, server *Server) error {
+	if err := client.WriteEncrypted([]byte("ping"), 1, PacketTypeApplicationData); err != nil {
+		return err
+	}
+	if dataPacket, err := server.ReadAndDecrypt(PacketTypeApplicationData); err != nil {
+		return err
+	} else if string(dataPacket.fragment) != "ping" {
+		return fmt.Errorf("server received %q", dataPacket.fragment)
+	}
+	if err := server.WriteEncrypted([]byte("pong"), 1, PacketTypeApplicationData); err != nil {
+		return err
+	}
+	if dataPacket, err := client.ReadAndDecrypt(PacketTypeApplicationData); err != nil {
+		return err
+	} else if string(dataPacket.fragment) != "pong" {
+		return fmt.Errorf("client received %q", dataPacket.fragment)
+	}
+	return nil
+}
+
+// Test_LegacyClient checks that a server accepts clients running the original
+// handshake, and falls back to the legacy key exchange for them.
+func Test_LegacyClient(t *testing.T) {
+	clientConn, serverConn, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+	defer serverConn.Close()
+	client, server := newTestPeers(t, clientConn, serverConn)
+	resumption, err := NewResumption(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetResumption(resumption)
+
+	serverDone := make(chan error, 1)
+	go func() { serverDone <- server.PerformHandshake() }()
+	if err := legacyClientHandshake(client); err != nil {
+		t.Fatalf("legacy client handshake failed: %v", err)
+	}
+	if err := <-serverDone; err != nil {
+		t.Fatalf("server handshake failed: %v", err)
+	}
+	if kx := server.KeyExchange(); kx != oqs.Kyber512KemName {
+		t.Errorf("server negotiated key exchange %q, want %q", kx, oqs.Kyber512KemName)
+	}
+	if server.version != handshakeVersion1 || server.aead != AeadAes256Gcm {
+		t.Errorf("server negotiated version %d with %s, want version 1 with %s", server.version, server.aead, AeadAes256Gcm)
+	}
+	if err := server.UpdateKeys(false); err != errKeyUpdateUnsupported {
+		t.Errorf("key update: have %v, want %v", err, errKeyUpdateUnsupported)
+	}
+	if err := exchangePings(client, server); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Test_LegacyServer checks that a client connects to servers running the original
+// handshake, and falls back to the legacy key exchange with them.
+func Test_LegacyServer(t *testing.T) {
+	clientConn, serverConn, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+	defer serverConn.Close()
+	client, server := newTestPeers(t, clientConn, serverConn)
+	resumption, err := NewResumption(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetResumption(resumption)
+
+	serverDone := make(chan error, 1)
+	go func() { serverDone <- legacyServerHandshake(server, &client.clientSigningPrivateKey.PublicKey) }()
+	if err := client.PerformHandshake(); err != nil {
+		t.Fatalf("client handshake failed: %v", err)
+	}
+	if err := <-serverDone; err != nil {
+		t.Fatalf("legacy server handshake failed: %v", err)
+	}
+	if kx := client.KeyExchange(); kx != oqs.Kyber512KemName {
+		t.Errorf("client negotiated key exchange %q, want %q", kx, oqs.Kyber512KemName)
+	}
+	if client.version != handshakeVersion1 || client.aead != AeadAes256Gcm {
+		t.Errorf("client negotiated version %d with %s, want version 1 with %s", client.version, client.aead, AeadAes256Gcm)
+	}
+	if err := client.UpdateKeys(false); err != errKeyUpdateUnsupported {
+		t.Errorf("key update: have %v, want %v", err, errKeyUpdateUnsupported)
+	}
+	if err := exchangePings(client, server); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Test_DowngradeDetected checks that a MITM stripping the version 2 offer from the
+// client hello makes the handshake fail, since the transcripts of both ends differ.
+func Test_DowngradeDetected(t *testing.T) {
+	clientConn, proxyClientConn, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyServerConn, serverConn, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxyClientConn.Close()
+	defer proxyServerConn.Close()
+	client, server := newTestPeers(t, clientConn, serverConn)
+
+	go func() {
+		serializer := NewRlpxSerializer()
+		hello := new(clientHelloMessage)
+		if _, err := serializer.Deserialize(hello, proxyClientConn); err != nil {
+			return
+		}
+		hello.Versions, hello.Aeads, hello.SignatureAlgorithms = nil, nil, nil
+		packet, err := serializer.Serialize(hello)
+		if err != nil {
+			return
+		}
+		if _, err := proxyServerConn.Write(packet); err != nil {
+			return
+		}
+		go func() {
+			io.Copy(proxyServerConn, proxyClientConn)
+			proxyServerConn.Close()
+		}()
+		io.Copy(proxyClientConn, proxyServerConn)
+		proxyClientConn.Close()
+	}()
+
+	clientErr, serverErr := runHandshake(client, server, clientConn, serverConn)
+	if clientErr == nil {
+		t.Fatal("client accepted downgraded handshake")
+	}
+	if serverErr == nil {
+		t.Fatal("server accepted downgraded handshake")
+	}
+	if server.version != handshakeVersion1 {
+		t.Fatalf("server negotiated version %d, want the downgraded version %d", server.version, handshakeVersion1)
+	}
+}
+
+// resumptionHandshake connects a client and server sharing the given resumption
+// state, and exchanges a message each way so that the client receives its ticket.
+func resumptionHandshake(t *testing.T, clientKey, serverKey *signaturealgorithm.PrivateKey, clientResumption, serverResumption *Resumption, serverVersions []uint) (*Client, *Server) {
+	clientConn, serverConn, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+	defer serverConn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	clientConn.SetDeadline(deadline)
+	serverConn.SetDeadline(deadline)
+
+	client := NewClient(clientConn, clientKey, &serverKey.PublicKey, "test")
+	client.SetResumption(clientResumption)
+	server := NewServer(serverConn, serverKey, "test")
+	server.SetResumption(serverResumption)
+	server.SetVersions(serverVersions)
+
+	clientErr, serverErr := runHandshake(client, server, clientConn, serverConn)
+	if clientErr != nil || serverErr != nil {
+		t.Fatalf("handshake failed: client %v, server %v", clientErr, serverErr)
+	}
+
+	if err := server.WriteEncrypted([]byte("ping"), 1, PacketTypeApplicationData); err != nil {
+		t.Fatal(err)
+	}
+	if dataPacket, err := client.ReadAndDecrypt(PacketTypeApplicationData); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(dataPacket.fragment, []byte("ping")) {
+		t.Fatalf("wrong payload %x", dataPacket.fragment)
+	}
+	if err := client.WriteEncrypted([]byte("pong"), 1, PacketTypeApplicationData); err != nil {
+		t.Fatal(err)
+	}
+	if dataPacket, err := server.ReadAndDecrypt(PacketTypeApplicationData); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(dataPacket.fragment, []byte("pong")) {
+		t.Fatalf("wrong payload %x", dataPacket.fragment)
+	}
+	if client.Resumed() != server.Resumed() {
+		t.Fatalf("client resumed %v, server resumed %v", client.Resumed(), server.Resumed())
+	}
+	return client, server
+}
+
+func newResumptionTest(t *testing.T) (clientKey, serverKey *signaturealgorithm.PrivateKey, clientResumption, serverResumption *Resumption) {
+	serverKey, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey, err = cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientResumption, err = NewResumption(TicketLifetime); err != nil {
+		t.Fatal(err)
+	}
+	if serverResumption, err = NewResumption(TicketLifetime); err != nil {
+		t.Fatal(err)
+	}
+	return clientKey, serverKey, clientResumption, serverResumption
+}
+
+func Test_SessionResumption(t *testing.T) {
+	clientKey, serverKey, clientResumption, serverResumption := newResumptionTest(t)
+
+	client, _ := resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, SupportedVersions)
+	if client.Resumed() {
+		t.Fatal("first handshake resumed a session")
+	}
+	if len(clientResumption.sessions) != 1 {
+		t.Fatalf("client stored %d sessions, want 1", len(clientResumption.sessions))
+	}
+
+	client, server := resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, SupportedVersions)
+	if !client.Resumed() {
+		t.Fatal("second handshake didn't resume the session")
+	}
+	if len(client.clientVerifyMessage.Signature) != 0 || len(server.serverVerifyMessage.Signature) != 0 {
+		t.Fatal("resumed handshake was signed")
+	}
+	if !bytes.Equal(server.clientSigningPublicKey.PubData, clientKey.PublicKey.PubData) {
+		t.Fatal("resumed session has the wrong client public key")
+	}
+
+	//Every handshake hands out a new ticket
+	if len(clientResumption.sessions) != 1 {
+		t.Fatalf("client stored %d sessions after resuming, want 1", len(clientResumption.sessions))
+	}
+	client, _ = resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, SupportedVersions)
+	if !client.Resumed() {
+		t.Fatal("third handshake didn't resume the session")
+	}
+}
+
+func Test_SessionTicketReplay(t *testing.T) {
+	clientKey, serverKey, clientResumption, serverResumption := newResumptionTest(t)
+
+	resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, SupportedVersions)
+	session, ok := clientResumption.takeSession(serverKey.PublicKey.PubData)
+	if !ok {
+		t.Fatal("no session stored")
+	}
+
+	for i, want := range []bool{true, false} {
+		clientResumption.storeSession(serverKey.PublicKey.PubData, session.ticket, session.psk, TicketLifetime)
+		client, _ := resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, SupportedVersions)
+		if client.Resumed() != want {
+			t.Fatalf("handshake %d: resumed %v, want %v", i, client.Resumed(), want)
+		}
+	}
+}
+
+func Test_SessionTicketExpired(t *testing.T) {
+	clientKey, serverKey, clientResumption, serverResumption := newResumptionTest(t)
+
+	resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, SupportedVersions)
+	serverResumption.lifetime = -time.Second
+
+	client, _ := resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, SupportedVersions)
+	if client.Resumed() {
+		t.Fatal("handshake resumed a session with an expired ticket")
+	}
+}
+
+func Test_SessionResumptionVersion2(t *testing.T) {
+	clientKey, serverKey, clientResumption, serverResumption := newResumptionTest(t)
+	version2 := []uint{handshakeVersion2, handshakeVersion1}
+
+	resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, version2)
+	if len(clientResumption.sessions) != 0 {
+		t.Fatal("version 2 server issued a session ticket")
+	}
+
+	resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, SupportedVersions)
+	client, _ := resumptionHandshake(t, clientKey, serverKey, clientResumption, serverResumption, version2)
+	if client.Resumed() || client.version != handshakeVersion2 {
+		t.Fatalf("version 2 server resumed a session, negotiated version %d", client.version)
+	}
+}
@@ -0,0 +1,116 @@
+package rlpx
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"github.com/DogeProtocol/dp/crypto/oqs"
+	"github.com/DogeProtocol/dp/rlp"
+	"golang.org/x/crypto/curve25519"
+)
+
+// SupportedKems lists the KEMs the handshake can negotiate, strongest first. The
+// client offers a key share for each of them, and the server picks the first one
+// in its own list that the client offered. The KEM shared secret is always combined
+// with an X25519 one, so the session stays secure unless both are broken.
+var SupportedKems = []string{oqs.MLKEM768KemName, oqs.Sntrup761KemName, oqs.Kyber512KemName}
+
+const x25519KeyExchangeName = "X25519"
+
+// legacyKemName is the KEM of the original handshake, which predates the hybrid
+// key exchange and knows neither key shares nor X25519. A key share for it is sent
+// in the leading field of the client hello, where such peers expect their KEM
+// public key, and a handshake with them uses it alone.
+const legacyKemName = oqs.Kyber512KemName
+
+var (
+	errNoCommonKem      = errors.New("no common key encapsulation mechanism")
+	errUnexpectedKem    = errors.New("server selected a key encapsulation mechanism that wasn't offered")
+	errInvalidX25519Key = errors.New("invalid X25519 public key")
+	errNoKemEnabled     = errors.New("none of the key encapsulation mechanisms is enabled")
+)
+
+// kemKeyShare is an ephemeral KEM public key offered in the client hello.
+type kemKeyShare struct {
+	KemName   string
+	PublicKey []byte
+	Rest      []rlp.RawValue `rlp:"tail"`
+}
+
+// enabledKems filters the given KEMs down to the ones provided by liboqs.
+func enabledKems(kems []string) []string {
+	var enabled []string
+	for _, kemName := range kems {
+		if oqs.IsKEMEnabled(kemName) {
+			enabled = append(enabled, kemName)
+		}
+	}
+	return enabled
+}
+
+// selectKemKeyShare returns the first KEM in preference order that the client
+// offered a key share for.
+func selectKemKeyShare(preference []string, keyShares []kemKeyShare) (*kemKeyShare, error) {
+	for _, kemName := range preference {
+		for i := range keyShares {
+			if keyShares[i].KemName == kemName {
+				return &keyShares[i], nil
+			}
+		}
+	}
+	return nil, errNoCommonKem
+}
+
+// offeredKeyShares returns the key shares of the client hello, including the one for
+// the legacy KEM.
+func offeredKeyShares(clientHelloMessage *clientHelloMessage) []kemKeyShare {
+	keyShares := clientHelloMessage.KeyShares
+	if len(clientHelloMessage.ClientKemPublicKey) != 0 {
+		keyShares = append(append([]kemKeyShare{}, keyShares...), legacyKeyShare(clientHelloMessage))
+	}
+	return keyShares
+}
+
+// legacyKeyShare returns the key share for the legacy KEM in the client hello.
+func legacyKeyShare(clientHelloMessage *clientHelloMessage) kemKeyShare {
+	return kemKeyShare{KemName: legacyKemName, PublicKey: clientHelloMessage.ClientKemPublicKey}
+}
+
+// generateX25519Key creates an ephemeral X25519 key pair.
+func generateX25519Key() (privateKey []byte, publicKey []byte, err error) {
+	privateKey = make([]byte, curve25519.ScalarSize)
+	if _, err = rand.Read(privateKey); err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, err = curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// x25519SharedSecret computes the X25519 shared secret with the remote public key.
+// Low order points are rejected, since they would make the secret all zeros.
+func x25519SharedSecret(privateKey []byte, remotePublicKey []byte) ([]byte, error) {
+	if len(remotePublicKey) != curve25519.PointSize {
+		return nil, errInvalidX25519Key
+	}
+
+	sharedSecret, err := curve25519.X25519(privateKey, remotePublicKey)
+	if err != nil {
+		return nil, errInvalidX25519Key
+	}
+
+	return sharedSecret, nil
+}
+
+// keyExchangeName describes the key exchange using the given KEM, which is hybrid
+// unless it is a legacy one.
+func keyExchangeName(kemName string, legacy bool) string {
+	if legacy {
+		return kemName
+	}
+	return x25519KeyExchangeName + "+" + kemName
+}
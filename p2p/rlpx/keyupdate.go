@@ -0,0 +1,70 @@
+package rlpx
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/DogeProtocol/dp/rlp"
+)
+
+const (
+	// KeyUpdateRecordLimit is the default number of application records sent
+	// under one traffic secret before it is ratcheted forward.
+	KeyUpdateRecordLimit uint = 1 << 24
+
+	// KeyUpdateInterval is the default time after which the sending traffic
+	// secret is ratcheted forward, regardless of how many records were sent.
+	KeyUpdateInterval = 60 * time.Minute
+)
+
+var errKeyUpdateUnsupported = errors.New("key updates not supported by legacy peer")
+
+// keyUpdateMessage is the payload of a PacketTypeKeyUpdate record. It is sent
+// under the old traffic secret, and every record following it is protected by
+// the next one. If UpdateRequested is set, the receiver must also update its
+// sending keys before it sends any further application data.
+type keyUpdateMessage struct {
+	UpdateRequested bool
+	Rest            []rlp.RawValue `rlp:"tail"`
+}
+
+// keyUpdateSchedule decides when the sending side of a connection updates its
+// keys. It is only accessed by the writer, except for the requested flag which
+// the reader sets when the remote end asks for an update.
+type keyUpdateSchedule struct {
+	recordLimit uint
+	interval    time.Duration
+	lastUpdate  time.Time
+	requested   int32
+}
+
+func newKeyUpdateSchedule() keyUpdateSchedule {
+	return keyUpdateSchedule{
+		recordLimit: KeyUpdateRecordLimit,
+		interval:    KeyUpdateInterval,
+	}
+}
+
+// reset restarts the schedule after new sending keys were installed.
+func (k *keyUpdateSchedule) reset() {
+	k.lastUpdate = time.Now()
+}
+
+// request marks that the remote end asked for a key update.
+func (k *keyUpdateSchedule) request() {
+	atomic.StoreInt32(&k.requested, 1)
+}
+
+// due reports whether the sending keys must be updated before the record with
+// the given sequence number is sent. A zero limit or interval disables the
+// respective trigger.
+func (k *keyUpdateSchedule) due(seqNum uint) bool {
+	if atomic.CompareAndSwapInt32(&k.requested, 1, 0) {
+		return true
+	}
+	if k.recordLimit != 0 && seqNum > k.recordLimit {
+		return true
+	}
+	return k.interval != 0 && !k.lastUpdate.IsZero() && time.Since(k.lastUpdate) >= k.interval
+}
@@ -0,0 +1,99 @@
+package rlpx
+
+import (
+	"errors"
+
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+)
+
+// Handshake versions. Version 1 always protects records with AES-256-GCM and
+// authenticates with cryptobase.SigAlg. Version 2 lets the server pick the AEAD and
+// signature algorithm from the lists offered in the client hello. Version 3 adds
+// session resumption, see Resumption.
+const (
+	handshakeVersion1 uint = 1
+	handshakeVersion2 uint = 2
+	handshakeVersion3 uint = 3
+)
+
+// SupportedVersions lists the handshake versions this implementation speaks,
+// highest first.
+var SupportedVersions = []uint{handshakeVersion3, handshakeVersion2, handshakeVersion1}
+
+// SupportedAeads lists the AEADs records can be protected with, preferred first.
+var SupportedAeads = []string{AeadAes256Gcm, AeadChaCha20Poly1305}
+
+// SupportedSignatureAlgorithms lists the algorithms the handshake can be authenticated
+// with, preferred first. The node key must be of the negotiated algorithm.
+var SupportedSignatureAlgorithms = []string{cryptobase.SigAlg.SignatureName()}
+
+// Parameters implied by a version 1 handshake.
+var (
+	version1Aead               = AeadAes256Gcm
+	version1SignatureAlgorithm = cryptobase.SigAlg.SignatureName()
+)
+
+var (
+	errNoCommonVersion            = errors.New("no common handshake version")
+	errNoCommonAead               = errors.New("no common AEAD")
+	errNoCommonSignatureAlgorithm = errors.New("no common signature algorithm")
+	errUnexpectedSelection        = errors.New("server selected a parameter that wasn't offered")
+)
+
+// signatureAlgorithm returns the algorithm with the given name.
+func signatureAlgorithm(name string) (signaturealgorithm.SignatureAlgorithm, error) {
+	if name == cryptobase.SigAlg.SignatureName() {
+		return cryptobase.SigAlg, nil
+	}
+	return nil, errNoCommonSignatureAlgorithm
+}
+
+// selectVersion returns the first version in preference order that was offered.
+func selectVersion(preference []uint, offered []uint) (uint, error) {
+	for _, version := range preference {
+		if containsVersion(offered, version) {
+			return version, nil
+		}
+	}
+	return 0, errNoCommonVersion
+}
+
+// selectName returns the first name in preference order that was offered, or
+// errNone if there is none.
+func selectName(preference []string, offered []string, errNone error) (string, error) {
+	for _, name := range preference {
+		if containsName(offered, name) {
+			return name, nil
+		}
+	}
+	return "", errNone
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func highestVersion(versions []uint) uint {
+	var highest uint
+	for _, v := range versions {
+		if v > highest {
+			highest = v
+		}
+	}
+	return highest
+}
+
+func containsVersion(versions []uint, version uint) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
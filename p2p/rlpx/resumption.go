@@ -0,0 +1,204 @@
+package rlpx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/rlp"
+)
+
+const (
+	// TicketLifetime is the default time a session ticket can be used to resume
+	// a session, starting from the handshake it was issued in.
+	TicketLifetime = 12 * time.Hour
+
+	// maxRedeemedTickets bounds the anti-replay cache. Resumption is refused while
+	// it is full, which only costs a full handshake.
+	maxRedeemedTickets = 1 << 16
+)
+
+var (
+	errTicketInvalid  = errors.New("invalid session ticket")
+	errTicketExpired  = errors.New("session ticket expired")
+	errTicketReplayed = errors.New("session ticket already used")
+	errTooManyTickets = errors.New("too many session tickets in use")
+)
+
+// newSessionTicketMessage is the payload of a PacketTypeNewSessionTicket record,
+// which the server sends after a version 3 handshake.
+type newSessionTicketMessage struct {
+	Ticket   []byte
+	Lifetime uint64         //seconds
+	Rest     []rlp.RawValue `rlp:"tail"`
+}
+
+// sessionTicket is the content of a ticket. Tickets are encrypted with a key only
+// known to the node that issued them, so the client just stores them as they are.
+type sessionTicket struct {
+	Psk             []byte
+	ClientPublicKey []byte
+	IssuedAt        uint64
+	Rest            []rlp.RawValue `rlp:"tail"`
+}
+
+// clientSession is a ticket received from a server, along with the PSK it resumes.
+type clientSession struct {
+	ticket  []byte
+	psk     []byte
+	expires time.Time
+}
+
+// Resumption holds the session resumption state shared by all connections of a
+// node. As a server, it encrypts the tickets handed out and remembers the ones
+// redeemed so that each can only be used once. As a client, it keeps the tickets
+// received from servers until they are used.
+//
+// Resumed handshakes skip the signatures, but still run a fresh key exchange, so
+// they keep forward secrecy.
+type Resumption struct {
+	lifetime   time.Duration
+	ticketAead cipher.AEAD
+
+	mutex    sync.Mutex
+	redeemed map[common.Hash]time.Time //ticket hash -> expiry
+	sessions map[string]clientSession  //server public key -> session
+}
+
+// NewResumption creates the resumption state with a random ticket key. Tickets
+// issued with it become invalid when the node restarts.
+func NewResumption(lifetime time.Duration) (*Resumption, error) {
+	ticketKey := make([]byte, symmetricKeySize)
+	if _, err := rand.Read(ticketKey); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(ticketKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ticketAead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resumption{
+		lifetime:   lifetime,
+		ticketAead: ticketAead,
+		redeemed:   make(map[common.Hash]time.Time),
+		sessions:   make(map[string]clientSession),
+	}, nil
+}
+
+// issueTicket creates a ticket resuming a session with the given client.
+func (r *Resumption) issueTicket(psk []byte, clientPublicKey []byte) ([]byte, error) {
+	plaintext, err := rlp.EncodeToBytes(&sessionTicket{
+		Psk:             psk,
+		ClientPublicKey: clientPublicKey,
+		IssuedAt:        uint64(time.Now().Unix()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, r.ticketAead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return r.ticketAead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// redeemTicket decrypts a ticket presented by a client. Every ticket is accepted
+// once, a replayed one is rejected.
+func (r *Resumption) redeemTicket(ticket []byte) (*sessionTicket, error) {
+	nonceSize := r.ticketAead.NonceSize()
+	if len(ticket) < nonceSize {
+		return nil, errTicketInvalid
+	}
+
+	plaintext, err := r.ticketAead.Open(nil, ticket[:nonceSize], ticket[nonceSize:], nil)
+	if err != nil {
+		return nil, errTicketInvalid
+	}
+
+	content := new(sessionTicket)
+	if err = rlp.DecodeBytes(plaintext, content); err != nil {
+		return nil, errTicketInvalid
+	}
+
+	now := time.Now()
+	expires := time.Unix(int64(content.IssuedAt), 0).Add(r.lifetime)
+	if now.After(expires) {
+		return nil, errTicketExpired
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ticketHash := crypto.Keccak256Hash(ticket)
+	if _, ok := r.redeemed[ticketHash]; ok {
+		return nil, errTicketReplayed
+	}
+	if len(r.redeemed) >= maxRedeemedTickets {
+		for hash, exp := range r.redeemed {
+			if now.After(exp) {
+				delete(r.redeemed, hash)
+			}
+		}
+		if len(r.redeemed) >= maxRedeemedTickets {
+			return nil, errTooManyTickets
+		}
+	}
+	r.redeemed[ticketHash] = expires
+
+	return content, nil
+}
+
+// storeSession keeps a ticket received from the server with the given public key,
+// replacing any older one.
+func (r *Resumption) storeSession(serverPublicKey []byte, ticket []byte, psk []byte, lifetime time.Duration) {
+	if lifetime > r.lifetime {
+		lifetime = r.lifetime
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	for key, session := range r.sessions {
+		if now.After(session.expires) {
+			delete(r.sessions, key)
+		}
+	}
+	r.sessions[string(serverPublicKey)] = clientSession{
+		ticket:  ticket,
+		psk:     psk,
+		expires: now.Add(lifetime),
+	}
+}
+
+// takeSession removes and returns the ticket for the server with the given public
+// key, if there is one that hasn't expired. Tickets are only offered once, since
+// the server rejects replays anyway.
+func (r *Resumption) takeSession(serverPublicKey []byte) (clientSession, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	session, ok := r.sessions[string(serverPublicKey)]
+	if !ok {
+		return clientSession{}, false
+	}
+	delete(r.sessions, string(serverPublicKey))
+
+	if time.Now().After(session.expires) {
+		return clientSession{}, false
+	}
+	return session, true
+}
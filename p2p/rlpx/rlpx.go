@@ -18,11 +18,17 @@
 package rlpx
 
 import (
-	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"errors"
+	"io"
 	"net"
 	"time"
+
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/golang/snappy"
 )
 
+var errPlainMessageTooLarge = errors.New("message length >= 16MB")
+
 // Conn is an RLPx network connection. It wraps a low-level network connection. The
 // underlying connection should not be used for other activity when it is wrapped by Conn.
 //
@@ -32,6 +38,7 @@ import (
 type Conn struct {
 	dialDest *signaturealgorithm.PublicKey
 	conn     net.Conn
+	wire     *wireCounter
 
 	// These are the buffers for snappy compression.
 	// Compression is enabled if they are non-nil.
@@ -51,13 +58,14 @@ func NewConn(conn net.Conn, dialDest *signaturealgorithm.PublicKey, context stri
 	connection := &Conn{
 		dialDest: dialDest,
 		conn:     conn,
+		wire:     &wireCounter{rw: conn},
 		context:  context,
 	}
 
 	if dialDest == nil {
-		connection.server = NewServer(conn, nil, context)
+		connection.server = NewServer(connection.wire, nil, context)
 	} else {
-		connection.client = NewClient(conn, nil, dialDest, context)
+		connection.client = NewClient(connection.wire, nil, dialDest, context)
 	}
 
 	return connection
@@ -68,11 +76,9 @@ func NewConn(conn net.Conn, dialDest *signaturealgorithm.PublicKey, context stri
 // compression is available on both ends of the connection.
 func (c *Conn) SetSnappy(snappy bool) {
 	if snappy {
-
 		c.snappyReadBuffer = []byte{}
 		c.snappyWriteBuffer = []byte{}
 	} else {
-
 		c.snappyReadBuffer = nil
 		c.snappyWriteBuffer = nil
 	}
@@ -97,49 +103,61 @@ func (c *Conn) SetDeadline(deadlineTime time.Time) error {
 // Read reads a message from the connection.
 // The returned data buffer is valid until the next call to Read.
 func (c *Conn) Read() (code uint64, data []byte, wireSize int, err error) {
+	start := c.wire.read
 
+	var dataPacket *DataPacket
 	if c.client != nil {
-		dataPacket, err := c.client.ReadAndDecrypt(PacketTypeApplicationData)
-		if err != nil {
-
-			return 0, nil, 0, err
-		}
-
-		return dataPacket.context, dataPacket.fragment, len(dataPacket.fragment), nil
+		dataPacket, err = c.client.ReadAndDecrypt(PacketTypeApplicationData)
 	} else {
-		dataPacket, err := c.server.ReadAndDecrypt(PacketTypeApplicationData)
-		if err != nil {
+		dataPacket, err = c.server.ReadAndDecrypt(PacketTypeApplicationData)
+	}
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	code, data, wireSize = dataPacket.context, dataPacket.fragment, c.wire.read-start
 
-			return 0, nil, 0, err
+	// If snappy is enabled, verify and decompress message.
+	if c.snappyReadBuffer != nil {
+		var actualSize int
+		actualSize, err = snappy.DecodedLen(data)
+		if err != nil {
+			return code, nil, 0, err
 		}
-
-		return dataPacket.context, dataPacket.fragment, len(dataPacket.fragment), nil
+		if actualSize > maxUint24 {
+			return code, nil, 0, errPlainMessageTooLarge
+		}
+		c.snappyReadBuffer = growslice(c.snappyReadBuffer, actualSize)
+		data, err = snappy.Decode(c.snappyReadBuffer, data)
 	}
+	return code, data, wireSize, err
 }
 
 // Write writes a message to the connection.
 //
-// Write returns the written size of the message data. This may be less than or equal to
-// len(data) depending on whether snappy compression is enabled.
+// Write returns the number of bytes the message occupied on the wire, including the
+// record header and encryption overhead. With snappy compression enabled this may be
+// less than len(data).
 func (c *Conn) Write(code uint64, data []byte) (uint32, error) {
+	if len(data) > maxUint24 {
+		return 0, errPlainMessageTooLarge
+	}
+	if c.snappyWriteBuffer != nil {
+		// Ensure the buffer has sufficient size.
+		// Package snappy will allocate its own buffer if the provided
+		// one is smaller than MaxEncodedLen.
+		c.snappyWriteBuffer = growslice(c.snappyWriteBuffer, snappy.MaxEncodedLen(len(data)))
+		data = snappy.Encode(c.snappyWriteBuffer, data)
+	}
 
-	size := uint32(len(data))
+	start := c.wire.written
 
+	var err error
 	if c.client != nil {
-		err := c.client.WriteEncrypted(data, code, PacketTypeApplicationData)
-		if err != nil {
-
-			return size, err
-		}
+		err = c.client.WriteEncrypted(data, code, PacketTypeApplicationData)
 	} else {
-		err := c.server.WriteEncrypted(data, code, PacketTypeApplicationData)
-		if err != nil {
-
-			return size, err
-		}
+		err = c.server.WriteEncrypted(data, code, PacketTypeApplicationData)
 	}
-
-	return size, nil
+	return uint32(c.wire.written - start), err
 }
 
 // Handshake performs the handshake. This must be called before any data is written
@@ -166,11 +184,61 @@ func (c *Conn) Handshake(prv *signaturealgorithm.PrivateKey) (*signaturealgorith
 	return nil, nil
 }
 
+// KeyExchange returns the name of the key exchange negotiated in the handshake,
+// e.g. "X25519+ML-KEM-768". It is empty until the handshake has completed.
+func (c *Conn) KeyExchange() string {
+	if c.client != nil {
+		return c.client.KeyExchange()
+	}
+	return c.server.KeyExchange()
+}
+
+// SetResumption enables session resumption with the given state, which is normally
+// shared by all connections of a node. It must be called before Handshake.
+func (c *Conn) SetResumption(resumption *Resumption) {
+	if c.client != nil {
+		c.client.SetResumption(resumption)
+	} else {
+		c.server.SetResumption(resumption)
+	}
+}
+
+// Resumed reports whether the handshake resumed an earlier session instead of
+// authenticating both ends with signatures.
+func (c *Conn) Resumed() bool {
+	if c.client != nil {
+		return c.client.Resumed()
+	}
+	return c.server.Resumed()
+}
+
 // Close closes the underlying network connection.
 func (c *Conn) Close() error {
 	return c.conn.Close()
 }
 
+// UpdateKeys ratchets the sending keys of the connection forward. If requestUpdate
+// is set, the remote end is asked to update its sending keys as well. Keys are also
+// updated automatically, see SetKeyUpdateLimits. UpdateKeys must not be called
+// concurrently with Write.
+func (c *Conn) UpdateKeys(requestUpdate bool) error {
+	if c.client != nil {
+		return c.client.UpdateKeys(requestUpdate)
+	}
+	return c.server.UpdateKeys(requestUpdate)
+}
+
+// SetKeyUpdateLimits sets after how many written messages, or how much time, the
+// sending keys are updated automatically. A zero value disables the respective limit.
+// The defaults are KeyUpdateRecordLimit and KeyUpdateInterval.
+func (c *Conn) SetKeyUpdateLimits(records uint, interval time.Duration) {
+	if c.client != nil {
+		c.client.SetKeyUpdateLimits(records, interval)
+	} else {
+		c.server.SetKeyUpdateLimits(records, interval)
+	}
+}
+
 func (c *Conn) InitWithSecrets(secret SessionSecret) {
 	if c.client != nil {
 		c.client.InitWithSecrets(secret)
@@ -178,3 +246,25 @@ func (c *Conn) InitWithSecrets(secret SessionSecret) {
 		c.server.InitWithSecrets(secret)
 	}
 }
+
+// wireCounter tracks the number of bytes read from and written to the
+// underlying connection, so that Conn can report message sizes as they
+// appear on the wire. The read and written counters are only touched by
+// the reading and the writing goroutine respectively.
+type wireCounter struct {
+	rw      io.ReadWriter
+	read    int
+	written int
+}
+
+func (w *wireCounter) Read(p []byte) (int, error) {
+	n, err := w.rw.Read(p)
+	w.read += n
+	return n, err
+}
+
+func (w *wireCounter) Write(p []byte) (int, error) {
+	n, err := w.rw.Write(p)
+	w.written += n
+	return n, err
+}
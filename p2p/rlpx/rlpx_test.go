@@ -15,3 +15,319 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 package rlpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/oqs"
+	"github.com/DogeProtocol/dp/p2p/simulations/pipes"
+)
+
+type handshakeTest struct {
+	client, server *Conn
+}
+
+// createPeers runs the handshake over a TCP pipe and returns both ends.
+func createPeers(t *testing.T) handshakeTest {
+	ht, err := handshake(t, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ht
+}
+
+// handshake runs the handshake over a TCP pipe. Non-nil KEM lists override
+// the ones offered by the client and accepted by the server.
+func handshake(t *testing.T, clientKems, serverKems []string) (handshakeTest, error) {
+	clientConn, serverConn, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	clientConn.SetDeadline(deadline)
+	serverConn.SetDeadline(deadline)
+
+	serverKey, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht := handshakeTest{
+		client: NewConn(clientConn, &serverKey.PublicKey, "test"),
+		server: NewConn(serverConn, nil, "test"),
+	}
+	if clientKems != nil {
+		ht.client.client.SetKems(clientKems)
+	}
+	if serverKems != nil {
+		ht.server.server.SetKems(serverKems)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := ht.server.Handshake(serverKey)
+		if err != nil {
+			ht.server.Close()
+		}
+		errc <- err
+	}()
+	_, clientErr := ht.client.Handshake(clientKey)
+	if serverErr := <-errc; serverErr != nil {
+		ht.close()
+		return ht, fmt.Errorf("server handshake failed: %v", serverErr)
+	}
+	if clientErr != nil {
+		ht.close()
+		return ht, fmt.Errorf("client handshake failed: %v", clientErr)
+	}
+	return ht, nil
+}
+
+func (ht handshakeTest) close() {
+	ht.client.Close()
+	ht.server.Close()
+}
+
+type writeResult struct {
+	size uint32
+	err  error
+}
+
+// send writes a message from the client in the background, so that large
+// messages don't block on the socket buffers.
+func (ht handshakeTest) send(code uint64, data []byte) <-chan writeResult {
+	c := make(chan writeResult, 1)
+	go func() {
+		size, err := ht.client.Write(code, data)
+		c <- writeResult{size, err}
+	}()
+	return c
+}
+
+func TestMessageWireSize(t *testing.T) {
+	ht := createPeers(t)
+	defer ht.close()
+
+	payload := bytes.Repeat([]byte{0x42}, 4096)
+	wc := ht.send(8, payload)
+	code, data, wireSize, err := ht.server.Read()
+	if err != nil {
+		t.Fatal("read error:", err)
+	}
+	w := <-wc
+	if w.err != nil {
+		t.Fatal("write error:", w.err)
+	}
+	if code != 8 || !bytes.Equal(data, payload) {
+		t.Fatalf("wrong message received: code %d, %d bytes", code, len(data))
+	}
+	if int(w.size) != wireSize {
+		t.Errorf("write reported %d bytes, read reported %d", w.size, wireSize)
+	}
+	if wireSize <= len(payload) {
+		t.Errorf("wire size %d does not account for record overhead", wireSize)
+	}
+}
+
+func TestMessageSnappy(t *testing.T) {
+	ht := createPeers(t)
+	defer ht.close()
+	ht.client.SetSnappy(true)
+	ht.server.SetSnappy(true)
+
+	for _, size := range []int{0, 1, 100, 64 * 1024, 1024 * 1024} {
+		payload := bytes.Repeat([]byte("signature"), size/9+1)[:size]
+		wc := ht.send(uint64(size), payload)
+		code, data, wireSize, err := ht.server.Read()
+		if err != nil {
+			t.Fatalf("size %d: read error: %v", size, err)
+		}
+		w := <-wc
+		if w.err != nil {
+			t.Fatalf("size %d: write error: %v", size, w.err)
+		}
+		if code != uint64(size) || !bytes.Equal(data, payload) {
+			t.Fatalf("size %d: wrong message received: code %d, %d bytes", size, code, len(data))
+		}
+		if int(w.size) != wireSize {
+			t.Errorf("size %d: write reported %d bytes, read reported %d", size, w.size, wireSize)
+		}
+		if size >= 64*1024 && wireSize >= size/2 {
+			t.Errorf("size %d: message not compressed, %d bytes on the wire", size, wireSize)
+		}
+	}
+}
+
+func TestMessageSnappyTooLarge(t *testing.T) {
+	ht := createPeers(t)
+	defer ht.close()
+	ht.server.SetSnappy(true)
+
+	// The client doesn't compress, so it can send a snappy block header
+	// claiming a decoded size beyond the limit.
+	bomb := make([]byte, binary.MaxVarintLen64)
+	bomb = bomb[:binary.PutUvarint(bomb, uint64(maxUint24)+1)]
+	wc := ht.send(1, bomb)
+	if _, _, _, err := ht.server.Read(); err != errPlainMessageTooLarge {
+		t.Fatalf("wrong read error: got %v, want %v", err, errPlainMessageTooLarge)
+	}
+	if w := <-wc; w.err != nil {
+		t.Fatal("write error:", w.err)
+	}
+
+	// Plain messages over the limit are refused by the writer.
+	if _, err := ht.client.Write(1, make([]byte, maxUint24+1)); err != errPlainMessageTooLarge {
+		t.Fatalf("wrong write error: got %v, want %v", err, errPlainMessageTooLarge)
+	}
+}
+
+// exchange sends a message from one end to the other and checks that it arrives intact.
+func exchange(t *testing.T, from, to *Conn, code uint64) {
+	t.Helper()
+	payload := []byte("message")
+	wc := make(chan error, 1)
+	go func() {
+		_, err := from.Write(code, payload)
+		wc <- err
+	}()
+	rcode, data, _, err := to.Read()
+	if err != nil {
+		t.Fatal("read error:", err)
+	}
+	if err := <-wc; err != nil {
+		t.Fatal("write error:", err)
+	}
+	if rcode != code || !bytes.Equal(data, payload) {
+		t.Fatalf("wrong message received: code %d, data %x", rcode, data)
+	}
+}
+
+func TestKeyUpdateRecordLimit(t *testing.T) {
+	ht := createPeers(t)
+	defer ht.close()
+	ht.client.SetKeyUpdateLimits(3, 0)
+
+	initialKey := ht.client.client.secret.ClientApplicationKey
+	for i := uint64(1); i <= 10; i++ {
+		exchange(t, ht.client, ht.server, i)
+	}
+	clientKey := ht.client.client.secret.ClientApplicationKey
+	if bytes.Equal(clientKey, initialKey) {
+		t.Fatal("client sending key was not updated")
+	}
+	if !bytes.Equal(clientKey, ht.server.server.secret.ClientApplicationKey) {
+		t.Fatal("server did not follow client key update")
+	}
+	if seq := ht.client.client.clientSeqNumApplication; seq > 4 {
+		t.Fatalf("sequence number %d not reset by key update", seq)
+	}
+}
+
+func TestKeyUpdateRequested(t *testing.T) {
+	ht := createPeers(t)
+	defer ht.close()
+
+	initialClientKey := ht.client.client.secret.ClientApplicationKey
+	initialServerKey := ht.server.server.secret.ServerApplicationKey
+
+	if err := ht.client.UpdateKeys(true); err != nil {
+		t.Fatal("key update failed:", err)
+	}
+	exchange(t, ht.client, ht.server, 1)
+	exchange(t, ht.server, ht.client, 2)
+
+	if bytes.Equal(ht.client.client.secret.ClientApplicationKey, initialClientKey) {
+		t.Fatal("client sending key was not updated")
+	}
+	serverKey := ht.server.server.secret.ServerApplicationKey
+	if bytes.Equal(serverKey, initialServerKey) {
+		t.Fatal("server did not answer key update request")
+	}
+	if !bytes.Equal(serverKey, ht.client.client.secret.ServerApplicationKey) {
+		t.Fatal("client did not follow server key update")
+	}
+
+	// Answering the request must not trigger another update.
+	exchange(t, ht.server, ht.client, 3)
+	if !bytes.Equal(serverKey, ht.server.server.secret.ServerApplicationKey) {
+		t.Fatal("server updated its keys twice")
+	}
+}
+
+func TestSeqNumExhausted(t *testing.T) {
+	secret, err := NewSessionSecret(make([]byte, 32), make([]byte, 32), make([]byte, 32), AeadAes256Gcm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, iv := secret.ClientHandshakeCipher, secret.ClientHandshakeIv
+	if _, err := Encrypt(cipher, []byte{1}, nil, PacketTypeApplicationData, iv, maxSeqNum); err != errSeqNumExhausted {
+		t.Fatalf("wrong encrypt error: got %v, want %v", err, errSeqNumExhausted)
+	}
+	if _, err := Decrypt(cipher, make([]byte, 32), nil, PacketTypeApplicationData, iv, maxSeqNum); err != errSeqNumExhausted {
+		t.Fatalf("wrong decrypt error: got %v, want %v", err, errSeqNumExhausted)
+	}
+}
+
+func TestKeyExchangeNegotiation(t *testing.T) {
+	tests := []struct {
+		clientKems, serverKems []string
+		want                   string
+	}{
+		{nil, nil, "X25519+ML-KEM-768"},
+		{[]string{oqs.Kyber512KemName, oqs.Sntrup761KemName}, nil, "X25519+sntrup761"},
+		{nil, []string{oqs.Kyber512KemName}, "X25519+Kyber512"},
+		{[]string{oqs.Kyber512KemName}, []string{oqs.MLKEM768KemName}, ""},
+	}
+	for i, test := range tests {
+		ht, err := handshake(t, test.clientKems, test.serverKems)
+		if test.want == "" {
+			if err == nil {
+				ht.close()
+				t.Errorf("test %d: handshake succeeded without a common KEM", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if kx := ht.client.KeyExchange(); kx != test.want {
+			t.Errorf("test %d: client negotiated %q, want %q", i, kx, test.want)
+		}
+		if kx := ht.server.KeyExchange(); kx != test.want {
+			t.Errorf("test %d: server negotiated %q, want %q", i, kx, test.want)
+		}
+		exchange(t, ht.client, ht.server, 1)
+		ht.close()
+	}
+}
+
+func TestSessionSecretCombinesSharedSecrets(t *testing.T) {
+	transcriptHash := make([]byte, 32)
+	kemSecret := bytes.Repeat([]byte{1}, 32)
+	ecdhSecret := bytes.Repeat([]byte{2}, 32)
+
+	base, err := NewSessionSecret(transcriptHash, kemSecret, ecdhSecret, AeadAes256Gcm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct{ kem, ecdh []byte }{
+		{kemSecret, make([]byte, 32)},
+		{make([]byte, 32), ecdhSecret},
+	} {
+		other, err := NewSessionSecret(transcriptHash, test.kem, test.ecdh, AeadAes256Gcm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(other.ClientHandshakeKey, base.ClientHandshakeKey) {
+			t.Fatal("handshake key doesn't depend on both shared secrets")
+		}
+	}
+}
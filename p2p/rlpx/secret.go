@@ -5,8 +5,10 @@ import (
 	crypto2 "crypto"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"errors"
 	"github.com/DogeProtocol/dp/common"
+	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/hkdf"
 	"golang.org/x/crypto/sha3"
 )
@@ -20,8 +22,19 @@ const (
 
 	clientApplicationTrafficLabelName = "c ap traffic"
 	serverApplicationTrafficLabelName = "s ap traffic"
+	trafficUpdateLabelName            = "traffic upd"
+	finishedLabelName                 = "finished"
+	resumptionLabelName               = "res master"
 )
 
+// Names of the AEADs records can be protected with.
+const (
+	AeadAes256Gcm        = "AES-256-GCM"
+	AeadChaCha20Poly1305 = "ChaCha20-Poly1305"
+)
+
+var errUnknownAead = errors.New("unknown AEAD")
+
 type SessionSecret struct {
 	handshakeSecret []byte
 
@@ -45,14 +58,32 @@ type SessionSecret struct {
 	ClientApplicationCipher cipher.AEAD
 	ServerApplicationCipher cipher.AEAD
 
-	masterSecret   []byte
-	TranscriptHash []byte
+	masterSecret     []byte
+	resumptionSecret []byte
+	TranscriptHash   []byte
+
+	aead string
 }
 
-func NewSessionSecret(transcriptHash []byte, sharedSecret []byte) (*SessionSecret, error) {
+// NewSessionSecret derives the handshake secrets from the hybrid key exchange. The
+// KEM and X25519 shared secrets are concatenated before extraction, so the result
+// is secure as long as either of them is. Records are protected with the given AEAD.
+func NewSessionSecret(transcriptHash []byte, kemSharedSecret []byte, ecdhSharedSecret []byte, aead string) (*SessionSecret, error) {
+	return newSessionSecret(nil, transcriptHash, kemSharedSecret, ecdhSharedSecret, aead)
+}
+
+// newSessionSecret is NewSessionSecret for sessions resumed with a PSK, which is
+// extracted into the early secret. A nil psk starts a full handshake.
+func newSessionSecret(psk []byte, transcriptHash []byte, kemSharedSecret []byte, ecdhSharedSecret []byte, aead string) (*SessionSecret, error) {
+	sharedSecret := make([]byte, 0, len(kemSharedSecret)+len(ecdhSharedSecret))
+	sharedSecret = append(sharedSecret, kemSharedSecret...)
+	sharedSecret = append(sharedSecret, ecdhSharedSecret...)
+
 	//Create early secrets
-	zeroKey := bytes.Repeat([]byte{0}, common.HashLength)
-	earlySecret := hkdf.Extract(sha3.New256, zeroKey, transcriptHash)
+	if psk == nil {
+		psk = bytes.Repeat([]byte{0}, common.HashLength)
+	}
+	earlySecret := hkdf.Extract(sha3.New256, psk, transcriptHash)
 
 	var hash crypto2.Hash
 	hash = crypto2.SHA3_256
@@ -131,26 +162,17 @@ func NewSessionSecret(transcriptHash []byte, sharedSecret []byte) (*SessionSecre
 		ServerHandshakeKey:           serverHandshakeKey,
 		ClientHandshakeIv:            clientHandshakeIv,
 		ServerHandshakeIv:            serverHandshakeIv,
+		aead:                         aead,
 	}
 
 	//Create the Client Handshake Cipher
-	blockHandshakeClient, err := aes.NewCipher(clientHandshakeKey)
-	if err != nil {
-		return nil, err
-	}
-
-	secret.ClientHandshakeCipher, err = cipher.NewGCM(blockHandshakeClient)
+	secret.ClientHandshakeCipher, err = newAead(aead, clientHandshakeKey)
 	if err != nil {
 		return nil, err
 	}
 
 	//Create the Server Handshake Cipher
-	blockHandshakeServer, err := aes.NewCipher(serverHandshakeKey)
-	if err != nil {
-		return nil, err
-	}
-
-	secret.ServerHandshakeCipher, err = cipher.NewGCM(blockHandshakeServer)
+	secret.ServerHandshakeCipher, err = newAead(aead, serverHandshakeKey)
 	if err != nil {
 		return nil, err
 	}
@@ -237,31 +259,144 @@ func (ss *SessionSecret) CreateApplicationSecrets(transcriptHash []byte) error {
 	}
 	ss.ServerApplicationIv = serverApplicationIv
 
-	//Create the Client Application Cipher
-	blockApplicationClient, err := aes.NewCipher(clientApplicationKey)
+	resumptionSecret, err := HkdfExpandLabel(
+		masterSecret,
+		resumptionLabelName,
+		transcriptHash,
+		shaLength)
 	if err != nil {
 		return err
 	}
+	ss.resumptionSecret = resumptionSecret
 
-	ss.ClientApplicationCipher, err = cipher.NewGCM(blockApplicationClient)
+	//Create the Client Application Cipher
+	ss.ClientApplicationCipher, err = newAead(ss.aead, clientApplicationKey)
 	if err != nil {
 		return err
 	}
 
 	//Create the Server Application Cipher
-	blockApplicationServer, err := aes.NewCipher(serverApplicationKey)
+	ss.ServerApplicationCipher, err = newAead(ss.aead, serverApplicationKey)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateClientApplicationSecrets ratchets the client application traffic secret
+// forward and replaces the client application key, iv and cipher with ones
+// derived from the new secret.
+func (ss *SessionSecret) UpdateClientApplicationSecrets() error {
+	trafficSecret, key, iv, aead, err := updateTrafficSecret(ss.aead, ss.clientApplicationTrafficSecret)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	ss.ServerApplicationCipher, err = cipher.NewGCM(blockApplicationServer)
+	ss.clientApplicationTrafficSecret = trafficSecret
+	ss.ClientApplicationKey = key
+	ss.ClientApplicationIv = iv
+	ss.ClientApplicationCipher = aead
+
+	return nil
+}
+
+// UpdateServerApplicationSecrets ratchets the server application traffic secret
+// forward and replaces the server application key, iv and cipher with ones
+// derived from the new secret.
+func (ss *SessionSecret) UpdateServerApplicationSecrets() error {
+	trafficSecret, key, iv, aead, err := updateTrafficSecret(ss.aead, ss.serverApplicationTrafficSecret)
 	if err != nil {
-		return nil
+		return err
 	}
 
+	ss.serverApplicationTrafficSecret = trafficSecret
+	ss.ServerApplicationKey = key
+	ss.ServerApplicationIv = iv
+	ss.ServerApplicationCipher = aead
+
 	return nil
 }
 
+func updateTrafficSecret(aeadName string, trafficSecret []byte) (nextSecret []byte, key []byte, iv []byte, aead cipher.AEAD, err error) {
+	nextSecret, err = HkdfExpandLabel(
+		trafficSecret,
+		trafficUpdateLabelName,
+		nil,
+		shaLength)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	key, err = HkdfExpandLabel(
+		nextSecret,
+		secretKeyLabelName,
+		nil,
+		symmetricKeySize)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	iv, err = HkdfExpandLabel(
+		nextSecret,
+		secretIvLabelName,
+		nil,
+		ivSize)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	aead, err = newAead(aeadName, key)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return nextSecret, key, iv, aead, nil
+}
+
+// clientFinished authenticates the transcript with the client handshake traffic
+// secret. It replaces the client's signature when a session is resumed.
+func (ss *SessionSecret) clientFinished(transcriptHash []byte) ([]byte, error) {
+	return finishedMac(ss.clientHandshakeTrafficSecret, transcriptHash)
+}
+
+// serverFinished authenticates the transcript with the server handshake traffic
+// secret. It replaces the server's signature when a session is resumed.
+func (ss *SessionSecret) serverFinished(transcriptHash []byte) ([]byte, error) {
+	return finishedMac(ss.serverHandshakeTrafficSecret, transcriptHash)
+}
+
+func finishedMac(trafficSecret []byte, transcriptHash []byte) ([]byte, error) {
+	finishedKey, err := HkdfExpandLabel(
+		trafficSecret,
+		finishedLabelName,
+		nil,
+		shaLength)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha3.New256, finishedKey)
+	mac.Write(transcriptHash)
+	return mac.Sum(nil), nil
+}
+
+// newAead creates the named AEAD. Both take a symmetricKeySize key and an ivSize nonce.
+func newAead(name string, key []byte) (cipher.AEAD, error) {
+	switch name {
+	case AeadAes256Gcm:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case AeadChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, errUnknownAead
+	}
+}
+
 func HkdfExpandLabel(secret []byte, label string, hashVal []byte, outputLength int) ([]byte, error) {
 	hkdfLabel := hkdfEncodeLabel(label, hashVal, outputLength)
 
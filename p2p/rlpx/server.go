@@ -3,34 +3,45 @@ package rlpx
 import (
 	"bytes"
 	cipher2 "crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"errors"
 	"github.com/DogeProtocol/dp/crypto"
-	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/crypto/keyestablishmentalgorithm"
 	"github.com/DogeProtocol/dp/crypto/oqs"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"github.com/DogeProtocol/dp/rlp"
 	"io"
 	"sync"
+	"time"
 )
 
+// serverHelloMessage starts with the fields of the original handshake, see
+// clientHelloMessage. Legacy clients only get those.
 type serverHelloMessage struct {
 	CipherText            []byte //kemCipherTextLength
 	ServerHelloRandomData [shaLen]byte
-	Version               uint
+	Version               uint           //selected handshake version
+	KemName               string         `rlp:"optional"` //unset for legacy clients
+	ServerX25519PublicKey []byte         `rlp:"optional"` //unset for legacy clients
+	Aead                  string         `rlp:"optional"` //from version 2 on
+	SignatureAlgorithm    string         `rlp:"optional"` //from version 2 on
+	Resumed               bool           `rlp:"optional"` //from version 3 on, set when the client's ticket was accepted
 	Rest                  []rlp.RawValue `rlp:"tail"`
 }
 
 type serverVerifyMessage struct {
 	Signature    []byte //SignPublicKeyLen
 	SignatureLen uint
+	Finished     []byte         `rlp:"optional"` //replaces the signature in resumed sessions
 	Rest         []rlp.RawValue `rlp:"tail"`
 }
 
 type Server struct {
 	ephemeralKemPrivateKey  *keyestablishmentalgorithm.PrivateKey
+	kems                    []string
 	kem                     *oqs.KeyEncapsulation
+	kemName                 string
 	serverSigningPrivateKey *signaturealgorithm.PrivateKey
 	clientSigningPublicKey  *signaturealgorithm.PublicKey
 
@@ -45,6 +56,22 @@ type Server struct {
 	kemCipherText   []byte //kemCipherTextLength
 	kemSharedSecret []byte //kemSecretLength
 
+	x25519PublicKey   []byte
+	ecdhSharedSecret  []byte
+	legacyKeyExchange bool //the client predates the hybrid key exchange
+
+	versions               []uint
+	aeads                  []string
+	signatureAlgorithms    []string
+	version                uint
+	aead                   string
+	signatureAlgorithmName string
+	sigAlg                 signaturealgorithm.SignatureAlgorithm
+
+	resumption *Resumption
+	psk        []byte //of the resumed session
+	resumed    bool
+
 	serverSeqNumHandshake uint
 	clientSeqNumHandshake uint
 
@@ -64,6 +91,8 @@ type Server struct {
 	handshakeDone bool
 	mutex         sync.Mutex
 
+	keyUpdate keyUpdateSchedule
+
 	context string
 }
 
@@ -71,6 +100,10 @@ func NewServer(conn io.ReadWriter, serverSigningPrivateKey *signaturealgorithm.P
 	server := Server{
 		conn:                    conn,
 		serverSigningPrivateKey: serverSigningPrivateKey,
+		kems:                    SupportedKems,
+		versions:                SupportedVersions,
+		aeads:                   SupportedAeads,
+		signatureAlgorithms:     SupportedSignatureAlgorithms,
 		context:                 context,
 	}
 
@@ -80,6 +113,7 @@ func NewServer(conn io.ReadWriter, serverSigningPrivateKey *signaturealgorithm.P
 
 	server.serverSeqNumApplication = 1
 	server.clientSeqNumApplication = 1
+	server.keyUpdate = newKeyUpdateSchedule()
 	server.serializer.SetContext("server " + context)
 
 	return &server
@@ -93,6 +127,40 @@ func (s *Server) SetServerSigningPrivateKey(serverSigningPrivateKey *signatureal
 	s.serverSigningPrivateKey = serverSigningPrivateKey
 }
 
+// SetKems sets the KEMs the server accepts, in order of preference.
+func (s *Server) SetKems(kems []string) {
+	s.kems = kems
+}
+
+// SetVersions sets the handshake versions the server accepts, highest first.
+func (s *Server) SetVersions(versions []uint) {
+	s.versions = versions
+}
+
+// SetAeads sets the AEADs the server accepts, in order of preference.
+func (s *Server) SetAeads(aeads []string) {
+	s.aeads = aeads
+}
+
+// SetResumption sets the state used to issue and redeem session tickets. Without
+// it, the server neither issues tickets nor accepts them.
+func (s *Server) SetResumption(resumption *Resumption) {
+	s.resumption = resumption
+}
+
+// Resumed reports whether the handshake resumed an earlier session.
+func (s *Server) Resumed() bool {
+	return s.resumed
+}
+
+// KeyExchange returns the name of the key exchange negotiated in the handshake.
+func (s *Server) KeyExchange() string {
+	if s.kemName == "" {
+		return ""
+	}
+	return keyExchangeName(s.kemName, s.legacyKeyExchange)
+}
+
 func (s *Server) PerformHandshake() error {
 
 	s.mutex.Lock()
@@ -102,18 +170,9 @@ func (s *Server) PerformHandshake() error {
 		return errors.New("Handshake already done")
 	}
 
-	//Initialize KEM
-	kem := oqs.KeyEncapsulation{}
-
-	err := kem.Init(oqs.KemName, nil)
-	if err != nil {
-		return err
-	}
-	s.kem = &kem
-
 	//Receive client hello message
 	clientHelloMessage := new(clientHelloMessage)
-	_, err = s.serializer.Deserialize(clientHelloMessage, s.conn)
+	_, err := s.serializer.Deserialize(clientHelloMessage, s.conn)
 	if err != nil {
 		return err
 	}
@@ -155,20 +214,30 @@ func (s *Server) PerformHandshake() error {
 	transcriptHash := crypto.Keccak256(s.transcript)
 
 	//Create the secrets
-	secret, err := NewSessionSecret(transcriptHash, s.kemSharedSecret[:])
-	s.secret = *secret
-
-	//Sign the transcript hash
-	signature, err := cryptobase.SigAlg.Sign(transcriptHash, s.serverSigningPrivateKey)
+	secret, err := newSessionSecret(s.psk, transcriptHash, s.kemSharedSecret[:], s.ecdhSharedSecret, s.aead)
 	if err != nil {
 		return err
 	}
+	s.secret = *secret
 
 	//Serialize the server verify message
 	serverVerifyMessage := new(serverVerifyMessage)
-	serverVerifyMessage.Signature = make([]byte, cryptobase.SigAlg.SignatureWithPublicKeyLength())
-	copy(serverVerifyMessage.Signature[:], signature)
-	serverVerifyMessage.SignatureLen = uint(len(signature))
+	if s.resumed {
+		serverVerifyMessage.Finished, err = s.secret.serverFinished(transcriptHash)
+		if err != nil {
+			return err
+		}
+	} else {
+		//Sign the transcript hash
+		signature, err := s.sigAlg.Sign(transcriptHash, s.serverSigningPrivateKey)
+		if err != nil {
+			return err
+		}
+
+		serverVerifyMessage.Signature = make([]byte, s.sigAlg.SignatureWithPublicKeyLength())
+		copy(serverVerifyMessage.Signature[:], signature)
+		serverVerifyMessage.SignatureLen = uint(len(signature))
+	}
 	s.serverVerifyMessage = serverVerifyMessage
 
 	serverVerifyPacket, err := s.serializer.Serialize(serverVerifyMessage)
@@ -195,8 +264,14 @@ func (s *Server) PerformHandshake() error {
 	}
 
 	s.handshakeDone = true
+	s.keyUpdate.reset()
 
-	return nil
+	//Legacy peers don't know key update records
+	if s.legacyKeyExchange {
+		s.keyUpdate.recordLimit, s.keyUpdate.interval = 0, 0
+	}
+
+	return s.sendSessionTicket()
 }
 
 func (s *Server) Read() error {
@@ -212,7 +287,12 @@ func (s *Server) Read() error {
 
 func (s *Server) makeServerHello() error {
 	serverHelloMessage := new(serverHelloMessage)
-	serverHelloMessage.Version = 1
+	serverHelloMessage.Version = s.version
+	if s.version >= handshakeVersion2 {
+		serverHelloMessage.Aead = s.aead
+		serverHelloMessage.SignatureAlgorithm = s.signatureAlgorithmName
+	}
+	serverHelloMessage.Resumed = s.resumed
 
 	// Generate ServerRandomData
 	randomData := make([]byte, shaLength)
@@ -224,6 +304,10 @@ func (s *Server) makeServerHello() error {
 
 	serverHelloMessage.CipherText = make([]byte, s.kem.AlgDetails.LengthCiphertext)
 	copy(serverHelloMessage.CipherText[:], s.kemCipherText[:])
+	if !s.legacyKeyExchange {
+		serverHelloMessage.KemName = s.kemName
+		serverHelloMessage.ServerX25519PublicKey = s.x25519PublicKey
+	}
 	s.serverHelloMessage = serverHelloMessage
 
 	return nil
@@ -231,7 +315,39 @@ func (s *Server) makeServerHello() error {
 
 func (s *Server) handleClientHello() error {
 
-	ciphertext, sharedSecret, err := s.kem.EncapsulateSecret(s.clientHelloMessage.ClientKemPublicKey[:])
+	err := s.negotiate()
+	if err != nil {
+		return err
+	}
+	s.resumeSession()
+
+	//A legacy client only offers the legacy KEM, without X25519
+	keyShares := offeredKeyShares(s.clientHelloMessage)
+	if len(s.clientHelloMessage.ClientX25519PublicKey) == 0 {
+		if s.version != handshakeVersion1 || len(s.clientHelloMessage.ClientKemPublicKey) == 0 {
+			return errNoCommonKem
+		}
+		keyShares = []kemKeyShare{legacyKeyShare(s.clientHelloMessage)}
+		s.legacyKeyExchange = true
+	}
+
+	//Select the strongest KEM both sides support
+	keyShare, err := selectKemKeyShare(enabledKems(s.kems), keyShares)
+	if err != nil {
+		return err
+	}
+
+	//Initialize KEM
+	kem := oqs.KeyEncapsulation{}
+
+	err = kem.Init(keyShare.KemName, nil)
+	if err != nil {
+		return err
+	}
+	s.kem = &kem
+	s.kemName = keyShare.KemName
+
+	ciphertext, sharedSecret, err := s.kem.EncapsulateSecret(keyShare.PublicKey)
 	if err != nil {
 		return err
 	}
@@ -242,9 +358,119 @@ func (s *Server) handleClientHello() error {
 	s.kemSharedSecret = make([]byte, s.kem.AlgDetails.LengthSharedSecret)
 	copy(s.kemSharedSecret[:], sharedSecret[:])
 
+	if s.legacyKeyExchange {
+		return nil
+	}
+
+	//Complete the X25519 exchange
+	x25519PrivateKey, x25519PublicKey, err := generateX25519Key()
+	if err != nil {
+		return err
+	}
+	s.x25519PublicKey = x25519PublicKey
+
+	s.ecdhSharedSecret, err = x25519SharedSecret(x25519PrivateKey, s.clientHelloMessage.ClientX25519PublicKey)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// negotiate selects the highest common handshake version, and the AEAD and signature
+// algorithm the server prefers among the ones the client offered.
+func (s *Server) negotiate() error {
+	clientHelloMessage := s.clientHelloMessage
+
+	//A version 1 client only sends its own version
+	offeredVersions := clientHelloMessage.Versions
+	if len(offeredVersions) == 0 {
+		offeredVersions = []uint{clientHelloMessage.Version}
+	}
+	version, err := selectVersion(s.versions, offeredVersions)
+	if err != nil {
+		return err
+	}
+
+	offeredAeads, offeredSignatureAlgorithms := []string{version1Aead}, []string{version1SignatureAlgorithm}
+	if version >= handshakeVersion2 {
+		offeredAeads, offeredSignatureAlgorithms = clientHelloMessage.Aeads, clientHelloMessage.SignatureAlgorithms
+	}
+
+	aead, err := selectName(s.aeads, offeredAeads, errNoCommonAead)
+	if err != nil {
+		return err
+	}
+
+	signatureAlgorithmName, err := selectName(s.signatureAlgorithms, offeredSignatureAlgorithms, errNoCommonSignatureAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	sigAlg, err := signatureAlgorithm(signatureAlgorithmName)
+	if err != nil {
+		return err
+	}
+
+	s.version = version
+	s.aead = aead
+	s.signatureAlgorithmName = signatureAlgorithmName
+	s.sigAlg = sigAlg
+
+	return nil
+}
+
+// resumeSession redeems the ticket in the client hello, if any. The handshake falls
+// back to a full one when the ticket can't be used.
+func (s *Server) resumeSession() {
+	ticket := s.clientHelloMessage.SessionTicket
+	if s.resumption == nil || s.version < handshakeVersion3 || len(ticket) == 0 {
+		return
+	}
+
+	content, err := s.resumption.redeemTicket(ticket)
+	if err != nil {
+		return
+	}
+
+	clientSigningPublicKey, err := s.sigAlg.DeserializePublicKey(content.ClientPublicKey)
+	if err != nil {
+		return
+	}
+
+	s.psk = content.Psk
+	s.clientSigningPublicKey = clientSigningPublicKey
+	s.resumed = true
+}
+
+// sendSessionTicket issues a ticket after a version 3 handshake, which the client
+// can use to resume the session later on.
+func (s *Server) sendSessionTicket() error {
+	if s.resumption == nil || s.version < handshakeVersion3 {
+		return nil
+	}
+
+	clientPublicKey, err := s.sigAlg.SerializePublicKey(s.clientSigningPublicKey)
+	if err != nil {
+		return err
+	}
+
+	ticket, err := s.resumption.issueTicket(s.secret.resumptionSecret, clientPublicKey)
+	if err != nil {
+		return err
+	}
+
+	newSessionTicketPacket, err := s.serializer.Serialize(&newSessionTicketMessage{
+		Ticket:   ticket,
+		Lifetime: uint64(s.resumption.lifetime / time.Second),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.WriteEncrypted(newSessionTicketPacket, 0, PacketTypeNewSessionTicket)
+}
+
 func (s *Server) handleClientVerify() error {
 
 	//Receive the client verify message
@@ -265,20 +491,31 @@ func (s *Server) handleClientVerify() error {
 
 	transcriptHash := crypto.Keccak256(s.transcript)
 
-	//Recover the public key from the signature
-	clientPubKeyDataRemote, err := cryptobase.SigAlg.PublicKeyBytesFromSignature(transcriptHash, clientVerifyMessage.Signature[:clientVerifyMessage.SignatureLen])
-	if err != nil {
+	if s.resumed {
+		//The client is the one the ticket was issued to
+		finished, err := s.secret.clientFinished(transcriptHash)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal(finished, clientVerifyMessage.Finished) {
+			return errors.New("client's finished verification failed")
+		}
+	} else {
+		//Recover the public key from the signature
+		clientPubKeyDataRemote, err := s.sigAlg.PublicKeyBytesFromSignature(transcriptHash, clientVerifyMessage.Signature[:clientVerifyMessage.SignatureLen])
+		if err != nil {
 
-		return err
-	}
+			return err
+		}
 
-	if !cryptobase.SigAlg.Verify(clientPubKeyDataRemote, transcriptHash, clientVerifyMessage.Signature[:clientVerifyMessage.SignatureLen]) {
-		return errors.New("client's signature verification failed")
-	}
+		if !s.sigAlg.Verify(clientPubKeyDataRemote, transcriptHash, clientVerifyMessage.Signature[:clientVerifyMessage.SignatureLen]) {
+			return errors.New("client's signature verification failed")
+		}
 
-	s.clientSigningPublicKey, err = cryptobase.SigAlg.DeserializePublicKey(clientPubKeyDataRemote)
-	if err != nil {
-		return err
+		s.clientSigningPublicKey, err = s.sigAlg.DeserializePublicKey(clientPubKeyDataRemote)
+		if err != nil {
+			return err
+		}
 	}
 
 	s.transcript = append(s.transcript, clientVerifyTranscript...)
@@ -297,6 +534,12 @@ func (s *Server) createApplicationSecrets() error {
 }
 
 func (s *Server) WriteEncrypted(data []byte, context uint64, packetType PacketType) error {
+	if packetType == PacketTypeApplicationData && s.keyUpdate.due(s.serverSeqNumApplication) {
+		if err := s.UpdateKeys(false); err != nil {
+			return err
+		}
+	}
+
 	additionalData := make([]byte, shaLength)
 	_, err := rand.Read(additionalData)
 	if err != nil {
@@ -355,7 +598,31 @@ func (s *Server) WriteEncrypted(data []byte, context uint64, packetType PacketTy
 	return nil
 }
 
+// ReadAndDecrypt reads the next record of the given type. Key update records
+// received while reading application data are processed transparently.
 func (s *Server) ReadAndDecrypt(packetType PacketType) (*DataPacket, error) {
+	for {
+		dataPacket, err := s.readRecord(packetType)
+		if err != nil {
+			return nil, err
+		}
+
+		if packetType == PacketTypeApplicationData && dataPacket.packetType == PacketTypeKeyUpdate {
+			if err = s.handleKeyUpdate(dataPacket); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if dataPacket.packetType != packetType {
+			return nil, errors.New("packetType mismatch")
+		}
+
+		return dataPacket, nil
+	}
+}
+
+func (s *Server) readRecord(packetType PacketType) (*DataPacket, error) {
 
 	if packetType == PacketTypeApplicationData {
 	}
@@ -400,9 +667,6 @@ func (s *Server) ReadAndDecrypt(packetType PacketType) (*DataPacket, error) {
 		return nil, err
 	}
 
-	if dataPacket.packetType != packetType {
-		return nil, errors.New("packetType mismatch")
-	}
 	dataPacket.context = header.Context
 
 	if packetType == PacketTypeHandshake {
@@ -435,6 +699,62 @@ func (s *Server) Cleanup() {
 	}
 }
 
+// UpdateKeys sends a key update record and ratchets the server's sending keys
+// forward. If requestUpdate is set, the client is asked to do the same with its
+// own sending keys. It must not be called concurrently with WriteEncrypted, and
+// fails if the peer predates key updates.
+func (s *Server) UpdateKeys(requestUpdate bool) error {
+	if s.legacyKeyExchange {
+		return errKeyUpdateUnsupported
+	}
+	keyUpdatePacket, err := s.serializer.Serialize(&keyUpdateMessage{UpdateRequested: requestUpdate})
+	if err != nil {
+		return err
+	}
+
+	err = s.WriteEncrypted(keyUpdatePacket, 0, PacketTypeKeyUpdate)
+	if err != nil {
+		return err
+	}
+
+	err = s.secret.UpdateServerApplicationSecrets()
+	if err != nil {
+		return err
+	}
+	s.serverSeqNumApplication = 1
+	s.keyUpdate.reset()
+
+	return nil
+}
+
+// SetKeyUpdateLimits sets after how many application records, or how much time,
+// the server automatically updates its sending keys. Zero disables a limit.
+func (s *Server) SetKeyUpdateLimits(records uint, interval time.Duration) {
+	s.keyUpdate.recordLimit = records
+	s.keyUpdate.interval = interval
+}
+
+func (s *Server) handleKeyUpdate(dataPacket *DataPacket) error {
+	keyUpdateMessage := new(keyUpdateMessage)
+	_, err := s.serializer.Deserialize(keyUpdateMessage, bytes.NewReader(dataPacket.fragment))
+	if err != nil {
+		return err
+	}
+
+	err = s.secret.UpdateClientApplicationSecrets()
+	if err != nil {
+		return err
+	}
+	s.clientSeqNumApplication = 1
+
+	if keyUpdateMessage.UpdateRequested {
+		s.keyUpdate.request()
+	}
+
+	return nil
+}
+
 func (s *Server) InitWithSecrets(secret SessionSecret) {
 	s.secret = secret
+	s.keyUpdate.reset()
 }
@@ -40,6 +40,7 @@ import (
 	"github.com/DogeProtocol/dp/p2p/enr"
 	"github.com/DogeProtocol/dp/p2p/nat"
 	"github.com/DogeProtocol/dp/p2p/netutil"
+	"github.com/DogeProtocol/dp/p2p/rlpx"
 )
 
 const (
@@ -175,7 +176,8 @@ type Server struct {
 
 	listener     net.Listener
 	ourHandshake *protoHandshake
-	loopWG       sync.WaitGroup // loop, listenLoop
+	resumption   *rlpx.Resumption // session tickets, shared by all connections
+	loopWG       sync.WaitGroup   // loop, listenLoop
 	peerFeed     event.Feed
 	log          log.Logger
 
@@ -462,6 +464,9 @@ func (srv *Server) Start() (err error) {
 	if srv.listenFunc == nil {
 		srv.listenFunc = net.Listen
 	}
+	if srv.resumption, err = rlpx.NewResumption(rlpx.TicketLifetime); err != nil {
+		return err
+	}
 	srv.quit = make(chan struct{})
 	srv.delpeer = make(chan peerDrop)
 	srv.checkpointPostHandshake = make(chan *conn)
@@ -943,6 +948,9 @@ func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *enode.Node)
 
 		c.transport = srv.newTransport(fd, dialDest.Pubkey(), fd.RemoteAddr().String())
 	}
+	if t, ok := c.transport.(*rlpxTransport); ok && srv.resumption != nil {
+		t.conn.SetResumption(srv.resumption)
+	}
 
 	err := srv.setupConn(c, flags, dialDest)
 	if err != nil {
@@ -45,7 +45,7 @@ func newTestTransport(rpub *signaturealgorithm.PublicKey, fd net.Conn, dialDest
 	wrapped := newRLPX(fd, dialDest, "test").(*rlpxTransport)
 
 	dummyData := make([]byte, 32)
-	secret, err := rlpx.NewSessionSecret(dummyData, dummyData)
+	secret, err := rlpx.NewSessionSecret(dummyData, dummyData, dummyData, rlpx.AeadAes256Gcm)
 	if err != nil {
 		return nil
 	}
@@ -132,7 +132,16 @@ func (t *rlpxTransport) doEncHandshake(prv *signaturealgorithm.PrivateKey) (*sig
 
 	t.conn.SetDeadline(time.Now().Add(handshakeTimeout))
 
-	return t.conn.Handshake(prv)
+	pubkey, err := t.conn.Handshake(prv)
+	if err != nil {
+		return nil, err
+	}
+	if t.conn.Resumed() {
+		resumedHandshakeMeter.Mark(1)
+	} else {
+		fullHandshakeMeter.Mark(1)
+	}
+	return pubkey, nil
 }
 
 func (t *rlpxTransport) doProtoHandshake(our *protoHandshake) (their *protoHandshake, err error) {
@@ -152,8 +161,7 @@ func (t *rlpxTransport) doProtoHandshake(our *protoHandshake) (their *protoHands
 		return nil, fmt.Errorf("write error: %v", err)
 	}
 	// If the protocol version supports Snappy encoding, upgrade immediately
-
-	t.conn.SetSnappy(false)
+	t.conn.SetSnappy(their.Version >= snappyProtocolVersion)
 
 	return their, nil
 }
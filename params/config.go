@@ -20,8 +20,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math/big"
+	"reflect"
 
 	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -247,23 +249,23 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), new(EthashConfig), nil, nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, nil}
 
 	// AllProofOfStakeProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the ProofOfStake consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllProofOfStakeProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &ProofOfStakeConfig{Period: 0, Epoch: 30000}}
+	AllProofOfStakeProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &ProofOfStakeConfig{Period: 0, Epoch: 30000}, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), new(EthashConfig), nil, nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -345,10 +347,39 @@ type ChainConfig struct {
 
 	CatalystBlock *big.Int `json:"catalystBlock,omitempty"` // Catalyst switch block (nil = no fork, 0 = already on catalyst)
 
+	FalconBlock      *big.Int `json:"falconBlock,omitempty"`      // Falcon-512 signatures switch block (nil = no fork, 0 = already accepted)
+	SignatureTxBlock *big.Int `json:"signatureTxBlock,omitempty"` // Signature transactions switch block (nil = no fork, 0 = already accepted)
+	PQVerifyBlock    *big.Int `json:"pqVerifyBlock,omitempty"`    // Post-quantum signature verification precompiles switch block (nil = no fork, 0 = already activated)
+	MultisigBlock    *big.Int `json:"multisigBlock,omitempty"`    // Multisignature accounts switch block (nil = no fork, 0 = already accepted)
+
+	StakingRewardsBlock *big.Int `json:"stakingRewardsBlock,omitempty"` // Proof-of-stake reward schedule and fee sharing switch block (nil = no fork, 0 = already activated)
+	SlashingBlock       *big.Int `json:"slashingBlock,omitempty"`       // Proof-of-stake double-sign slashing switch block (nil = no fork, 0 = already activated)
+	JailingBlock        *big.Int `json:"jailingBlock,omitempty"`        // Proof-of-stake jailing of offline validators switch block (nil = no fork, 0 = already activated)
+	DelegationBlock     *big.Int `json:"delegationBlock,omitempty"`     // Proof-of-stake delegation in the staking contract switch block (nil = no fork, 0 = already activated)
+	UnbondingBlock      *big.Int `json:"unbondingBlock,omitempty"`      // Proof-of-stake unbonding period for staking withdrawals switch block (nil = no fork, 0 = already activated)
+	StakeScheduleBlock  *big.Int `json:"stakeScheduleBlock,omitempty"`  // Proof-of-stake stake-weighted proposer schedule switch block (nil = no fork, 0 = already activated)
+
 	// Various consensus engines
 	Ethash       *EthashConfig       `json:"ethash,omitempty"`
 	Clique       *CliqueConfig       `json:"clique,omitempty"`
 	ProofOfStake *ProofOfStakeConfig `json:"proofofstake,omitempty"`
+
+	SystemContractUpgrades []*SystemContractUpgrade `json:"systemContractUpgrades,omitempty"` // Forks replacing the code of system contracts, in block order
+}
+
+// SystemContractUpgrade is a named fork replacing the code of system contracts
+// at a given block. Storage migrations accompanying an upgrade are registered
+// by name with the systemcontracts package.
+type SystemContractUpgrade struct {
+	Name      string                `json:"name"`
+	Block     *big.Int              `json:"block"`
+	Contracts []*SystemContractCode `json:"contracts"`
+}
+
+// SystemContractCode is the code a system contract is upgraded to.
+type SystemContractCode struct {
+	Address common.Address `json:"address"`
+	Code    hexutil.Bytes  `json:"code"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -374,6 +405,22 @@ func (c *CliqueConfig) String() string {
 type ProofOfStakeConfig struct {
 	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
 	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
+
+	StakingContract common.Address `json:"stakingContract,omitempty"` // Address of the staking contract, predeployed at genesis unless allocated with code
+
+	DoubleSignSlashPercent uint64 `json:"doubleSignSlashPercent,omitempty"` // Percentage of a double-signing validator's deposit to burn
+	MissedSlotsThreshold   uint64 `json:"missedSlotsThreshold,omitempty"`   // Number of in-turn slots a validator may miss per epoch before being jailed
+	UnbondingEpochs        uint64 `json:"unbondingEpochs,omitempty"`        // Number of epochs withdrawn stake stays slashable before it can be claimed, set in the staking contract at the unbonding fork
+	JailPeriod             uint64 `json:"jailPeriod,omitempty"`             // Number of blocks a jailed validator has to wait before it can be unjailed
+
+	MinStake *big.Int `json:"minStake,omitempty"` // Minimum deposit in wei a depositor needs to unjail its validator
+
+	BlockReward             *big.Int       `json:"blockReward,omitempty"`             // Base reward in wei paid to the depositor of a block's sealer
+	RewardReductionInterval uint64         `json:"rewardReductionInterval,omitempty"` // Number of blocks after which the base reward is reduced (0 = never)
+	RewardReductionPercent  uint64         `json:"rewardReductionPercent,omitempty"`  // Percentage the base reward is reduced by every interval (50 = halving)
+	FeeSharePercent         uint64         `json:"feeSharePercent,omitempty"`         // Percentage of the collected fees paid to the sealer's depositor, the rest is burned
+	TreasuryPercent         uint64         `json:"treasuryPercent,omitempty"`         // Percentage of the sealer's reward diverted to the treasury
+	TreasuryAddress         common.Address `json:"treasuryAddress,omitempty"`         // Account receiving the treasury share
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -381,6 +428,25 @@ func (c *ProofOfStakeConfig) String() string {
 	return "proofofstake"
 }
 
+const (
+	DefaultProofOfStakeEpoch = 30000 // Default number of blocks after which to checkpoint and reset the pending votes
+	DefaultUnbondingEpochs   = 2     // Default number of epochs withdrawn stake stays locked
+)
+
+// UnbondingPeriod returns the epoch length and the number of full epochs after
+// which stake withdrawn from the staking contract can be claimed, defaulting
+// the unset values.
+func (c *ProofOfStakeConfig) UnbondingPeriod() (epoch uint64, epochs uint64) {
+	epoch, epochs = c.Epoch, c.UnbondingEpochs
+	if epoch == 0 {
+		epoch = DefaultProofOfStakeEpoch
+	}
+	if epochs == 0 {
+		epochs = DefaultUnbondingEpochs
+	}
+	return epoch, epochs
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -392,7 +458,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v, Muir Glacier: %v, Berlin: %v, London: %v, Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v, Muir Glacier: %v, Berlin: %v, London: %v, Falcon: %v, SignatureTx: %v, PQVerify: %v, Multisig: %v, StakingRewards: %v, Slashing: %v, Jailing: %v, Delegation: %v, Unbonding: %v, StakeSchedule: %v, Engine: %v}",
 		c.ChainID,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -407,6 +473,16 @@ func (c *ChainConfig) String() string {
 		c.MuirGlacierBlock,
 		c.BerlinBlock,
 		c.LondonBlock,
+		c.FalconBlock,
+		c.SignatureTxBlock,
+		c.PQVerifyBlock,
+		c.MultisigBlock,
+		c.StakingRewardsBlock,
+		c.SlashingBlock,
+		c.JailingBlock,
+		c.DelegationBlock,
+		c.UnbondingBlock,
+		c.StakeScheduleBlock,
 		engine,
 	)
 }
@@ -473,11 +549,73 @@ func (c *ChainConfig) IsLondon(num *big.Int) bool {
 	return isForked(c.LondonBlock, num)
 }
 
+// SystemContractUpgradesAt returns the system contract upgrades scheduled for
+// the given block.
+func (c *ChainConfig) SystemContractUpgradesAt(num *big.Int) []*SystemContractUpgrade {
+	var upgrades []*SystemContractUpgrade
+	for _, upgrade := range c.SystemContractUpgrades {
+		if upgrade.Block != nil && num != nil && upgrade.Block.Cmp(num) == 0 {
+			upgrades = append(upgrades, upgrade)
+		}
+	}
+	return upgrades
+}
+
 // IsCatalyst returns whether num is either equal to the Merge fork block or greater.
 func (c *ChainConfig) IsCatalyst(num *big.Int) bool {
 	return isForked(c.CatalystBlock, num)
 }
 
+// IsFalcon returns whether num is either equal to the Falcon-512 signatures fork block or greater.
+func (c *ChainConfig) IsFalcon(num *big.Int) bool {
+	return isForked(c.FalconBlock, num)
+}
+
+// IsSignatureTx returns whether num is either equal to the signature transactions fork block or greater.
+func (c *ChainConfig) IsSignatureTx(num *big.Int) bool {
+	return isForked(c.SignatureTxBlock, num)
+}
+
+// IsPQVerify returns whether num is either equal to the post-quantum signature verification fork block or greater.
+func (c *ChainConfig) IsPQVerify(num *big.Int) bool {
+	return isForked(c.PQVerifyBlock, num)
+}
+
+// IsMultisig returns whether num is either equal to the multisignature accounts fork block or greater.
+func (c *ChainConfig) IsMultisig(num *big.Int) bool {
+	return isForked(c.MultisigBlock, num)
+}
+
+// IsStakingRewards returns whether num is either equal to the proof-of-stake rewards fork block or greater.
+func (c *ChainConfig) IsStakingRewards(num *big.Int) bool {
+	return isForked(c.StakingRewardsBlock, num)
+}
+
+// IsSlashing returns whether num is either equal to the proof-of-stake slashing fork block or greater.
+func (c *ChainConfig) IsSlashing(num *big.Int) bool {
+	return isForked(c.SlashingBlock, num)
+}
+
+// IsJailing returns whether num is either equal to the proof-of-stake jailing fork block or greater.
+func (c *ChainConfig) IsJailing(num *big.Int) bool {
+	return isForked(c.JailingBlock, num)
+}
+
+// IsDelegation returns whether num is either equal to the proof-of-stake delegation fork block or greater.
+func (c *ChainConfig) IsDelegation(num *big.Int) bool {
+	return isForked(c.DelegationBlock, num)
+}
+
+// IsUnbonding returns whether num is either equal to the proof-of-stake unbonding fork block or greater.
+func (c *ChainConfig) IsUnbonding(num *big.Int) bool {
+	return isForked(c.UnbondingBlock, num)
+}
+
+// IsStakeSchedule returns whether num is either equal to the proof-of-stake stake schedule fork block or greater.
+func (c *ChainConfig) IsStakeSchedule(num *big.Int) bool {
+	return isForked(c.StakeScheduleBlock, num)
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -518,6 +656,8 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		{name: "muirGlacierBlock", block: c.MuirGlacierBlock, optional: true},
 		{name: "berlinBlock", block: c.BerlinBlock},
 		{name: "londonBlock", block: c.LondonBlock},
+		{name: "signatureTxBlock", block: c.SignatureTxBlock},
+		{name: "multisigBlock", block: c.MultisigBlock, optional: true},
 	} {
 		if lastFork.name != "" {
 			// Next one must be higher number
@@ -537,6 +677,27 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	// The staking contract upgraded at the unbonding fork builds on the one
+	// upgraded at the delegation fork...
+	if c.UnbondingBlock != nil && (c.DelegationBlock == nil || c.DelegationBlock.Cmp(c.UnbondingBlock) > 0) {
+		return fmt.Errorf("unsupported fork ordering: unbondingBlock enabled at %v, but delegationBlock enabled at %v",
+			c.UnbondingBlock, c.DelegationBlock)
+	}
+	// and the one upgraded at the jailing fork on the unbonding one
+	if c.JailingBlock != nil && (c.UnbondingBlock == nil || c.UnbondingBlock.Cmp(c.JailingBlock) > 0) {
+		return fmt.Errorf("unsupported fork ordering: jailingBlock enabled at %v, but unbondingBlock enabled at %v",
+			c.JailingBlock, c.UnbondingBlock)
+	}
+	// Fee and treasury shares are percentages of what the sealer earns, more
+	// than all of it can't be paid out
+	if pos := c.ProofOfStake; pos != nil {
+		if pos.FeeSharePercent > 100 {
+			return fmt.Errorf("invalid proof-of-stake config: feeSharePercent %d exceeds 100", pos.FeeSharePercent)
+		}
+		if pos.TreasuryPercent > 100 {
+			return fmt.Errorf("invalid proof-of-stake config: treasuryPercent %d exceeds 100", pos.TreasuryPercent)
+		}
+	}
 	return nil
 }
 
@@ -587,6 +748,66 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.LondonBlock, newcfg.LondonBlock, head) {
 		return newCompatError("London fork block", c.LondonBlock, newcfg.LondonBlock)
 	}
+	if isForkIncompatible(c.FalconBlock, newcfg.FalconBlock, head) {
+		return newCompatError("Falcon fork block", c.FalconBlock, newcfg.FalconBlock)
+	}
+	if isForkIncompatible(c.SignatureTxBlock, newcfg.SignatureTxBlock, head) {
+		return newCompatError("Signature tx fork block", c.SignatureTxBlock, newcfg.SignatureTxBlock)
+	}
+	if isForkIncompatible(c.PQVerifyBlock, newcfg.PQVerifyBlock, head) {
+		return newCompatError("PQ verify fork block", c.PQVerifyBlock, newcfg.PQVerifyBlock)
+	}
+	if isForkIncompatible(c.MultisigBlock, newcfg.MultisigBlock, head) {
+		return newCompatError("Multisig fork block", c.MultisigBlock, newcfg.MultisigBlock)
+	}
+	if isForkIncompatible(c.StakingRewardsBlock, newcfg.StakingRewardsBlock, head) {
+		return newCompatError("Staking rewards fork block", c.StakingRewardsBlock, newcfg.StakingRewardsBlock)
+	}
+	if isForkIncompatible(c.SlashingBlock, newcfg.SlashingBlock, head) {
+		return newCompatError("Slashing fork block", c.SlashingBlock, newcfg.SlashingBlock)
+	}
+	if isForkIncompatible(c.JailingBlock, newcfg.JailingBlock, head) {
+		return newCompatError("Jailing fork block", c.JailingBlock, newcfg.JailingBlock)
+	}
+	if isForkIncompatible(c.DelegationBlock, newcfg.DelegationBlock, head) {
+		return newCompatError("Delegation fork block", c.DelegationBlock, newcfg.DelegationBlock)
+	}
+	if isForkIncompatible(c.UnbondingBlock, newcfg.UnbondingBlock, head) {
+		return newCompatError("Unbonding fork block", c.UnbondingBlock, newcfg.UnbondingBlock)
+	}
+	if isForkIncompatible(c.StakeScheduleBlock, newcfg.StakeScheduleBlock, head) {
+		return newCompatError("Stake schedule fork block", c.StakeScheduleBlock, newcfg.StakeScheduleBlock)
+	}
+	if err := checkSystemContractUpgrades(c.SystemContractUpgrades, newcfg.SystemContractUpgrades, head); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkSystemContractUpgrades ensures that no system contract upgrade already
+// applied at the given head was rescheduled, removed or modified.
+func checkSystemContractUpgrades(stored, upgrades []*SystemContractUpgrade, head *big.Int) *ConfigCompatError {
+	find := func(list []*SystemContractUpgrade, name string) *SystemContractUpgrade {
+		for _, upgrade := range list {
+			if upgrade.Name == name {
+				return upgrade
+			}
+		}
+		return &SystemContractUpgrade{Name: name}
+	}
+	for _, list := range [][]*SystemContractUpgrade{stored, upgrades} {
+		for _, upgrade := range list {
+			s1, s2 := find(stored, upgrade.Name), find(upgrades, upgrade.Name)
+			what := fmt.Sprintf("System contract upgrade %s", upgrade.Name)
+
+			if isForkIncompatible(s1.Block, s2.Block, head) {
+				return newCompatError(what+" block", s1.Block, s2.Block)
+			}
+			if isForked(s1.Block, head) && !reflect.DeepEqual(s1.Contracts, s2.Contracts) {
+				return newCompatError(what+" contracts", s1.Block, s2.Block)
+			}
+		}
+	}
 	return nil
 }
 
@@ -655,6 +876,7 @@ type Rules struct {
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158               bool
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
 	IsBerlin, IsLondon, IsCatalyst                          bool
+	IsPQVerify                                              bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -676,5 +898,6 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 		IsBerlin:         c.IsBerlin(num),
 		IsLondon:         c.IsLondon(num),
 		IsCatalyst:       c.IsCatalyst(num),
+		IsPQVerify:       c.IsPQVerify(num),
 	}
 }
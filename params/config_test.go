@@ -87,6 +87,23 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     30,
 			},
 		},
+		{
+			stored:  &ChainConfig{SystemContractUpgrades: []*SystemContractUpgrade{{Name: "A", Block: big.NewInt(30)}}},
+			new:     &ChainConfig{SystemContractUpgrades: []*SystemContractUpgrade{{Name: "A", Block: big.NewInt(40)}}},
+			head:    20,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{SystemContractUpgrades: []*SystemContractUpgrade{{Name: "A", Block: big.NewInt(30)}}},
+			new:    &ChainConfig{},
+			head:   40,
+			wantErr: &ConfigCompatError{
+				What:         "System contract upgrade A block",
+				StoredConfig: big.NewInt(30),
+				NewConfig:    nil,
+				RewindTo:     29,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -96,3 +113,23 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+// Tests that proof-of-stake configs sharing out more than the sealer earns are
+// rejected.
+func TestCheckProofOfStakeShares(t *testing.T) {
+	tests := []struct {
+		config *ProofOfStakeConfig
+		valid  bool
+	}{
+		{config: &ProofOfStakeConfig{FeeSharePercent: 100, TreasuryPercent: 100}, valid: true},
+		{config: &ProofOfStakeConfig{FeeSharePercent: 101}, valid: false},
+		{config: &ProofOfStakeConfig{TreasuryPercent: 101}, valid: false},
+	}
+	for i, test := range tests {
+		config := *AllProofOfStakeProtocolChanges
+		config.ProofOfStake = test.config
+		if err := config.CheckConfigForkOrder(); (err == nil) != test.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, test.valid)
+		}
+	}
+}
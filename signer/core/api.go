@@ -31,6 +31,7 @@ import (
 	"github.com/DogeProtocol/dp/accounts/usbwallet"
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/core/types"
 	"github.com/DogeProtocol/dp/internal/ethapi"
 	"github.com/DogeProtocol/dp/log"
 	"github.com/DogeProtocol/dp/signer/core/apitypes"
@@ -41,7 +42,7 @@ const (
 	// numberOfAccountsToDerive For hardware wallets, the number of accounts to derive
 	numberOfAccountsToDerive = 10
 	// ExternalAPIVersion -- see extapi_changelog.md
-	ExternalAPIVersion = "6.1.0"
+	ExternalAPIVersion = "6.2.0"
 	// InternalAPIVersion -- see intapi_changelog.md
 	InternalAPIVersion = "7.0.1"
 )
@@ -64,6 +65,8 @@ type ExternalAPI interface {
 	Version(ctx context.Context) (string, error)
 	// SignGnosisSafeTransaction signs/confirms a gnosis-safe multisig transaction
 	SignGnosisSafeTx(ctx context.Context, signerAddress common.MixedcaseAddress, gnosisTx GnosisSafeTx, methodSelector *string) (*GnosisSafeTx, error)
+	// SignMultisigTransaction adds the signature of a member account to a multisig transaction
+	SignMultisigTransaction(ctx context.Context, signerAddress common.MixedcaseAddress, rawTx hexutil.Bytes, methodSelector *string) (*ethapi.SignTransactionResult, error)
 }
 
 // UIClientAPI specifies what method a UI needs to implement to be able to be used as a
@@ -272,7 +275,17 @@ type (
 	}
 )
 
-var ErrRequestDenied = errors.New("request denied")
+var (
+	ErrRequestDenied = errors.New("request denied")
+
+	// ErrMultisigTxModified is returned if the UI modifies a multisig transaction
+	// upon approval.
+	ErrMultisigTxModified = errors.New("multisig transaction modified by UI")
+
+	// ErrMultisigUnsupported is returned if the wallet of the signing account
+	// can't sign multisig transactions.
+	ErrMultisigUnsupported = errors.New("wallet does not support multisig transactions")
+)
 
 // NewSignerAPI creates a new API that can be used for Account management.
 // ksLocation specifies the directory where to store the password protected private
@@ -613,6 +626,116 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args apitypes.SendTxA
 
 }
 
+// SignMultisigTransaction adds the signature of a member account to a multisig
+// transaction, given in binary form with the signatures collected so far, and
+// returns it both as json and rlp-encoded form. The transaction goes through the
+// same validation and approval as with SignTransaction, but the UI can't modify
+// it, as that would void the signatures of the other members.
+func (api *SignerAPI) SignMultisigTransaction(ctx context.Context, signerAddress common.MixedcaseAddress, rawTx hexutil.Bytes, methodSelector *string) (*ethapi.SignTransactionResult, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(rawTx); err != nil {
+		return nil, err
+	}
+	sigs, err := tx.MultisigSignatures()
+	if err != nil {
+		return nil, err
+	}
+	_, pub := tx.RawSignature()
+	key, err := types.DecodeMultisigKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if tx.ChainId().Cmp(api.chainID) != 0 {
+		log.Error("Signing request with wrong chain id", "requested", tx.ChainId(), "configured", api.chainID)
+		return nil, fmt.Errorf("requested chainid %d does not match the configuration of the signer",
+			tx.ChainId())
+	}
+	args := multisigTxArgs(key.Address(), tx)
+	msgs, err := api.validator.ValidateTransaction(methodSelector, args)
+	if err != nil {
+		return nil, err
+	}
+	// If we are in 'rejectMode', then reject rather than show the user warnings
+	if api.rejectMode {
+		if err := msgs.GetWarnings(); err != nil {
+			return nil, err
+		}
+	}
+	msgs.Info(fmt.Sprintf("Multisig transaction of %d-of-%d account, %d signatures collected so far",
+		key.Threshold, len(key.Keys), len(sigs)))
+	req := SignTxRequest{
+		Transaction: *args,
+		Meta:        MetadataFromContext(ctx),
+		Callinfo:    msgs.Messages,
+	}
+	// Process approval
+	result, err := api.UI.ApproveTx(&req)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Approved {
+		return nil, ErrRequestDenied
+	}
+	if logDiff(&req, &result) {
+		return nil, ErrMultisigTxModified
+	}
+	acc := accounts.Account{Address: signerAddress.Address()}
+	wallet, err := api.am.Find(acc)
+	if err != nil {
+		return nil, err
+	}
+	msWallet, ok := wallet.(accounts.MultisigWallet)
+	if !ok {
+		return nil, ErrMultisigUnsupported
+	}
+	// Get the password for the transaction
+	pw, err := api.lookupOrQueryPassword(acc.Address, "Account password",
+		fmt.Sprintf("Please enter the password for account %s", acc.Address.String()))
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := msWallet.SignMultisigTxWithPassphrase(acc, pw, tx, api.chainID)
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+	data, err := signedTx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	response := ethapi.SignTransactionResult{Raw: data, Tx: signedTx}
+
+	// Finally, send the signed tx to the UI
+	api.UI.OnApprovedTx(response)
+	// ...and to the external caller
+	return &response, nil
+}
+
+// multisigTxArgs returns the transaction arguments of a multisig transaction,
+// for validation and approval.
+func multisigTxArgs(from common.Address, tx *types.Transaction) *apitypes.SendTxArgs {
+	var (
+		data       = hexutil.Bytes(tx.Data())
+		accessList = tx.AccessList()
+		args       = &apitypes.SendTxArgs{
+			From:                 common.NewMixedcaseAddress(from),
+			Gas:                  hexutil.Uint64(tx.Gas()),
+			MaxFeePerGas:         (*hexutil.Big)(tx.GasFeeCap()),
+			MaxPriorityFeePerGas: (*hexutil.Big)(tx.GasTipCap()),
+			Value:                hexutil.Big(*tx.Value()),
+			Nonce:                hexutil.Uint64(tx.Nonce()),
+			Data:                 &data,
+			AccessList:           &accessList,
+			ChainID:              (*hexutil.Big)(tx.ChainId()),
+		}
+	)
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+	return args
+}
+
 func (api *SignerAPI) SignGnosisSafeTx(ctx context.Context, signerAddress common.MixedcaseAddress, gnosisTx GnosisSafeTx, methodSelector *string) (*GnosisSafeTx, error) {
 	// Do the usual validations, but on the last-stage transaction
 	args := gnosisTx.ArgsForValidation()
@@ -62,6 +62,23 @@ func (l *AuditLogger) SignTransaction(ctx context.Context, args apitypes.SendTxA
 	return res, e
 }
 
+func (l *AuditLogger) SignMultisigTransaction(ctx context.Context, addr common.MixedcaseAddress, rawTx hexutil.Bytes, methodSelector *string) (*ethapi.SignTransactionResult, error) {
+	sel := "<nil>"
+	if methodSelector != nil {
+		sel = *methodSelector
+	}
+	l.log.Info("SignMultisigTransaction", "type", "request", "metadata", MetadataFromContext(ctx).String(),
+		"addr", addr.String(), "tx", common.Bytes2Hex(rawTx), "methodSelector", sel)
+
+	res, e := l.api.SignMultisigTransaction(ctx, addr, rawTx, methodSelector)
+	if res != nil {
+		l.log.Info("SignMultisigTransaction", "type", "response", "data", common.Bytes2Hex(res.Raw), "error", e)
+	} else {
+		l.log.Info("SignMultisigTransaction", "type", "response", "data", res, "error", e)
+	}
+	return res, e
+}
+
 func (l *AuditLogger) SignData(ctx context.Context, contentType string, addr common.MixedcaseAddress, data interface{}) (hexutil.Bytes, error) {
 	marshalledData, _ := json.Marshal(data) // can ignore error, marshalling what we just unmarshalled
 	l.log.Info("SignData", "type", "request", "metadata", MetadataFromContext(ctx).String(),
@@ -3,6 +3,7 @@ package systemcontracts_test
 import (
 	"fmt"
 	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/params"
 	"github.com/DogeProtocol/dp/systemcontracts"
 	"testing"
 )
@@ -10,15 +11,20 @@ import (
 func TestFunctions(t *testing.T) {
 	//fmt.Println(common.HexToAddress("0xB5c2F2779716bBa6Ba9B4372501208110581EDec").Bytes())
 
-	cont := systemcontracts.GetContracts()
+	config := &params.ChainConfig{
+		ProofOfStake: &params.ProofOfStakeConfig{
+			StakingContract: common.HexToAddress("0x0000000000000000000000000000000000001000"),
+		},
+	}
+	cont := systemcontracts.GetContracts(config)
 	fmt.Println("Contracts : ", cont)
-	verify := systemcontracts.GetContractVerify(common.HexToAddress("0x0000000000000000000000000000000000001000"))
+	verify := systemcontracts.GetContractVerify(config, common.HexToAddress("0x0000000000000000000000000000000000001000"))
 	fmt.Println("Verify contract bool : ", verify)
-	verify = systemcontracts.GetContractVerify(common.HexToAddress("0x0000000000000000000000000000000000000000"))
+	verify = systemcontracts.GetContractVerify(config, common.HexToAddress("0x0000000000000000000000000000000000000000"))
 	fmt.Println("Verify contract bool : ", verify)
-	c := systemcontracts.GetStakingContract_Address_String()
+	c := systemcontracts.GetStakingContract_Address_String(config)
 	fmt.Println("Contract address string : ", c)
-	v := systemcontracts.GetStakingContract_Address()
+	v := systemcontracts.GetStakingContract_Address(config)
 	fmt.Println("Contract address ", v)
 	s := systemcontracts.GetContract_Method_ListValidator()
 	fmt.Println("Method", s)
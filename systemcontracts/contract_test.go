@@ -2,42 +2,49 @@ package systemcontracts
 
 import (
 	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/params"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
+var testConfig = &params.ChainConfig{
+	ProofOfStake: &params.ProofOfStakeConfig{
+		StakingContract: common.HexToAddress("0x0000000000000000000000000000000000001000"),
+	},
+}
+
 func TestSystemContracts(t *testing.T) {
-	for _, c := range systemContracts {
-		assert.Equal(t, c, "0x0000000000000000000000000000000000001000")
+	for _, c := range GetContracts(testConfig) {
+		assert.Equal(t, common.HexToAddress(c), common.HexToAddress("0x0000000000000000000000000000000000001000"))
 	}
 }
 
 func TestSystemContractsFail(t *testing.T) {
-	for _, c := range systemContracts {
-		assert.NotEqual(t, c, "0x0000000000000000000000000000000000000000")
+	for _, c := range GetContracts(testConfig) {
+		assert.NotEqual(t, common.HexToAddress(c), common.HexToAddress("0x0000000000000000000000000000000000000000"))
 	}
+	assert.Empty(t, GetContracts(&params.ChainConfig{}))
 }
 
 func TestSystemContractsData(t *testing.T) {
-	c := systemContractsData["0x0000000000000000000000000000000000001000"]
-	assert.Equal(t, c, systemContractsData["0x0000000000000000000000000000000000001000"])
+	c := GetContract_Data(testConfig, "0x0000000000000000000000000000000000001000")
+	assert.Equal(t, c.ContractAddress, common.HexToAddress("0x0000000000000000000000000000000000001000"))
 }
 
 func TestSystemContractsDataFail(t *testing.T) {
-	c := systemContractsData["0x0000000000000000000000000000000000001000"]
-	assert.NotEqual(t, c, systemContractsData["0x0000000000000000000000000000000000000000"])
+	c := GetContract_Data(testConfig, "0x0000000000000000000000000000000000000000")
+	assert.Nil(t, c)
 }
 
 func TestSystemContractVerify(t *testing.T) {
-	contract := systemContractsData["0x0000000000000000000000000000000000001000"]
-	if contract == nil {
-		t.Fatalf("contract is nil")
-	}
-	s := systemContractVerify[common.HexToAddress(systemContractsData["0x0000000000000000000000000000000000001000"].ContractAddressString)]
+	s := GetContractVerify(testConfig, common.HexToAddress("0x0000000000000000000000000000000000001000"))
 	assert.Equal(t, s, true)
 }
 
 func TestSystemContractVerifyFail(t *testing.T) {
-	s := systemContractVerify[common.HexToAddress("0x0000000000000000000000000000000000000000")]
+	s := GetContractVerify(testConfig, common.HexToAddress("0x0000000000000000000000000000000000000000"))
+	assert.Equal(t, s, false)
+
+	s = GetContractVerify(&params.ChainConfig{}, common.Address{})
 	assert.Equal(t, s, false)
 }
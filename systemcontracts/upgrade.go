@@ -1,16 +1,15 @@
 package systemcontracts
 
 import (
-	"encoding/hex"
-	"errors"
 	"fmt"
 	"math/big"
-	"strings"
+	"sync"
 
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/core/state"
 	"github.com/DogeProtocol/dp/log"
 	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/systemcontracts1"
 )
 
 type UpgradeConfig struct {
@@ -18,7 +17,7 @@ type UpgradeConfig struct {
 	AfterUpgrade  upgradeHook
 	ContractAddr  common.Address
 	CommitUrl     string
-	Code          string
+	Code          []byte
 }
 
 type Upgrade struct {
@@ -28,37 +27,107 @@ type Upgrade struct {
 
 type upgradeHook func(blockNumber *big.Int, contractAddr common.Address, statedb *state.StateDB) error
 
+// upgradeHooks are the storage migrations accompanying contract upgrades,
+// registered by upgrade name and contract address.
+type upgradeHooks struct {
+	before upgradeHook
+	after  upgradeHook
+}
+
 var (
-// GenesisHash common.Hash
+	hooks     = make(map[string]map[common.Address]*upgradeHooks)
+	hooksLock sync.RWMutex
 )
 
-func init() {
-	// reserved for future use to instantiate Upgrade vars
+// RegisterUpgradeHooks registers the storage migrations to run before and after
+// the code of a contract is replaced by the named upgrade. Either hook may be
+// nil. Hooks must be registered before the upgrade block is processed.
+func RegisterUpgradeHooks(upgradeName string, contractAddr common.Address, before upgradeHook, after upgradeHook) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+
+	if hooks[upgradeName] == nil {
+		hooks[upgradeName] = make(map[common.Address]*upgradeHooks)
+	}
+	hooks[upgradeName][contractAddr] = &upgradeHooks{before: before, after: after}
 }
 
+// UpgradeBuildInSystemContract applies the system contract upgrades the chain
+// configuration schedules for the given block.
 func UpgradeBuildInSystemContract(config *params.ChainConfig, blockNumber *big.Int, statedb *state.StateDB) error {
 	if config == nil || blockNumber == nil || statedb == nil {
 		return nil
 	}
+	for _, upgrade := range config.SystemContractUpgradesAt(blockNumber) {
+		applySystemContractUpgrade(collectContracts(upgrade), blockNumber, statedb, log.Root())
+	}
+	return UpgradeStakingContract(config, blockNumber, statedb)
+}
+
+// UpgradeStakingContract replaces the code of the staking contract as required
+// by the staking forks activated at the given block. It is also called at
+// genesis to bring the predeployed contract up to date.
+func UpgradeStakingContract(config *params.ChainConfig, blockNumber *big.Int, statedb *state.StateDB) error {
+	if config == nil || blockNumber == nil || statedb == nil || systemcontracts1.IsStakingContract(config) != nil {
+		return nil
+	}
+	forks := []struct {
+		name  string
+		block *big.Int
+		after upgradeHook
+	}{
+		{"Delegation", config.DelegationBlock, nil},
+		{"Unbonding", config.UnbondingBlock, setUnbondingPeriod(config.ProofOfStake)},
+		{"Jailing", config.JailingBlock, nil},
+	}
+	for _, fork := range forks {
+		if fork.block == nil || fork.block.Cmp(blockNumber) != 0 {
+			continue
+		}
+		applySystemContractUpgrade(&Upgrade{
+			UpgradeName: fork.name,
+			Configs: []*UpgradeConfig{{
+				ContractAddr: systemcontracts1.GetStakingContract_Address(config),
+				Code:         systemcontracts1.GetStakingContract_CodeAt(config, blockNumber),
+				AfterUpgrade: fork.after,
+			}},
+		}, blockNumber, statedb, log.Root())
+	}
 	return nil
 }
 
-func collectContracts(config *params.ChainConfig) ([]*UpgradeConfig, error) {
-	contracts := GetContracts()
-	if len(contracts) == 0 {
-		return nil, errors.New("Missing systemContracts in  config for Bombay fork")
+// setUnbondingPeriod returns the storage migration of the unbonding upgrade,
+// which sets the epoch length and the number of epochs withdrawals stay queued
+// in the staking contract's _epoch and _unbondingEpochs state variables.
+func setUnbondingPeriod(config *params.ProofOfStakeConfig) upgradeHook {
+	return func(blockNumber *big.Int, contractAddr common.Address, statedb *state.StateDB) error {
+		epoch, epochs := config.UnbondingPeriod()
+		statedb.SetState(contractAddr, common.BigToHash(big.NewInt(19)), common.BigToHash(new(big.Int).SetUint64(epoch)))
+		statedb.SetState(contractAddr, common.BigToHash(big.NewInt(20)), common.BigToHash(new(big.Int).SetUint64(epochs)))
+		return nil
 	}
+}
 
-	upgrades := make([]*UpgradeConfig, len(contracts))
-	for i, contract := range contracts {
-		c := GetContract_Data(contract)
-		upgrades[i] = &UpgradeConfig{
-			ContractAddr: c.ContractAddress,
-			Code:         c.BIN,
+// collectContracts assembles the contract upgrades of a fork along with their
+// registered storage migrations.
+func collectContracts(upgrade *params.SystemContractUpgrade) *Upgrade {
+	hooksLock.RLock()
+	defer hooksLock.RUnlock()
+
+	configs := make([]*UpgradeConfig, len(upgrade.Contracts))
+	for i, contract := range upgrade.Contracts {
+		configs[i] = &UpgradeConfig{
+			ContractAddr: contract.Address,
+			Code:         contract.Code,
+		}
+		if h := hooks[upgrade.Name][contract.Address]; h != nil {
+			configs[i].BeforeUpgrade, configs[i].AfterUpgrade = h.before, h.after
 		}
 	}
-
-	return upgrades, nil
+	return &Upgrade{
+		UpgradeName: upgrade.Name,
+		Configs:     configs,
+	}
 }
 
 func applySystemContractUpgrade(upgrade *Upgrade, blockNumber *big.Int, statedb *state.StateDB, logger log.Logger) {
@@ -79,12 +148,7 @@ func applySystemContractUpgrade(upgrade *Upgrade, blockNumber *big.Int, statedb
 			}
 		}
 
-		newContractCode, err := hex.DecodeString(strings.TrimPrefix(cfg.Code, "0x"))
-		fmt.Println("applySystemContractUpgrade : ", cfg.ContractAddr.String(), newContractCode)
-		if err != nil {
-			panic(fmt.Errorf("failed to decode new contract code: %s", err.Error()))
-		}
-		statedb.SetCode(cfg.ContractAddr, newContractCode)
+		statedb.SetCode(cfg.ContractAddr, cfg.Code)
 
 		if cfg.AfterUpgrade != nil {
 			err := cfg.AfterUpgrade(blockNumber, cfg.ContractAddr, statedb)
@@ -0,0 +1,80 @@
+package systemcontracts
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/core/state"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/systemcontracts1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeBuildInSystemContract(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	contract := common.HexToAddress("0x0000000000000000000000000000000000001000")
+	config := &params.ChainConfig{
+		SystemContractUpgrades: []*params.SystemContractUpgrade{{
+			Name:      "upgrade-test",
+			Block:     big.NewInt(10),
+			Contracts: []*params.SystemContractCode{{Address: contract, Code: []byte{0x60, 0x00}}},
+		}},
+	}
+	statedb.SetCode(contract, []byte{0x00})
+
+	var calls []string
+	RegisterUpgradeHooks("upgrade-test", contract,
+		func(blockNumber *big.Int, addr common.Address, statedb *state.StateDB) error {
+			assert.Equal(t, []byte{0x00}, statedb.GetCode(addr))
+			calls = append(calls, "before")
+			return nil
+		},
+		func(blockNumber *big.Int, addr common.Address, statedb *state.StateDB) error {
+			statedb.SetState(addr, common.Hash{}, common.BigToHash(blockNumber))
+			calls = append(calls, "after")
+			return nil
+		},
+	)
+	// Blocks other than the upgrade block are left alone
+	assert.NoError(t, UpgradeBuildInSystemContract(config, big.NewInt(9), statedb))
+	assert.Equal(t, []byte{0x00}, statedb.GetCode(contract))
+	assert.Empty(t, calls)
+
+	assert.NoError(t, UpgradeBuildInSystemContract(config, big.NewInt(10), statedb))
+	assert.Equal(t, []byte{0x60, 0x00}, statedb.GetCode(contract))
+	assert.Equal(t, []string{"before", "after"}, calls)
+	assert.Equal(t, common.BigToHash(big.NewInt(10)), statedb.GetState(contract, common.Hash{}))
+}
+
+func TestUpgradeStakingContract(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	contract := common.HexToAddress("0x0000000000000000000000000000000000001000")
+	config := &params.ChainConfig{
+		DelegationBlock: big.NewInt(10),
+		ProofOfStake:    &params.ProofOfStakeConfig{StakingContract: contract},
+	}
+	statedb.SetCode(contract, systemcontracts1.GetStakingContract_Code())
+	statedb.SetState(contract, common.Hash{}, common.BigToHash(common.Big1))
+
+	// The staking contract keeps its code until the delegation fork
+	assert.NoError(t, UpgradeBuildInSystemContract(config, big.NewInt(9), statedb))
+	assert.Equal(t, systemcontracts1.GetStakingContract_Code(), statedb.GetCode(contract))
+
+	assert.NoError(t, UpgradeBuildInSystemContract(config, big.NewInt(10), statedb))
+	assert.Equal(t, systemcontracts1.GetStakingContract_CodeAt(config, big.NewInt(10)), statedb.GetCode(contract))
+	assert.NotEqual(t, systemcontracts1.GetStakingContract_Code(), statedb.GetCode(contract))
+	assert.Equal(t, common.BigToHash(common.Big1), statedb.GetState(contract, common.Hash{}))
+
+	// The unbonding upgrade sets the unbonding period of the contract
+	config.UnbondingBlock = big.NewInt(20)
+	config.ProofOfStake.Epoch = 100
+
+	assert.NoError(t, UpgradeBuildInSystemContract(config, big.NewInt(20), statedb))
+	assert.Equal(t, systemcontracts1.GetStakingContract_CodeAt(config, big.NewInt(20)), statedb.GetCode(contract))
+	assert.Equal(t, common.BigToHash(big.NewInt(100)), statedb.GetState(contract, common.BigToHash(big.NewInt(19))))
+	assert.Equal(t, common.BigToHash(big.NewInt(params.DefaultUnbondingEpochs)), statedb.GetState(contract, common.BigToHash(big.NewInt(20))))
+}
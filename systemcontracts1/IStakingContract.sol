@@ -8,6 +8,15 @@ interface IStakingContract {
 
     //Withdraw
     function withdraw(uint256 value)  external;
+    function claim()  external;
+
+    //Delegation
+    function delegate(address validator)  external payable;
+    function undelegate(address validator, uint256 amount)  external;
+    function setCommission(uint256 percent)  external;
+
+    //Jailing
+    function unjail()  external;
 
     //get data
     function depositCount() external view returns (uint256);
@@ -15,6 +24,11 @@ interface IStakingContract {
     function depositBalanceOf(address owner)  external view returns (uint256);
     function listValidator() external view returns (address[] memory);
     function getDepositor(address validator) external view returns (address);
+    function delegationOf(address validator, address delegator) external view returns (uint256);
+    function delegatedStakeOf(address validator) external view returns (uint256);
+    function commissionOf(address validator) external view returns (uint256);
+    function listDelegators(address validator) external view returns (address[] memory);
+    function listDelegates(address delegator) external view returns (address[] memory);
 
     event OnNewDeposit(
         address indexed sender, 
@@ -32,4 +46,40 @@ interface IStakingContract {
         uint256 blockNumber,
         uint256 blockTime
     );
+
+    event OnClaim(
+        address indexed sender,
+        uint256 value,
+        uint256 blockNumber,
+        uint256 blockTime
+    );
+
+    event OnDelegate(
+        address indexed delegator,
+        address indexed validator,
+        uint256 value,
+        uint256 blockNumber,
+        uint256 blockTime
+    );
+
+    event OnUndelegate(
+        address indexed delegator,
+        address indexed validator,
+        uint256 value,
+        uint256 blockNumber,
+        uint256 blockTime
+    );
+
+    event OnSetCommission(
+        address indexed validator,
+        uint256 percent,
+        uint256 blockNumber,
+        uint256 blockTime
+    );
+
+    event OnUnjail(
+        address indexed validator,
+        uint256 blockNumber,
+        uint256 blockTime
+    );
 }
@@ -22,6 +22,39 @@ contract StakingContract is IStakingContract {
 
     //list of validator id 
     address[] private _validatorList; 
+
+    //delegations: validator => delegator => amount
+    mapping (address => mapping (address => uint256)) private _delegations;
+    mapping (address => uint256) private _delegatedStake;
+
+    //share of the delegators' rewards kept by a validator, in percent
+    mapping (address => uint256) private _commission;
+
+    //delegators of a validator and validators of a delegator, indexed by position plus one
+    mapping (address => address[]) private _delegators;
+    mapping (address => mapping (address => uint256)) private _delegatorIndex;
+    mapping (address => address[]) private _delegates;
+    mapping (address => mapping (address => uint256)) private _delegateIndex;
+
+    //withdrawn deposits and unbonded delegations waiting for the end of the unbonding period
+    struct Withdrawal {
+        uint256 amount;
+        uint256 maturity;
+        address validator;
+    }
+
+    //withdrawal queue of an account, the entries before the head being claimed already
+    mapping (address => Withdrawal[]) private _withdrawals;
+    mapping (address => uint256) private _withdrawalHead;
+
+    //accounts with withdrawals pending from a validator, indexed by position plus one, and their number of pending withdrawals
+    mapping (address => address[]) private _unbonding;
+    mapping (address => mapping (address => uint256)) private _unbondingIndex;
+    mapping (address => mapping (address => uint256)) private _unbondingCount;
+
+    //epoch length and number of full epochs withdrawals stay queued, set by the chain at the unbonding fork
+    uint256 private _epoch;
+    uint256 private _unbondingEpochs;
    
     constructor() {
         _depositCount = 0;
@@ -37,6 +70,51 @@ contract StakingContract is IStakingContract {
         return bytes32(uint256(uint160(data)) << 96);
     }
 
+    function bytes32tovalidator(bytes32 data) internal pure returns (address) {
+        return address(uint160(uint256(data) >> 96));
+    }
+
+    function addAddress(address[] storage list, mapping (address => uint256) storage index, address member) private {
+        if (index[member] != 0) {
+            return;
+        }
+        list.push(member);
+        index[member] = list.length;
+    }
+
+    function removeAddress(address[] storage list, mapping (address => uint256) storage index, address member) private {
+        uint256 position = index[member];
+        if (position == 0) {
+            return;
+        }
+        uint256 last = list.length - 1;
+        if (position - 1 != last) {
+            address moved = list[last];
+            list[position - 1] = moved;
+            index[moved] = position;
+        }
+        list.pop();
+        delete index[member];
+    }
+
+    function removeValidator(address validator) private {
+        for (uint256 i = 0; i < _validatorList.length; i++) {
+            if (_validatorList[i] == validator) {
+                _validatorList[i] = _validatorList[_validatorList.length - 1];
+                _validatorList.pop();
+                return;
+            }
+        }
+    }
+
+    function enqueueWithdrawal(address account, address validator, uint256 amount) private {
+        uint256 maturity = block.number.div(_epoch).add(1).add(_unbondingEpochs).mul(_epoch);
+        _withdrawals[account].push(Withdrawal(amount, maturity, validator));
+
+        _unbondingCount[validator][account] = _unbondingCount[validator][account].add(1);
+        addAddress(_unbonding[validator], _unbondingIndex[validator], account);
+    }
+
     function newDeposit(bytes calldata pubkey) override external payable {
         require(pubkey.length > 0, "Public key is invalid");
         require(_validatorIdSenderMapping[_senderValidatorIdMapping[msg.sender]] != msg.sender, "Sender already exists");
@@ -68,10 +146,16 @@ contract StakingContract is IStakingContract {
     }
 
     function withdraw(uint256 value)  override external {
+        require(value > 0, "Invalid amount");
         require(_balances[msg.sender] >= value, "Insufficient funds");
+        _balances[msg.sender] = _balances[msg.sender] - value;
         _totalDepositBalance = _totalDepositBalance.sub(value);
-        _balances[msg.sender] = _balances[msg.sender].sub(value);
-         msg.sender.transfer(value);
+
+        address validator = bytes32tovalidator(_senderValidatorIdMapping[msg.sender]);
+        if (_balances[msg.sender] == 0) {
+            removeValidator(validator);
+        }
+        enqueueWithdrawal(msg.sender, validator, value);
 
         emit OnWithdrawKey(
             msg.sender,
@@ -102,4 +186,121 @@ contract StakingContract is IStakingContract {
         address depositor = _validatorIdSenderMapping[validatorId];
         return depositor;
     }
+
+    function delegate(address validator) override external payable {
+        require(msg.value > 0, "Invalid amount");
+        require(_validatorIdSenderMapping[addresstobytes32(validator)] != address(0), "Unknown validator");
+
+        _delegations[validator][msg.sender] = _delegations[validator][msg.sender].add(msg.value);
+        _delegatedStake[validator] = _delegatedStake[validator].add(msg.value);
+
+        addAddress(_delegators[validator], _delegatorIndex[validator], msg.sender);
+        addAddress(_delegates[msg.sender], _delegateIndex[msg.sender], validator);
+
+        emit OnDelegate(
+            msg.sender,
+            validator,
+            msg.value,
+            block.number,
+            block.timestamp
+        );
+    }
+
+    function undelegate(address validator, uint256 amount) override external {
+        require(amount > 0, "Invalid amount");
+        uint256 bonded = _delegations[validator][msg.sender];
+        require(bonded >= amount, "Insufficient delegation");
+
+        _delegations[validator][msg.sender] = bonded - amount;
+        _delegatedStake[validator] = _delegatedStake[validator].sub(amount);
+
+        if (bonded == amount) {
+            removeAddress(_delegators[validator], _delegatorIndex[validator], msg.sender);
+            removeAddress(_delegates[msg.sender], _delegateIndex[msg.sender], validator);
+        }
+        enqueueWithdrawal(msg.sender, validator, amount);
+
+        emit OnUndelegate(
+            msg.sender,
+            validator,
+            amount,
+            block.number,
+            block.timestamp
+        );
+    }
+
+    function claim() override external {
+        Withdrawal[] storage queue = _withdrawals[msg.sender];
+        uint256 head = _withdrawalHead[msg.sender];
+        uint256 paid = 0;
+
+        for (; head < queue.length && queue[head].maturity <= block.number; head++) {
+            Withdrawal memory withdrawal = queue[head];
+            paid = paid.add(withdrawal.amount);
+            delete queue[head];
+
+            uint256 count = _unbondingCount[withdrawal.validator][msg.sender].sub(1);
+            _unbondingCount[withdrawal.validator][msg.sender] = count;
+            if (count == 0) {
+                removeAddress(_unbonding[withdrawal.validator], _unbondingIndex[withdrawal.validator], msg.sender);
+            }
+        }
+        _withdrawalHead[msg.sender] = head;
+        msg.sender.transfer(paid);
+
+        emit OnClaim(
+            msg.sender,
+            paid,
+            block.number,
+            block.timestamp
+        );
+    }
+
+    function setCommission(uint256 percent) override external {
+        require(percent <= 100, "Invalid commission");
+        bytes32 validatorId = _senderValidatorIdMapping[msg.sender];
+        require(_validatorIdSenderMapping[validatorId] == msg.sender, "Sender is not a depositor");
+
+        address validator = bytes32tovalidator(validatorId);
+        _commission[validator] = percent;
+
+        emit OnSetCommission(
+            validator,
+            percent,
+            block.number,
+            block.timestamp
+        );
+    }
+
+    //the consensus engine relists the jailed validator once the jail period is over, if the deposit meets the minimum stake
+    function unjail() override external {
+        bytes32 validatorId = _senderValidatorIdMapping[msg.sender];
+        require(_validatorIdSenderMapping[validatorId] == msg.sender, "Sender is not a depositor");
+
+        emit OnUnjail(
+            bytes32tovalidator(validatorId),
+            block.number,
+            block.timestamp
+        );
+    }
+
+    function delegationOf(address validator, address delegator) override external view returns (uint256) {
+        return _delegations[validator][delegator];
+    }
+
+    function delegatedStakeOf(address validator) override external view returns (uint256) {
+        return _delegatedStake[validator];
+    }
+
+    function commissionOf(address validator) override external view returns (uint256) {
+        return _commission[validator];
+    }
+
+    function listDelegators(address validator) override external view returns (address[] memory) {
+        return _delegators[validator];
+    }
+
+    function listDelegates(address delegator) override external view returns (address[] memory) {
+        return _delegates[delegator];
+    }
 }
\ No newline at end of file
@@ -1,23 +1,36 @@
 package systemcontracts1
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
 	"github.com/DogeProtocol/dp/accounts/abi"
 	"github.com/DogeProtocol/dp/common"
-	"os"
+	"github.com/DogeProtocol/dp/params"
+	"math/big"
 	"strings"
 )
 
 var (
-	testContract    = "0x0000000000000000000000000000000000000000"
-	stakingContract = os.Getenv("GETH_STAKING_CONTRACT_ADDRESS")
-
 	stakingContractABI = "[{\"inputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"validatorId\",\"type\":\"bytes32\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validatorAddress\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"pubkey\",\"type\":\"bytes\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnNewDeposit\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnWithdrawKey\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"depositor\",\"type\":\"address\"}],\"name\":\"depositBalanceOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"depositCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"getDepositor\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"listValidator\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes\",\"name\":\"pubkey\",\"type\":\"bytes\"}],\"name\":\"newDeposit\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"totalDepositBalance\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"}],\"name\":\"withdraw\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]"
 	stakingContractBIN = "0x608060405234801561001057600080fd5b50600080819055506000600181905550610f478061002f6000396000f3fe6080604052600436106100705760003560e01c806375697e661161004e57806375697e6614610106578063dfcd068f14610131578063e8c0a0df1461014d578063fba13bd01461017857610070565b80632dfdf0b5146100755780632e1a7d4d146100a05780636e2baf48146100c9575b600080fd5b34801561008157600080fd5b5061008a6101b5565b6040516100979190610d9d565b60405180910390f35b3480156100ac57600080fd5b506100c760048036038101906100c29190610a67565b6101be565b005b3480156100d557600080fd5b506100f060048036038101906100eb91906109f9565b610377565b6040516100fd9190610c6d565b60405180910390f35b34801561011257600080fd5b5061011b6103c7565b6040516101289190610ccd565b60405180910390f35b61014b60048036038101906101469190610a22565b610455565b005b34801561015957600080fd5b50610162610820565b60405161016f9190610d9d565b60405180910390f35b34801561018457600080fd5b5061019f600480360381019061019a91906109f9565b61082a565b6040516101ac9190610d9d565b60405180910390f35b60008054905090565b80600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020541015610240576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161023790610d5d565b60405180910390fd5b6102558160015461087390919063ffffffff16565b6001819055506102ad81600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019081526020016000205461087390919063ffffffff16565b600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020819055503373ffffffffffffffffffffffffffffffffffffffff166108fc829081150290604051600060405180830381858888f19350505050158015610336573d6000803e3d6000fd5b507f4d4666331ec61727075c5624fde25f5510c566e528d0565f2a2263a23b70d81a3382434260405161036c9493929190610c88565b60405180910390a150565b6000806103838361088a565b905060006004600083815260200190815260200160002060009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1690508092505050919050565b6060600680548060200260200160405190810160405280929190818152602001828054801561044b57602002820191906000526020600020905b8160009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019060010190808311610401575b5050505050905090565b6000828290501161049b576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161049290610d3d565b60405180910390fd5b3373ffffffffffffffffffffffffffffffffffffffff1660046000600560003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff16815260200190815260200160002054815260200190815260200160002060009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff16141561057c576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161057390610d7d565b60405180910390fd5b61059260016000546108b190919063ffffffff16565b6000819055506105ad346001546108b190919063ffffffff16565b60018190555061060534600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020546108b190919063ffffffff16565b600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019081526020016000208190555060008282600190809261065d93929190610e1e565b60405161066b929190610c54565b604051809103902090506000610680826108cd565b9050600061068d8261088a565b905084846003600084815260200190815260200160002091906106b19291906108da565b50336004600083815260200190815260200160002060006101000a81548173ffffffffffffffffffffffffffffffffffffffff021916908373ffffffffffffffffffffffffffffffffffffffff16021790555080600560003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020819055506006829080600181540180825580915050600190039060005260206000200160009091909190916101000a81548173ffffffffffffffffffffffffffffffffffffffff021916908373ffffffffffffffffffffffffffffffffffffffff1602179055508173ffffffffffffffffffffffffffffffffffffffff16813373ffffffffffffffffffffffffffffffffffffffff167f9a1f4f083763f8508b19d4301c0110d2b47d99a8c5cf52c825c9e8cfea17f89c8888344342604051610811959493929190610cef565b60405180910390a45050505050565b6000600154905090565b6000600260008373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020549050919050565b60008282111561087f57fe5b818303905092915050565b600060608273ffffffffffffffffffffffffffffffffffffffff16901b60001b9050919050565b6000808284019050838110156108c357fe5b8091505092915050565b60008160001c9050919050565b828054600181600116156101000203166002900490600052602060002090601f0160209004810192826109105760008555610957565b82601f1061092957803560ff1916838001178555610957565b82800160010185558215610957579182015b8281111561095657823582559160200191906001019061093b565b5b5090506109649190610968565b5090565b5b80821115610981576000816000905550600101610969565b5090565b60008135905061099481610ee3565b92915050565b60008083601f8401126109ac57600080fd5b8235905067ffffffffffffffff8111156109c557600080fd5b6020830191508360018202830111156109dd57600080fd5b9250929050565b6000813590506109f381610efa565b92915050565b600060208284031215610a0b57600080fd5b6000610a1984828501610985565b91505092915050565b60008060208385031215610a3557600080fd5b600083013567ffffffffffffffff811115610a4f57600080fd5b610a5b8582860161099a565b92509250509250929050565b600060208284031215610a7957600080fd5b6000610a87848285016109e4565b91505092915050565b6000610a9c8383610ab7565b60208301905092915050565b610ab181610e8d565b82525050565b610ac081610e51565b82525050565b610acf81610e51565b82525050565b6000610ae082610dc8565b610aea8185610de0565b9350610af583610db8565b8060005b83811015610b26578151610b0d8882610a90565b9750610b1883610dd3565b925050600181019050610af9565b5085935050505092915050565b6000610b3f8385610df1565b9350610b4c838584610ec3565b610b5583610ed2565b840190509392505050565b6000610b6c8385610e02565b9350610b79838584610ec3565b82840190509392505050565b6000610b92601583610e0d565b91507f5075626c6963206b657920697320696e76616c696400000000000000000000006000830152602082019050919050565b6000610bd2601283610e0d565b91507f496e73756666696369656e742066756e647300000000000000000000000000006000830152602082019050919050565b6000610c12601583610e0d565b91507f53656e64657220616c72656164792065786973747300000000000000000000006000830152602082019050919050565b610c4e81610e83565b82525050565b6000610c61828486610b60565b91508190509392505050565b6000602082019050610c826000830184610ac6565b92915050565b6000608082019050610c9d6000830187610aa8565b610caa6020830186610c45565b610cb76040830185610c45565b610cc46060830184610c45565b95945050505050565b60006020820190508181036000830152610ce78184610ad5565b905092915050565b60006080820190508181036000830152610d0a818789610b33565b9050610d196020830186610c45565b610d266040830185610c45565b610d336060830184610c45565b9695505050505050565b60006020820190508181036000830152610d5681610b85565b9050919050565b60006020820190508181036000830152610d7681610bc5565b9050919050565b60006020820190508181036000830152610d9681610c05565b9050919050565b6000602082019050610db26000830184610c45565b92915050565b6000819050602082019050919050565b600081519050919050565b6000602082019050919050565b600082825260208201905092915050565b600082825260208201905092915050565b600081905092915050565b600082825260208201905092915050565b60008085851115610e2e57600080fd5b83861115610e3b57600080fd5b6001850283019150848603905094509492505050565b6000610e5c82610e63565b9050919050565b600073ffffffffffffffffffffffffffffffffffffffff82169050919050565b6000819050919050565b6000610e9882610e9f565b9050919050565b6000610eaa82610eb1565b9050919050565b6000610ebc82610e63565b9050919050565b82818337600083830152505050565b6000601f19601f8301169050919050565b610eec81610e51565b8114610ef757600080fd5b50565b610f0381610e83565b8114610f0e57600080fd5b5056fea2646970667358221220181b27743bf08caf1acd6da3c4bdbd6904a61bcc46be748c38e4a0ca4f2b4e5964736f6c63430007060033"
 
-	systemContracts      []string
-	systemContractsData  = make(map[string]*Contracts)
-	systemContractVerify map[common.Address]bool
+	// The staking contract as upgraded at the delegation fork: any account can
+	// bond funds to a validator and validators set the commission they keep on
+	// their delegators' rewards
+	stakingContractDelegationABI = "[{\"inputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnDelegate\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"validatorId\",\"type\":\"bytes32\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validatorAddress\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"pubkey\",\"type\":\"bytes\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnNewDeposit\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"percent\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnSetCommission\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnUndelegate\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnWithdrawKey\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"commissionOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"delegate\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"delegatedStakeOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"}],\"name\":\"delegationOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"depositor\",\"type\":\"address\"}],\"name\":\"depositBalanceOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"depositCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"getDepositor\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"}],\"name\":\"listDelegates\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"listDelegators\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"listValidator\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes\",\"name\":\"pubkey\",\"type\":\"bytes\"}],\"name\":\"newDeposit\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"percent\",\"type\":\"uint256\"}],\"name\":\"setCommission\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"totalDepositBalance\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"}],\"name\":\"undelegate\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"}],\"name\":\"withdraw\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]"
+	stakingContractDelegationBIN = "0x608060405234801561001057600080fd5b506000808190555060006001819055506116908061002f6000396000f3fe6080604052600436106100705760003560e01c806375697e661161004e57806375697e6614610106578063dfcd068f14610131578063e8c0a0df1461014d578063fba13bd01461017857610070565b80632dfdf0b5146100755780632e1a7d4d146100a05780636e2baf48146100c9575b610f47565b34801561008157600080fd5b5061008a6101b5565b6040516100979190610d9d565b60405180910390f35b3480156100ac57600080fd5b506100c760048036038101906100c29190610a67565b6101be565b005b3480156100d557600080fd5b506100f060048036038101906100eb91906109f9565b610377565b6040516100fd9190610c6d565b60405180910390f35b34801561011257600080fd5b5061011b6103c7565b6040516101289190610ccd565b60405180910390f35b61014b60048036038101906101469190610a22565b610455565b005b34801561015957600080fd5b50610162610820565b60405161016f9190610d9d565b60405180910390f35b34801561018457600080fd5b5061019f600480360381019061019a91906109f9565b61082a565b6040516101ac9190610d9d565b60405180910390f35b60008054905090565b80600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020541015610240576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161023790610d5d565b60405180910390fd5b6102558160015461087390919063ffffffff16565b6001819055506102ad81600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019081526020016000205461087390919063ffffffff16565b600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020819055503373ffffffffffffffffffffffffffffffffffffffff166108fc829081150290604051600060405180830381858888f19350505050158015610336573d6000803e3d6000fd5b507f4d4666331ec61727075c5624fde25f5510c566e528d0565f2a2263a23b70d81a3382434260405161036c9493929190610c88565b60405180910390a150565b6000806103838361088a565b905060006004600083815260200190815260200160002060009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1690508092505050919050565b6060600680548060200260200160405190810160405280929190818152602001828054801561044b57602002820191906000526020600020905b8160009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019060010190808311610401575b5050505050905090565b6000828290501161049b576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161049290610d3d565b60405180910390fd5b3373ffffffffffffffffffffffffffffffffffffffff1660046000600560003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff16815260200190815260200160002054815260200190815260200160002060009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff16141561057c576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161057390610d7d565b60405180910390fd5b61059260016000546108b190919063ffffffff16565b6000819055506105ad346001546108b190919063ffffffff16565b60018190555061060534600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020546108b190919063ffffffff16565b600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019081526020016000208190555060008282600190809261065d93929190610e1e565b60405161066b929190610c54565b604051809103902090506000610680826108cd565b9050600061068d8261088a565b905084846003600084815260200190815260200160002091906106b19291906108da565b50336004600083815260200190815260200160002060006101000a81548173ffffffffffffffffffffffffffffffffffffffff021916908373ffffffffffffffffffffffffffffffffffffffff16021790555080600560003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020819055506006829080600181540180825580915050600190039060005260206000200160009091909190916101000a81548173ffffffffffffffffffffffffffffffffffffffff021916908373ffffffffffffffffffffffffffffffffffffffff1602179055508173ffffffffffffffffffffffffffffffffffffffff16813373ffffffffffffffffffffffffffffffffffffffff167f9a1f4f083763f8508b19d4301c0110d2b47d99a8c5cf52c825c9e8cfea17f89c8888344342604051610811959493929190610cef565b60405180910390a45050505050565b6000600154905090565b6000600260008373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020549050919050565b60008282111561087f57fe5b818303905092915050565b600060608273ffffffffffffffffffffffffffffffffffffffff16901b60001b9050919050565b6000808284019050838110156108c357fe5b8091505092915050565b60008160001c9050919050565b828054600181600116156101000203166002900490600052602060002090601f0160209004810192826109105760008555610957565b82601f1061092957803560ff1916838001178555610957565b82800160010185558215610957579182015b8281111561095657823582559160200191906001019061093b565b5b5090506109649190610968565b5090565b5b80821115610981576000816000905550600101610969565b5090565b60008135905061099481610ee3565b92915050565b60008083601f8401126109ac57600080fd5b8235905067ffffffffffffffff8111156109c557600080fd5b6020830191508360018202830111156109dd57600080fd5b9250929050565b6000813590506109f381610efa565b92915050565b600060208284031215610a0b57600080fd5b6000610a1984828501610985565b91505092915050565b60008060208385031215610a3557600080fd5b600083013567ffffffffffffffff811115610a4f57600080fd5b610a5b8582860161099a565b92509250509250929050565b600060208284031215610a7957600080fd5b6000610a87848285016109e4565b91505092915050565b6000610a9c8383610ab7565b60208301905092915050565b610ab181610e8d565b82525050565b610ac081610e51565b82525050565b610acf81610e51565b82525050565b6000610ae082610dc8565b610aea8185610de0565b9350610af583610db8565b8060005b83811015610b26578151610b0d8882610a90565b9750610b1883610dd3565b925050600181019050610af9565b5085935050505092915050565b6000610b3f8385610df1565b9350610b4c838584610ec3565b610b5583610ed2565b840190509392505050565b6000610b6c8385610e02565b9350610b79838584610ec3565b82840190509392505050565b6000610b92601583610e0d565b91507f5075626c6963206b657920697320696e76616c696400000000000000000000006000830152602082019050919050565b6000610bd2601283610e0d565b91507f496e73756666696369656e742066756e647300000000000000000000000000006000830152602082019050919050565b6000610c12601583610e0d565b91507f53656e64657220616c72656164792065786973747300000000000000000000006000830152602082019050919050565b610c4e81610e83565b82525050565b6000610c61828486610b60565b91508190509392505050565b6000602082019050610c826000830184610ac6565b92915050565b6000608082019050610c9d6000830187610aa8565b610caa6020830186610c45565b610cb76040830185610c45565b610cc46060830184610c45565b95945050505050565b60006020820190508181036000830152610ce78184610ad5565b905092915050565b60006080820190508181036000830152610d0a818789610b33565b9050610d196020830186610c45565b610d266040830185610c45565b610d336060830184610c45565b9695505050505050565b60006020820190508181036000830152610d5681610b85565b9050919050565b60006020820190508181036000830152610d7681610bc5565b9050919050565b60006020820190508181036000830152610d9681610c05565b9050919050565b6000602082019050610db26000830184610c45565b92915050565b6000819050602082019050919050565b600081519050919050565b6000602082019050919050565b600082825260208201905092915050565b600082825260208201905092915050565b600081905092915050565b600082825260208201905092915050565b60008085851115610e2e57600080fd5b83861115610e3b57600080fd5b6001850283019150848603905094509492505050565b6000610e5c82610e63565b9050919050565b600073ffffffffffffffffffffffffffffffffffffffff82169050919050565b6000819050919050565b6000610e9882610e9f565b9050919050565b6000610eaa82610eb1565b9050919050565b6000610ebc82610e63565b9050919050565b82818337600083830152505050565b6000601f19601f8301169050919050565b610eec81610e51565b8114610ef757600080fd5b50565b610f0381610e83565b8114610f0e57600080fd5b5056fea2646970667358221220181b27743bf08caf1acd6da3c4bdbd6904a61bcc46be748c38e4a0ca4f2b4e5964736f6c634300070600335b600436106113f35760003560e01c80635c19a95c14610fb25780634d99dd16146110bf578063355e6b43146111da578063628da527146112635780639797d6c1146112d9578063661f479214611322578063a209f54c1461136b5780630ad6bfb1146113af576113f3565b602060043603126113f3576004358073ffffffffffffffffffffffffffffffffffffffff168114156113f35734156114805760048160601b600052602052604060002054156114bf576007816000526020526040600020336000526020526040600020805434810180911161140257905560088160005260205260406000208054348101809111611402579055611064600a826000526020526040600020600b836000526020526040600020336115bb565b611089600c336000526020526040600020600d336000526020526040600020836115bb565b346080524360a0524260c05280337fef3fb9b909804df84516b05376850222e582b5429dd37f9d00531f5ff650960460606080a3005b346113f357604060043603126113f3576004358073ffffffffffffffffffffffffffffffffffffffff168114156113f357602435801561148057600782600052602052604060002033600052602052604060002080548281106114fe578290038091556008836000526020526040600020805483818111611441579003905561118c57611167600a836000526020526040600020600b846000526020526040600020336115ed565b61118c600c336000526020526040600020600d336000526020526040600020846115ed565b806000600060006000843386156108fc02f1156113f85750806080524360a0524260c05281337ff1aab7af9e251548ce6f173614a4d9919b5abbe8c84f09e47e3aaa3e0963783e60606080a3005b346113f357602060043603126113f3576004356064811161153d5760053360005260205260406000205460048160005260205260406000205433141561157c5760601c6009816000526020526040600020829055816080524360a0524260c052807f8c22ea8f071f9d7867656e745126ebec0b35a67c5670cb14fed2a985f134af8c60606080a2005b346113f357604060043603126113f3576004358073ffffffffffffffffffffffffffffffffffffffff168114156113f3576024358073ffffffffffffffffffffffffffffffffffffffff168114156113f35760078260005260205260406000208160005260205260406000205460805260206080f35b346113f357602060043603126113f3576004358073ffffffffffffffffffffffffffffffffffffffff168114156113f35760089060005260205260406000205460805260206080f35b346113f357602060043603126113f3576004358073ffffffffffffffffffffffffffffffffffffffff168114156113f35760099060005260205260406000205460805260206080f35b346113f357602060043603126113f3576004358073ffffffffffffffffffffffffffffffffffffffff168114156113f357600a906000526020526040600020611651565b346113f357602060043603126113f3576004358073ffffffffffffffffffffffffffffffffffffffff168114156113f357600c906000526020526040600020611651565b600080fd5b3d6000803e3d6000fd5b6308c379a060e01b6080526020608452601b60a4527f536166654d6174683a206164646974696f6e206f766572666c6f77000000000060c45260646080fd5b6308c379a060e01b6080526020608452601e60a4527f536166654d6174683a207375627472616374696f6e206f766572666c6f77000060c45260646080fd5b6308c379a060e01b6080526020608452600e60a4527f496e76616c696420616d6f756e7400000000000000000000000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601160a4527f556e6b6e6f776e2076616c696461746f7200000000000000000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601760a4527f496e73756666696369656e742064656c65676174696f6e00000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601260a4527f496e76616c696420636f6d6d697373696f6e000000000000000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601960a4527f53656e646572206973206e6f742061206465706f7369746f720000000000000060c45260646080fd5b8181600052602052604060002080546115e7578354806001018083558555846000526020600020018290555b50505050565b818160005260205260406000208054801561164a57600185540385600052602060002060018303821461163757818101548060018503830155869060005260205260406000208390555b6000828201555085555060009055505050565b5050505050565b602060805280548060a05290600052602060002060005b8281101561168457808201548160200260c00152600101611668565b50506020026040016080f3"
+
+	// The staking contract as upgraded at the unbonding fork: withdrawn deposits
+	// and unbonded delegations are queued in the contract until the end of the
+	// unbonding period, then claimed
+	stakingContractUnbondingABI = "[{\"inputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnClaim\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnDelegate\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"validatorId\",\"type\":\"bytes32\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validatorAddress\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"pubkey\",\"type\":\"bytes\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnNewDeposit\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"percent\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnSetCommission\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnUndelegate\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnWithdrawKey\",\"type\":\"event\"},{\"inputs\":[],\"name\":\"claim\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"commissionOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"delegate\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"delegatedStakeOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"}],\"name\":\"delegationOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"depositor\",\"type\":\"address\"}],\"name\":\"depositBalanceOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"depositCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"getDepositor\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"}],\"name\":\"listDelegates\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"listDelegators\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"listValidator\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes\",\"name\":\"pubkey\",\"type\":\"bytes\"}],\"name\":\"newDeposit\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"percent\",\"type\":\"uint256\"}],\"name\":\"setCommission\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"totalDepositBalance\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"}],\"name\":\"undelegate\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"}],\"name\":\"withdraw\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]"
+	stakingContractUnbondingBIN = "0x608060405234801561001057600080fd5b50600080819055506000600181905550611a4e8061002f6000396000f3fe6080604052600436106100705760003560e01c806375697e661161004e57806375697e6614610106578063dfcd068f14610131578063e8c0a0df1461014d578063fba13bd01461017857610070565b80632dfdf0b5146100755780632e1a7d4d146113f15780636e2baf48146100c9575b610f47565b34801561008157600080fd5b5061008a6101b5565b6040516100979190610d9d565b60405180910390f35b3480156100ac57600080fd5b506100c760048036038101906100c29190610a67565b6101be565b005b3480156100d557600080fd5b506100f060048036038101906100eb91906109f9565b610377565b6040516100fd9190610c6d565b60405180910390f35b34801561011257600080fd5b5061011b6103c7565b6040516101289190610ccd565b60405180910390f35b61014b60048036038101906101469190610a22565b610455565b005b34801561015957600080fd5b50610162610820565b60405161016f9190610d9d565b60405180910390f35b34801561018457600080fd5b5061019f600480360381019061019a91906109f9565b61082a565b6040516101ac9190610d9d565b60405180910390f35b60008054905090565b80600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020541015610240576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161023790610d5d565b60405180910390fd5b6102558160015461087390919063ffffffff16565b6001819055506102ad81600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019081526020016000205461087390919063ffffffff16565b600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020819055503373ffffffffffffffffffffffffffffffffffffffff166108fc829081150290604051600060405180830381858888f19350505050158015610336573d6000803e3d6000fd5b507f4d4666331ec61727075c5624fde25f5510c566e528d0565f2a2263a23b70d81a3382434260405161036c9493929190610c88565b60405180910390a150565b6000806103838361088a565b905060006004600083815260200190815260200160002060009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1690508092505050919050565b6060600680548060200260200160405190810160405280929190818152602001828054801561044b57602002820191906000526020600020905b8160009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019060010190808311610401575b5050505050905090565b6000828290501161049b576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161049290610d3d565b60405180910390fd5b3373ffffffffffffffffffffffffffffffffffffffff1660046000600560003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff16815260200190815260200160002054815260200190815260200160002060009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff16141561057c576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161057390610d7d565b60405180910390fd5b61059260016000546108b190919063ffffffff16565b6000819055506105ad346001546108b190919063ffffffff16565b60018190555061060534600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020546108b190919063ffffffff16565b600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019081526020016000208190555060008282600190809261065d93929190610e1e565b60405161066b929190610c54565b604051809103902090506000610680826108cd565b9050600061068d8261088a565b905084846003600084815260200190815260200160002091906106b19291906108da565b50336004600083815260200190815260200160002060006101000a81548173ffffffffffffffffffffffffffffffffffffffff021916908373ffffffffffffffffffffffffffffffffffffffff16021790555080600560003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020819055506006829080600181540180825580915050600190039060005260206000200160009091909190916101000a81548173ffffffffffffffffffffffffffffffffffffffff021916908373ffffffffffffffffffffffffffffffffffffffff1602179055508173ffffffffffffffffffffffffffffffffffffffff16813373ffffffffffffffffffffffffffffffffffffffff167f9a1f4f083763f8508b19d4301c0110d2b47d99a8c5cf52c825c9e8cfea17f89c8888344342604051610811959493929190610cef565b60405180910390a45050505050565b6000600154905090565b6000600260008373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020549050919050565b60008282111561087f57fe5b818303905092915050565b600060608273ffffffffffffffffffffffffffffffffffffffff16901b60001b9050919050565b6000808284019050838110156108c357fe5b8091505092915050565b60008160001c9050919050565b828054600181600116156101000203166002900490600052602060002090601f0160209004810192826109105760008555610957565b82601f1061092957803560ff1916838001178555610957565b82800160010185558215610957579182015b8281111561095657823582559160200191906001019061093b565b5b5090506109649190610968565b5090565b5b80821115610981576000816000905550600101610969565b5090565b60008135905061099481610ee3565b92915050565b60008083601f8401126109ac57600080fd5b8235905067ffffffffffffffff8111156109c557600080fd5b6020830191508360018202830111156109dd57600080fd5b9250929050565b6000813590506109f381610efa565b92915050565b600060208284031215610a0b57600080fd5b6000610a1984828501610985565b91505092915050565b60008060208385031215610a3557600080fd5b600083013567ffffffffffffffff811115610a4f57600080fd5b610a5b8582860161099a565b92509250509250929050565b600060208284031215610a7957600080fd5b6000610a87848285016109e4565b91505092915050565b6000610a9c8383610ab7565b60208301905092915050565b610ab181610e8d565b82525050565b610ac081610e51565b82525050565b610acf81610e51565b82525050565b6000610ae082610dc8565b610aea8185610de0565b9350610af583610db8565b8060005b83811015610b26578151610b0d8882610a90565b9750610b1883610dd3565b925050600181019050610af9565b5085935050505092915050565b6000610b3f8385610df1565b9350610b4c838584610ec3565b610b5583610ed2565b840190509392505050565b6000610b6c8385610e02565b9350610b79838584610ec3565b82840190509392505050565b6000610b92601583610e0d565b91507f5075626c6963206b657920697320696e76616c696400000000000000000000006000830152602082019050919050565b6000610bd2601283610e0d565b91507f496e73756666696369656e742066756e647300000000000000000000000000006000830152602082019050919050565b6000610c12601583610e0d565b91507f53656e64657220616c72656164792065786973747300000000000000000000006000830152602082019050919050565b610c4e81610e83565b82525050565b6000610c61828486610b60565b91508190509392505050565b6000602082019050610c826000830184610ac6565b92915050565b6000608082019050610c9d6000830187610aa8565b610caa6020830186610c45565b610cb76040830185610c45565b610cc46060830184610c45565b95945050505050565b60006020820190508181036000830152610ce78184610ad5565b905092915050565b60006080820190508181036000830152610d0a818789610b33565b9050610d196020830186610c45565b610d266040830185610c45565b610d336060830184610c45565b9695505050505050565b60006020820190508181036000830152610d5681610b85565b9050919050565b60006020820190508181036000830152610d7681610bc5565b9050919050565b60006020820190508181036000830152610d9681610c05565b9050919050565b6000602082019050610db26000830184610c45565b92915050565b6000819050602082019050919050565b600081519050919050565b6000602082019050919050565b600082825260208201905092915050565b600082825260208201905092915050565b600081905092915050565b600082825260208201905092915050565b60008085851115610e2e57600080fd5b83861115610e3b57600080fd5b6001850283019150848603905094509492505050565b6000610e5c82610e63565b9050919050565b600073ffffffffffffffffffffffffffffffffffffffff82169050919050565b6000819050919050565b6000610e9882610e9f565b9050919050565b6000610eaa82610eb1565b9050919050565b6000610ebc82610e63565b9050919050565b82818337600083830152505050565b6000601f19601f8301169050919050565b610eec81610e51565b8114610ef757600080fd5b50565b610f0381610e83565b8114610f0e57600080fd5b5056fea2646970667358221220181b27743bf08caf1acd6da3c4bdbd6904a61bcc46be748c38e4a0ca4f2b4e5964736f6c634300070600335b600436106116d05760003560e01c80635c19a95c14610fbd5780634d99dd16146110ca578063355e6b43146111d8578063628da527146112615780639797d6c1146112d7578063661f479214611320578063a209f54c146113695780630ad6bfb1146113ad5780634e71d92d1461149a576116d0565b602060043603126116d0576004358073ffffffffffffffffffffffffffffffffffffffff168114156116d05734156117ff5760048160601b6000526020526040600020541561183e57600781600052602052604060002033600052602052604060002080543481018091116116df579055600881600052602052604060002080543481018091116116df57905561106f600a826000526020526040600020600b83600052602052604060002033611979565b611094600c336000526020526040600020600d33600052602052604060002083611979565b346080524360a0524260c05280337fef3fb9b909804df84516b05376850222e582b5429dd37f9d00531f5ff650960460606080a3005b346116d057604060043603126116d0576004358073ffffffffffffffffffffffffffffffffffffffff168114156116d05760243580156117ff576007826000526020526040600020336000526020526040600020805482811061187d57829003809155600883600052602052604060002080548381811161171e579003905561119757611172600a836000526020526040600020600b846000526020526040600020336119ab565b611197600c336000526020526040600020600d336000526020526040600020846119ab565b6111a23383836115bc565b806080524360a0524260c05281337ff1aab7af9e251548ce6f173614a4d9919b5abbe8c84f09e47e3aaa3e0963783e60606080a3005b346116d057602060043603126116d057600435606481116118bc576005336000526020526040600020546004816000526020526040600020543314156118fb5760601c6009816000526020526040600020829055816080524360a0524260c052807f8c22ea8f071f9d7867656e745126ebec0b35a67c5670cb14fed2a985f134af8c60606080a2005b346116d057604060043603126116d0576004358073ffffffffffffffffffffffffffffffffffffffff168114156116d0576024358073ffffffffffffffffffffffffffffffffffffffff168114156116d05760078260005260205260406000208160005260205260406000205460805260206080f35b346116d057602060043603126116d0576004358073ffffffffffffffffffffffffffffffffffffffff168114156116d05760089060005260205260406000205460805260206080f35b346116d057602060043603126116d0576004358073ffffffffffffffffffffffffffffffffffffffff168114156116d05760099060005260205260406000205460805260206080f35b346116d057602060043603126116d0576004358073ffffffffffffffffffffffffffffffffffffffff168114156116d057600a906000526020526040600020611a0f565b346116d057602060043603126116d0576004358073ffffffffffffffffffffffffffffffffffffffff168114156116d057600c906000526020526040600020611a0f565b346116d057602060043603126116d05760043580156117ff576002336000526020526040600020805482811061193a578290038091556001548281811161171e57900360015560053360005260205260406000205460601c906114575761145781611688565b6114623382846115bc565b336080528160a0524360c0524260e0527f4d4666331ec61727075c5624fde25f5510c566e528d0565f2a2263a23b70d81a60806080a1005b346116d057600e336000526020526040600020600f3360005260205260406000208054825483600052602060002060005b8284101561156d578360030282018060010154431061156b5780548281018091116116df579150806002015460008255600082600101556000826002015560128160005260205260406000203360005260205260406000208054600181811161171e57900380825561155d575061155f60108260005260205260406000206011836000526020526040600020336119ab565b505b505092600101926114cb565b505b838555806000600060006000843386156108fc02f1156116d557506080524360a0524260c052337fdc7a97cba2f8ed6e552db4cf7b0e185a10c361fdd5884e960552064caaa78ab360606080a2005b600e83600052602052604060002080548060010182556003029060005260206000200181815560135480156117c057804304600181018091116116df5760145481018091116116df57818102821561161c57828104821461161c5761175d565b9150508160010155829060020155506012816000526020526040600020826000526020526040600020805480600181018091116116df578255611683575061167f6010826000526020526040600020601183600052602052604060002084611979565b5050565b505050565b6006546006600052602060002060005b828110156116ca578181015484146116b257600101611698565b60018303820180548383015560009055600183036006555b50505050565b600080fd5b3d6000803e3d6000fd5b6308c379a060e01b6080526020608452601b60a4527f536166654d6174683a206164646974696f6e206f766572666c6f77000000000060c45260646080fd5b6308c379a060e01b6080526020608452601e60a4527f536166654d6174683a207375627472616374696f6e206f766572666c6f77000060c45260646080fd5b6308c379a060e01b6080526020608452602160a4527f536166654d6174683a206d756c7469706c69636174696f6e206f766572666c6f60c4527f770000000000000000000000000000000000000000000000000000000000000060e45260846080fd5b6308c379a060e01b6080526020608452601a60a4527f536166654d6174683a206469766973696f6e206279207a65726f00000000000060c45260646080fd5b6308c379a060e01b6080526020608452600e60a4527f496e76616c696420616d6f756e7400000000000000000000000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601160a4527f556e6b6e6f776e2076616c696461746f7200000000000000000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601760a4527f496e73756666696369656e742064656c65676174696f6e00000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601260a4527f496e76616c696420636f6d6d697373696f6e000000000000000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601960a4527f53656e646572206973206e6f742061206465706f7369746f720000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601260a4527f496e73756666696369656e742066756e6473000000000000000000000000000060c45260646080fd5b8181600052602052604060002080546119a5578354806001018083558555846000526020600020018290555b50505050565b8181600052602052604060002080548015611a085760018554038560005260206000206001830382146119f557818101548060018503830155869060005260205260406000208390555b6000828201555085555060009055505050565b5050505050565b602060805280548060a05290600052602060002060005b82811015611a4257808201548160200260c00152600101611a26565b50506020026040016080f3"
+
+	// The staking contract as upgraded at the jailing fork: depositors ask for
+	// their jailed validator to be listed again through the contract
+	stakingContractJailingABI = "[{\"inputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnClaim\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnDelegate\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"validatorId\",\"type\":\"bytes32\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validatorAddress\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"pubkey\",\"type\":\"bytes\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnNewDeposit\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"percent\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnSetCommission\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnUndelegate\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnUnjail\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"blockTime\",\"type\":\"uint256\"}],\"name\":\"OnWithdrawKey\",\"type\":\"event\"},{\"inputs\":[],\"name\":\"claim\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"commissionOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"delegate\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"delegatedStakeOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"}],\"name\":\"delegationOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"depositor\",\"type\":\"address\"}],\"name\":\"depositBalanceOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"depositCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"getDepositor\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"delegator\",\"type\":\"address\"}],\"name\":\"listDelegates\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"}],\"name\":\"listDelegators\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"listValidator\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"\",\"type\":\"address[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes\",\"name\":\"pubkey\",\"type\":\"bytes\"}],\"name\":\"newDeposit\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"percent\",\"type\":\"uint256\"}],\"name\":\"setCommission\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"totalDepositBalance\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"}],\"name\":\"undelegate\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"unjail\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"}],\"name\":\"withdraw\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]"
+	stakingContractJailingBIN = "0x608060405234801561001057600080fd5b50600080819055506000600181905550611ab78061002f6000396000f3fe6080604052600436106100705760003560e01c806375697e661161004e57806375697e6614610106578063dfcd068f14610131578063e8c0a0df1461014d578063fba13bd01461017857610070565b80632dfdf0b5146100755780632e1a7d4d146113fc5780636e2baf48146100c9575b610f47565b34801561008157600080fd5b5061008a6101b5565b6040516100979190610d9d565b60405180910390f35b3480156100ac57600080fd5b506100c760048036038101906100c29190610a67565b6101be565b005b3480156100d557600080fd5b506100f060048036038101906100eb91906109f9565b610377565b6040516100fd9190610c6d565b60405180910390f35b34801561011257600080fd5b5061011b6103c7565b6040516101289190610ccd565b60405180910390f35b61014b60048036038101906101469190610a22565b610455565b005b34801561015957600080fd5b50610162610820565b60405161016f9190610d9d565b60405180910390f35b34801561018457600080fd5b5061019f600480360381019061019a91906109f9565b61082a565b6040516101ac9190610d9d565b60405180910390f35b60008054905090565b80600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020541015610240576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161023790610d5d565b60405180910390fd5b6102558160015461087390919063ffffffff16565b6001819055506102ad81600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019081526020016000205461087390919063ffffffff16565b600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020819055503373ffffffffffffffffffffffffffffffffffffffff166108fc829081150290604051600060405180830381858888f19350505050158015610336573d6000803e3d6000fd5b507f4d4666331ec61727075c5624fde25f5510c566e528d0565f2a2263a23b70d81a3382434260405161036c9493929190610c88565b60405180910390a150565b6000806103838361088a565b905060006004600083815260200190815260200160002060009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1690508092505050919050565b6060600680548060200260200160405190810160405280929190818152602001828054801561044b57602002820191906000526020600020905b8160009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019060010190808311610401575b5050505050905090565b6000828290501161049b576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161049290610d3d565b60405180910390fd5b3373ffffffffffffffffffffffffffffffffffffffff1660046000600560003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff16815260200190815260200160002054815260200190815260200160002060009054906101000a900473ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff16141561057c576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161057390610d7d565b60405180910390fd5b61059260016000546108b190919063ffffffff16565b6000819055506105ad346001546108b190919063ffffffff16565b60018190555061060534600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020546108b190919063ffffffff16565b600260003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff1681526020019081526020016000208190555060008282600190809261065d93929190610e1e565b60405161066b929190610c54565b604051809103902090506000610680826108cd565b9050600061068d8261088a565b905084846003600084815260200190815260200160002091906106b19291906108da565b50336004600083815260200190815260200160002060006101000a81548173ffffffffffffffffffffffffffffffffffffffff021916908373ffffffffffffffffffffffffffffffffffffffff16021790555080600560003373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020819055506006829080600181540180825580915050600190039060005260206000200160009091909190916101000a81548173ffffffffffffffffffffffffffffffffffffffff021916908373ffffffffffffffffffffffffffffffffffffffff1602179055508173ffffffffffffffffffffffffffffffffffffffff16813373ffffffffffffffffffffffffffffffffffffffff167f9a1f4f083763f8508b19d4301c0110d2b47d99a8c5cf52c825c9e8cfea17f89c8888344342604051610811959493929190610cef565b60405180910390a45050505050565b6000600154905090565b6000600260008373ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001908152602001600020549050919050565b60008282111561087f57fe5b818303905092915050565b600060608273ffffffffffffffffffffffffffffffffffffffff16901b60001b9050919050565b6000808284019050838110156108c357fe5b8091505092915050565b60008160001c9050919050565b828054600181600116156101000203166002900490600052602060002090601f0160209004810192826109105760008555610957565b82601f1061092957803560ff1916838001178555610957565b82800160010185558215610957579182015b8281111561095657823582559160200191906001019061093b565b5b5090506109649190610968565b5090565b5b80821115610981576000816000905550600101610969565b5090565b60008135905061099481610ee3565b92915050565b60008083601f8401126109ac57600080fd5b8235905067ffffffffffffffff8111156109c557600080fd5b6020830191508360018202830111156109dd57600080fd5b9250929050565b6000813590506109f381610efa565b92915050565b600060208284031215610a0b57600080fd5b6000610a1984828501610985565b91505092915050565b60008060208385031215610a3557600080fd5b600083013567ffffffffffffffff811115610a4f57600080fd5b610a5b8582860161099a565b92509250509250929050565b600060208284031215610a7957600080fd5b6000610a87848285016109e4565b91505092915050565b6000610a9c8383610ab7565b60208301905092915050565b610ab181610e8d565b82525050565b610ac081610e51565b82525050565b610acf81610e51565b82525050565b6000610ae082610dc8565b610aea8185610de0565b9350610af583610db8565b8060005b83811015610b26578151610b0d8882610a90565b9750610b1883610dd3565b925050600181019050610af9565b5085935050505092915050565b6000610b3f8385610df1565b9350610b4c838584610ec3565b610b5583610ed2565b840190509392505050565b6000610b6c8385610e02565b9350610b79838584610ec3565b82840190509392505050565b6000610b92601583610e0d565b91507f5075626c6963206b657920697320696e76616c696400000000000000000000006000830152602082019050919050565b6000610bd2601283610e0d565b91507f496e73756666696369656e742066756e647300000000000000000000000000006000830152602082019050919050565b6000610c12601583610e0d565b91507f53656e64657220616c72656164792065786973747300000000000000000000006000830152602082019050919050565b610c4e81610e83565b82525050565b6000610c61828486610b60565b91508190509392505050565b6000602082019050610c826000830184610ac6565b92915050565b6000608082019050610c9d6000830187610aa8565b610caa6020830186610c45565b610cb76040830185610c45565b610cc46060830184610c45565b95945050505050565b60006020820190508181036000830152610ce78184610ad5565b905092915050565b60006080820190508181036000830152610d0a818789610b33565b9050610d196020830186610c45565b610d266040830185610c45565b610d336060830184610c45565b9695505050505050565b60006020820190508181036000830152610d5681610b85565b9050919050565b60006020820190508181036000830152610d7681610bc5565b9050919050565b60006020820190508181036000830152610d9681610c05565b9050919050565b6000602082019050610db26000830184610c45565b92915050565b6000819050602082019050919050565b600081519050919050565b6000602082019050919050565b600082825260208201905092915050565b600082825260208201905092915050565b600081905092915050565b600082825260208201905092915050565b60008085851115610e2e57600080fd5b83861115610e3b57600080fd5b6001850283019150848603905094509492505050565b6000610e5c82610e63565b9050919050565b600073ffffffffffffffffffffffffffffffffffffffff82169050919050565b6000819050919050565b6000610e9882610e9f565b9050919050565b6000610eaa82610eb1565b9050919050565b6000610ebc82610e63565b9050919050565b82818337600083830152505050565b6000601f19601f8301169050919050565b610eec81610e51565b8114610ef757600080fd5b50565b610f0381610e83565b8114610f0e57600080fd5b5056fea2646970667358221220181b27743bf08caf1acd6da3c4bdbd6904a61bcc46be748c38e4a0ca4f2b4e5964736f6c634300070600335b600436106117395760003560e01c80635c19a95c14610fc85780634d99dd16146110d5578063355e6b43146111e3578063628da5271461126c5780639797d6c1146112e2578063661f47921461132b578063a209f54c146113745780630ad6bfb1146113b85780634e71d92d146114a5578063f679d305146116db57611739565b60206004360312611739576004358073ffffffffffffffffffffffffffffffffffffffff168114156117395734156118685760048160601b600052602052604060002054156118a757600781600052602052604060002033600052602052604060002080543481018091116117485790556008816000526020526040600020805434810180911161174857905561107a600a826000526020526040600020600b836000526020526040600020336119e2565b61109f600c336000526020526040600020600d336000526020526040600020836119e2565b346080524360a0524260c05280337fef3fb9b909804df84516b05376850222e582b5429dd37f9d00531f5ff650960460606080a3005b346117395760406004360312611739576004358073ffffffffffffffffffffffffffffffffffffffff1681141561173957602435801561186857600782600052602052604060002033600052602052604060002080548281106118e657829003809155600883600052602052604060002080548381811161178757900390556111a25761117d600a836000526020526040600020600b84600052602052604060002033611a14565b6111a2600c336000526020526040600020600d33600052602052604060002084611a14565b6111ad3383836115c7565b806080524360a0524260c05281337ff1aab7af9e251548ce6f173614a4d9919b5abbe8c84f09e47e3aaa3e0963783e60606080a3005b3461173957602060043603126117395760043560648111611925576005336000526020526040600020546004816000526020526040600020543314156119645760601c6009816000526020526040600020829055816080524360a0524260c052807f8c22ea8f071f9d7867656e745126ebec0b35a67c5670cb14fed2a985f134af8c60606080a2005b346117395760406004360312611739576004358073ffffffffffffffffffffffffffffffffffffffff16811415611739576024358073ffffffffffffffffffffffffffffffffffffffff168114156117395760078260005260205260406000208160005260205260406000205460805260206080f35b346117395760206004360312611739576004358073ffffffffffffffffffffffffffffffffffffffff168114156117395760089060005260205260406000205460805260206080f35b346117395760206004360312611739576004358073ffffffffffffffffffffffffffffffffffffffff168114156117395760099060005260205260406000205460805260206080f35b346117395760206004360312611739576004358073ffffffffffffffffffffffffffffffffffffffff1681141561173957600a906000526020526040600020611a78565b346117395760206004360312611739576004358073ffffffffffffffffffffffffffffffffffffffff1681141561173957600c906000526020526040600020611a78565b34611739576020600436031261173957600435801561186857600233600052602052604060002080548281106119a3578290038091556001548281811161178757900360015560053360005260205260406000205460601c906114625761146281611693565b61146d3382846115c7565b336080528160a0524360c0524260e0527f4d4666331ec61727075c5624fde25f5510c566e528d0565f2a2263a23b70d81a60806080a1005b3461173957600e336000526020526040600020600f3360005260205260406000208054825483600052602060002060005b8284101561157857836003028201806001015443106115765780548281018091116117485791508060020154600082556000826001015560008260020155601281600052602052604060002033600052602052604060002080546001818111611787579003808255611568575061156a6010826000526020526040600020601183600052602052604060002033611a14565b505b505092600101926114d6565b505b838555806000600060006000843386156108fc02f11561173e57506080524360a0524260c052337fdc7a97cba2f8ed6e552db4cf7b0e185a10c361fdd5884e960552064caaa78ab360606080a2005b600e83600052602052604060002080548060010182556003029060005260206000200181815560135480156118295780430460018101809111611748576014548101809111611748578181028215611627578281048214611627576117c6565b91505081600101558290600201555060128160005260205260406000208260005260205260406000208054806001810180911161174857825561168e575061168a60108260005260205260406000206011836000526020526040600020846119e2565b5050565b505050565b6006546006600052602060002060005b828110156116d5578181015484146116bd576001016116a3565b60018303820180548383015560009055600183036006555b50505050565b34611739576005336000526020526040600020546004816000526020526040600020543314156119645760601c436080524260a052807f09ce9c785f7e7d8067841d61d7c961acc004116fe4aa02789138fa335b6ba3df60406080a2005b600080fd5b3d6000803e3d6000fd5b6308c379a060e01b6080526020608452601b60a4527f536166654d6174683a206164646974696f6e206f766572666c6f77000000000060c45260646080fd5b6308c379a060e01b6080526020608452601e60a4527f536166654d6174683a207375627472616374696f6e206f766572666c6f77000060c45260646080fd5b6308c379a060e01b6080526020608452602160a4527f536166654d6174683a206d756c7469706c69636174696f6e206f766572666c6f60c4527f770000000000000000000000000000000000000000000000000000000000000060e45260846080fd5b6308c379a060e01b6080526020608452601a60a4527f536166654d6174683a206469766973696f6e206279207a65726f00000000000060c45260646080fd5b6308c379a060e01b6080526020608452600e60a4527f496e76616c696420616d6f756e7400000000000000000000000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601160a4527f556e6b6e6f776e2076616c696461746f7200000000000000000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601760a4527f496e73756666696369656e742064656c65676174696f6e00000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601260a4527f496e76616c696420636f6d6d697373696f6e000000000000000000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601960a4527f53656e646572206973206e6f742061206465706f7369746f720000000000000060c45260646080fd5b6308c379a060e01b6080526020608452601260a4527f496e73756666696369656e742066756e6473000000000000000000000000000060c45260646080fd5b818160005260205260406000208054611a0e578354806001018083558555846000526020600020018290555b50505050565b8181600052602052604060002080548015611a71576001855403856000526020600020600183038214611a5e57818101548060018503830155869060005260205260406000208390555b6000828201555085555060009055505050565b5050505050565b602060805280548060a05290600052602060002060005b82811015611aab57808201548160200260c00152600101611a8f565b50506020026040016080f3"
 )
 
 type Contracts struct {
@@ -62,65 +75,102 @@ var (
 	}
 )
 
-func init() {
-	systemContracts = []string{
-		stakingContract,
+// GetContracts returns the addresses of the system contracts configured for
+// the chain.
+func GetContracts(config *params.ChainConfig) []string {
+	if IsStakingContract(config) != nil {
+		return nil
 	}
+	return []string{GetStakingContract_Address_String(config)}
+}
 
-	systemContractsData[stakingContract] = &Contracts{
-		ContractAddressString: stakingContract,
-		Contracts: &Contract{
-			ContractAddress: common.HexToAddress(stakingContract),
-			ABI:             stakingContractABI,
-			BIN:             stakingContractBIN,
-			Methods:         methods_collection,
-		},
+func GetContract_Data(config *params.ChainConfig, contract string) *Contract {
+	if IsStakingContract(config) != nil || common.HexToAddress(contract) != GetStakingContract_Address(config) {
+		return nil
 	}
-
-	systemContractVerify = map[common.Address]bool{
-		common.HexToAddress(stakingContract): true,
+	return &Contract{
+		ContractAddress: GetStakingContract_Address(config),
+		ABI:             stakingContractJailingABI,
+		BIN:             stakingContractJailingBIN,
+		Methods:         methods_collection,
 	}
 }
 
-func GetContracts() []string {
-	return systemContracts
+func GetContractVerify(config *params.ChainConfig, address common.Address) bool {
+	return IsStakingContract(config) == nil && address == GetStakingContract_Address(config)
 }
 
-func GetContract_Data(contract string) *Contract {
-	return systemContractsData[contract].Contracts
+// IsStakingContract returns an error if the chain configuration doesn't declare
+// a staking contract.
+func IsStakingContract(config *params.ChainConfig) error {
+	if GetStakingContract_Address(config) == (common.Address{}) {
+		return fmt.Errorf("Staking contract is not configured")
+	}
+	return nil
 }
 
-func GetContractVerify(address common.Address) bool {
-	return systemContractVerify[address]
+func GetStakingContract_Address_String(config *params.ChainConfig) string {
+	return GetStakingContract_Address(config).Hex()
 }
 
-func IsStakingContract() error {
-	if len(stakingContract) < 40 {
-		return fmt.Errorf("Staking contractor is not found")
+func GetStakingContract_Address(config *params.ChainConfig) common.Address {
+	if config == nil || config.ProofOfStake == nil {
+		return common.Address{}
 	}
-	return nil
+	return config.ProofOfStake.StakingContract
 }
 
-func GetStakingContract_Address_String() string {
-	return systemContractsData[stakingContract].ContractAddressString
+// GetStakingContract_ABI returns the ABI of the latest staking contract, which
+// extends the ABI of every earlier version.
+func GetStakingContract_ABI() abi.ABI {
+	abi, _ := abi.JSON(strings.NewReader(stakingContractJailingABI))
+	return abi
 }
 
-func GetStakingContract_Address() common.Address {
-	return systemContractsData[stakingContract].Contracts.ContractAddress
+// GetStakingContract_Code returns the runtime code of the staking contract, as
+// deployed by its creation code, to predeploy it at genesis.
+func GetStakingContract_Code() []byte {
+	return runtimeCode(stakingContractBIN)
 }
 
-func GetStakingContract_ABI() abi.ABI {
-	s := systemContractsData[stakingContract].Contracts.ABI
-	abi, _ := abi.JSON(strings.NewReader(s))
-	return abi
+// GetStakingContract_CodeAt returns the runtime code of the staking contract in
+// force at the given block, following the staking forks of the chain.
+func GetStakingContract_CodeAt(config *params.ChainConfig, number *big.Int) []byte {
+	if config.IsJailing(number) {
+		return runtimeCode(stakingContractJailingBIN)
+	}
+	if config.IsUnbonding(number) {
+		return runtimeCode(stakingContractUnbondingBIN)
+	}
+	if config.IsDelegation(number) {
+		return runtimeCode(stakingContractDelegationBIN)
+	}
+	return runtimeCode(stakingContractBIN)
+}
+
+// runtimeCode extracts the runtime code from the creation code of a contract.
+func runtimeCode(creation string) []byte {
+	bin, _ := hex.DecodeString(strings.TrimPrefix(creation, "0x"))
+
+	// The creation code ends by copying the runtime code that follows it into
+	// memory and returning it: CODECOPY, RETURN and an INVALID terminator
+	end := bytes.Index(bin, []byte{0x60, 0x00, 0x39, 0x60, 0x00, 0xf3, 0xfe})
+	if end < 0 {
+		return nil
+	}
+	return bin[end+7:]
 }
 
 // Validators method
 
 func GetContract_Method_ListValidator() string {
-	return systemContractsData[stakingContract].Contracts.Methods.Validators.ListValidator
+	return methods_collection.Validators.ListValidator
 }
 
 func GetContract_Method_GetDepositor() string {
-	return systemContractsData[stakingContract].Contracts.Methods.Validators.GetDepositor
+	return methods_collection.Validators.GetDepositor
+}
+
+func GetContract_Method_GetDepositBalanceOf() string {
+	return methods_collection.Validators.GetDepositBalanceOf
 }
@@ -0,0 +1,89 @@
+package systemcontracts1
+
+import (
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+)
+
+// Delegation is implemented by the staking contract from the delegation fork
+// on: any account can bond funds to an existing validator, and the depositor
+// backing a validator sets the share of its delegators' rewards it keeps.
+type Delegation struct {
+	Delegate         string `json:"Delegate"`
+	Undelegate       string `json:"Undelegate"`
+	SetCommission    string `json:"SetCommission"`
+	DelegationOf     string `json:"DelegationOf"`
+	DelegatedStakeOf string `json:"DelegatedStakeOf"`
+	CommissionOf     string `json:"CommissionOf"`
+	ListDelegators   string `json:"ListDelegators"`
+	ListDelegates    string `json:"ListDelegates"`
+}
+
+var (
+	delegation_methods = &Delegation{
+		Delegate:         "delegate",
+		Undelegate:       "undelegate",
+		SetCommission:    "setCommission",
+		DelegationOf:     "delegationOf",
+		DelegatedStakeOf: "delegatedStakeOf",
+		CommissionOf:     "commissionOf",
+		ListDelegators:   "listDelegators",
+		ListDelegates:    "listDelegates",
+	}
+)
+
+// Delegation methods
+
+func GetContract_Method_Delegate() string {
+	return delegation_methods.Delegate
+}
+
+func GetContract_Method_Undelegate() string {
+	return delegation_methods.Undelegate
+}
+
+func GetContract_Method_SetCommission() string {
+	return delegation_methods.SetCommission
+}
+
+func GetContract_Method_DelegationOf() string {
+	return delegation_methods.DelegationOf
+}
+
+func GetContract_Method_DelegatedStakeOf() string {
+	return delegation_methods.DelegatedStakeOf
+}
+
+func GetContract_Method_CommissionOf() string {
+	return delegation_methods.CommissionOf
+}
+
+func GetContract_Method_ListDelegators() string {
+	return delegation_methods.ListDelegators
+}
+
+func GetContract_Method_ListDelegates() string {
+	return delegation_methods.ListDelegates
+}
+
+// PackDelegate returns the payload of a staking contract call bonding its value
+// to the given validator.
+func PackDelegate(validator common.Address) ([]byte, error) {
+	abiData := GetStakingContract_ABI()
+	return abiData.Pack(GetContract_Method_Delegate(), validator)
+}
+
+// PackUndelegate returns the payload of a staking contract call unbonding the
+// given amount from a validator.
+func PackUndelegate(validator common.Address, amount *big.Int) ([]byte, error) {
+	abiData := GetStakingContract_ABI()
+	return abiData.Pack(GetContract_Method_Undelegate(), validator, amount)
+}
+
+// PackSetCommission returns the payload of a staking contract call setting the
+// share of the delegators' rewards, in percent, kept by the sender's validator.
+func PackSetCommission(percent *big.Int) ([]byte, error) {
+	abiData := GetStakingContract_ABI()
+	return abiData.Pack(GetContract_Method_SetCommission(), percent)
+}
@@ -0,0 +1,27 @@
+package systemcontracts1
+
+// Jailing is implemented by the staking contract from the jailing fork on: the
+// depositor of a jailed validator asks for it to be listed again, which the
+// consensus engine grants once the jail period is over.
+type Jailing struct {
+	Unjail string `json:"Unjail"`
+}
+
+var (
+	jailing_methods = &Jailing{
+		Unjail: "unjail",
+	}
+)
+
+// Jailing methods
+
+func GetContract_Method_Unjail() string {
+	return jailing_methods.Unjail
+}
+
+// PackUnjail returns the payload of a staking contract call asking for the
+// sender's jailed validator to be listed again.
+func PackUnjail() ([]byte, error) {
+	abiData := GetStakingContract_ABI()
+	return abiData.Pack(GetContract_Method_Unjail())
+}
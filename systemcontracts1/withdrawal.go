@@ -0,0 +1,44 @@
+package systemcontracts1
+
+import (
+	"math/big"
+)
+
+// Withdrawal is implemented by the staking contract from the unbonding fork on:
+// withdrawn deposits and unbonded delegations are queued until the end of the
+// unbonding period, after which their owner claims them.
+type Withdrawal struct {
+	Withdraw string `json:"Withdraw"`
+	Claim    string `json:"Claim"`
+}
+
+var (
+	withdrawal_methods = &Withdrawal{
+		Withdraw: "withdraw",
+		Claim:    "claim",
+	}
+)
+
+// Withdrawal methods
+
+func GetContract_Method_Withdraw() string {
+	return withdrawal_methods.Withdraw
+}
+
+func GetContract_Method_Claim() string {
+	return withdrawal_methods.Claim
+}
+
+// PackWithdraw returns the payload of a staking contract call withdrawing the
+// given amount of the sender's deposit into the withdrawal queue.
+func PackWithdraw(value *big.Int) ([]byte, error) {
+	abiData := GetStakingContract_ABI()
+	return abiData.Pack(GetContract_Method_Withdraw(), value)
+}
+
+// PackClaim returns the payload of a staking contract call paying out the
+// sender's matured withdrawals.
+func PackClaim() ([]byte, error) {
+	abiData := GetStakingContract_ABI()
+	return abiData.Pack(GetContract_Method_Claim())
+}
@@ -0,0 +1,71 @@
+//go:build gofuzz
+// +build gofuzz
+
+package falcon
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/DogeProtocol/dp/crypto/falcon"
+	"github.com/DogeProtocol/dp/crypto/hybrid"
+	fuzz "github.com/google/gofuzz"
+)
+
+type keys struct {
+	falconPub, falconPriv []byte
+	hybridPub, hybridPriv []byte
+}
+
+var (
+	testKeys     keys
+	testKeysOnce sync.Once
+)
+
+func loadKeys() keys {
+	testKeysOnce.Do(func() {
+		var err error
+		if testKeys.falconPub, testKeys.falconPriv, err = falcon.GenerateKey(); err != nil {
+			panic(err)
+		}
+		if testKeys.hybridPub, testKeys.hybridPriv, err = hybrid.GenerateKey(); err != nil {
+			panic(err)
+		}
+	})
+	return testKeys
+}
+
+// Fuzz signs a fuzzed message, corrupts the signature at fuzzed positions and
+// checks that the C and the pure Go verifiers agree on the result.
+func Fuzz(input []byte) int {
+	var (
+		fuzzer    = fuzz.NewFromGoFuzz(input)
+		keys      = loadKeys()
+		msg       [32]byte
+		flips     []uint16
+		useHybrid bool
+	)
+	fuzzer.Fuzz(&msg)
+	fuzzer.Fuzz(&flips)
+	fuzzer.Fuzz(&useHybrid)
+
+	sign, verifyC, verifyGo, priv, pub := falcon.Sign, falcon.Verify, falcon.VerifyPure, keys.falconPriv, keys.falconPub
+	if useHybrid {
+		sign, verifyC, verifyGo, priv, pub = hybrid.Sign, hybrid.Verify, hybrid.VerifyPure, keys.hybridPriv, keys.hybridPub
+	}
+	sig, err := sign(priv, msg[:])
+	if err != nil {
+		panic(err)
+	}
+	for _, flip := range flips {
+		sig[int(flip>>3)%len(sig)] ^= 1 << (flip & 7)
+	}
+	errC, errGo := verifyC(msg[:], sig, pub), verifyGo(msg[:], sig, pub)
+	if errC != errGo {
+		panic(fmt.Sprintf("verifiers disagree (hybrid %v): C %v, Go %v, signature %x", useHybrid, errC, errGo, sig))
+	}
+	if errC != nil {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,14 @@
+//go:build gofuzz && cgo
+// +build gofuzz,cgo
+
+// The fuzzer compares the C and the pure Go verifiers, signing with the C
+// implementation, so it can only run in cgo builds.
+
+package falcon
+
+import "testing"
+
+func TestFuzzer(t *testing.T) {
+	test := "00000000N0000000/R00000000000000000U0000S0000000mkhP000000000000000U"
+	Fuzz([]byte(test))
+}